@@ -4,9 +4,12 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	srv "github-hub/internal/server"
@@ -30,7 +33,19 @@ func main() {
 	token := cfg.Token
 	defaultUser := cfg.DefaultUser
 	downloadTO := cfg.DownloadTimeout
+	cleanupInterval := cfg.CleanupInterval
+	ttl := cfg.TTL
+	githubAPIURL := cfg.GitHubAPIURL
+	githubCodeloadURL := cfg.GitHubCodeloadURL
+	githubURL := cfg.GitHubURL
+	fallbackBranch := cfg.FallbackBranch
+	missWebhookURL := cfg.MissWebhookURL
+	missWebhookMinBytes := cfg.MissWebhookMinBytes
 	showVersion := false
+	debug := false
+	var userQuota, maxCacheBytes int64
+	shutdownGrace := defaultShutdownGrace
+	readyDelay := defaultReadyDelay
 
 	flag.StringVar(&configPath, "config", configPath, "path to server config (yaml or json)")
 	flag.StringVar(&addr, "addr", addr, "listen address (e.g., :8080)")
@@ -38,7 +53,20 @@ func main() {
 	flag.StringVar(&token, "github-token", token, "GitHub token for higher rate limits (env: GITHUB_TOKEN)")
 	flag.StringVar(&defaultUser, "default-user", defaultUser, "default user grouping when client user is empty")
 	flag.BoolVar(&showVersion, "version", showVersion, "print version and exit")
+	flag.BoolVar(&debug, "debug", debug, "log remaining GitHub API rate-limit quota after each API call")
 	flag.StringVar(&downloadTO, "download-timeout", downloadTO, "timeout for download/package handlers (e.g., 10m, 5m)")
+	flag.StringVar(&cleanupInterval, "cleanup-interval", cleanupInterval, "how often expired cache entries are removed (e.g., 1m, 1h)")
+	flag.StringVar(&ttl, "ttl", ttl, "remove cached entries not accessed within this duration (e.g., 24h, 168h)")
+	flag.StringVar(&githubAPIURL, "github-api-url", githubAPIURL, "GitHub REST API base URL (default: https://api.github.com; Enterprise: https://HOST/api/v3)")
+	flag.StringVar(&githubCodeloadURL, "github-codeload-url", githubCodeloadURL, "GitHub codeload base URL for zip archives (default: https://codeload.github.com)")
+	flag.StringVar(&githubURL, "github-url", githubURL, "GitHub git clone base URL (default: https://github.com)")
+	flag.StringVar(&fallbackBranch, "fallback-branch", fallbackBranch, "branch to use when the default branch cannot be resolved (default: most recently cached branch)")
+	flag.StringVar(&missWebhookURL, "miss-webhook-url", missWebhookURL, "optional URL notified (POST JSON) when a repo archive is fetched fresh")
+	flag.Int64Var(&missWebhookMinBytes, "miss-webhook-min-bytes", missWebhookMinBytes, "only notify the miss webhook for archives at least this many bytes")
+	flag.Int64Var(&userQuota, "user-quota-bytes", 0, "max bytes of cached repo zips per user (0 = unlimited)")
+	flag.Int64Var(&maxCacheBytes, "max-cache-bytes", 0, "janitor removes least recently used repo archives until the cache is under this size (0 = unlimited)")
+	flag.DurationVar(&shutdownGrace, "shutdown-grace", shutdownGrace, "how long to wait for in-flight requests on SIGINT/SIGTERM before closing them")
+	flag.DurationVar(&readyDelay, "ready-delay", readyDelay, "on SIGINT/SIGTERM, how long /readyz reports 503 before the listener stops accepting connections")
 	flag.Parse()
 
 	if showVersion {
@@ -51,10 +79,47 @@ func main() {
 		log.Fatalf("invalid download-timeout: %v", err)
 	}
 
-	s, err := srv.NewServer(root, defaultUser, token, dlTimeout)
+	cleanupEvery, err := time.ParseDuration(strings.TrimSpace(cleanupInterval))
+	if err != nil || cleanupEvery <= 0 {
+		log.Fatalf("invalid cleanup-interval: %v", err)
+	}
+	cacheTTL, err := time.ParseDuration(strings.TrimSpace(ttl))
+	if err != nil || cacheTTL <= 0 {
+		log.Fatalf("invalid ttl: %v", err)
+	}
+	if maxCacheBytes < 0 {
+		log.Fatalf("invalid max-cache-bytes: %d", maxCacheBytes)
+	}
+	if shutdownGrace <= 0 {
+		log.Fatalf("invalid shutdown-grace: %s", shutdownGrace)
+	}
+	if readyDelay < 0 {
+		log.Fatalf("invalid ready-delay: %s", readyDelay)
+	}
+
+	s, err := srv.NewServerWithOptions(srv.Options{
+		Root:            root,
+		DefaultUser:     defaultUser,
+		GitHubToken:     token,
+		DownloadTimeout: dlTimeout,
+		CleanupInterval: cleanupEvery,
+		TTL:             cacheTTL,
+		MaxCacheBytes:   maxCacheBytes,
+
+		GitHubAPIURL:      githubAPIURL,
+		GitHubCodeloadURL: githubCodeloadURL,
+		GitHubURL:         githubURL,
+		FallbackBranch:    fallbackBranch,
+		Debug:             debug,
+	})
 	if err != nil {
 		log.Fatalf("init server: %v", err)
 	}
+	if userQuota < 0 {
+		log.Fatalf("invalid user-quota-bytes: %d", userQuota)
+	}
+	s.SetUserQuota(userQuota)
+	s.SetMissWebhook(missWebhookURL, missWebhookMinBytes)
 
 	mux := http.NewServeMux()
 	s.RegisterRoutes(mux)
@@ -64,8 +129,26 @@ func main() {
 		Handler:           logging(mux),
 		ReadHeaderTimeout: 10 * time.Second,
 	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("listen: %v", err)
+	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
 	fmt.Printf("ghh-server listening on %s, root=%s, default_user=%s\n", addr, root, defaultUser)
-	log.Fatal(httpSrv.ListenAndServe())
+	// Fail readiness first so load balancers stop sending traffic while the
+	// listener still accepts it, then drain.
+	beforeDrain := func() {
+		s.MarkNotReady()
+		time.Sleep(readyDelay)
+	}
+	_, err = serveUntilSignal(httpSrv, ln, shutdownGrace, beforeDrain, stop)
+	s.Shutdown()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("ghh-server stopped")
 }
 
 type statusRecorder struct {
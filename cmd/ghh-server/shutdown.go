@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultShutdownGrace = 2 * time.Minute
+
+// defaultReadyDelay is how long /readyz reports 503 before the listener closes,
+// giving load balancers time to stop routing new requests to this instance.
+const defaultReadyDelay = 5 * time.Second
+
+// connTracker counts connections that are serving a request, so shutdown can
+// report how many in-flight downloads it waited for.
+type connTracker struct {
+	mu     sync.Mutex
+	active map[net.Conn]struct{}
+}
+
+func newConnTracker() *connTracker {
+	return &connTracker{active: make(map[net.Conn]struct{})}
+}
+
+// track is installed as http.Server.ConnState.
+func (t *connTracker) track(c net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch state {
+	case http.StateActive:
+		t.active[c] = struct{}{}
+	case http.StateIdle, http.StateHijacked, http.StateClosed:
+		delete(t.active, c)
+	}
+}
+
+// Active returns the number of connections currently serving a request.
+func (t *connTracker) Active() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.active)
+}
+
+// serveUntilSignal serves on ln until it fails or a signal arrives on stop.
+// On a signal it first runs beforeDrain (if non-nil) while still serving, then
+// stops accepting connections and waits up to grace for in-flight requests;
+// connections still open after that are closed. It returns the number of
+// connections that finished within the grace period.
+func serveUntilSignal(httpSrv *http.Server, ln net.Listener, grace time.Duration, beforeDrain func(), stop <-chan os.Signal) (int, error) {
+	tracker := newConnTracker()
+	httpSrv.ConnState = tracker.track
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- httpSrv.Serve(ln) }()
+
+	select {
+	case err := <-errCh:
+		return 0, err
+	case sig := <-stop:
+		if beforeDrain != nil {
+			beforeDrain()
+		}
+		inflight := tracker.Active()
+		fmt.Printf("received %s, draining %d active connection(s) (grace %s)\n", sig, inflight, grace)
+
+		ctx, cancel := context.WithTimeout(context.Background(), grace)
+		defer cancel()
+		err := httpSrv.Shutdown(ctx)
+		left := tracker.Active()
+		if err != nil {
+			_ = httpSrv.Close()
+		}
+		drained := inflight - left
+		if drained < 0 {
+			drained = 0
+		}
+		fmt.Printf("drained %d connection(s), %d closed after grace period\n", drained, left)
+		if serveErr := <-errCh; serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+			return drained, serveErr
+		}
+		if err != nil {
+			return drained, fmt.Errorf("shutdown: %w", err)
+		}
+		return drained, nil
+	}
+}
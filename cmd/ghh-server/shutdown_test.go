@@ -0,0 +1,133 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestServeUntilSignal_DrainsInFlightRequests(t *testing.T) {
+	tests := []struct {
+		name        string
+		grace       time.Duration
+		release     bool // let the handler finish while draining
+		wantDrained int
+		wantErr     bool
+	}{
+		{name: "request finishes within grace", grace: 5 * time.Second, release: true, wantDrained: 1},
+		{name: "grace period expires", grace: 50 * time.Millisecond, wantDrained: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			release := make(chan struct{})
+			httpSrv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				select {
+				case <-release:
+				case <-r.Context().Done():
+				}
+				_, _ = io.WriteString(w, "done")
+			})}
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stop := make(chan os.Signal, 1)
+			type result struct {
+				drained int
+				err     error
+			}
+			done := make(chan result, 1)
+			go func() {
+				n, err := serveUntilSignal(httpSrv, ln, tt.grace, nil, stop)
+				done <- result{n, err}
+			}()
+
+			respCh := make(chan int, 1)
+			go func() {
+				resp, err := http.Get("http://" + ln.Addr().String() + "/")
+				if err != nil {
+					respCh <- 0
+					return
+				}
+				_ = resp.Body.Close()
+				respCh <- resp.StatusCode
+			}()
+
+			<-started
+			stop <- syscall.SIGTERM
+			if tt.release {
+				time.Sleep(20 * time.Millisecond)
+				close(release)
+			}
+
+			select {
+			case res := <-done:
+				if res.drained != tt.wantDrained {
+					t.Errorf("drained=%d, want %d", res.drained, tt.wantDrained)
+				}
+				if (res.err != nil) != tt.wantErr {
+					t.Errorf("err=%v, wantErr=%v", res.err, tt.wantErr)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("serveUntilSignal did not return")
+			}
+			if code := <-respCh; tt.release && code != http.StatusOK {
+				t.Errorf("in-flight request got status %d, want 200", code)
+			}
+			if !tt.release {
+				close(release)
+			}
+		})
+	}
+}
+
+func TestServeUntilSignal_BeforeDrainRunsWhileServing(t *testing.T) {
+	httpSrv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	})}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The listener must still accept requests while beforeDrain runs, so a
+	// load balancer polling /readyz sees the 503 before connections are refused.
+	beforeStatus := 0
+	beforeDrain := func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/")
+		if err != nil {
+			t.Errorf("request during beforeDrain: %v", err)
+			return
+		}
+		_ = resp.Body.Close()
+		beforeStatus = resp.StatusCode
+	}
+
+	stop := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	go func() {
+		_, err := serveUntilSignal(httpSrv, ln, time.Second, beforeDrain, stop)
+		done <- err
+	}()
+	stop <- syscall.SIGTERM
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("serveUntilSignal: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serveUntilSignal did not return")
+	}
+	if beforeStatus != http.StatusOK {
+		t.Fatalf("request during beforeDrain got status %d, want 200", beforeStatus)
+	}
+}
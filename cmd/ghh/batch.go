@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const defaultBatchConcurrency = 4
+
+// errBatchSkipped marks repos that never started because a fail-fast batch
+// was cancelled by an earlier error.
+var errBatchSkipped = errors.New("skipped after earlier failure")
+
+// batchOptions controls how download-all schedules its repositories.
+type batchOptions struct {
+	Concurrency     int
+	RepoTimeout     time.Duration
+	ContinueOnError bool
+}
+
+// batchResult is the outcome of one repository in a batch.
+type batchResult struct {
+	Repo     string
+	Err      error
+	Duration time.Duration
+}
+
+// runBatch calls fetch for every repo with at most opts.Concurrency calls in
+// flight, each bounded by opts.RepoTimeout. Without ContinueOnError the first
+// failure cancels in-flight downloads and marks the rest as skipped. Results
+// are returned in input order.
+func runBatch(ctx context.Context, repos []string, opts batchOptions, fetch func(ctx context.Context, repo string) error) []batchResult {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]batchResult, len(repos))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, repo := range repos {
+		results[i].Repo = repo
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			results[i].Err = errBatchSkipped
+			continue
+		}
+		wg.Add(1)
+		go func(i int, repo string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			repoCtx := ctx
+			if opts.RepoTimeout > 0 {
+				var repoCancel context.CancelFunc
+				repoCtx, repoCancel = context.WithTimeout(ctx, opts.RepoTimeout)
+				defer repoCancel()
+			}
+			start := time.Now()
+			err := fetch(repoCtx, repo)
+			results[i].Err = err
+			results[i].Duration = time.Since(start)
+			if err != nil && !opts.ContinueOnError {
+				cancel()
+			}
+		}(i, repo)
+	}
+	wg.Wait()
+	return results
+}
+
+// printBatchSummary writes a per-repo status table and totals, and reports
+// whether every repo succeeded.
+func printBatchSummary(w io.Writer, results []batchResult, total time.Duration) bool {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPO\tSTATUS\tTIME\tERROR")
+	var ok, failed, skipped int
+	for _, r := range results {
+		status, msg := "ok", ""
+		switch {
+		case errors.Is(r.Err, errBatchSkipped):
+			status = "skipped"
+			skipped++
+		case r.Err != nil:
+			status, msg = "failed", r.Err.Error()
+			failed++
+		default:
+			ok++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Repo, status, r.Duration.Round(time.Millisecond), msg)
+	}
+	_ = tw.Flush()
+	fmt.Fprintf(w, "total: %d repos, %d ok, %d failed, %d skipped in %s\n",
+		len(results), ok, failed, skipped, total.Round(time.Millisecond))
+	return failed == 0 && skipped == 0
+}
+
+// readRepoList reads one repository per line, ignoring blanks and # comments.
+func readRepoList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var repos []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		repos = append(repos, line)
+	}
+	return repos, sc.Err()
+}
+
+// batchDest returns the destination for one repo inside the batch directory.
+// Each repo gets its own zip, or its own directory when extracting, so
+// archives never overwrite each other.
+func batchDest(dir, repo string, extract bool) (zipPath, extractDir string) {
+	name := repo
+	if idx := strings.LastIndex(name, "/"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	if extract {
+		zipPath, extractDir, _ = resolveDest(repo, filepath.Join(dir, name), true)
+		return zipPath, extractDir
+	}
+	return filepath.Join(dir, name+".zip"), ""
+}
+
+// checkBatchNames rejects repo lists whose destinations would collide.
+func checkBatchNames(repos []string) error {
+	seen := make(map[string]string, len(repos))
+	for _, repo := range repos {
+		zipPath, _ := batchDest("", repo, false)
+		if prev, ok := seen[zipPath]; ok {
+			return fmt.Errorf("%s and %s would be saved to the same file %s", prev, repo, zipPath)
+		}
+		seen[zipPath] = repo
+	}
+	return nil
+}
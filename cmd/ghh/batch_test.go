@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunBatch_ConcurrencyBound(t *testing.T) {
+	repos := []string{"a/1", "a/2", "a/3", "a/4", "a/5", "a/6", "a/7", "a/8", "a/9", "a/10"}
+	var inFlight, peak, calls int32
+	results := runBatch(context.Background(), repos, batchOptions{Concurrency: 3, ContinueOnError: true},
+		func(ctx context.Context, repo string) error {
+			n := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		})
+
+	if peak > 3 {
+		t.Fatalf("expected at most 3 downloads in flight, saw %d", peak)
+	}
+	if peak < 2 {
+		t.Errorf("expected downloads to run in parallel, peak was %d", peak)
+	}
+	if calls != int32(len(repos)) {
+		t.Errorf("expected %d calls, got %d", len(repos), calls)
+	}
+	for i, r := range results {
+		if r.Repo != repos[i] || r.Err != nil {
+			t.Errorf("result %d: got %+v", i, r)
+		}
+	}
+}
+
+func TestRunBatch_FailFast(t *testing.T) {
+	repos := []string{"a/ok", "a/bad", "a/3", "a/4", "a/5"}
+	boom := errors.New("boom")
+	var calls int32
+	results := runBatch(context.Background(), repos, batchOptions{Concurrency: 1},
+		func(ctx context.Context, repo string) error {
+			atomic.AddInt32(&calls, 1)
+			if repo == "a/bad" {
+				return boom
+			}
+			return nil
+		})
+
+	if calls != 2 {
+		t.Fatalf("expected the batch to stop after the first failure (2 calls), got %d", calls)
+	}
+	if results[0].Err != nil || !errors.Is(results[1].Err, boom) {
+		t.Errorf("unexpected results for started repos: %+v", results[:2])
+	}
+	for _, r := range results[2:] {
+		if !errors.Is(r.Err, errBatchSkipped) {
+			t.Errorf("%s: expected skipped, got %v", r.Repo, r.Err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if printBatchSummary(&buf, results, time.Second) {
+		t.Error("expected summary to report failure")
+	}
+	if !strings.Contains(buf.String(), "total: 5 repos, 1 ok, 1 failed, 3 skipped") {
+		t.Errorf("unexpected summary:\n%s", buf.String())
+	}
+}
+
+func TestRunBatch_ContinueOnErrorAndTimeout(t *testing.T) {
+	repos := []string{"a/slow", "a/bad", "a/ok"}
+	results := runBatch(context.Background(), repos, batchOptions{Concurrency: 2, RepoTimeout: 20 * time.Millisecond, ContinueOnError: true},
+		func(ctx context.Context, repo string) error {
+			switch repo {
+			case "a/slow":
+				<-ctx.Done()
+				return ctx.Err()
+			case "a/bad":
+				return errors.New("boom")
+			}
+			return nil
+		})
+
+	if !errors.Is(results[0].Err, context.DeadlineExceeded) {
+		t.Errorf("expected the stuck repo to time out, got %v", results[0].Err)
+	}
+	if results[1].Err == nil || results[2].Err != nil {
+		t.Errorf("expected the batch to continue past the failure, got %+v", results)
+	}
+}
+
+func TestReadRepoListAndNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "repos.txt")
+	if err := os.WriteFile(path, []byte("# team repos\nfoo/bar\n\n  foo/baz  \n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repos, err := readRepoList(path)
+	if err != nil {
+		t.Fatalf("readRepoList: %v", err)
+	}
+	if strings.Join(repos, ",") != "foo/bar,foo/baz" {
+		t.Errorf("unexpected repos %v", repos)
+	}
+
+	if err := checkBatchNames(repos); err != nil {
+		t.Errorf("unexpected collision: %v", err)
+	}
+	if err := checkBatchNames([]string{"foo/bar", "other/bar"}); err == nil {
+		t.Error("expected repos with the same name to collide")
+	}
+
+	if zip, dir := batchDest("out", "foo/bar", false); zip != filepath.Join("out", "bar.zip") || dir != "" {
+		t.Errorf("batchDest without extract: %s %s", zip, dir)
+	}
+	if zip, dir := batchDest("out", "foo/bar", true); zip != filepath.Join("out", "bar", "bar.zip") || dir != filepath.Join("out", "bar") {
+		t.Errorf("batchDest with extract: %s %s", zip, dir)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	ic "github-hub/internal/client"
+	cfgpkg "github-hub/internal/config"
+)
+
+// configSetting is one effective client setting and where it came from:
+// flag, env, config or default.
+type configSetting struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// settingSource reports which layer supplied a setting; flags win over the
+// environment, which wins over the config file.
+func settingSource(flagSet bool, envKey, fromConfig string) string {
+	switch {
+	case flagSet:
+		return "flag"
+	case strings.TrimSpace(os.Getenv(envKey)) != "":
+		return "env"
+	case strings.TrimSpace(fromConfig) != "":
+		return "config"
+	default:
+		return "default"
+	}
+}
+
+// runConfigInit implements `ghh config init [--path P] [--force]`. Without
+// --path it writes to the --config/GHH_CONFIG location, or DefaultPath.
+func runConfigInit(args []string, configPath string) error {
+	cmd := flag.NewFlagSet("config init", flag.ExitOnError)
+	path := cmd.String("path", configPath, "where to write the config (default: --config, GHH_CONFIG or "+cfgpkg.DefaultPath()+")")
+	force := cmd.Bool("force", false, "overwrite an existing config file")
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+	if *path == "" {
+		*path = cfgpkg.DefaultPath()
+	}
+	if err := cfgpkg.WriteTemplate(*path, *force); err != nil {
+		if errors.Is(err, cfgpkg.ErrExists) {
+			return fmt.Errorf("%w (use --force to overwrite)", err)
+		}
+		return err
+	}
+	fmt.Printf("wrote %s\n", *path)
+	return nil
+}
+
+// printConfig writes the effective configuration with the token masked.
+func printConfig(w io.Writer, path string, settings []configSetting) {
+	switch {
+	case path == "":
+		fmt.Fprintln(w, "# config file: none")
+	default:
+		state := ""
+		if _, err := os.Stat(path); err != nil {
+			state = " (not found)"
+		}
+		fmt.Fprintf(w, "# config file: %s%s\n", path, state)
+	}
+	for _, s := range settings {
+		value := s.Value
+		if s.Key == "token" {
+			value = cfgpkg.MaskToken(value)
+		}
+		fmt.Fprintf(w, "%s: %q  # %s\n", s.Key, value, s.Source)
+	}
+}
+
+// applyEndpointOverrides replaces the endpoint templates set in the config
+// file's endpoints section.
+func applyEndpointOverrides(eps *ic.Endpoints, o cfgpkg.Endpoints) {
+	for _, ov := range []struct {
+		dst *string
+		val string
+	}{
+		{&eps.Download, o.Download},
+		{&eps.BranchSwitch, o.BranchSwitch},
+		{&eps.DirList, o.DirList},
+		{&eps.DirDelete, o.DirDelete},
+	} {
+		if v := strings.TrimSpace(ov.val); v != "" {
+			*ov.dst = v
+		}
+	}
+}
@@ -10,8 +10,10 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	ic "github-hub/internal/client"
@@ -38,6 +40,7 @@ func main() {
 	retryMax := defaultRetryMax
 	retryBackoff := defaultRetryBackoff
 	insecure := false
+	quiet := false
 	configPath := os.Getenv("GHH_CONFIG")
 	user := strings.TrimSpace(os.Getenv("GHH_USER"))
 	showVersion := false
@@ -62,6 +65,7 @@ func main() {
 	global.IntVar(&retryMax, "retry", retryMax, "retry times for failed downloads (env: GHH_RETRY)")
 	global.DurationVar(&retryBackoff, "retry-backoff", retryBackoff, "wait before retrying a failed download (env: GHH_RETRY_BACKOFF)")
 	global.BoolVar(&insecure, "insecure", insecure, "skip TLS verification")
+	global.BoolVar(&quiet, "quiet", quiet, "suppress download progress output")
 	global.StringVar(&configPath, "config", configPath, "path to YAML config (env: GHH_CONFIG); JSON compatible")
 	global.BoolVar(&showVersion, "version", showVersion, "print version and exit")
 
@@ -88,6 +92,20 @@ func main() {
 		os.Exit(2)
 	}
 
+	setFlags := map[string]bool{}
+	global.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	if configPath == "" {
+		configPath = cfgpkg.DefaultPath()
+	}
+
+	// config init runs before the config is loaded so it can replace a broken file.
+	if args[0] == "config" && len(args) > 1 && args[1] == "init" {
+		if err := runConfigInit(args[2:], configPath); err != nil {
+			exitErr(err)
+		}
+		return
+	}
+
 	// Load config and merge with flags
 	cfg, err := cfgpkg.Load(configPath)
 	if err != nil {
@@ -104,6 +122,7 @@ func main() {
 		user = cfg.User
 	}
 	eps := ic.DefaultEndpoints()
+	applyEndpointOverrides(&eps, cfg.Endpoints)
 
 	if server == "" {
 		server = "http://localhost:8080"
@@ -134,6 +153,9 @@ func main() {
 	client.RetryMax = retryMax
 	client.RetryBackoff = retryBackoff
 	client.ProgressInterval = time.Second
+	if quiet {
+		client.ProgressOutput = nil
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
@@ -146,7 +168,10 @@ func main() {
 		branch := cmd.String("branch", "", "branch name (default: server default)")
 		dest := cmd.String("dest", "", "destination path (default: current directory)")
 		extract := cmd.Bool("extract", false, "extract zip archive into dest directory")
+		noKeepZip := cmd.Bool("no-keep-zip", false, "with --extract, delete the downloaded archive once extraction succeeds")
 		legacy := cmd.Bool("legacy", false, "use legacy GitHub zipball API instead of git archive")
+		format := cmd.String("format", "zip", "archive format: zip or tar.gz")
+		pathsCSV := cmd.String("paths", "", "comma-separated directories/files to include (uses sparse download)")
 		debugDelay := cmd.String("debug-delay", "", "DEBUG: request server to add artificial delay (e.g., 90s, 2m)")
 		debugStreamDelay := cmd.String("debug-stream-delay", "", "DEBUG: slow down server streaming to client (e.g., 90s, 2m)")
 		if err := cmd.Parse(args[1:]); err != nil {
@@ -173,7 +198,25 @@ func main() {
 			fmt.Fprintln(os.Stderr, "download requires --repo")
 			os.Exit(2)
 		}
-		zipPath, extractDir := resolveDest(*repo, *dest, *extract)
+		if paths := splitPaths([]string{*pathsCSV}); len(paths) > 0 {
+			zipPath, extractDir, ownZip := resolveDest(sparseName(*repo, *branch), *dest, *extract)
+			client.RemoveZipAfterExtract = removeZipAfterExtract(*noKeepZip, *extract, ownZip)
+			if err := client.DownloadSparse(ctx, *repo, *branch, paths, zipPath, extractDir); err != nil {
+				exitErr(err)
+			}
+			return
+		}
+		zipPath, extractDir, ownZip := resolveDest(*repo, *dest, *extract)
+		client.RemoveZipAfterExtract = removeZipAfterExtract(*noKeepZip, *extract, ownZip)
+		switch strings.ToLower(strings.TrimSpace(*format)) {
+		case "", "zip":
+		case "tar.gz", "tgz":
+			client.Format = "tar.gz"
+			zipPath = tarballPath(zipPath)
+		default:
+			fmt.Fprintf(os.Stderr, "unsupported --format %q (want zip or tar.gz)\n", *format)
+			os.Exit(2)
+		}
 		if err := client.Download(ctx, *repo, *branch, zipPath, extractDir); err != nil {
 			exitErr(err)
 		}
@@ -194,28 +237,66 @@ func main() {
 			os.Exit(2)
 		}
 		// Parse paths from flag (empty paths = download all)
-		var paths []string
-		for _, p := range pathsFlag {
-			for _, part := range strings.Split(p, ",") {
-				part = strings.TrimSpace(part)
-				if part != "" {
-					paths = append(paths, part)
-				}
+		paths := splitPaths(pathsFlag)
+		zipPath, extractDir, _ := resolveDest(sparseName(*repo, *branch), *dest, *extract)
+		if err := client.DownloadSparse(ctx, *repo, *branch, paths, zipPath, extractDir); err != nil {
+			exitErr(err)
+		}
+
+	case "download-all":
+		cmd := flag.NewFlagSet("download-all", flag.ExitOnError)
+		var repoFlag multiFlag
+		cmd.Var(&repoFlag, "repo", "repository to download (repeatable or comma-separated)")
+		reposFile := cmd.String("repos-file", "", "file listing one repository per line (# starts a comment)")
+		branch := cmd.String("branch", "", "branch name for every repo (default: server default)")
+		dest := cmd.String("dest", ".", "destination directory")
+		extract := cmd.Bool("extract", false, "extract each archive into dest/<name>")
+		concurrency := cmd.Int("concurrency", defaultBatchConcurrency, "maximum repositories downloaded at once")
+		repoTimeout := cmd.Duration("repo-timeout", 0, "time limit per repository (default: --timeout)")
+		continueOnError := cmd.Bool("continue-on-error", true, "keep going after a failed repo; false stops at the first failure")
+		if err := cmd.Parse(args[1:]); err != nil {
+			exitErr(err)
+		}
+		repos := splitPaths(repoFlag)
+		if *reposFile != "" {
+			listed, err := readRepoList(*reposFile)
+			if err != nil {
+				exitErr(err)
 			}
+			repos = append(repos, listed...)
 		}
-		// Build default name: repo-branch (sanitize branch: replace / with -)
-		defaultName := *repo
-		branchName := strings.TrimSpace(*branch)
-		if branchName == "" {
-			branchName = "main"
+		if len(repos) == 0 {
+			fmt.Fprintln(os.Stderr, "download-all requires --repo or --repos-file")
+			os.Exit(2)
 		}
-		// Sanitize branch name for filesystem (e.g., release/0.2.0 -> release-0.2.0)
-		safeBranch := strings.ReplaceAll(branchName, "/", "-")
-		defaultName = defaultName + "-" + safeBranch
-		zipPath, extractDir := resolveDest(defaultName, *dest, *extract)
-		if err := client.DownloadSparse(ctx, *repo, *branch, paths, zipPath, extractDir); err != nil {
+		if *concurrency <= 0 {
+			fmt.Fprintln(os.Stderr, "--concurrency must be positive")
+			os.Exit(2)
+		}
+		if err := checkBatchNames(repos); err != nil {
+			exitErr(err)
+		}
+		if err := os.MkdirAll(*dest, 0o755); err != nil {
 			exitErr(err)
 		}
+		if *repoTimeout <= 0 {
+			*repoTimeout = timeout
+		}
+		// Interleaved progress bars from parallel downloads are unreadable;
+		// the summary table reports each repo instead.
+		client.ProgressOutput = nil
+		start := time.Now()
+		results := runBatch(context.Background(), repos, batchOptions{
+			Concurrency:     *concurrency,
+			RepoTimeout:     *repoTimeout,
+			ContinueOnError: *continueOnError,
+		}, func(ctx context.Context, repo string) error {
+			zipPath, extractDir := batchDest(*dest, repo, *extract)
+			return client.Download(ctx, repo, *branch, zipPath, extractDir)
+		})
+		if !printBatchSummary(os.Stdout, results, time.Since(start)) {
+			os.Exit(1)
+		}
 
 	case "switch":
 		cmd := flag.NewFlagSet("switch", flag.ExitOnError)
@@ -232,10 +313,108 @@ func main() {
 			exitErr(err)
 		}
 
+	case "branches":
+		cmd := flag.NewFlagSet("branches", flag.ExitOnError)
+		repo := cmd.String("repo", "", "repository identifier (e.g. owner/name)")
+		if err := cmd.Parse(args[1:]); err != nil {
+			exitErr(err)
+		}
+		if *repo == "" {
+			fmt.Fprintln(os.Stderr, "branches requires --repo")
+			os.Exit(2)
+		}
+		branches, err := client.ListBranches(ctx, *repo)
+		if err != nil {
+			exitErr(err)
+		}
+		for _, b := range branches {
+			fmt.Println(b)
+		}
+
+	case "stat":
+		cmd := flag.NewFlagSet("stat", flag.ExitOnError)
+		repo := cmd.String("repo", "", "repository identifier (e.g. owner/name)")
+		branch := cmd.String("branch", "", "branch name (default: main)")
+		legacy := cmd.Bool("legacy", false, "inspect the legacy zipball cache instead of git archive")
+		format := cmd.String("format", "zip", "archive format: zip or tar.gz")
+		if err := cmd.Parse(args[1:]); err != nil {
+			exitErr(err)
+		}
+		if *repo == "" {
+			fmt.Fprintln(os.Stderr, "stat requires --repo")
+			os.Exit(2)
+		}
+		switch strings.ToLower(strings.TrimSpace(*format)) {
+		case "", "zip":
+		case "tar.gz", "tgz":
+			client.Format = "tar.gz"
+		default:
+			fmt.Fprintf(os.Stderr, "unsupported --format %q (want zip or tar.gz)\n", *format)
+			os.Exit(2)
+		}
+		client.Legacy = *legacy
+		st, err := client.Stat(ctx, *repo, *branch)
+		if err != nil {
+			exitErr(err)
+		}
+		printStat(*repo, *branch, st)
+
+	case "upload":
+		cmd := flag.NewFlagSet("upload", flag.ExitOnError)
+		src := cmd.String("src", "", "local directory to upload")
+		path := cmd.String("path", "", "remote path relative to user root (e.g. artifacts/build-1)")
+		if err := cmd.Parse(args[1:]); err != nil {
+			exitErr(err)
+		}
+		if *src == "" || *path == "" {
+			fmt.Fprintln(os.Stderr, "upload requires --src and --path")
+			os.Exit(2)
+		}
+		if err := client.Upload(ctx, *src, *path); err != nil {
+			exitErr(err)
+		}
+
+	case "cache":
+		if len(args) < 2 || (args[1] != "stats" && args[1] != "clear") {
+			fmt.Fprintln(os.Stderr, "usage: ghh cache stats | ghh cache clear --yes")
+			os.Exit(2)
+		}
+		if args[1] == "clear" {
+			cmd := flag.NewFlagSet("cache clear", flag.ExitOnError)
+			yes := cmd.Bool("yes", false, "confirm removing everything cached for your user")
+			if err := cmd.Parse(args[2:]); err != nil {
+				exitErr(err)
+			}
+			if !*yes {
+				fmt.Fprintln(os.Stderr, "cache clear removes your entire server cache; rerun with --yes to confirm")
+				os.Exit(2)
+			}
+			freed, err := client.ClearCache(ctx)
+			if err != nil {
+				exitErr(err)
+			}
+			fmt.Printf("cache cleared, %d bytes freed\n", freed)
+			break
+		}
+		cmd := flag.NewFlagSet("cache stats", flag.ExitOnError)
+		if err := cmd.Parse(args[2:]); err != nil {
+			exitErr(err)
+		}
+		st, err := client.CacheStats(ctx)
+		if err != nil {
+			exitErr(err)
+		}
+		printCacheStats(st)
+
 	case "ls":
 		cmd := flag.NewFlagSet("ls", flag.ExitOnError)
 		path := cmd.String("path", ".", "remote path to list (relative to user root, e.g. repos/owner/repo)")
 		raw := cmd.Bool("raw", false, "print raw JSON returned by server")
+		asJSON := cmd.Bool("json", false, "print entries as JSON (directories first, with total_size)")
+		var recursive bool
+		cmd.BoolVar(&recursive, "recursive", false, "list the whole subtree (server caps depth and entry count)")
+		cmd.BoolVar(&recursive, "r", false, "shorthand for --recursive")
+		glob := cmd.String("glob", "", "only list entries whose name matches this shell pattern (e.g. '*.zip')")
 		if err := cmd.Parse(args[1:]); err != nil {
 			exitErr(err)
 		}
@@ -243,7 +422,17 @@ func main() {
 		if cmd.NArg() > 0 && *path == "." {
 			*path = cmd.Arg(0)
 		}
-		if err := client.ListDir(ctx, *path, *raw); err != nil {
+		format := ic.ListTable
+		switch {
+		case *raw && *asJSON:
+			fmt.Fprintln(os.Stderr, "ls: --raw and --json are mutually exclusive")
+			os.Exit(2)
+		case *raw:
+			format = ic.ListRaw
+		case *asJSON:
+			format = ic.ListJSON
+		}
+		if err := client.ListDir(ctx, *path, format, recursive, *glob); err != nil {
 			exitErr(err)
 		}
 
@@ -262,6 +451,37 @@ func main() {
 			exitErr(err)
 		}
 
+	case "mv":
+		cmd := flag.NewFlagSet("mv", flag.ExitOnError)
+		from := cmd.String("from", "", "remote path to move (relative to user root)")
+		to := cmd.String("to", "", "new remote path; must not exist")
+		if err := cmd.Parse(args[1:]); err != nil {
+			exitErr(err)
+		}
+		if *from == "" || *to == "" {
+			fmt.Fprintln(os.Stderr, "mv requires --from and --to")
+			os.Exit(2)
+		}
+		if err := client.Move(ctx, *from, *to); err != nil {
+			exitErr(err)
+		}
+
+	case "config":
+		if len(args) < 2 || args[1] != "show" {
+			fmt.Fprintln(os.Stderr, "usage: ghh config init [--path PATH] [--force] | ghh config show")
+			os.Exit(2)
+		}
+		// Load falls back to the default base URL, so only a different value came from the file.
+		cfgBaseURL := cfg.BaseURL
+		if cfgBaseURL == cfgpkg.Default().BaseURL {
+			cfgBaseURL = ""
+		}
+		printConfig(os.Stdout, configPath, []configSetting{
+			{"base_url", server, settingSource(setFlags["server"], "GHH_BASE_URL", cfgBaseURL)},
+			{"token", token, settingSource(setFlags["token"], "GHH_TOKEN", cfg.Token)},
+			{"user", client.User, settingSource(setFlags["user"], "GHH_USER", cfg.User)},
+		})
+
 	case "help", "-h", "--help":
 		printUsage()
 	default:
@@ -303,6 +523,43 @@ func (f *multiFlag) Set(v string) error {
 	return nil
 }
 
+func printStat(repo, branch string, st *ic.RepoStat) {
+	if branch == "" {
+		branch = "main"
+	}
+	if !st.Cached {
+		fmt.Printf("%s@%s: not cached\n", repo, branch)
+		return
+	}
+	fmt.Printf("%s@%s: cached\n", repo, branch)
+	fmt.Printf("  size:        %d\n", st.Size)
+	if st.SHA != "" {
+		fmt.Printf("  sha:         %s\n", st.SHA)
+	}
+	if st.LastAccess != nil {
+		fmt.Printf("  last access: %s\n", st.LastAccess.Local().Format(time.RFC3339))
+	}
+}
+
+func printCacheStats(st *ic.CacheStats) {
+	entries := append([]ic.CacheEntry(nil), st.Entries...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Size > entries[j].Size })
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPO\tBRANCH\tSIZE\tMODIFIED")
+	for _, e := range entries {
+		branch := e.Branch
+		if e.Legacy {
+			branch += " (legacy)"
+		}
+		if e.Format == "tar.gz" {
+			branch += " (tar.gz)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", e.Repo, branch, e.Size, e.ModTime.Local().Format(time.RFC3339))
+	}
+	_ = tw.Flush()
+	fmt.Printf("total: %d archives, %d bytes (user %s)\n", st.TotalCount, st.TotalSize, st.User)
+}
+
 func printUsage() {
 	fmt.Print(`ghh - GitHub Hub client (offline-friendly)
 
@@ -313,20 +570,30 @@ Usage:
 Commands:
   download         Download repository code as archive (optionally extract) or release package (--package URL)
   download-sparse  Download selected directories from a repository using sparse checkout
+  download-all     Download several repositories in parallel and print a per-repo summary
   switch           Switch repository branch on server
-  ls               List remote directory contents (path is relative to user root; no leading "users/")
+  branches         List remote branches of a repository (--repo owner/name)
+  stat             Show whether a repo/branch is cached on the server, its size and commit
+  cache stats      List your cached archives sorted by size, with totals
+  cache clear      Remove everything cached for your user (requires --yes)
+  upload           Upload a local directory into the server cache (--src DIR --path REL)
+  ls               List remote directory contents (path is relative to user root; no leading "users/"; --json for scripts; -r for the whole subtree)
   rm               Delete remote directory (use -r for recursive)
+  mv               Move or rename a remote path (--from REL --to REL; never overwrites)
+  config init      Write a starter config file (--path PATH, --force to overwrite)
+  config show      Print the effective settings (config file + env + flags), token masked
   help             Show this help message
 
 Global Flags:
   --server     Server base URL (env: GHH_BASE_URL) (default: http://localhost:8080)
   --token      Auth token (env: GHH_TOKEN)
   --user       User name for grouping cache (env: GHH_USER)
-  --config     Path to YAML config (env: GHH_CONFIG); JSON compatible
+  --config     Path to YAML config (env: GHH_CONFIG; default: <user config dir>/ghh/config.yaml); JSON compatible
   --timeout    HTTP timeout (default: 30s)
   --retry      Retry times for failed downloads (env: GHH_RETRY)
   --retry-backoff  Wait before retrying a failed download (env: GHH_RETRY_BACKOFF)
   --insecure   Skip TLS verification
+  --quiet      Suppress download progress (progress is shown on stderr when it is a terminal)
   --version    Print version and exit
 
 Download Flags:
@@ -335,6 +602,8 @@ Download Flags:
   --dest         Destination path (default: current directory)
   --extract      Extract zip archive into dest directory
   --legacy       Use legacy GitHub zipball API instead of git archive
+  --format       Archive format: zip (default) or tar.gz
+  --paths        Comma-separated directories/files to include (sparse download)
   --package      Package download URL (alternative to --repo)
   --debug-delay  DEBUG: request server to add artificial delay (e.g., 90s, 2m)
   --debug-stream-delay  DEBUG: slow down server streaming to client (e.g., 90s, 2m)
@@ -346,26 +615,94 @@ Download-Sparse Flags:
   --dest       Destination path (default: current directory)
   --extract    Extract zip archive into dest directory
 
+Download-All Flags:
+  --repo               Repository to download (repeatable or comma-separated)
+  --repos-file         File with one repository per line (# starts a comment)
+  --branch             Branch name for every repo (default: server default)
+  --dest               Destination directory (default: current directory)
+  --extract            Extract each archive into dest/<name>
+  --concurrency        Maximum repositories downloaded at once (default: 4)
+  --repo-timeout       Time limit per repository (default: --timeout)
+  --continue-on-error  Keep going after a failed repo (default: true; false stops at the first failure)
+
 Examples:
   ghh --server http://localhost:8080 download --repo foo/bar --branch main
   ghh --server http://localhost:8080 download --repo foo/bar --dest out.zip
   ghh --server http://localhost:8080 download --repo foo --extract
+  ghh --server http://localhost:8080 download --repo foo/bar --paths src,docs --extract
   ghh --server http://localhost:8080 download --package https://example.com/pkg.tar.gz --dest ./pkg.tar.gz
   ghh --server http://localhost:8080 download-sparse --repo foo/bar --path src --path docs
   ghh --server http://localhost:8080 download-sparse --repo foo/bar --path src,docs --extract
   ghh --server http://localhost:8080 download-sparse --repo foo/bar  # download all (no --path)
+  ghh --server http://localhost:8080 download-all --repo foo/bar,foo/baz --concurrency 2 --dest ./repos
   ghh --server http://localhost:8080 switch --repo foo/bar --branch dev
+  ghh --server http://localhost:8080 branches --repo foo/bar
+  ghh --server http://localhost:8080 stat --repo foo/bar --branch main
+  ghh --server http://localhost:8080 stat --repo foo/bar --format tar.gz
+  ghh --server http://localhost:8080 cache stats
+  ghh --server http://localhost:8080 cache clear --yes
+  ghh --server http://localhost:8080 upload --src ./dist --path artifacts/build-1
   ghh --server http://localhost:8080 ls --path repos/foo/bar
   ghh --server http://localhost:8080 rm --path repos/foo/bar --r
   ghh --timeout 3m download --repo foo/bar --debug-delay 90s
 `)
 }
 
+// splitPaths flattens repeated and comma-separated path flag values, dropping blanks.
+func splitPaths(values []string) []string {
+	var paths []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			part = strings.TrimSpace(part)
+			if part != "" {
+				paths = append(paths, part)
+			}
+		}
+	}
+	return paths
+}
+
+// sparseName builds the default sparse download name: repo-branch, with the
+// branch sanitized for the filesystem (e.g., release/0.2.0 -> release-0.2.0).
+func sparseName(repo, branch string) string {
+	branchName := strings.TrimSpace(branch)
+	if branchName == "" {
+		branchName = "main"
+	}
+	return repo + "-" + strings.ReplaceAll(branchName, "/", "-")
+}
+
+// removeZipAfterExtract decides whether --no-keep-zip applies. The archive is only
+// removed when it is extracted and ghh chose its path; a zip named by --dest is kept.
+func removeZipAfterExtract(noKeepZip, extract, ownZip bool) bool {
+	if !noKeepZip {
+		return false
+	}
+	if !extract {
+		fmt.Fprintln(os.Stderr, "note: --no-keep-zip has no effect without --extract")
+		return false
+	}
+	if !ownZip {
+		fmt.Fprintln(os.Stderr, "note: keeping the archive named by --dest despite --no-keep-zip")
+		return false
+	}
+	return true
+}
+
+// tarballPath swaps a default ".zip" destination for ".tar.gz".
+func tarballPath(zipPath string) string {
+	if strings.HasSuffix(strings.ToLower(zipPath), ".zip") {
+		return zipPath[:len(zipPath)-len(".zip")] + ".tar.gz"
+	}
+	return zipPath
+}
+
 // resolveDest determines the zip file path and extract directory based on repo and dest flag.
-// Returns (zipPath, extractDir):
+// Returns (zipPath, extractDir, ownZip):
 // - zipPath: where to save the zip file
 // - extractDir: where to extract (empty if extract=false, or same as zip's parent dir)
-func resolveDest(repo, dest string, extract bool) (zipPath, extractDir string) {
+// - ownZip: zipPath was derived by ghh rather than named by dest (safe for --no-keep-zip)
+func resolveDest(repo, dest string, extract bool) (zipPath, extractDir string, ownZip bool) {
 	// Extract repo name from owner/repo
 	repoName := repo
 	if idx := strings.LastIndex(repoName, "/"); idx >= 0 {
@@ -379,7 +716,7 @@ func resolveDest(repo, dest string, extract bool) (zipPath, extractDir string) {
 		if extract {
 			extractDir = "."
 		}
-		return
+		return zipPath, extractDir, true
 	}
 
 	// If dest is an existing directory, save zip inside it
@@ -388,7 +725,7 @@ func resolveDest(repo, dest string, extract bool) (zipPath, extractDir string) {
 		if extract {
 			extractDir = dest
 		}
-		return
+		return zipPath, extractDir, true
 	}
 
 	// dest is a file path (or non-existent path)
@@ -398,7 +735,7 @@ func resolveDest(repo, dest string, extract bool) (zipPath, extractDir string) {
 			// Create the directory and extract there
 			extractDir = dest
 			zipPath = filepath.Join(dest, repoName+".zip")
-			return
+			return zipPath, extractDir, true
 		}
 		// dest is a .zip file, extract to its parent directory
 		zipPath = dest
@@ -406,12 +743,11 @@ func resolveDest(repo, dest string, extract bool) (zipPath, extractDir string) {
 		if extractDir == "" {
 			extractDir = "."
 		}
-		return
+		return zipPath, extractDir, false
 	}
 
 	// No extract, just save to dest
-	zipPath = dest
-	return
+	return dest, "", false
 }
 
 // resolvePackageDest determines package save path. If dest is empty, use basename of URL in cwd.
@@ -3,7 +3,11 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	ic "github-hub/internal/client"
+	cfgpkg "github-hub/internal/config"
 )
 
 func TestResolveDest(t *testing.T) {
@@ -126,7 +130,7 @@ func TestResolveDest(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotZip, gotExtDir := resolveDest(tt.repo, tt.dest, tt.extract)
+			gotZip, gotExtDir, _ := resolveDest(tt.repo, tt.dest, tt.extract)
 			if gotZip != tt.wantZip {
 				t.Errorf("resolveDest(%q, %q, %v) zipPath = %q, want %q",
 					tt.repo, tt.dest, tt.extract, gotZip, tt.wantZip)
@@ -139,6 +143,45 @@ func TestResolveDest(t *testing.T) {
 	}
 }
 
+func TestResolveDest_OwnZip(t *testing.T) {
+	tmpDir := t.TempDir()
+	tests := []struct {
+		name    string
+		dest    string
+		extract bool
+		want    bool
+	}{
+		{"empty dest", "", true, true},
+		{"existing directory", tmpDir, true, true},
+		{"new directory with extract", filepath.Join(tmpDir, "out"), true, true},
+		{"explicit zip with extract", filepath.Join(tmpDir, "out.zip"), true, false},
+		{"explicit file without extract", filepath.Join(tmpDir, "out.zip"), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, got := resolveDest("owner/myrepo", tt.dest, tt.extract); got != tt.want {
+				t.Errorf("ownZip = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveZipAfterExtract(t *testing.T) {
+	tests := []struct {
+		noKeepZip, extract, ownZip, want bool
+	}{
+		{true, true, true, true},
+		{false, true, true, false},
+		{true, false, true, false},
+		{true, true, false, false},
+	}
+	for _, tt := range tests {
+		if got := removeZipAfterExtract(tt.noKeepZip, tt.extract, tt.ownZip); got != tt.want {
+			t.Errorf("removeZipAfterExtract(%v, %v, %v) = %v, want %v", tt.noKeepZip, tt.extract, tt.ownZip, got, tt.want)
+		}
+	}
+}
+
 func TestResolveDest_CurrentDirExists(t *testing.T) {
 	// Save current directory
 	origDir, err := os.Getwd()
@@ -154,24 +197,59 @@ func TestResolveDest_CurrentDirExists(t *testing.T) {
 	defer func() { _ = os.Chdir(origDir) }()
 
 	// Test with empty dest (uses default naming)
-	gotZip, gotExtDir := resolveDest("owner/myrepo", "", false)
+	gotZip, gotExtDir, _ := resolveDest("owner/myrepo", "", false)
 	if gotZip != "./myrepo.zip" || gotExtDir != "" {
 		t.Errorf("resolveDest empty dest = (%q, %q), want (\"./myrepo.zip\", \"\")", gotZip, gotExtDir)
 	}
 
-	gotZip, gotExtDir = resolveDest("owner/myrepo", "", true)
+	gotZip, gotExtDir, _ = resolveDest("owner/myrepo", "", true)
 	if gotZip != "./myrepo.zip" || gotExtDir != "." {
 		t.Errorf("resolveDest empty dest (extract) = (%q, %q), want (\"./myrepo.zip\", \".\")", gotZip, gotExtDir)
 	}
 
 	// Test with "." as dest (existing directory)
-	gotZip, gotExtDir = resolveDest("owner/myrepo", ".", false)
+	gotZip, gotExtDir, _ = resolveDest("owner/myrepo", ".", false)
 	if gotZip != "myrepo.zip" || gotExtDir != "" {
 		t.Errorf("resolveDest dot dest = (%q, %q), want (\"myrepo.zip\", \"\")", gotZip, gotExtDir)
 	}
 
-	gotZip, gotExtDir = resolveDest("owner/myrepo", ".", true)
+	gotZip, gotExtDir, _ = resolveDest("owner/myrepo", ".", true)
 	if gotZip != "myrepo.zip" || gotExtDir != "." {
 		t.Errorf("resolveDest dot dest (extract) = (%q, %q), want (\"myrepo.zip\", \".\")", gotZip, gotExtDir)
 	}
 }
+
+func TestPrintConfig(t *testing.T) {
+	t.Setenv("GHH_USER", "alice")
+	var buf strings.Builder
+	printConfig(&buf, filepath.Join(t.TempDir(), "missing.yaml"), []configSetting{
+		{"base_url", "http://hub:8080", settingSource(true, "GHH_BASE_URL", "")},
+		{"token", "ghp_secrettoken1234", settingSource(false, "GHH_TOKEN_UNSET_FOR_TEST", "ghp_secrettoken1234")},
+		{"user", "alice", settingSource(false, "GHH_USER", "")},
+	})
+	out := buf.String()
+	for _, want := range []string{
+		"(not found)",
+		`base_url: "http://hub:8080"  # flag`,
+		`token: "****1234"  # config`,
+		`user: "alice"  # env`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "secrettoken") {
+		t.Errorf("token not masked:\n%s", out)
+	}
+}
+
+func TestApplyEndpointOverrides(t *testing.T) {
+	eps := ic.DefaultEndpoints()
+	applyEndpointOverrides(&eps, cfgpkg.Endpoints{Download: "/v2/download", DirDelete: " "})
+	if eps.Download != "/v2/download" {
+		t.Errorf("download not overridden: %q", eps.Download)
+	}
+	if def := ic.DefaultEndpoints(); eps.DirDelete != def.DirDelete || eps.DirList != def.DirList {
+		t.Errorf("empty overrides must keep defaults, got %+v", eps)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github-hub/internal/quality/api"
+	"github-hub/internal/quality/models"
+	"github-hub/internal/quality/storage"
+)
+
+func TestGzipMiddleware_EventsList(t *testing.T) {
+	store := storage.NewMockStorage()
+	for i := 0; i < 20; i++ {
+		event, err := models.NewGitHubEvent(map[string]interface{}{
+			"event_type": "push",
+			"repository": "test/repo",
+			"branch":     "main",
+			"commit_sha": fmt.Sprintf("sha-%d", i),
+			"pusher":     "tester",
+		}, models.EventTypePush)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.CreateEvent(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server, err := api.NewServerWithStorage(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	ts := httptest.NewServer(gzipMiddleware(mux))
+	defer ts.Close()
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		wantGzip       bool
+	}{
+		{"gzip accepted", "br, gzip", true},
+		{"gzip refused", "gzip;q=0", false},
+		{"identity", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/events", nil)
+			// 显式设置后 Transport 不会自动解压
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			var body io.Reader = resp.Body
+			if got := resp.Header.Get("Content-Encoding") == "gzip"; got != tt.wantGzip {
+				t.Fatalf("Content-Encoding=%q, want gzip=%v", resp.Header.Get("Content-Encoding"), tt.wantGzip)
+			}
+			if tt.wantGzip {
+				gz, err := gzip.NewReader(resp.Body)
+				if err != nil {
+					t.Fatalf("gzip reader: %v", err)
+				}
+				body = gz
+			}
+			var response struct {
+				Success bool              `json:"success"`
+				Data    []json.RawMessage `json:"data"`
+			}
+			if err := json.NewDecoder(body).Decode(&response); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if !response.Success || len(response.Data) != 20 {
+				t.Fatalf("success=%v events=%d, want 20", response.Success, len(response.Data))
+			}
+			if !strings.Contains(resp.Header.Get("Vary"), "Accept-Encoding") {
+				t.Errorf("missing Vary: Accept-Encoding")
+			}
+		})
+	}
+}
+
+func TestGzipMiddleware_SkipsSmallAndCompressed(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/small", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	})
+	mux.HandleFunc("/zip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		_, _ = w.Write(make([]byte, 4*gzipMinSize))
+	})
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = w.Write([]byte("{\"line\":1}\n"))
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte("{\"line\":2}\n"))
+	})
+	handler := gzipMiddleware(mux)
+
+	tests := []struct {
+		path     string
+		wantGzip bool
+		wantBody string
+	}{
+		{"/small", false, `{"ok":true}`},
+		{"/zip", false, ""},
+		{"/stream", true, "{\"line\":1}\n{\"line\":2}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding") == "gzip"; got != tt.wantGzip {
+				t.Fatalf("Content-Encoding=%q, want gzip=%v", rec.Header().Get("Content-Encoding"), tt.wantGzip)
+			}
+			if tt.wantBody == "" {
+				return
+			}
+			var body io.Reader = rec.Body
+			if tt.wantGzip {
+				gz, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = gz
+			}
+			b, _ := io.ReadAll(body)
+			if string(b) != tt.wantBody {
+				t.Errorf("body=%q, want %q", b, tt.wantBody)
+			}
+		})
+	}
+}
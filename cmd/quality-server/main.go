@@ -2,22 +2,62 @@ package main
 
 import (
 	"flag"
+	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
 
 	"github-hub/internal/quality/api"
+	"github-hub/internal/quality/grpcapi"
 	"github-hub/internal/quality/logger"
+	"github-hub/internal/quality/models"
+	"github-hub/internal/quality/notify"
 	"github-hub/internal/quality/storage"
+	"github-hub/internal/version"
 )
 
 func main() {
 	// 解析命令行参数
 	var (
-		addr       = flag.String("addr", ":5001", "服务器监听地址")
-		dbDSN      = flag.String("db", "", "MySQL数据库连接字符串 (必需)")
-		logLevel   = flag.String("log-level", "info", "日志级别: debug, info, warn, error")
-		jsonFormat = flag.Bool("log-json", false, "使用 JSON 格式日志")
-		noColor    = flag.Bool("log-no-color", false, "禁用彩色日志输出")
+		addr        = flag.String("addr", ":5001", "服务器监听地址")
+		grpcAddr    = flag.String("grpc-addr", "", "gRPC 接口监听地址，如 :5002（为空表示不启用）")
+		dbDSN       = flag.String("db", "", "MySQL数据库连接字符串 (必需)")
+		migrateOn   = flag.Bool("migrate", true, "启动时执行待执行的数据库迁移")
+		migrateDry  = flag.Bool("migrate-dry-run", false, "只打印待执行的数据库迁移及其 DDL，然后退出，不修改数据库")
+		logLevel    = flag.String("log-level", "info", "日志级别: debug, info, warn, error")
+		jsonFormat  = flag.Bool("log-json", false, "使用 JSON 格式日志")
+		noColor     = flag.Bool("log-no-color", false, "禁用彩色日志输出")
+		logFile     = flag.String("log-file", "", "日志文件路径（为空表示输出到标准输出）")
+		logMaxSize  = flag.Int("log-max-size", 100, "日志文件轮转阈值（MB），0 表示不轮转")
+		logBackups  = flag.Int("log-max-backups", 5, "轮转时保留的日志备份数")
+		logSample   = flag.Int("log-sample", 0, "DEBUG/INFO 日志采样：同一调用点每秒超过突发额度后只记录 1/N（0 或 1 表示不采样）")
+		events      = flag.String("accepted-events", "", "允许处理的事件键，逗号分隔，如 push,pull_request.opened,pull_request.synchronize（为空表示全部）")
+		recordSkip  = flag.Bool("record-skipped", false, "将被过滤跳过的 push/PR 事件以 skipped 状态保存并记录跳过原因")
+		payloadKeys = flag.String("payload-keys", "", "持久化 payload 时保留的键，逗号分隔，支持嵌套如 repository.full_name（为空表示保存完整 payload）")
+		redactOn    = flag.Bool("redact-secrets", true, "持久化前将 payload 中疑似密钥的字符串替换为 [REDACTED]")
+		redactFile  = flag.String("redact-patterns-file", "", "密钥正则文件，每行一个，替换内置规则（为空表示使用内置规则）")
+		redactHigh  = flag.Bool("redact-high-entropy", true, "同时替换高熵的长字符串（疑似未知格式的密钥）")
+		timezone    = flag.String("timezone", models.DefaultTimeZone, "事件时间序列化和显示使用的时区（IANA 名称，如 America/Los_Angeles、UTC）")
+		pipelines   = flag.String("pipeline-config", "", "按仓库选择检查流水线的 JSON 配置文件，收到 SIGHUP 时重新加载（为空表示所有仓库使用默认流水线）")
+
+		notifyURL         = flag.String("notify-url", "", "事件完成时 POST 通知的地址（为空表示不通知）")
+		notifyMaxAttempts = flag.Int("notify-max-attempts", notify.DefaultMaxAttempts, "通知最大投递次数，超过后进入死信列表")
+		notifyBackoff     = flag.Duration("notify-backoff", notify.DefaultBaseBackoff, "通知首次重试等待时间，之后指数增长")
+		notifyStateFile   = flag.String("notify-state-file", "", "通知队列持久化文件（为空表示仅保存在内存中）")
+
+		rateLimits        = flag.String("rate-limits", "", "按路径前缀限流，逗号分隔的 前缀=每秒请求数[:突发]，如 /webhook=100,/api/=5（为空表示不限流）")
+		webhookSecret     = flag.String("webhook-secret", "", "Webhook 签名密钥，用于校验 X-Hub-Signature-256（环境变量: QUALITY_WEBHOOK_SECRET；推荐使用 -webhook-secret-file）")
+		webhookSecretFile = flag.String("webhook-secret-file", "", "从文件读取 Webhook 签名密钥，优先于 -webhook-secret 和环境变量；文件权限须为 600")
+		workers           = flag.Int("workers", api.DefaultWorkers, "异步处理 Webhook 事件的并发 worker 数")
+		queueSize         = flag.Int("queue-size", api.DefaultQueueSize, "等待处理的事件队列容量，队列满时 Webhook 返回 503")
+		outputPreview     = flag.Int("output-preview-bytes", api.DefaultOutputPreviewBytes, "事件和检查列表响应中每个检查 output 保留的字节数，完整输出通过 /api/quality-checks/{id}/output 获取（0 表示不截断）")
+		shutdownGrace     = flag.Duration("shutdown-grace", defaultShutdownGrace, "收到 SIGINT/SIGTERM 后等待进行中请求完成的时长，超时后强制关闭连接")
 	)
 	flag.Parse()
 
@@ -37,11 +77,31 @@ func main() {
 	logger.SetLevel(level)
 	logger.SetJSONFormat(*jsonFormat)
 	logger.SetColor(!*noColor)
+	logger.SetSampling(*logSample)
+	if *logFile != "" {
+		out := logger.NewRotatingWriter(*logFile, *logMaxSize, *logBackups)
+		defer out.Close()
+		logger.SetOutput(out)
+		logger.SetColor(false)
+		// 处理器中使用标准库 log 的输出也写入同一文件
+		log.SetOutput(out)
+	}
 
 	logger.Info("Starting Quality Server")
-	logger.Infof("Version: %s", "1.0.0")
+	logger.Infof("Version: %s", version.String())
 	logger.Infof("Log level: %s", *logLevel)
 
+	loc, err := time.LoadLocation(*timezone)
+	if err != nil {
+		logger.ErrorWithFields("Invalid timezone", map[string]interface{}{
+			"error":    err.Error(),
+			"timezone": *timezone,
+		})
+		os.Exit(1)
+	}
+	models.SetTimeZone(loc)
+	logger.Infof("Timezone: %s", loc)
+
 	// 检查数据库连接字符串
 	if *dbDSN == "" {
 		logger.Fatal("MySQL database connection string is required. Use -db flag to provide it.")
@@ -52,12 +112,29 @@ func main() {
 	if err != nil {
 		logger.ErrorWithFields("Failed to create MySQL storage", map[string]interface{}{
 			"error": err.Error(),
-			"dsn":   *dbDSN,
+			"db":    storage.RedactDSN(*dbDSN),
 		})
 		os.Exit(1)
 	}
 	logger.Info("MySQL storage initialized successfully")
 
+	// 数据库迁移
+	if *migrateDry || *migrateOn {
+		plan, err := storage.Migrate(store, *migrateDry, os.Stdout)
+		if err != nil {
+			logger.ErrorWithFields("Failed to migrate database", map[string]interface{}{
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+		if *migrateDry {
+			os.Exit(0)
+		}
+		if len(plan) > 0 {
+			logger.Infof("Applied %d database migration(s)", len(plan))
+		}
+	}
+
 	// 创建质量引擎服务器
 	server, err := api.NewServerWithStorage(store)
 	if err != nil {
@@ -67,11 +144,134 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *workers <= 0 || *queueSize <= 0 {
+		logger.ErrorWithFields("Invalid worker pool size", map[string]interface{}{
+			"workers":    *workers,
+			"queue_size": *queueSize,
+		})
+		os.Exit(1)
+	}
+	server.SetWorkers(*workers, *queueSize)
+	logger.Infof("Event workers: %d, queue size: %d", *workers, *queueSize)
+
+	if *shutdownGrace <= 0 {
+		logger.ErrorWithFields("Invalid shutdown grace", map[string]interface{}{
+			"shutdown_grace": shutdownGrace.String(),
+		})
+		os.Exit(1)
+	}
+
+	if *outputPreview < 0 {
+		logger.ErrorWithFields("Invalid output preview size", map[string]interface{}{
+			"output_preview_bytes": *outputPreview,
+		})
+		os.Exit(1)
+	}
+	server.SetOutputPreviewBytes(*outputPreview)
+
+	secret, err := resolveSecret(*webhookSecretFile, *webhookSecret, "QUALITY_WEBHOOK_SECRET")
+	if err != nil {
+		logger.ErrorWithFields("Failed to load webhook secret", map[string]interface{}{
+			"error": err.Error(),
+		})
+		os.Exit(1)
+	}
+	if secret != "" {
+		server.SetWebhookSecret(secret)
+		logger.Info("Webhook signature verification enabled")
+	}
+
+	if *events != "" {
+		server.SetAcceptedEvents(strings.Split(*events, ","))
+		logger.Infof("Accepted events: %s", *events)
+	}
+
+	if *recordSkip {
+		server.SetRecordSkipped(true)
+		logger.Info("Recording skipped events")
+	}
+
+	var dispatcher *notify.Dispatcher
+	if *notifyURL != "" {
+		dispatcher, err = notify.NewDispatcher(*notifyURL, *notifyStateFile, nil)
+		if err != nil {
+			logger.ErrorWithFields("Failed to create notification dispatcher", map[string]interface{}{
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+		dispatcher.MaxAttempts = *notifyMaxAttempts
+		dispatcher.BaseBackoff = *notifyBackoff
+		dispatcher.Start()
+		server.SetNotifier(dispatcher)
+		logger.Infof("Completion notifications: %s", *notifyURL)
+	}
+
+	if *payloadKeys != "" {
+		models.SetPayloadAllowList(strings.Split(*payloadKeys, ","))
+		logger.Infof("Payload allow-list: %s", *payloadKeys)
+	}
+
+	if *redactOn {
+		patterns := models.DefaultSecretPatterns
+		if *redactFile != "" {
+			if patterns, err = models.LoadSecretPatterns(*redactFile); err != nil {
+				logger.ErrorWithFields("Failed to load secret patterns", map[string]interface{}{
+					"error": err.Error(),
+					"file":  *redactFile,
+				})
+				os.Exit(1)
+			}
+		}
+		redactor, err := models.NewSecretRedactor(patterns, *redactHigh)
+		if err != nil {
+			logger.ErrorWithFields("Invalid secret pattern", map[string]interface{}{
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+		models.SetSecretRedactor(redactor)
+		logger.Infof("Payload secret redaction: %d patterns, high-entropy detection %v", len(patterns), *redactHigh)
+	} else {
+		models.SetSecretRedactor(nil)
+		logger.Warn("Payload secret redaction disabled")
+	}
+
+	if *pipelines != "" {
+		set, err := models.LoadPipelineSet(*pipelines)
+		if err != nil {
+			logger.ErrorWithFields("Failed to load pipeline config", map[string]interface{}{
+				"error": err.Error(),
+				"file":  *pipelines,
+			})
+			os.Exit(1)
+		}
+		models.SetPipelineSet(set)
+		logger.Infof("Pipeline config: %s (%d pipelines, %d repository overrides, fingerprint %s)", *pipelines, len(set.Pipelines), len(set.Repositories), set.Fingerprint())
+		watchReload(*pipelines)
+	}
+
+	// 状态接口展示真实的数据库地址（不包含凭据）
+	if dbHost, dbName, err := storage.ParseDSNInfo(*dbDSN); err == nil {
+		server.SetDatabaseInfo("MySQL", dbHost, dbName)
+	}
+
 	// 创建HTTP多路复用器
 	mux := http.NewServeMux()
 
-	// 添加日志中间件
-	handler := logger.LoggingMiddleware(mux)
+	rules, err := parseRateLimits(*rateLimits, time.Now())
+	if err != nil {
+		logger.ErrorWithFields("Invalid rate limits", map[string]interface{}{
+			"error": err.Error(),
+		})
+		os.Exit(1)
+	}
+	if len(rules) > 0 {
+		logger.Infof("Rate limits: %s", *rateLimits)
+	}
+
+	// 添加日志中间件（被限流的请求同样记录），外层按 Accept-Encoding 压缩响应
+	handler := gzipMiddleware(logger.LoggingMiddleware(rateLimitMiddleware(rules, mux)))
 
 	// 注册路由
 	server.RegisterRoutes(mux)
@@ -82,11 +282,62 @@ func main() {
 	logger.Infof("API endpoint: http://localhost%s/api", *addr)
 	logger.Info("Ready to accept requests")
 
-	if err := http.ListenAndServe(*addr, handler); err != nil {
+	httpSrv := &http.Server{
+		Addr:              *addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
 		logger.ErrorWithFields("Failed to start server", map[string]interface{}{
 			"error": err.Error(),
 			"addr":  *addr,
 		})
 		os.Exit(1)
 	}
+
+	var grpcSrv *grpc.Server
+	if *grpcAddr != "" {
+		grpcLn, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			logger.ErrorWithFields("Failed to start gRPC server", map[string]interface{}{
+				"error": err.Error(),
+				"addr":  *grpcAddr,
+			})
+			os.Exit(1)
+		}
+		grpcSrv = grpc.NewServer()
+		grpcapi.NewServer(store).Register(grpcSrv)
+		go func() {
+			if err := grpcSrv.Serve(grpcLn); err != nil {
+				logger.ErrorWithFields("gRPC server stopped", map[string]interface{}{
+					"error": err.Error(),
+					"addr":  *grpcAddr,
+				})
+			}
+		}()
+		logger.Infof("gRPC endpoint: %s", *grpcAddr)
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	err = serveUntilSignal(httpSrv, ln, *shutdownGrace, stop)
+	if grpcSrv != nil {
+		stopGRPC(grpcSrv, *shutdownGrace)
+	}
+	// HTTP 服务停止后不再有新事件入队，等待 worker 处理完已入队的事件，
+	// 再停止通知投递，让这些事件产生的完成通知写入持久化队列
+	server.Close()
+	if dispatcher != nil {
+		dispatcher.Stop()
+	}
+	if err != nil {
+		logger.ErrorWithFields("Server stopped with error", map[string]interface{}{
+			"error": err.Error(),
+			"addr":  *addr,
+		})
+		os.Exit(1)
+	}
+	logger.Info("Server stopped")
 }
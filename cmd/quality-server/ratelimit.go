@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenBucket 令牌桶：每秒补充 rate 个令牌，最多积累 burst 个
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate, burst float64, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+// take 取一个令牌；令牌不足时返回需要等待的时间
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	return false, wait
+}
+
+// rateRule 一条路径限流规则，按路径前缀匹配
+type rateRule struct {
+	prefix string
+	bucket *tokenBucket
+}
+
+// parseRateLimits 解析限流配置，格式为逗号分隔的 前缀=每秒请求数[:突发]，
+// 如 "/webhook=100,/api/=5:10"；突发默认等于每秒请求数（至少 1）
+// 规则按前缀长度降序排列，请求匹配最长的前缀
+func parseRateLimits(spec string, now time.Time) ([]rateRule, error) {
+	var rules []rateRule
+	seen := map[string]bool{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		prefix, value, ok := strings.Cut(part, "=")
+		prefix = strings.TrimSpace(prefix)
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("invalid rate limit %q, expected /path=rate[:burst]", part)
+		}
+		if seen[prefix] {
+			return nil, fmt.Errorf("duplicate rate limit for %s", prefix)
+		}
+		seen[prefix] = true
+
+		rateStr, burstStr, hasBurst := strings.Cut(strings.TrimSpace(value), ":")
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("invalid rate for %s: %q", prefix, rateStr)
+		}
+		burst := math.Max(1, rate)
+		if hasBurst {
+			n, err := strconv.Atoi(burstStr)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid burst for %s: %q", prefix, burstStr)
+			}
+			burst = float64(n)
+		}
+		rules = append(rules, rateRule{prefix: prefix, bucket: newTokenBucket(rate, burst, now)})
+	}
+	sort.SliceStable(rules, func(i, j int) bool { return len(rules[i].prefix) > len(rules[j].prefix) })
+	return rules, nil
+}
+
+// rateLimitMiddleware 按路径限流，超限返回 429 并设置 Retry-After（秒）
+// 未匹配任何规则的路径不限流；rules 为空时直接返回 next
+func rateLimitMiddleware(rules []rateRule, next http.Handler) http.Handler {
+	if len(rules) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, rule := range rules {
+			if !strings.HasPrefix(r.URL.Path, rule.prefix) {
+				continue
+			}
+			if ok, wait := rule.bucket.take(time.Now()); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+			break
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimitMiddleware_WebhookAllowsMoreThanAPI(t *testing.T) {
+	rules, err := parseRateLimits("/webhook=0.01:20,/api/=0.01:3", time.Now())
+	if err != nil {
+		t.Fatalf("parseRateLimits: %v", err)
+	}
+	handler := rateLimitMiddleware(rules, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	allowedBeforeLimit := func(path string) (int, *httptest.ResponseRecorder) {
+		for i := 0; i < 100; i++ {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+			if rec.Code == http.StatusTooManyRequests {
+				return i, rec
+			}
+		}
+		return 100, nil
+	}
+
+	webhook, webhookRec := allowedBeforeLimit("/webhook")
+	admin, adminRec := allowedBeforeLimit("/api/events")
+	if webhook != 20 || admin != 3 {
+		t.Fatalf("expected 20 webhook and 3 admin requests before limiting, got %d and %d", webhook, admin)
+	}
+	for _, rec := range []*httptest.ResponseRecorder{webhookRec, adminRec} {
+		if s, err := strconv.Atoi(rec.Header().Get("Retry-After")); err != nil || s < 1 {
+			t.Errorf("expected a positive Retry-After, got %q", rec.Header().Get("Retry-After"))
+		}
+	}
+
+	// 未配置的路径不限流
+	if n, _ := allowedBeforeLimit("/health"); n != 100 {
+		t.Errorf("expected unmatched paths to be unlimited, got limited after %d", n)
+	}
+}
+
+func TestTokenBucket_Refill(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	b := newTokenBucket(2, 2, now)
+	for i := 0; i < 2; i++ {
+		if ok, _ := b.take(now); !ok {
+			t.Fatalf("request %d: expected burst to allow", i)
+		}
+	}
+	ok, wait := b.take(now)
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("expected 500ms wait after burst, got ok=%v wait=%v", ok, wait)
+	}
+	if ok, _ := b.take(now.Add(500 * time.Millisecond)); !ok {
+		t.Error("expected one token after 500ms at 2/s")
+	}
+}
+
+func TestParseRateLimits(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{spec: "", want: nil},
+		{spec: "/api/=5, /api/events/ingest=1, /webhook=100", want: []string{"/api/events/ingest", "/webhook", "/api/"}},
+		{spec: "webhook=5", wantErr: true},
+		{spec: "/webhook", wantErr: true},
+		{spec: "/webhook=0", wantErr: true},
+		{spec: "/webhook=5:0", wantErr: true},
+		{spec: "/webhook=5,/webhook=6", wantErr: true},
+	}
+	for _, tt := range tests {
+		rules, err := parseRateLimits(tt.spec, time.Now())
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected error", tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.spec, err)
+			continue
+		}
+		if len(rules) != len(tt.want) {
+			t.Errorf("%q: expected %d rules, got %d", tt.spec, len(tt.want), len(rules))
+			continue
+		}
+		for i, prefix := range tt.want {
+			if rules[i].prefix != prefix {
+				t.Errorf("%q: rule %d prefix %q, want %q", tt.spec, i, rules[i].prefix, prefix)
+			}
+		}
+	}
+
+	rules, _ := parseRateLimits("/webhook=0.5", time.Now())
+	if rules[0].bucket.burst != 1 {
+		t.Errorf("expected burst of at least 1, got %v", rules[0].bucket.burst)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github-hub/internal/quality/logger"
+	"github-hub/internal/quality/models"
+)
+
+// reloadPipelines 重新加载流水线配置文件；校验失败时保留当前配置
+func reloadPipelines(path string) error {
+	set, err := models.LoadPipelineSet(path)
+	if err != nil {
+		return err
+	}
+	models.SetPipelineSet(set)
+	return nil
+}
+
+// watchReload 收到 SIGHUP 时重新加载流水线配置，不影响正在处理的请求
+// 已创建的检查项不变，新事件使用新配置
+func watchReload(path string) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	go func() {
+		for range ch {
+			old := models.PipelineFingerprint()
+			if err := reloadPipelines(path); err != nil {
+				logger.ErrorWithFields("Failed to reload pipeline config, keeping current config", map[string]interface{}{
+					"error":       err.Error(),
+					"file":        path,
+					"fingerprint": old,
+				})
+				continue
+			}
+			logger.WithFields(map[string]interface{}{
+				"file":            path,
+				"old_fingerprint": old,
+				"new_fingerprint": models.PipelineFingerprint(),
+			}).Info("Pipeline config reloaded")
+		}
+	}()
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github-hub/internal/quality/models"
+)
+
+func TestReloadPipelines(t *testing.T) {
+	defer models.SetPipelineSet(nil)
+	file := filepath.Join(t.TempDir(), "pipelines.json")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"pipelines":{"lint":{"stages":[{"stage":"basic_ci","checks":["code_lint"]}]}},"repositories":{"myorg/*":"lint"}}`)
+	if err := reloadPipelines(file); err != nil {
+		t.Fatalf("initial load: %v", err)
+	}
+	first := models.PipelineFingerprint()
+	if models.PipelineFor("myorg/app").Name != "lint" {
+		t.Fatalf("expected lint pipeline after load")
+	}
+
+	write(`{"pipelines":{"build":{"stages":[{"stage":"basic_ci","checks":["compilation"]}]}},"repositories":{"myorg/*":"build"}}`)
+	if err := reloadPipelines(file); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	second := models.PipelineFingerprint()
+	if second == first {
+		t.Fatalf("fingerprint unchanged after reload: %s", second)
+	}
+	if models.PipelineFor("myorg/app").Name != "build" {
+		t.Fatalf("expected build pipeline after reload")
+	}
+
+	write(`{"pipelines":{},"repositories":{"myorg/*":"missing"}}`)
+	if err := reloadPipelines(file); err == nil {
+		t.Fatal("expected invalid config to be rejected")
+	}
+	if got := models.PipelineFingerprint(); got != second {
+		t.Fatalf("invalid reload replaced config: fingerprint %s, want %s", got, second)
+	}
+}
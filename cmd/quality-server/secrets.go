@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// readSecretFile 从文件读取密钥，去除首尾空白
+// 文件对组或其他用户可读写时拒绝加载（Windows 不检查权限位）
+func readSecretFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("stat secret file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("secret file %s is not a regular file", path)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		return "", fmt.Errorf("secret file %s has permissions %#o, must not be accessible by group or others (chmod 600)", path, info.Mode().Perm())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read secret file: %w", err)
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("secret file %s is empty", path)
+	}
+	return secret, nil
+}
+
+// resolveSecret 按优先级确定密钥：文件 > 命令行参数 > 环境变量
+func resolveSecret(filePath, flagValue, envName string) (string, error) {
+	if filePath != "" {
+		return readSecretFile(filePath)
+	}
+	if flagValue != "" {
+		return flagValue, nil
+	}
+	return strings.TrimSpace(os.Getenv(envName)), nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func writeSecret(t *testing.T, content string, perm os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, perm); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadSecretFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		perm    os.FileMode
+		want    string
+		wantErr bool
+	}{
+		{name: "owner only", content: "s3cret\n", perm: 0o600, want: "s3cret"},
+		{name: "empty", content: " \n", perm: 0o600, wantErr: true},
+		{name: "group readable", content: "s3cret", perm: 0o640, wantErr: true},
+		{name: "world readable", content: "s3cret", perm: 0o644, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if runtime.GOOS == "windows" && tt.perm != 0o600 {
+				t.Skip("permission bits are not checked on windows")
+			}
+			got, err := readSecretFile(writeSecret(t, tt.content, tt.perm))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := readSecretFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestResolveSecret_Precedence(t *testing.T) {
+	const envName = "QUALITY_TEST_SECRET"
+	file := writeSecret(t, "from-file", 0o600)
+
+	tests := []struct {
+		name string
+		file string
+		flag string
+		env  string
+		want string
+	}{
+		{name: "file wins over flag and env", file: file, flag: "from-flag", env: "from-env", want: "from-file"},
+		{name: "flag wins over env", flag: "from-flag", env: "from-env", want: "from-flag"},
+		{name: "env as fallback", env: "from-env", want: "from-env"},
+		{name: "nothing set", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envName, tt.env)
+			got, err := resolveSecret(tt.file, tt.flag, envName)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
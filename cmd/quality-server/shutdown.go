@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github-hub/internal/quality/logger"
+)
+
+// defaultShutdownGrace 收到 SIGINT/SIGTERM 后等待进行中请求完成的默认时长
+const defaultShutdownGrace = 30 * time.Second
+
+// serveUntilSignal 在 ln 上提供 HTTP 服务，直到服务出错或 stop 收到信号
+// 收到信号后停止接受新连接，最多等待 grace 让进行中的请求完成，超时后强制关闭剩余连接
+func serveUntilSignal(httpSrv *http.Server, ln net.Listener, grace time.Duration, stop <-chan os.Signal) error {
+	errCh := make(chan error, 1)
+	go func() { errCh <- httpSrv.Serve(ln) }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case sig := <-stop:
+		logger.Infof("Received %s, shutting down (grace %s)", sig, grace)
+		ctx, cancel := context.WithTimeout(context.Background(), grace)
+		defer cancel()
+		err := httpSrv.Shutdown(ctx)
+		if err != nil {
+			_ = httpSrv.Close()
+		}
+		if serveErr := <-errCh; serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+			return serveErr
+		}
+		if err != nil {
+			return fmt.Errorf("shutdown: %w", err)
+		}
+		return nil
+	}
+}
+
+// stopGRPC 停止接受新的 gRPC 调用，最多等待 grace 让进行中的调用完成，超时后强制关闭
+func stopGRPC(gs *grpc.Server, grace time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		gs.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(grace):
+		gs.Stop()
+		<-done
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestServeUntilSignal 测试收到信号后等待进行中的请求完成，超过 grace 时返回错误
+func TestServeUntilSignal(t *testing.T) {
+	tests := []struct {
+		name       string
+		grace      time.Duration
+		release    bool // 关闭过程中让处理器返回
+		wantStatus int
+		wantErr    bool
+	}{
+		{name: "request finishes within grace", grace: 5 * time.Second, release: true, wantStatus: http.StatusOK},
+		{name: "grace period expires", grace: 50 * time.Millisecond, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			release := make(chan struct{})
+			httpSrv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				select {
+				case <-release:
+				case <-r.Context().Done():
+				}
+				_, _ = io.WriteString(w, "done")
+			})}
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stop := make(chan os.Signal, 1)
+			done := make(chan error, 1)
+			go func() { done <- serveUntilSignal(httpSrv, ln, tt.grace, stop) }()
+
+			respCh := make(chan int, 1)
+			go func() {
+				resp, err := http.Get("http://" + ln.Addr().String() + "/")
+				if err != nil {
+					respCh <- 0
+					return
+				}
+				_ = resp.Body.Close()
+				respCh <- resp.StatusCode
+			}()
+
+			<-started
+			stop <- syscall.SIGTERM
+			if tt.release {
+				time.Sleep(20 * time.Millisecond)
+				close(release)
+			}
+
+			select {
+			case err := <-done:
+				if (err != nil) != tt.wantErr {
+					t.Errorf("err=%v, wantErr=%v", err, tt.wantErr)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("serveUntilSignal did not return")
+			}
+			if status := <-respCh; status != tt.wantStatus {
+				t.Errorf("status=%d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
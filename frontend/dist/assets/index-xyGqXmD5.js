@@ -294,4 +294,4 @@ Error generating stack: `+i.message+`
   "pr_author": "username",
   "source_branch": "feature/branch",
   "target_branch": "main"
-}`,required:!0})]}),a.jsxs("p",{className:"help-text",children:[a.jsx("strong",{children:"注意："}),"自定义测试会检查是否与预定义数据重复。如果参数相同，请使用预定义测试。"]}),a.jsx("button",{type:"submit",className:"btn btn-primary",disabled:o,children:o?"测试中...":"提交测试"}),v&&a.jsxs("div",{className:"test-error",style:{marginTop:"16px",backgroundColor:"#fee2e2",border:"1px solid #ef4444",borderRadius:"8px",padding:"16px"},children:[a.jsx("div",{style:{color:"#b91c1c",fontWeight:"600",marginBottom:"8px"},children:"测试失败"}),a.jsx("pre",{style:{margin:0,color:"#7f1d1d",fontSize:"14px"},children:v})]}),u&&a.jsxs("div",{className:"test-result",style:{marginTop:"16px"},children:[a.jsx("h3",{children:"测试结果"}),a.jsx("pre",{children:JSON.stringify(u,null,2)})]})]})]})})}const Mf=e=>(typeof e=="string"?e:JSON.stringify(e,null,2)).replace(/"([^"]+)"\s*:/g,'<span class="json-key">"$1"</span>:').replace(/:\s*"([^"]+)"/g,': <span class="json-string">"$1"</span>').replace(/:\s*(\d+)/g,': <span class="json-number">$1</span>').replace(/:\s*(true|false)/g,': <span class="json-boolean">$1</span>').replace(/:\s*(null)/g,': <span class="json-null">$1</span>');function Ws({isLoggedIn:e}){const[t,n]=D.useState([]),[r,l]=D.useState(!0),[i,o]=D.useState(!1),[s,u]=D.useState(""),[d,v]=D.useState(null),[m,h]=D.useState(!1),[k,S]=D.useState(!1),[g,O]=D.useState({page:1,page_size:20,total:0,total_pages:0});D.useEffect(()=>{f()},[g.page]);const f=async(w=!1)=>{try{w?l(!0):o(!0);const F=new URLSearchParams({page:g.page.toString(),page_size:g.page_size.toString()}),J=await(await fetch(`/api/events?${F}`)).json();J.success?(n(J.data||[]),O(J.pagination||g)):u(J.message||"加载事件失败")}catch(F){u("加载事件失败："+F.message)}finally{w?l(!1):o(!1)}};D.useEffect(()=>{f(!0)},[]);const c=w=>{w>=1&&w<=g.total_pages&&O({...g,page:w})},p=w=>{O({...g,page_size:w,page:1})},y=async w=>{if(window.confirm("确定要删除这个事件吗？此操作不可恢复。"))try{const J=await(await fetch(`/api/events/${w}`,{method:"DELETE",headers:{"Content-Type":"application/json"},credentials:"include"})).json();J.success?f():u(J.message||"删除失败")}catch(A){u("删除失败："+A.message)}},E=async()=>{if(window.confirm("危险操作！确定要清空整个数据库吗？所有事件记录将被永久删除，此操作不可恢复。"))try{const A=await(await fetch("/api/events",{method:"DELETE",headers:{"Content-Type":"application/json"},credentials:"include"})).json();A.success?f():u(A.message||"清空数据库失败")}catch(F){u("清空数据库失败："+F.message)}},C=async w=>{try{S(!0);const A=await(await fetch(`/api/events/${w}`)).json();A.success?(v(A.data),h(!0)):u(A.message||"获取事件详情失败")}catch(F){u("获取事件详情失败："+F.message)}finally{S(!1)}},N=()=>{h(!1),v(null)},P=w=>{switch(w.toLowerCase()){case"pending":return"status-pending";case"completed":return"status-completed";case"failed":return"status-failed";case"skipped":return"status-skipped";default:return"status-processing"}},$=w=>{switch(w.toLowerCase()){case"pending":return"待处理";case"completed":return"已完成";case"failed":return"失败";case"skipped":return"跳过";default:return"处理中"}},L=w=>{const F=w.toLowerCase();return{compilation:"编译检查",code_lint:"代码规范检查",security_scan:"安全扫描",unit_test:"单元测试",deployment:"部署",api_test:"API测试",module_e2e:"模块端到端测试",agent_e2e:"Agent端到端测试",ai_e2e:"AI端到端测试"}[F]||w},ce=w=>{switch(w.toLowerCase()){case"pending":return"status-pending";case"running":return"status-processing";case"passed":return"status-completed";case"failed":return"status-failed";case"skipped":return"status-skipped";case"cancelled":return"status-skipped";default:return""}},We=w=>{switch(w.toLowerCase()){case"pending":return"未开始";case"running":return"运行中";case"passed":return"通过";case"failed":return"失败";case"skipped":return"跳过";case"cancelled":return"取消";default:return w}},kt=()=>{const w=[],{page:F,total_pages:A}=g;if(w.push(1),A<=7)for(let J=2;J<=A;J++)w.push(J);else{F>4&&w.push("...");let J=Math.max(2,F-2),j=Math.min(F+2,A-1);F<=3?(J=2,j=5):F>=A-2&&(J=A-4,j=A-1);for(let z=J;z<=j;z++)w.push(z);F<A-3&&w.push("..."),A>1&&w.push(A)}return w};return r?a.jsx("div",{className:"loading",children:"加载中..."}):a.jsxs("div",{children:[a.jsx("div",{className:"event-history",children:a.jsxs("div",{className:"card",children:[a.jsx("h2",{children:"GitHub 事件"}),a.jsx("p",{children:"查看和管理所有 GitHub 事件记录"}),e&&a.jsx("div",{className:"action-buttons",children:a.jsx("button",{className:"btn btn-danger",onClick:E,children:"一键清理数据库"})}),s&&a.jsx("div",{className:"message message-error",children:s}),i&&a.jsx("div",{className:"message message-info",style:{marginBottom:"16px"},children:"加载中..."}),a.jsx("div",{className:"table-container",children:t.length>0?a.jsxs("table",{className:"table",children:[a.jsx("thead",{children:a.jsxs("tr",{children:[a.jsx("th",{children:"ID"}),a.jsx("th",{children:"事件类型"}),a.jsx("th",{children:"仓库"}),a.jsx("th",{children:"分支"}),a.jsx("th",{children:"状态"}),a.jsx("th",{children:"时间"}),a.jsx("th",{children:"操作"})]})}),a.jsx("tbody",{children:t.map(w=>a.jsxs("tr",{children:[a.jsx("td",{title:w.id,children:w.id}),a.jsx("td",{title:w.event_type,children:w.event_type}),a.jsx("td",{title:w.repository,children:w.repository}),a.jsx("td",{title:w.branch,children:w.branch}),a.jsx("td",{title:$(w.event_status),children:a.jsx("span",{className:`status ${P(w.event_status)}`,children:$(w.event_status)})}),a.jsx("td",{title:w.created_at,children:w.created_at}),a.jsxs("td",{children:[a.jsx("button",{className:"btn btn-info",onClick:()=>C(w.id),children:"详情"}),e&&a.jsx("button",{className:"btn btn-danger",onClick:()=>y(w.id),style:{marginLeft:"8px"},children:"删除"})]})]},w.id))})]}):a.jsxs("div",{className:"empty-state",children:[a.jsx("svg",{width:"64",height:"64",viewBox:"0 0 24 24",fill:"none",stroke:"currentColor",strokeWidth:"1.5",children:a.jsx("path",{d:"M9 12h6m-6 4h6m2 5H7a2 2 0 01-2-2V5a2 2 0 012-2h5.586a1 1 0 01.707.293l5.414 5.414a1 1 0 01.293.707V19a2 2 0 01-2 2z",strokeLinecap:"round",strokeLinejoin:"round"})}),a.jsx("p",{children:"暂无事件记录"}),a.jsx("p",{className:"empty-state-hint",children:"当接收到 GitHub Webhook 事件后，此处将显示事件列表"})]})}),g.total_pages>1&&a.jsxs("div",{className:"pagination",style:{marginTop:"20px",display:"flex",justifyContent:"center",alignItems:"center",gap:"10px",flexWrap:"wrap"},children:[a.jsxs("div",{className:"page-size-selector",style:{display:"flex",alignItems:"center",gap:"8px"},children:[a.jsx("span",{children:"每页显示:"}),a.jsxs("select",{value:g.page_size,onChange:w=>p(parseInt(w.target.value)),style:{padding:"5px 10px",borderRadius:"4px",border:"1px solid #ddd"},children:[a.jsx("option",{value:"10",children:"10"}),a.jsx("option",{value:"20",children:"20"}),a.jsx("option",{value:"50",children:"50"}),a.jsx("option",{value:"100",children:"100"})]})]}),a.jsxs("div",{className:"pagination-buttons",style:{display:"flex",gap:"5px"},children:[a.jsx("button",{className:"btn",onClick:()=>c(1),disabled:g.page===1,style:{opacity:g.page===1?.5:1},children:"首页"}),a.jsx("button",{className:"btn",onClick:()=>c(g.page-1),disabled:g.page===1,style:{opacity:g.page===1?.5:1},children:"上一页"}),kt().map((w,F)=>w==="..."?a.jsx("span",{style:{padding:"5px"},children:"..."},`ellipsis-${F}`):a.jsx("button",{className:"btn",onClick:()=>c(w),disabled:w===g.page,style:{padding:"5px 12px",backgroundColor:w===g.page?"#007bff":"#f8f9fa",color:w===g.page?"#fff":"#333",border:"1px solid #ddd"},children:w},w)),a.jsx("button",{className:"btn",onClick:()=>c(g.page+1),disabled:g.page===g.total_pages,style:{opacity:g.page===g.total_pages?.5:1},children:"下一页"}),a.jsx("button",{className:"btn",onClick:()=>c(g.total_pages),disabled:g.page===g.total_pages,style:{opacity:g.page===g.total_pages?.5:1},children:"末页"})]}),a.jsxs("div",{className:"pagination-info",style:{marginLeft:"20px",color:"#666",fontSize:"14px"},children:["共 ",g.total," 条记录 / ",g.total_pages," 页",a.jsx("br",{}),"当前第 ",g.page," 页"]})]})]})}),m&&d&&a.jsx("div",{className:"modal-overlay",children:a.jsxs("div",{className:"modal-content",style:{maxWidth:"800px",maxHeight:"80vh",overflowY:"auto"},children:[a.jsxs("div",{className:"modal-header",children:[a.jsx("h3",{children:"事件详情"}),a.jsx("button",{className:"btn btn-close",onClick:N,style:{background:"none",border:"none",fontSize:"24px",cursor:"pointer"},children:"×"})]}),a.jsx("div",{className:"modal-body",children:k?a.jsx("div",{className:"loading",children:"加载中..."}):a.jsxs(a.Fragment,{children:[a.jsxs("div",{className:"event-details",children:[a.jsx("h4",{children:"基本信息"}),a.jsxs("div",{className:"detail-grid",children:[a.jsxs("div",{className:"detail-item",children:[a.jsx("span",{className:"detail-label",children:"ID:"}),a.jsx("span",{className:"detail-value",children:d.id})]}),a.jsxs("div",{className:"detail-item",children:[a.jsx("span",{className:"detail-label",children:"事件类型:"}),a.jsx("span",{className:"detail-value",children:d.event_type})]}),a.jsxs("div",{className:"detail-item",children:[a.jsx("span",{className:"detail-label",children:"仓库:"}),a.jsx("span",{className:"detail-value",children:d.repository})]}),a.jsxs("div",{className:"detail-item",children:[a.jsx("span",{className:"detail-label",children:"分支:"}),a.jsx("span",{className:"detail-value",children:d.branch})]}),a.jsxs("div",{className:"detail-item",children:[a.jsx("span",{className:"detail-label",children:"状态:"}),a.jsx("span",{className:`detail-value status ${P(d.event_status)}`,children:$(d.event_status)})]}),a.jsxs("div",{className:"detail-item",children:[a.jsx("span",{className:"detail-label",children:"创建时间:"}),a.jsx("span",{className:"detail-value",children:d.created_at})]}),d.target_branch&&a.jsxs("div",{className:"detail-item",children:[a.jsx("span",{className:"detail-label",children:"目标分支:"}),a.jsx("span",{className:"detail-value",children:d.target_branch})]}),d.pr_number&&a.jsxs("div",{className:"detail-item",children:[a.jsx("span",{className:"detail-label",children:"PR编号:"}),a.jsx("span",{className:"detail-value",children:d.pr_number})]})]})]}),d.quality_checks&&d.quality_checks.length>0&&a.jsxs("div",{className:"quality-checks",style:{marginTop:"20px"},children:[a.jsx("h4",{children:"质量检查项"}),a.jsxs("div",{className:"quality-pipeline",children:[a.jsxs("div",{className:"quality-stage",children:[a.jsx("h5",{children:"基础CI流水线"}),a.jsx("div",{className:"stage-checks",children:d.quality_checks.filter(w=>w.stage==="basic_ci").map(w=>a.jsxs("div",{className:"quality-check",children:[a.jsxs("div",{className:"check-header",children:[a.jsx("span",{className:"check-type",children:L(w.check_type)}),a.jsx("span",{className:`status ${ce(w.check_status)}`,children:We(w.check_status)})]}),w.error_message&&a.jsx("div",{className:"check-error",children:w.error_message})]},w.id))})]}),a.jsxs("div",{className:"quality-stage",style:{marginTop:"20px"},children:[a.jsx("h5",{children:"测试环境部署"}),a.jsx("div",{className:"stage-checks",children:d.quality_checks.filter(w=>w.stage==="deployment").map(w=>a.jsxs("div",{className:"quality-check",children:[a.jsxs("div",{className:"check-header",children:[a.jsx("span",{className:"check-type",children:L(w.check_type)}),a.jsx("span",{className:`status ${ce(w.check_status)}`,children:We(w.check_status)})]}),w.error_message&&a.jsx("div",{className:"check-error",children:w.error_message})]},w.id))})]}),a.jsxs("div",{className:"quality-stage",style:{marginTop:"20px"},children:[a.jsx("h5",{children:"专项测试流水线"}),a.jsx("div",{className:"stage-checks",children:d.quality_checks.filter(w=>w.stage==="specialized_tests").map(w=>a.jsxs("div",{className:"quality-check",children:[a.jsxs("div",{className:"check-header",children:[a.jsx("span",{className:"check-type",children:L(w.check_type)}),a.jsx("span",{className:`status ${ce(w.check_status)}`,children:We(w.check_status)})]}),w.error_message&&a.jsx("div",{className:"check-error",children:w.error_message})]},w.id))})]})]})]}),d.payload&&Object.keys(d.payload).length>0&&a.jsxs("div",{className:"payload-info",style:{marginTop:"20px"},children:[a.jsx("h4",{children:"Payload信息"}),a.jsx("div",{className:"payload-content",style:{backgroundColor:"#f5f5f5",padding:"15px",borderRadius:"4px",maxHeight:"400px",overflow:"auto",fontFamily:"monospace",fontSize:"12px"},children:a.jsx("pre",{style:{margin:0,whiteSpace:"pre-wrap"},dangerouslySetInnerHTML:{__html:Mf(d.payload)}})})]})]})}),a.jsx("div",{className:"modal-footer",children:a.jsx("button",{className:"btn btn-primary",onClick:N,children:"关闭"})})]})})]})}function Df(){const[e,t]=D.useState(null),[n,r]=D.useState(!0),[l,i]=D.useState("");D.useEffect(()=>{o()},[]);const o=async()=>{try{r(!0);const u=await(await fetch("/api/status")).json();u.success?t(u.data):i(u.message||"加载系统状态失败")}catch(s){i("加载系统状态失败："+s.message)}finally{r(!1)}};return n?a.jsx("div",{className:"loading",children:"加载中..."}):a.jsx("div",{className:"system-status",children:a.jsxs("div",{className:"card",children:[a.jsx("h2",{children:"系统状态"}),a.jsx("p",{children:"监控系统运行状态和服务健康情况"}),l&&a.jsx("div",{className:"message message-error",children:l}),e&&a.jsxs("div",{className:"status-container",children:[a.jsxs("div",{className:"status-item",children:[a.jsx("h3",{children:"服务状态"}),a.jsx("div",{className:`status-indicator ${e.service_status==="healthy"?"healthy":"unhealthy"}`,children:e.service_status==="healthy"?"正常":"异常"})]}),a.jsxs("div",{className:"status-item",children:[a.jsx("h3",{children:"数据库状态"}),a.jsx("div",{className:`status-indicator ${e.database_status==="connected"?"healthy":"unhealthy"}`,children:e.database_status==="connected"?"已连接":"未连接"})]}),a.jsxs("div",{className:"status-item",children:[a.jsx("h3",{children:"系统信息"}),a.jsxs("div",{className:"status-details",children:[a.jsxs("p",{children:["版本: ",e.version||"1.0.0"]}),a.jsxs("p",{children:["运行时间: ",e.uptime||"N/A"]}),a.jsxs("p",{children:["事件处理数: ",e.total_events||0]})]})]})]})]})})}function If(){const[e,t]=D.useState("event-history"),[n,r]=D.useState(!1),[l,i]=D.useState(""),[o,s]=D.useState(!1);D.useEffect(()=>{u()},[]);const u=async()=>{try{const k=await(await fetch("/api/check-login",{credentials:"include"})).json();k.is_logged_in&&(r(!0),i(k.username))}catch(h){console.error("检查登录状态失败:",h)}},d=h=>{r(!0),i(h),s(!1)},v=async()=>{try{(await(await fetch("/api/logout",{method:"POST",headers:{"Content-Type":"application/json"},credentials:"include"})).json()).success&&(r(!1),i(""))}catch(h){console.error("退出登录失败:",h)}},m=()=>{switch(e){case"mock-test":return a.jsx(Of,{});case"event-history":return a.jsx(Ws,{isLoggedIn:n});case"system-status":return a.jsx(Df,{});default:return a.jsx(Ws,{isLoggedIn:n})}};return a.jsxs("div",{className:"app",children:[a.jsx("header",{children:a.jsxs("div",{className:"container header-content",children:[a.jsx("div",{className:"logo",children:"Dule Quality Engine"}),a.jsxs("nav",{children:[a.jsxs("div",{className:"nav-links",children:[a.jsx("button",{className:`nav-btn ${e==="event-history"?"active":""}`,onClick:()=>t("event-history"),children:"GitHub 事件"}),a.jsx("button",{className:`nav-link ${e==="mock-test"?"active":""}`,onClick:()=>t("mock-test"),children:"Mock测试"}),a.jsx("button",{className:`nav-link ${e==="system-status"?"active":""}`,onClick:()=>t("system-status"),children:"系统状态"})]}),a.jsx("div",{className:"admin-controls",children:n?a.jsx("button",{className:"admin-btn",onClick:v,children:"登出"}):a.jsx("button",{className:"admin-btn",onClick:()=>s(!0),children:"管理"})})]})]})}),a.jsx("main",{className:"content-container",children:m()}),a.jsx("footer",{children:a.jsxs("div",{className:"container footer-content",children:[a.jsx("div",{className:"footer-info",children:a.jsx("p",{children:"© 2026 Dule Quality Engine. All rights reserved."})}),a.jsxs("div",{className:"footer-links",children:[a.jsx("a",{href:"#",children:"关于"}),a.jsx("a",{href:"#",children:"文档"}),a.jsx("a",{href:"#",children:"支持"})]})]})}),o&&a.jsx(Rf,{onLogin:d,onClose:()=>s(!1)})]})}class Ff extends tu.Component{constructor(t){super(t),this.state={hasError:!1,error:null,errorInfo:null}}static getDerivedStateFromError(t){return{hasError:!0}}componentDidCatch(t,n){this.setState({error:t,errorInfo:n}),console.error("ErrorBoundary caught an error:",t,n)}render(){return this.state.hasError?a.jsxs("div",{style:{padding:"20px",backgroundColor:"#fee2e2",border:"1px solid #ef4444",borderRadius:"8px",margin:"20px"},children:[a.jsx("h2",{style:{color:"#991b1b"},children:"Something went wrong"}),a.jsxs("details",{style:{marginTop:"10px"},children:[a.jsx("summary",{style:{cursor:"pointer",marginBottom:"10px"},children:"Error details"}),a.jsxs("pre",{style:{backgroundColor:"#fef3c7",padding:"10px",borderRadius:"4px",overflow:"auto",fontSize:"12px"},children:[this.state.error&&this.state.error.toString(),a.jsx("br",{}),this.state.errorInfo&&this.state.errorInfo.componentStack]})]}),a.jsx("button",{onClick:()=>window.location.reload(),style:{padding:"10px 20px",backgroundColor:"#6366f1",color:"white",border:"none",borderRadius:"8px",cursor:"pointer",marginTop:"10px"},children:"Reload Page"})]}):this.props.children}}Yl.createRoot(document.getElementById("root")).render(a.jsx(tu.StrictMode,{children:a.jsx(Ff,{children:a.jsx(If,{})})}));
+}`,required:!0})]}),a.jsxs("p",{className:"help-text",children:[a.jsx("strong",{children:"注意："}),"自定义测试会检查是否与预定义数据重复。如果参数相同，请使用预定义测试。"]}),a.jsx("button",{type:"submit",className:"btn btn-primary",disabled:o,children:o?"测试中...":"提交测试"}),v&&a.jsxs("div",{className:"test-error",style:{marginTop:"16px",backgroundColor:"#fee2e2",border:"1px solid #ef4444",borderRadius:"8px",padding:"16px"},children:[a.jsx("div",{style:{color:"#b91c1c",fontWeight:"600",marginBottom:"8px"},children:"测试失败"}),a.jsx("pre",{style:{margin:0,color:"#7f1d1d",fontSize:"14px"},children:v})]}),u&&a.jsxs("div",{className:"test-result",style:{marginTop:"16px"},children:[a.jsx("h3",{children:"测试结果"}),a.jsx("pre",{children:JSON.stringify(u,null,2)})]})]})]})})}const Mf=e=>(typeof e=="string"?e:JSON.stringify(e,null,2)).replace(/"([^"]+)"\s*:/g,'<span class="json-key">"$1"</span>:').replace(/:\s*"([^"]+)"/g,': <span class="json-string">"$1"</span>').replace(/:\s*(\d+)/g,': <span class="json-number">$1</span>').replace(/:\s*(true|false)/g,': <span class="json-boolean">$1</span>').replace(/:\s*(null)/g,': <span class="json-null">$1</span>');function Ws({isLoggedIn:e}){const[t,n]=D.useState([]),[r,l]=D.useState(!0),[i,o]=D.useState(!1),[s,u]=D.useState(""),[d,v]=D.useState(null),[m,h]=D.useState(!1),[k,S]=D.useState(!1),[g,O]=D.useState({page:1,page_size:20,total:0,total_pages:0});D.useEffect(()=>{f()},[g.page]);const f=async(w=!1)=>{try{w?l(!0):o(!0);const F=new URLSearchParams({page:g.page.toString(),page_size:g.page_size.toString()}),J=await(await fetch(`/api/events?${F}`)).json();J.success?(n(J.data||[]),O(J.pagination||g)):u(J.message||"加载事件失败")}catch(F){u("加载事件失败："+F.message)}finally{w?l(!1):o(!1)}};D.useEffect(()=>{f(!0)},[]);const c=w=>{w>=1&&w<=g.total_pages&&O({...g,page:w})},p=w=>{O({...g,page_size:w,page:1})},y=async w=>{if(window.confirm("确定要删除这个事件吗？此操作不可恢复。"))try{const J=await(await fetch(`/api/events/${w}`,{method:"DELETE",headers:{"Content-Type":"application/json"},credentials:"include"})).json();J.success?f():u(J.message||"删除失败")}catch(A){u("删除失败："+A.message)}},E=async()=>{if(window.confirm("危险操作！确定要清空整个数据库吗？所有事件记录将被永久删除，此操作不可恢复。"))try{const A=await(await fetch("/api/events",{method:"DELETE",headers:{"Content-Type":"application/json","X-Confirm-Delete-All":"yes"},credentials:"include"})).json();A.success?f():u(A.message||"清空数据库失败")}catch(F){u("清空数据库失败："+F.message)}},C=async w=>{try{S(!0);const A=await(await fetch(`/api/events/${w}`)).json();A.success?(v(A.data),h(!0)):u(A.message||"获取事件详情失败")}catch(F){u("获取事件详情失败："+F.message)}finally{S(!1)}},N=()=>{h(!1),v(null)},P=w=>{switch(w.toLowerCase()){case"pending":return"status-pending";case"completed":return"status-completed";case"failed":return"status-failed";case"skipped":return"status-skipped";default:return"status-processing"}},$=w=>{switch(w.toLowerCase()){case"pending":return"待处理";case"completed":return"已完成";case"failed":return"失败";case"skipped":return"跳过";default:return"处理中"}},L=w=>{const F=w.toLowerCase();return{compilation:"编译检查",code_lint:"代码规范检查",security_scan:"安全扫描",unit_test:"单元测试",deployment:"部署",api_test:"API测试",module_e2e:"模块端到端测试",agent_e2e:"Agent端到端测试",ai_e2e:"AI端到端测试"}[F]||w},ce=w=>{switch(w.toLowerCase()){case"pending":return"status-pending";case"running":return"status-processing";case"passed":return"status-completed";case"failed":return"status-failed";case"skipped":return"status-skipped";case"cancelled":return"status-skipped";default:return""}},We=w=>{switch(w.toLowerCase()){case"pending":return"未开始";case"running":return"运行中";case"passed":return"通过";case"failed":return"失败";case"skipped":return"跳过";case"cancelled":return"取消";default:return w}},kt=()=>{const w=[],{page:F,total_pages:A}=g;if(w.push(1),A<=7)for(let J=2;J<=A;J++)w.push(J);else{F>4&&w.push("...");let J=Math.max(2,F-2),j=Math.min(F+2,A-1);F<=3?(J=2,j=5):F>=A-2&&(J=A-4,j=A-1);for(let z=J;z<=j;z++)w.push(z);F<A-3&&w.push("..."),A>1&&w.push(A)}return w};return r?a.jsx("div",{className:"loading",children:"加载中..."}):a.jsxs("div",{children:[a.jsx("div",{className:"event-history",children:a.jsxs("div",{className:"card",children:[a.jsx("h2",{children:"GitHub 事件"}),a.jsx("p",{children:"查看和管理所有 GitHub 事件记录"}),e&&a.jsx("div",{className:"action-buttons",children:a.jsx("button",{className:"btn btn-danger",onClick:E,children:"一键清理数据库"})}),s&&a.jsx("div",{className:"message message-error",children:s}),i&&a.jsx("div",{className:"message message-info",style:{marginBottom:"16px"},children:"加载中..."}),a.jsx("div",{className:"table-container",children:t.length>0?a.jsxs("table",{className:"table",children:[a.jsx("thead",{children:a.jsxs("tr",{children:[a.jsx("th",{children:"ID"}),a.jsx("th",{children:"事件类型"}),a.jsx("th",{children:"仓库"}),a.jsx("th",{children:"分支"}),a.jsx("th",{children:"状态"}),a.jsx("th",{children:"时间"}),a.jsx("th",{children:"操作"})]})}),a.jsx("tbody",{children:t.map(w=>a.jsxs("tr",{children:[a.jsx("td",{title:w.id,children:w.id}),a.jsx("td",{title:w.event_type,children:w.event_type}),a.jsx("td",{title:w.repository,children:w.repository}),a.jsx("td",{title:w.branch,children:w.branch}),a.jsx("td",{title:$(w.event_status),children:a.jsx("span",{className:`status ${P(w.event_status)}`,children:$(w.event_status)})}),a.jsx("td",{title:w.created_at,children:w.created_at}),a.jsxs("td",{children:[a.jsx("button",{className:"btn btn-info",onClick:()=>C(w.id),children:"详情"}),e&&a.jsx("button",{className:"btn btn-danger",onClick:()=>y(w.id),style:{marginLeft:"8px"},children:"删除"})]})]},w.id))})]}):a.jsxs("div",{className:"empty-state",children:[a.jsx("svg",{width:"64",height:"64",viewBox:"0 0 24 24",fill:"none",stroke:"currentColor",strokeWidth:"1.5",children:a.jsx("path",{d:"M9 12h6m-6 4h6m2 5H7a2 2 0 01-2-2V5a2 2 0 012-2h5.586a1 1 0 01.707.293l5.414 5.414a1 1 0 01.293.707V19a2 2 0 01-2 2z",strokeLinecap:"round",strokeLinejoin:"round"})}),a.jsx("p",{children:"暂无事件记录"}),a.jsx("p",{className:"empty-state-hint",children:"当接收到 GitHub Webhook 事件后，此处将显示事件列表"})]})}),g.total_pages>1&&a.jsxs("div",{className:"pagination",style:{marginTop:"20px",display:"flex",justifyContent:"center",alignItems:"center",gap:"10px",flexWrap:"wrap"},children:[a.jsxs("div",{className:"page-size-selector",style:{display:"flex",alignItems:"center",gap:"8px"},children:[a.jsx("span",{children:"每页显示:"}),a.jsxs("select",{value:g.page_size,onChange:w=>p(parseInt(w.target.value)),style:{padding:"5px 10px",borderRadius:"4px",border:"1px solid #ddd"},children:[a.jsx("option",{value:"10",children:"10"}),a.jsx("option",{value:"20",children:"20"}),a.jsx("option",{value:"50",children:"50"}),a.jsx("option",{value:"100",children:"100"})]})]}),a.jsxs("div",{className:"pagination-buttons",style:{display:"flex",gap:"5px"},children:[a.jsx("button",{className:"btn",onClick:()=>c(1),disabled:g.page===1,style:{opacity:g.page===1?.5:1},children:"首页"}),a.jsx("button",{className:"btn",onClick:()=>c(g.page-1),disabled:g.page===1,style:{opacity:g.page===1?.5:1},children:"上一页"}),kt().map((w,F)=>w==="..."?a.jsx("span",{style:{padding:"5px"},children:"..."},`ellipsis-${F}`):a.jsx("button",{className:"btn",onClick:()=>c(w),disabled:w===g.page,style:{padding:"5px 12px",backgroundColor:w===g.page?"#007bff":"#f8f9fa",color:w===g.page?"#fff":"#333",border:"1px solid #ddd"},children:w},w)),a.jsx("button",{className:"btn",onClick:()=>c(g.page+1),disabled:g.page===g.total_pages,style:{opacity:g.page===g.total_pages?.5:1},children:"下一页"}),a.jsx("button",{className:"btn",onClick:()=>c(g.total_pages),disabled:g.page===g.total_pages,style:{opacity:g.page===g.total_pages?.5:1},children:"末页"})]}),a.jsxs("div",{className:"pagination-info",style:{marginLeft:"20px",color:"#666",fontSize:"14px"},children:["共 ",g.total," 条记录 / ",g.total_pages," 页",a.jsx("br",{}),"当前第 ",g.page," 页"]})]})]})}),m&&d&&a.jsx("div",{className:"modal-overlay",children:a.jsxs("div",{className:"modal-content",style:{maxWidth:"800px",maxHeight:"80vh",overflowY:"auto"},children:[a.jsxs("div",{className:"modal-header",children:[a.jsx("h3",{children:"事件详情"}),a.jsx("button",{className:"btn btn-close",onClick:N,style:{background:"none",border:"none",fontSize:"24px",cursor:"pointer"},children:"×"})]}),a.jsx("div",{className:"modal-body",children:k?a.jsx("div",{className:"loading",children:"加载中..."}):a.jsxs(a.Fragment,{children:[a.jsxs("div",{className:"event-details",children:[a.jsx("h4",{children:"基本信息"}),a.jsxs("div",{className:"detail-grid",children:[a.jsxs("div",{className:"detail-item",children:[a.jsx("span",{className:"detail-label",children:"ID:"}),a.jsx("span",{className:"detail-value",children:d.id})]}),a.jsxs("div",{className:"detail-item",children:[a.jsx("span",{className:"detail-label",children:"事件类型:"}),a.jsx("span",{className:"detail-value",children:d.event_type})]}),a.jsxs("div",{className:"detail-item",children:[a.jsx("span",{className:"detail-label",children:"仓库:"}),a.jsx("span",{className:"detail-value",children:d.repository})]}),a.jsxs("div",{className:"detail-item",children:[a.jsx("span",{className:"detail-label",children:"分支:"}),a.jsx("span",{className:"detail-value",children:d.branch})]}),a.jsxs("div",{className:"detail-item",children:[a.jsx("span",{className:"detail-label",children:"状态:"}),a.jsx("span",{className:`detail-value status ${P(d.event_status)}`,children:$(d.event_status)})]}),a.jsxs("div",{className:"detail-item",children:[a.jsx("span",{className:"detail-label",children:"创建时间:"}),a.jsx("span",{className:"detail-value",children:d.created_at})]}),d.target_branch&&a.jsxs("div",{className:"detail-item",children:[a.jsx("span",{className:"detail-label",children:"目标分支:"}),a.jsx("span",{className:"detail-value",children:d.target_branch})]}),d.pr_number&&a.jsxs("div",{className:"detail-item",children:[a.jsx("span",{className:"detail-label",children:"PR编号:"}),a.jsx("span",{className:"detail-value",children:d.pr_number})]})]})]}),d.quality_checks&&d.quality_checks.length>0&&a.jsxs("div",{className:"quality-checks",style:{marginTop:"20px"},children:[a.jsx("h4",{children:"质量检查项"}),a.jsxs("div",{className:"quality-pipeline",children:[a.jsxs("div",{className:"quality-stage",children:[a.jsx("h5",{children:"基础CI流水线"}),a.jsx("div",{className:"stage-checks",children:d.quality_checks.filter(w=>w.stage==="basic_ci").map(w=>a.jsxs("div",{className:"quality-check",children:[a.jsxs("div",{className:"check-header",children:[a.jsx("span",{className:"check-type",children:L(w.check_type)}),a.jsx("span",{className:`status ${ce(w.check_status)}`,children:We(w.check_status)})]}),w.error_message&&a.jsx("div",{className:"check-error",children:w.error_message})]},w.id))})]}),a.jsxs("div",{className:"quality-stage",style:{marginTop:"20px"},children:[a.jsx("h5",{children:"测试环境部署"}),a.jsx("div",{className:"stage-checks",children:d.quality_checks.filter(w=>w.stage==="deployment").map(w=>a.jsxs("div",{className:"quality-check",children:[a.jsxs("div",{className:"check-header",children:[a.jsx("span",{className:"check-type",children:L(w.check_type)}),a.jsx("span",{className:`status ${ce(w.check_status)}`,children:We(w.check_status)})]}),w.error_message&&a.jsx("div",{className:"check-error",children:w.error_message})]},w.id))})]}),a.jsxs("div",{className:"quality-stage",style:{marginTop:"20px"},children:[a.jsx("h5",{children:"专项测试流水线"}),a.jsx("div",{className:"stage-checks",children:d.quality_checks.filter(w=>w.stage==="specialized_tests").map(w=>a.jsxs("div",{className:"quality-check",children:[a.jsxs("div",{className:"check-header",children:[a.jsx("span",{className:"check-type",children:L(w.check_type)}),a.jsx("span",{className:`status ${ce(w.check_status)}`,children:We(w.check_status)})]}),w.error_message&&a.jsx("div",{className:"check-error",children:w.error_message})]},w.id))})]})]})]}),d.payload&&Object.keys(d.payload).length>0&&a.jsxs("div",{className:"payload-info",style:{marginTop:"20px"},children:[a.jsx("h4",{children:"Payload信息"}),a.jsx("div",{className:"payload-content",style:{backgroundColor:"#f5f5f5",padding:"15px",borderRadius:"4px",maxHeight:"400px",overflow:"auto",fontFamily:"monospace",fontSize:"12px"},children:a.jsx("pre",{style:{margin:0,whiteSpace:"pre-wrap"},dangerouslySetInnerHTML:{__html:Mf(d.payload)}})})]})]})}),a.jsx("div",{className:"modal-footer",children:a.jsx("button",{className:"btn btn-primary",onClick:N,children:"关闭"})})]})})]})}function Df(){const[e,t]=D.useState(null),[n,r]=D.useState(!0),[l,i]=D.useState("");D.useEffect(()=>{o()},[]);const o=async()=>{try{r(!0);const u=await(await fetch("/api/status")).json();u.success?t(u.data):i(u.message||"加载系统状态失败")}catch(s){i("加载系统状态失败："+s.message)}finally{r(!1)}};return n?a.jsx("div",{className:"loading",children:"加载中..."}):a.jsx("div",{className:"system-status",children:a.jsxs("div",{className:"card",children:[a.jsx("h2",{children:"系统状态"}),a.jsx("p",{children:"监控系统运行状态和服务健康情况"}),l&&a.jsx("div",{className:"message message-error",children:l}),e&&a.jsxs("div",{className:"status-container",children:[a.jsxs("div",{className:"status-item",children:[a.jsx("h3",{children:"服务状态"}),a.jsx("div",{className:`status-indicator ${e.service_status==="healthy"?"healthy":"unhealthy"}`,children:e.service_status==="healthy"?"正常":"异常"})]}),a.jsxs("div",{className:"status-item",children:[a.jsx("h3",{children:"数据库状态"}),a.jsx("div",{className:`status-indicator ${e.database_status==="connected"?"healthy":"unhealthy"}`,children:e.database_status==="connected"?"已连接":"未连接"})]}),a.jsxs("div",{className:"status-item",children:[a.jsx("h3",{children:"系统信息"}),a.jsxs("div",{className:"status-details",children:[a.jsxs("p",{children:["版本: ",e.version||"1.0.0"]}),a.jsxs("p",{children:["运行时间: ",e.uptime||"N/A"]}),a.jsxs("p",{children:["事件处理数: ",e.total_events||0]})]})]})]})]})})}function If(){const[e,t]=D.useState("event-history"),[n,r]=D.useState(!1),[l,i]=D.useState(""),[o,s]=D.useState(!1);D.useEffect(()=>{u()},[]);const u=async()=>{try{const k=await(await fetch("/api/check-login",{credentials:"include"})).json();k.is_logged_in&&(r(!0),i(k.username))}catch(h){console.error("检查登录状态失败:",h)}},d=h=>{r(!0),i(h),s(!1)},v=async()=>{try{(await(await fetch("/api/logout",{method:"POST",headers:{"Content-Type":"application/json"},credentials:"include"})).json()).success&&(r(!1),i(""))}catch(h){console.error("退出登录失败:",h)}},m=()=>{switch(e){case"mock-test":return a.jsx(Of,{});case"event-history":return a.jsx(Ws,{isLoggedIn:n});case"system-status":return a.jsx(Df,{});default:return a.jsx(Ws,{isLoggedIn:n})}};return a.jsxs("div",{className:"app",children:[a.jsx("header",{children:a.jsxs("div",{className:"container header-content",children:[a.jsx("div",{className:"logo",children:"Dule Quality Engine"}),a.jsxs("nav",{children:[a.jsxs("div",{className:"nav-links",children:[a.jsx("button",{className:`nav-btn ${e==="event-history"?"active":""}`,onClick:()=>t("event-history"),children:"GitHub 事件"}),a.jsx("button",{className:`nav-link ${e==="mock-test"?"active":""}`,onClick:()=>t("mock-test"),children:"Mock测试"}),a.jsx("button",{className:`nav-link ${e==="system-status"?"active":""}`,onClick:()=>t("system-status"),children:"系统状态"})]}),a.jsx("div",{className:"admin-controls",children:n?a.jsx("button",{className:"admin-btn",onClick:v,children:"登出"}):a.jsx("button",{className:"admin-btn",onClick:()=>s(!0),children:"管理"})})]})]})}),a.jsx("main",{className:"content-container",children:m()}),a.jsx("footer",{children:a.jsxs("div",{className:"container footer-content",children:[a.jsx("div",{className:"footer-info",children:a.jsx("p",{children:"© 2026 Dule Quality Engine. All rights reserved."})}),a.jsxs("div",{className:"footer-links",children:[a.jsx("a",{href:"#",children:"关于"}),a.jsx("a",{href:"#",children:"文档"}),a.jsx("a",{href:"#",children:"支持"})]})]})}),o&&a.jsx(Rf,{onLogin:d,onClose:()=>s(!1)})]})}class Ff extends tu.Component{constructor(t){super(t),this.state={hasError:!1,error:null,errorInfo:null}}static getDerivedStateFromError(t){return{hasError:!0}}componentDidCatch(t,n){this.setState({error:t,errorInfo:n}),console.error("ErrorBoundary caught an error:",t,n)}render(){return this.state.hasError?a.jsxs("div",{style:{padding:"20px",backgroundColor:"#fee2e2",border:"1px solid #ef4444",borderRadius:"8px",margin:"20px"},children:[a.jsx("h2",{style:{color:"#991b1b"},children:"Something went wrong"}),a.jsxs("details",{style:{marginTop:"10px"},children:[a.jsx("summary",{style:{cursor:"pointer",marginBottom:"10px"},children:"Error details"}),a.jsxs("pre",{style:{backgroundColor:"#fef3c7",padding:"10px",borderRadius:"4px",overflow:"auto",fontSize:"12px"},children:[this.state.error&&this.state.error.toString(),a.jsx("br",{}),this.state.errorInfo&&this.state.errorInfo.componentStack]})]}),a.jsx("button",{onClick:()=>window.location.reload(),style:{padding:"10px 20px",backgroundColor:"#6366f1",color:"white",border:"none",borderRadius:"8px",cursor:"pointer",marginTop:"10px"},children:"Reload Page"})]}):this.props.children}}Yl.createRoot(document.getElementById("root")).render(a.jsx(tu.StrictMode,{children:a.jsx(Ff,{children:a.jsx(If,{})})}));
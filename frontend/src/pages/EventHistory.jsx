@@ -113,7 +113,8 @@ function EventHistory({ isLoggedIn }) {
       const response = await fetch('/api/events', {
         method: 'DELETE',
         headers: {
-          'Content-Type': 'application/json'
+          'Content-Type': 'application/json',
+          'X-Confirm-Delete-All': 'yes'
         },
         credentials: 'include'
       })
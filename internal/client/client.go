@@ -1,9 +1,13 @@
 package client
 
 import (
+	"archive/tar"
 	"archive/zip"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,10 +18,13 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github-hub/internal/storage"
 )
 
 // Client is a minimal HTTP API client for the ghh server.
@@ -26,13 +33,24 @@ type Client struct {
 	Token            string
 	User             string
 	Legacy           bool   // Use legacy GitHub zipball API instead of git archive
+	Format           string // Archive format requested by Download: "zip" (default) or "tar.gz"
 	DebugDelay       string // DEBUG: request server to add artificial delay (e.g., "90s", "2m")
 	DebugStreamDelay string // DEBUG: request server to slow streaming (e.g., "90s", "2m")
 	RetryMax         int
 	RetryBackoff     time.Duration
 	ProgressInterval time.Duration
-	http             *http.Client
-	Endpoint         Endpoints
+	// ProgressOutput receives the inline download progress; nil disables it.
+	// NewClient defaults it to stderr when stderr is a terminal so piped stdout stays clean.
+	ProgressOutput io.Writer
+	// RemoveZipAfterExtract deletes the downloaded archive once it was extracted
+	// successfully; it has no effect when Download/DownloadSparse do not extract.
+	RemoveZipAfterExtract bool
+	// MaxExtractBytes caps the total bytes written when extracting an archive and
+	// MaxExtractFileBytes caps any single entry; <= 0 uses the defaults.
+	MaxExtractBytes     int64
+	MaxExtractFileBytes int64
+	http                *http.Client
+	Endpoint            Endpoints
 }
 
 // NewClient creates a new API client.
@@ -48,9 +66,38 @@ func NewClient(baseURL, token string, httpClient *http.Client) *Client {
 		RetryMax:         5,
 		RetryBackoff:     2 * time.Second,
 		ProgressInterval: time.Second,
+		ProgressOutput:   defaultProgressOutput(),
+
+		MaxExtractBytes:     DefaultMaxExtractBytes,
+		MaxExtractFileBytes: DefaultMaxExtractFileBytes,
+	}
+}
+
+// defaultProgressOutput returns stderr when it is attached to a terminal, nil otherwise.
+func defaultProgressOutput() io.Writer {
+	if fi, err := os.Stderr.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		return os.Stderr
 	}
+	return nil
 }
 
+// ErrChecksumMismatch is returned when a downloaded archive does not match the server's X-GHH-SHA256 header.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ErrExtractLimit is returned when extracting an archive would exceed
+// MaxExtractBytes or MaxExtractFileBytes.
+var ErrExtractLimit = errors.New("extraction size limit exceeded")
+
+// Default extraction caps guarding against zip bombs, shared with the server's
+// upload extraction.
+const (
+	DefaultMaxExtractBytes     = storage.DefaultMaxExtractBytes
+	DefaultMaxExtractFileBytes = storage.DefaultMaxExtractFileBytes
+)
+
+// errNotModified is returned by downloadToFileWithRetry when the server answers 304.
+var errNotModified = errors.New("not modified")
+
 // HTTPError wraps non-2xx responses.
 type HTTPError struct {
 	StatusCode int
@@ -106,6 +153,10 @@ func (c *Client) Download(ctx context.Context, repo, branch, zipPath, extractDir
 	if c.Legacy {
 		q.Set("legacy", "true")
 	}
+	tarball := c.isTarGz()
+	if tarball {
+		q.Set("format", "tar.gz")
+	}
 	if strings.TrimSpace(c.DebugDelay) != "" {
 		q.Set("debug_delay", c.DebugDelay)
 	}
@@ -114,17 +165,43 @@ func (c *Client) Download(ctx context.Context, repo, branch, zipPath, extractDir
 	}
 	path := replacePlaceholders(c.Endpoint.Download, map[string]string{"repo": repo, "branch": branch, "path": ""})
 	endpoint := c.fullURL(path, q)
+
+	commitPath := ""
+	if extractDir != "" {
+		commitPath = filepath.Join(extractDir, "commit.txt")
+	} else {
+		commitPath = zipPath + ".commit.txt"
+	}
+	// If we already hold an archive for a known commit, let the server answer 304.
+	knownCommit := ""
+	if _, err := os.Stat(zipPath); err == nil {
+		if b, err := os.ReadFile(commitPath); err == nil {
+			knownCommit = strings.TrimSpace(string(b))
+		}
+	}
+
 	reqBuilder := func(ctx context.Context) (*http.Request, error) {
 		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 		if err != nil {
 			return nil, err
 		}
 		c.addAuth(req)
-		req.Header.Set("Accept", "application/zip, application/octet-stream")
+		if tarball {
+			req.Header.Set("Accept", "application/gzip, application/octet-stream")
+		} else {
+			req.Header.Set("Accept", "application/zip, application/octet-stream")
+		}
+		if knownCommit != "" {
+			req.Header.Set("X-GHH-Known-Commit", knownCommit)
+		}
 		return req, nil
 	}
 	fmt.Printf("downloading %s ...\n", repo)
 	headers, err := c.downloadToFileWithRetry(ctx, zipPath, "repo "+repo, reqBuilder)
+	if errors.Is(err, errNotModified) {
+		fmt.Printf("%s is up to date (commit %s), keeping %s\n", repo, knownCommit, zipPath)
+		return nil
+	}
 	if err != nil {
 		return err
 	}
@@ -137,7 +214,7 @@ func (c *Client) Download(ctx context.Context, repo, branch, zipPath, extractDir
 	}
 	fmt.Printf("saved archive to %s (%.2f MB, %s)\n", zipPath, float64(size)/(1024*1024), elapsed.Round(time.Millisecond))
 
-	// If extractDir is specified, extract the zip
+	// If extractDir is specified, extract the archive
 	if extractDir != "" {
 		f, err := os.Open(zipPath)
 		if err != nil {
@@ -150,18 +227,19 @@ func (c *Client) Download(ctx context.Context, repo, branch, zipPath, extractDir
 			return fmt.Errorf("stat zip: %w", err)
 		}
 
-		if err := extractZip(f, fi.Size(), extractDir); err != nil {
+		if tarball {
+			err = extractTarGz(f, extractDir, c.extractLimits())
+		} else {
+			err = extractZip(f, fi.Size(), extractDir, c.extractLimits())
+		}
+		if err != nil {
 			return fmt.Errorf("extract: %w", err)
 		}
 		fmt.Printf("extracted to %s\n", extractDir)
+		_ = f.Close()
+		c.removeExtractedArchive(zipPath)
 	}
 
-	commitPath := ""
-	if extractDir != "" {
-		commitPath = filepath.Join(extractDir, "commit.txt")
-	} else {
-		commitPath = zipPath + ".commit.txt"
-	}
 	if commit != "" {
 		if err := os.WriteFile(commitPath, []byte(commit+"\n"), 0o644); err != nil {
 			fmt.Printf("warning: failed to save commit info to %s: %v\n", commitPath, err)
@@ -243,10 +321,12 @@ func (c *Client) DownloadSparse(ctx context.Context, repo, branch string, paths
 			return fmt.Errorf("stat zip: %w", err)
 		}
 
-		if err := extractZip(f, fi.Size(), extractDir); err != nil {
+		if err := extractZip(f, fi.Size(), extractDir, c.extractLimits()); err != nil {
 			return fmt.Errorf("extract: %w", err)
 		}
 		fmt.Printf("extracted to %s\n", extractDir)
+		_ = f.Close()
+		c.removeExtractedArchive(zipPath)
 	}
 
 	// Write commit.txt
@@ -267,6 +347,20 @@ func (c *Client) DownloadSparse(ctx context.Context, repo, branch string, paths
 	return nil
 }
 
+// removeExtractedArchive deletes zipPath after a successful extraction when
+// RemoveZipAfterExtract is set. A failed removal is only reported: the
+// extracted tree is already in place.
+func (c *Client) removeExtractedArchive(zipPath string) {
+	if !c.RemoveZipAfterExtract {
+		return
+	}
+	if err := os.Remove(zipPath); err != nil {
+		fmt.Printf("warning: failed to remove %s: %v\n", zipPath, err)
+		return
+	}
+	fmt.Printf("removed archive %s\n", zipPath)
+}
+
 func (c *Client) fetchCommit(ctx context.Context, repo, branch string) string {
 	q := url.Values{}
 	if !strings.Contains(c.Endpoint.DownloadCommit, "{repo}") {
@@ -306,66 +400,306 @@ func (c *Client) SwitchBranch(ctx context.Context, repo, branch string) error {
 	payload := map[string]string{"repo": repo, "branch": branch}
 	body, _ := json.Marshal(payload)
 	path := replacePlaceholders(c.Endpoint.BranchSwitch, map[string]string{"repo": repo, "branch": branch})
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+path, bytes.NewReader(body))
+	_, err := c.doWithRetry(ctx, "switch branch failed", 1<<20, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
 	if err != nil {
 		return err
 	}
-	c.addAuth(req)
-	req.Header.Set("Content-Type", "application/json")
+	fmt.Println("branch switched")
+	return nil
+}
 
+// ListBranches returns the remote branch names of a repository.
+// Expected server endpoint default: GET /api/v1/branches?repo=<owner/name>
+func (c *Client) ListBranches(ctx context.Context, repo string) ([]string, error) {
+	q := url.Values{}
+	p := c.Endpoint.Branches
+	if strings.Contains(p, "{repo}") {
+		p = replacePlaceholders(p, map[string]string{"repo": repo})
+	} else {
+		q.Set("repo", repo)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.fullURL(p, q), nil)
+	if err != nil {
+		return nil, err
+	}
+	c.addAuth(req)
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer func() { _ = resp.Body.Close() }()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
-		return &HTTPError{StatusCode: resp.StatusCode, Message: "switch branch failed", Body: string(b)}
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Message: "list branches failed", Body: string(b)}
 	}
-	fmt.Println("branch switched")
-	return nil
+	var branches []string
+	if err := json.Unmarshal(b, &branches); err != nil {
+		return nil, fmt.Errorf("decode branches: %w", err)
+	}
+	return branches, nil
 }
 
-// ListDir lists a directory on the server.
-// Expected server endpoint default: GET /api/v1/dir/list?path=<path>
-func (c *Client) ListDir(ctx context.Context, path string, raw bool) error {
+// RepoStat is the server's view of a cached repo/branch archive.
+type RepoStat struct {
+	Cached     bool       `json:"cached"`
+	Size       int64      `json:"size"`
+	SHA        string     `json:"sha,omitempty"`
+	LastAccess *time.Time `json:"last_access,omitempty"`
+}
+
+// Stat reports whether repo@branch is cached on the server in c.Format without downloading it.
+// Expected server endpoint default: GET /api/v1/stat?repo=<owner/name>&branch=<branch>&format=<format>
+func (c *Client) Stat(ctx context.Context, repo, branch string) (*RepoStat, error) {
 	q := url.Values{}
-	p := c.Endpoint.DirList
-	if strings.Contains(p, "{path}") {
-		p = replacePlaceholders(p, map[string]string{"path": path})
-	} else {
-		q.Set("path", path)
+	q.Set("repo", repo)
+	if strings.TrimSpace(branch) != "" {
+		q.Set("branch", branch)
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.fullURL(p, q), nil)
+	if c.Legacy {
+		q.Set("legacy", "true")
+	}
+	if c.isTarGz() {
+		q.Set("format", "tar.gz")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.fullURL(c.Endpoint.Stat, q), nil)
+	if err != nil {
+		return nil, err
+	}
+	c.addAuth(req)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Message: "stat failed", Body: string(b)}
+	}
+	var st RepoStat
+	if err := json.Unmarshal(b, &st); err != nil {
+		return nil, fmt.Errorf("decode stat: %w", err)
+	}
+	return &st, nil
+}
+
+// CacheEntry is one cached repo archive reported by CacheStats.
+type CacheEntry struct {
+	User    string    `json:"user"`
+	Repo    string    `json:"repo"`
+	Branch  string    `json:"branch"`
+	Legacy  bool      `json:"legacy,omitempty"`
+	Format  string    `json:"format,omitempty"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// CacheStats is the server's cache usage for the current user.
+type CacheStats struct {
+	User       string       `json:"user"`
+	Entries    []CacheEntry `json:"entries"`
+	TotalSize  int64        `json:"total_size"`
+	TotalCount int          `json:"total_count"`
+}
+
+// CacheStats lists the current user's cached archives with sizes and totals.
+// Expected server endpoint default: GET /api/v1/cache/stats
+func (c *Client) CacheStats(ctx context.Context) (*CacheStats, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.fullURL(c.Endpoint.CacheStats, nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	c.addAuth(req)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Message: "cache stats failed", Body: string(b)}
+	}
+	var st CacheStats
+	if err := json.Unmarshal(b, &st); err != nil {
+		return nil, fmt.Errorf("decode cache stats: %w", err)
+	}
+	return &st, nil
+}
+
+// ClearCache removes everything the server has cached for the current user and
+// returns the number of bytes freed.
+// Expected server endpoint default: DELETE /api/v1/cache
+func (c *Client) ClearCache(ctx context.Context) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.fullURL(c.Endpoint.CacheClear, nil), nil)
+	if err != nil {
+		return 0, err
+	}
+	c.addAuth(req)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, &HTTPError{StatusCode: resp.StatusCode, Message: "cache clear failed", Body: string(b)}
+	}
+	var out struct {
+		FreedBytes int64 `json:"freed_bytes"`
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return 0, fmt.Errorf("decode cache clear response: %w", err)
+	}
+	return out.FreedBytes, nil
+}
+
+// Upload zips localDir and extracts it on the server under remotePath (relative to the user root).
+// Expected server endpoint default: POST /api/v1/upload?path=<remotePath>
+func (c *Client) Upload(ctx context.Context, localDir, remotePath string) error {
+	info, err := os.Stat(localDir)
 	if err != nil {
 		return err
 	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", localDir)
+	}
+	q := url.Values{}
+	q.Set("path", remotePath)
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(zipDir(localDir, pw))
+	}()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.fullURL(c.Endpoint.Upload, q), pr)
+	if err != nil {
+		_ = pr.Close()
+		return err
+	}
 	c.addAuth(req)
+	req.Header.Set("Content-Type", "application/zip")
+
 	resp, err := c.http.Do(req)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = resp.Body.Close() }()
-	b, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return &HTTPError{StatusCode: resp.StatusCode, Message: "list failed", Body: string(b)}
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		return &HTTPError{StatusCode: resp.StatusCode, Message: "upload failed", Body: string(b)}
+	}
+	fmt.Printf("uploaded %s to %s\n", localDir, remotePath)
+	return nil
+}
+
+// zipDir writes the regular files and directories under root as a zip archive to w.
+func zipDir(root string, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		name := filepath.ToSlash(rel)
+		if d.IsDir() {
+			_, err := zw.Create(name + "/")
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil // skip symlinks and special files
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hdr, err := zip.FileInfoHeader(fi)
+		if err != nil {
+			return err
+		}
+		hdr.Name = name
+		hdr.Method = zip.Deflate
+		dst, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(dst, f)
+		_ = f.Close()
+		return err
+	})
+	if err != nil {
+		return err
 	}
-	if raw {
+	return zw.Close()
+}
+
+// Entry is one item of a remote directory listing.
+type Entry struct {
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	IsDir bool   `json:"is_dir"`
+	Size  int64  `json:"size"`
+}
+
+// Output formats accepted by ListDir.
+const (
+	ListTable = "table" // human-readable table (default)
+	ListRaw   = "raw"   // server response bytes verbatim
+	ListJSON  = "json"  // parsed entries as stable, pretty-printed JSON
+)
+
+// ListDir lists a directory on the server and prints it to stdout in the given
+// format (ListTable, ListRaw or ListJSON). With recursive the whole subtree is
+// listed; a truncated listing is reported on stderr (or in the JSON output).
+// A non-empty glob keeps only entries whose name matches the shell pattern.
+// Expected server endpoint default: GET /api/v1/dir/list?path=<path>[&recursive=true][&glob=<pattern>]
+func (c *Client) ListDir(ctx context.Context, path, format string, recursive bool, glob string) error {
+	b, err := c.fetchDirList(ctx, path, recursive, glob)
+	if err != nil {
+		return err
+	}
+	if format == ListRaw {
 		fmt.Println(string(b))
 		return nil
 	}
-	// Try to pretty print into a simple table if JSON is compatible
-	var entries []struct {
-		Name  string `json:"name"`
-		Path  string `json:"path"`
-		IsDir bool   `json:"is_dir"`
-		Size  int64  `json:"size"`
+	var entries []Entry
+	truncated := false
+	if recursive {
+		var tree dirTree
+		err = json.Unmarshal(b, &tree)
+		entries, truncated = tree.Entries, tree.Truncated
+	} else {
+		// Try to pretty print into a simple table if JSON is compatible
+		err = json.Unmarshal(b, &entries)
 	}
-	if err := json.Unmarshal(b, &entries); err != nil {
+	if err != nil {
+		if format == ListJSON {
+			return fmt.Errorf("parse listing: %w", err)
+		}
 		// fallback to raw
 		fmt.Println(string(b))
 		return nil
 	}
+	if format == ListJSON {
+		if recursive {
+			return writeTreeJSON(os.Stdout, path, entries, truncated)
+		}
+		return writeListJSON(os.Stdout, path, entries)
+	}
 	for _, e := range entries {
 		typ := "file"
 		if e.IsDir {
@@ -373,9 +707,110 @@ func (c *Client) ListDir(ctx context.Context, path string, raw bool) error {
 		}
 		fmt.Printf("%-4s %10d  %s\n", typ, e.Size, nonEmpty(e.Path, e.Name))
 	}
+	if truncated {
+		fmt.Fprintf(os.Stderr, "listing truncated after %d entries\n", len(entries))
+	}
 	return nil
 }
 
+// ListEntries returns the parsed listing of a remote directory.
+func (c *Client) ListEntries(ctx context.Context, path string) ([]Entry, error) {
+	b, err := c.fetchDirList(ctx, path, false, "")
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, fmt.Errorf("parse listing: %w", err)
+	}
+	return entries, nil
+}
+
+// dirTree is the server response to a recursive listing.
+type dirTree struct {
+	Path      string  `json:"path"`
+	Entries   []Entry `json:"entries"`
+	Truncated bool    `json:"truncated"`
+}
+
+// ListTree returns every entry below a remote directory and whether the
+// server cut the listing short at its depth or entry limit.
+func (c *Client) ListTree(ctx context.Context, path string) ([]Entry, bool, error) {
+	b, err := c.fetchDirList(ctx, path, true, "")
+	if err != nil {
+		return nil, false, err
+	}
+	var tree dirTree
+	if err := json.Unmarshal(b, &tree); err != nil {
+		return nil, false, fmt.Errorf("parse listing: %w", err)
+	}
+	return tree.Entries, tree.Truncated, nil
+}
+
+func (c *Client) fetchDirList(ctx context.Context, path string, recursive bool, glob string) ([]byte, error) {
+	q := url.Values{}
+	p := c.Endpoint.DirList
+	if strings.Contains(p, "{path}") {
+		p = replacePlaceholders(p, map[string]string{"path": path})
+	} else {
+		q.Set("path", path)
+	}
+	if recursive {
+		q.Set("recursive", "true")
+	}
+	if glob != "" {
+		q.Set("glob", glob)
+	}
+	return c.doWithRetry(ctx, "list failed", 8<<20, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, c.fullURL(p, q), nil)
+	})
+}
+
+// SortEntries orders entries directories first, then alphabetically by name.
+func SortEntries(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
+		return nonEmpty(entries[i].Name, entries[i].Path) < nonEmpty(entries[j].Name, entries[j].Path)
+	})
+}
+
+// writeListJSON writes the listing as {"path", "entries", "total_size"} with
+// entries sorted by SortEntries.
+func writeListJSON(w io.Writer, path string, entries []Entry) error {
+	SortEntries(entries)
+	return encodeListing(w, path, entries, map[string]interface{}{})
+}
+
+// writeTreeJSON writes a recursive listing like writeListJSON, with entries
+// in path order so each directory is followed by its contents, plus the
+// server's "truncated" flag.
+func writeTreeJSON(w io.Writer, path string, entries []Entry, truncated bool) error {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return encodeListing(w, path, entries, map[string]interface{}{"truncated": truncated})
+}
+
+// encodeListing writes path, entries and total_size merged with out.
+func encodeListing(w io.Writer, path string, entries []Entry, out map[string]interface{}) error {
+	if entries == nil {
+		entries = []Entry{}
+	}
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+	out["path"] = path
+	out["entries"] = entries
+	out["total_size"] = total
+	b, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
 // DeleteDir deletes a directory on the server.
 // Expected server endpoint default: DELETE /api/v1/dir?path=<path>&recursive=true
 func (c *Client) DeleteDir(ctx context.Context, path string, recursive bool) error {
@@ -389,10 +824,29 @@ func (c *Client) DeleteDir(ctx context.Context, path string, recursive bool) err
 	if recursive {
 		q.Set("recursive", "true")
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.fullURL(p, q), nil)
+	_, err := c.doWithRetry(ctx, "delete failed", 1<<20, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodDelete, c.fullURL(p, q), nil)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("deleted")
+	return nil
+}
+
+// Move renames a path on the server without re-uploading it. Both paths are
+// relative to the user root; an existing destination is never overwritten.
+// Expected server endpoint default: POST /api/v1/dir/move {"from", "to"}
+func (c *Client) Move(ctx context.Context, from, to string) error {
+	body, err := json.Marshal(map[string]string{"from": from, "to": to})
 	if err != nil {
 		return err
 	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.fullURL(c.Endpoint.DirMove, url.Values{}), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
 	c.addAuth(req)
 	resp, err := c.http.Do(req)
 	if err != nil {
@@ -401,9 +855,9 @@ func (c *Client) DeleteDir(ctx context.Context, path string, recursive bool) err
 	defer func() { _ = resp.Body.Close() }()
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
-		return &HTTPError{StatusCode: resp.StatusCode, Message: "delete failed", Body: string(b)}
+		return &HTTPError{StatusCode: resp.StatusCode, Message: "move failed", Body: string(b)}
 	}
-	fmt.Println("deleted")
+	fmt.Println("moved")
 	return nil
 }
 
@@ -422,8 +876,14 @@ type Endpoints struct {
 	DownloadCommit  string
 	DownloadSparse  string
 	BranchSwitch    string
+	Branches        string
+	Stat            string
+	CacheStats      string
+	CacheClear      string
+	Upload          string
 	DirList         string
 	DirDelete       string
+	DirMove         string
 	ServerVersion   string
 	DownloadPackage string
 }
@@ -434,8 +894,14 @@ func DefaultEndpoints() Endpoints {
 		DownloadCommit:  "/api/v1/download/commit",
 		DownloadSparse:  "/api/v1/download/sparse",
 		BranchSwitch:    "/api/v1/branch/switch",
+		Branches:        "/api/v1/branches",
+		Stat:            "/api/v1/stat",
+		CacheStats:      "/api/v1/cache/stats",
+		CacheClear:      "/api/v1/cache",
+		Upload:          "/api/v1/upload",
 		DirList:         "/api/v1/dir/list",
 		DirDelete:       "/api/v1/dir",
+		DirMove:         "/api/v1/dir/move",
 		ServerVersion:   "/api/v1/version",
 		DownloadPackage: "/api/v1/download/package",
 	}
@@ -484,7 +950,7 @@ func (c *Client) downloadToFileWithRetry(ctx context.Context, destPath, label st
 					return
 				case <-timer.C:
 					atomic.StoreInt32(&waitPrinted, 1)
-					printInline(fmt.Sprintf("waiting for server... %s", time.Since(started).Round(time.Second)), false)
+					c.printInline(fmt.Sprintf("waiting for server... %s", time.Since(started).Round(time.Second)), false)
 					timer.Reset(time.Second)
 				}
 			}
@@ -492,7 +958,7 @@ func (c *Client) downloadToFileWithRetry(ctx context.Context, destPath, label st
 		resp, err := c.http.Do(req)
 		close(waitStop)
 		if atomic.LoadInt32(&waitPrinted) == 1 {
-			clearInline()
+			c.clearInline()
 		}
 		if err != nil {
 			lastErr = err
@@ -503,6 +969,10 @@ func (c *Client) downloadToFileWithRetry(ctx context.Context, destPath, label st
 			continue
 		}
 		headers := resp.Header.Clone()
+		if resp.StatusCode == http.StatusNotModified {
+			_ = resp.Body.Close()
+			return headers, errNotModified
+		}
 		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 			body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
 			_ = resp.Body.Close()
@@ -526,8 +996,20 @@ func (c *Client) downloadToFileWithRetry(ctx context.Context, destPath, label st
 		}
 		tmpPath := tmpFile.Name()
 		_ = tmpFile.Close()
-		err = c.copyWithProgress(ctx, tmpPath, resp.Body, resp.ContentLength, label)
+		// Hash the body as it streams so a truncated or corrupted transfer is caught before the rename.
+		var body io.Reader = resp.Body
+		wantSum := strings.ToLower(strings.TrimSpace(headers.Get("X-GHH-SHA256")))
+		hasher := sha256.New()
+		if wantSum != "" {
+			body = io.TeeReader(resp.Body, hasher)
+		}
+		err = c.copyWithProgress(ctx, tmpPath, body, resp.ContentLength, label)
 		_ = resp.Body.Close()
+		if err == nil && wantSum != "" {
+			if got := hex.EncodeToString(hasher.Sum(nil)); got != wantSum {
+				err = fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, wantSum, got)
+			}
+		}
 		if err != nil {
 			_ = os.Remove(tmpPath)
 			lastErr = err
@@ -547,6 +1029,49 @@ func (c *Client) downloadToFileWithRetry(ctx context.Context, destPath, label st
 	return nil, lastErr
 }
 
+// doWithRetry sends a small request and returns up to bodyLimit bytes of the
+// response body. reqBuilder is called for every attempt; auth is added here.
+// Unlike downloads, only gateway errors (502/503/504) and network errors are
+// retried, since the server may have already acted on a request that failed
+// with another 5xx. 4xx responses are never retried.
+func (c *Client) doWithRetry(ctx context.Context, failMsg string, bodyLimit int64, reqBuilder func(context.Context) (*http.Request, error)) ([]byte, error) {
+	attempts := c.retryAttempts()
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := sleepWithBackoff(ctx, c.retryBackoff(), attempt); err != nil {
+				return nil, err
+			}
+		}
+		req, err := reqBuilder(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.addAuth(req)
+		resp, err := c.http.Do(req)
+		if err != nil {
+			lastErr = err
+			if attempt == attempts-1 || !isRetryableError(err) {
+				return nil, err
+			}
+			printRetry(attempt, attempts, err)
+			continue
+		}
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, bodyLimit))
+		_ = resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return b, nil
+		}
+		err = &HTTPError{StatusCode: resp.StatusCode, Message: failMsg, Body: string(b)}
+		lastErr = err
+		if attempt == attempts-1 || !isRetryableGatewayStatus(resp.StatusCode) {
+			return nil, err
+		}
+		printRetry(attempt, attempts, err)
+	}
+	return nil, lastErr
+}
+
 func (c *Client) copyWithProgress(ctx context.Context, dest string, r io.Reader, total int64, label string) error {
 	f, err := os.Create(dest)
 	if err != nil {
@@ -556,6 +1081,11 @@ func (c *Client) copyWithProgress(ctx context.Context, dest string, r io.Reader,
 
 	var written int64
 	start := time.Now()
+	cr := &countingReader{r: r, ctx: ctx, written: &written}
+	if c.ProgressOutput == nil {
+		_, err = io.Copy(f, cr)
+		return err
+	}
 	interval := c.progressInterval()
 	if label == "" {
 		label = "download"
@@ -570,15 +1100,14 @@ func (c *Client) copyWithProgress(ctx context.Context, dest string, r io.Reader,
 		for {
 			select {
 			case <-ticker.C:
-				printProgress(label, atomic.LoadInt64(&written), total, start, false)
+				c.printProgress(label, atomic.LoadInt64(&written), total, start, false)
 			case <-done:
-				printProgress(label, atomic.LoadInt64(&written), total, start, true)
+				c.printProgress(label, atomic.LoadInt64(&written), total, start, true)
 				return
 			}
 		}
 	}()
 
-	cr := &countingReader{r: r, ctx: ctx, written: &written}
 	_, err = io.Copy(f, cr)
 	close(done)
 	wg.Wait()
@@ -630,11 +1159,11 @@ func (cr *countingReader) Read(p []byte) (int, error) {
 func printRetry(attempt, attempts int, err error) {
 	next := attempt + 1
 	if next < attempts {
-		fmt.Printf("download failed: %v, retrying (%d/%d)\n", err, next, attempts-1)
+		fmt.Fprintf(os.Stderr, "download failed: %v, retrying (%d/%d)\n", err, next, attempts-1)
 	}
 }
 
-func printProgress(label string, written, total int64, start time.Time, final bool) {
+func (c *Client) printProgress(label string, written, total int64, start time.Time, final bool) {
 	elapsed := time.Since(start)
 	if elapsed <= 0 {
 		elapsed = time.Millisecond
@@ -650,28 +1179,36 @@ func printProgress(label string, written, total int64, start time.Time, final bo
 	} else {
 		msg = fmt.Sprintf("%s  %s  %s/s", label, formatBytes(written), formatBytes(int64(speed)))
 	}
-	printInline(msg, final)
+	c.printInline(msg, final)
 }
 
 var progressLastLen int32
 
-func printInline(msg string, final bool) {
+func (c *Client) printInline(msg string, final bool) {
+	out := c.ProgressOutput
+	if out == nil {
+		return
+	}
 	prev := int(atomic.LoadInt32(&progressLastLen))
 	if len(msg) < prev {
 		msg += strings.Repeat(" ", prev-len(msg))
 	}
-	fmt.Printf("\r%s", msg)
+	fmt.Fprintf(out, "\r%s", msg)
 	atomic.StoreInt32(&progressLastLen, int32(len(msg)))
 	if final {
-		fmt.Print("\n")
+		fmt.Fprint(out, "\n")
 		atomic.StoreInt32(&progressLastLen, 0)
 	}
 }
 
-func clearInline() {
+func (c *Client) clearInline() {
+	out := c.ProgressOutput
+	if out == nil {
+		return
+	}
 	prev := int(atomic.LoadInt32(&progressLastLen))
 	if prev > 0 {
-		fmt.Printf("\r%s\r", strings.Repeat(" ", prev))
+		fmt.Fprintf(out, "\r%s\r", strings.Repeat(" ", prev))
 		atomic.StoreInt32(&progressLastLen, 0)
 	}
 }
@@ -733,6 +1270,14 @@ func isRetryableStatus(status int) bool {
 		status >= 500
 }
 
+// isRetryableGatewayStatus reports whether a non-download request should be
+// retried: only when a proxy or the server says it is temporarily unavailable.
+func isRetryableGatewayStatus(status int) bool {
+	return status == http.StatusBadGateway ||
+		status == http.StatusServiceUnavailable ||
+		status == http.StatusGatewayTimeout
+}
+
 func isRetryableError(err error) bool {
 	if err == nil {
 		return false
@@ -749,7 +1294,65 @@ func isRetryableError(err error) bool {
 	return true
 }
 
-func extractZip(r io.ReaderAt, size int64, dest string) error {
+// extractLimits bounds how much an extraction may write.
+type extractLimits struct {
+	total   int64
+	perFile int64
+}
+
+func (c *Client) extractLimits() extractLimits {
+	l := extractLimits{total: c.MaxExtractBytes, perFile: c.MaxExtractFileBytes}
+	if l.total <= 0 {
+		l.total = DefaultMaxExtractBytes
+	}
+	if l.perFile <= 0 {
+		l.perFile = DefaultMaxExtractFileBytes
+	}
+	return l
+}
+
+// extractWriter writes extracted entries while enforcing extractLimits. It
+// remembers the files it created so a failed extraction can be rolled back.
+type extractWriter struct {
+	limits  extractLimits
+	written int64
+	created []string
+}
+
+// writeFile copies r into path, failing with ErrExtractLimit once either cap is exceeded.
+func (w *extractWriter) writeFile(path, name string, r io.Reader, mode os.FileMode) error {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	w.created = append(w.created, path)
+	allowed := w.limits.perFile
+	if remaining := w.limits.total - w.written; remaining < allowed {
+		allowed = remaining
+	}
+	n, err := io.Copy(out, io.LimitReader(r, allowed+1))
+	_ = out.Close()
+	w.written += n
+	if err != nil {
+		return err
+	}
+	if n > allowed {
+		if n > w.limits.perFile {
+			return fmt.Errorf("%s exceeds %d bytes per file: %w", name, w.limits.perFile, ErrExtractLimit)
+		}
+		return fmt.Errorf("archive exceeds %d bytes in total: %w", w.limits.total, ErrExtractLimit)
+	}
+	return nil
+}
+
+// cleanup removes the files written so far.
+func (w *extractWriter) cleanup() {
+	for _, p := range w.created {
+		_ = os.Remove(p)
+	}
+}
+
+func extractZip(r io.ReaderAt, size int64, dest string, limits extractLimits) (err error) {
 	if dest == "" {
 		return errors.New("dest required for extract")
 	}
@@ -765,6 +1368,15 @@ func extractZip(r io.ReaderAt, size int64, dest string) error {
 	if err != nil {
 		return err
 	}
+	ew := &extractWriter{limits: limits}
+	defer func() {
+		if err != nil {
+			ew.cleanup()
+		}
+	}()
+	// Symlinks are created after all regular entries so that no file is ever
+	// written through a link from the archive.
+	var links []*zip.File
 	for _, f := range zr.File {
 		fp := filepath.Join(dest, f.Name)
 		// Prevent ZipSlip using absolute paths
@@ -772,15 +1384,23 @@ func extractZip(r io.ReaderAt, size int64, dest string) error {
 		if err != nil {
 			return fmt.Errorf("resolve file path: %w", err)
 		}
-		if !strings.HasPrefix(absFp, absDest+string(os.PathSeparator)) && absFp != absDest {
+		if !withinDir(absDest, absFp) {
 			return fmt.Errorf("illegal file path: %s", f.Name)
 		}
+		if f.Mode()&os.ModeSymlink != 0 {
+			links = append(links, f)
+			continue
+		}
 		if f.FileInfo().IsDir() {
 			if err := os.MkdirAll(fp, f.Mode()); err != nil {
 				return err
 			}
 			continue
 		}
+		// Reject on the declared size before reading anything.
+		if f.UncompressedSize64 > uint64(limits.perFile) {
+			return fmt.Errorf("%s declares %d bytes, over the %d byte per-file cap: %w", f.Name, f.UncompressedSize64, limits.perFile, ErrExtractLimit)
+		}
 		if err := os.MkdirAll(filepath.Dir(fp), 0o755); err != nil {
 			return err
 		}
@@ -788,22 +1408,163 @@ func extractZip(r io.ReaderAt, size int64, dest string) error {
 		if err != nil {
 			return err
 		}
-		out, err := os.OpenFile(fp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, f.Mode())
+		err = ew.writeFile(fp, f.Name, rc, f.Mode())
+		_ = rc.Close()
 		if err != nil {
-			_ = rc.Close()
 			return err
 		}
-		if _, err := io.Copy(out, rc); err != nil {
-			_ = out.Close()
-			_ = rc.Close()
+	}
+	for _, f := range links {
+		if err := extractSymlink(f, absDest); err != nil {
 			return err
 		}
-		_ = out.Close()
-		_ = rc.Close()
+		ew.created = append(ew.created, filepath.Join(absDest, f.Name))
+	}
+	// A link's target may only become resolvable once a later link exists
+	// (x -> y/.. before y -> .), so check every link again with all of them in place.
+	for _, f := range links {
+		if err := checkSymlink(filepath.Join(absDest, f.Name), absDest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSymlink resolves the extracted link at linkPath and fails if it is
+// dangling or points outside absDest.
+func checkSymlink(linkPath, absDest string) error {
+	realDest, err := filepath.EvalSymlinks(absDest)
+	if err != nil {
+		realDest = absDest
+	}
+	resolved, err := filepath.EvalSymlinks(linkPath)
+	if err != nil {
+		return fmt.Errorf("illegal symlink target: %s is dangling: %w", linkPath, err)
+	}
+	if !withinDir(realDest, resolved) {
+		return fmt.Errorf("illegal symlink target: %s resolves outside %s", linkPath, absDest)
+	}
+	return nil
+}
+
+// extractSymlink creates the symlink stored in f under absDest. The target must be
+// relative and, resolved from the link's directory, stay inside absDest.
+func extractSymlink(f *zip.File, absDest string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	b, err := io.ReadAll(io.LimitReader(rc, 4096))
+	_ = rc.Close()
+	if err != nil {
+		return err
+	}
+	target := string(b)
+	linkPath := filepath.Join(absDest, f.Name)
+	if target == "" || filepath.IsAbs(target) || strings.HasPrefix(target, "/") {
+		return fmt.Errorf("illegal symlink target: %s -> %s", f.Name, target)
+	}
+	if !withinDir(absDest, filepath.Join(filepath.Dir(linkPath), target)) {
+		return fmt.Errorf("illegal symlink target: %s -> %s", f.Name, target)
+	}
+	if err := os.MkdirAll(filepath.Dir(linkPath), 0o755); err != nil {
+		return err
+	}
+	_ = os.Remove(linkPath)
+	if err := os.Symlink(target, linkPath); err != nil {
+		return err
+	}
+	// The lexical check cannot see through other links in the target (e.g. a -> b/..
+	// with b -> .), so also check where the link really points once it exists.
+	if resolved, err := filepath.EvalSymlinks(linkPath); err == nil {
+		realDest, derr := filepath.EvalSymlinks(absDest)
+		if derr != nil {
+			realDest = absDest
+		}
+		if !withinDir(realDest, resolved) {
+			_ = os.Remove(linkPath)
+			return fmt.Errorf("illegal symlink target: %s -> %s", f.Name, target)
+		}
 	}
 	return nil
 }
 
+// withinDir reports whether path is dir or lies beneath it.
+func withinDir(dir, path string) bool {
+	path = filepath.Clean(path)
+	return path == dir || strings.HasPrefix(path, dir+string(os.PathSeparator))
+}
+
+// extractTarGz extracts a gzipped tarball into dest, rejecting entries that escape it.
+func extractTarGz(r io.Reader, dest string, limits extractLimits) (err error) {
+	if dest == "" {
+		return errors.New("dest required for extract")
+	}
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return err
+	}
+	absDest, err := filepath.Abs(dest)
+	if err != nil {
+		return fmt.Errorf("resolve dest path: %w", err)
+	}
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = gz.Close() }()
+	ew := &extractWriter{limits: limits}
+	defer func() {
+		if err != nil {
+			ew.cleanup()
+		}
+	}()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fp := filepath.Join(dest, hdr.Name)
+		absFp, err := filepath.Abs(fp)
+		if err != nil {
+			return fmt.Errorf("resolve file path: %w", err)
+		}
+		if !strings.HasPrefix(absFp, absDest+string(os.PathSeparator)) && absFp != absDest {
+			return fmt.Errorf("illegal file path: %s", hdr.Name)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(fp, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if hdr.Size > limits.perFile {
+				return fmt.Errorf("%s declares %d bytes, over the %d byte per-file cap: %w", hdr.Name, hdr.Size, limits.perFile, ErrExtractLimit)
+			}
+			if err := os.MkdirAll(filepath.Dir(fp), 0o755); err != nil {
+				return err
+			}
+			if err := ew.writeFile(fp, hdr.Name, tr, os.FileMode(hdr.Mode).Perm()); err != nil {
+				return err
+			}
+		default:
+			// git archive only adds directories, files, symlinks and a pax header; skip the rest.
+		}
+	}
+}
+
+// isTarGz reports whether Download should request a gzipped tarball.
+func (c *Client) isTarGz() bool {
+	switch strings.ToLower(strings.TrimSpace(c.Format)) {
+	case "tar.gz", "tgz":
+		return true
+	}
+	return false
+}
+
 func nonEmpty(values ...string) string {
 	for _, v := range values {
 		if strings.TrimSpace(v) != "" {
@@ -1,7 +1,16 @@
 package client
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -47,6 +56,42 @@ func TestDownloadPackage_Retry(t *testing.T) {
 	}
 }
 
+func TestDownloadPackage_ProgressOutput(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/download/package", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "7")
+		_, _ = w.Write([]byte("package"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name     string
+		out      *bytes.Buffer
+		wantText string
+	}{
+		{name: "reports percentage", out: &bytes.Buffer{}, wantText: "100%"},
+		{name: "quiet", out: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient(server.URL, "", server.Client())
+			c.ProgressInterval = 10 * time.Millisecond
+			c.ProgressOutput = nil
+			if tt.out != nil {
+				c.ProgressOutput = tt.out
+			}
+			dest := filepath.Join(t.TempDir(), "pkg.bin")
+			if err := c.DownloadPackage(context.Background(), "https://example.com/pkg.bin", dest); err != nil {
+				t.Fatalf("DownloadPackage: %v", err)
+			}
+			if tt.out != nil && !strings.Contains(tt.out.String(), tt.wantText) {
+				t.Fatalf("progress output %q does not contain %q", tt.out.String(), tt.wantText)
+			}
+		})
+	}
+}
+
 func TestDownloadRepo_Retry(t *testing.T) {
 	var attempts int32
 	mux := http.NewServeMux()
@@ -159,3 +204,624 @@ func TestDownloadSparse_Retry(t *testing.T) {
 		t.Fatalf("expected 2 attempts, got %d", attempts)
 	}
 }
+
+func TestListBranches(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/branches", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("repo") != "foo/bar" {
+			http.Error(w, "bad repo", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`["main","release/1.0"]`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	c := NewClient(server.URL, "", server.Client())
+	branches, err := c.ListBranches(context.Background(), "foo/bar")
+	if err != nil {
+		t.Fatalf("ListBranches: %v", err)
+	}
+	if strings.Join(branches, ",") != "main,release/1.0" {
+		t.Fatalf("unexpected branches: %v", branches)
+	}
+
+	if _, err := c.ListBranches(context.Background(), "other/repo"); err == nil {
+		t.Fatalf("expected error for non-2xx response")
+	}
+}
+
+func TestStat(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/stat", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("branch") == "main" {
+			_, _ = w.Write([]byte(`{"cached":true,"size":42,"sha":"abc123","last_access":"2024-03-01T10:00:00Z"}`))
+			return
+		}
+		if r.URL.Query().Get("branch") == "dev" && r.URL.Query().Get("format") == "tar.gz" {
+			_, _ = w.Write([]byte(`{"cached":true,"size":7}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"cached":false,"size":0}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	c := NewClient(server.URL, "", server.Client())
+	hit, err := c.Stat(context.Background(), "foo/bar", "main")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if !hit.Cached || hit.Size != 42 || hit.SHA != "abc123" || hit.LastAccess == nil {
+		t.Fatalf("unexpected hit: %+v", hit)
+	}
+
+	miss, err := c.Stat(context.Background(), "foo/bar", "dev")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if miss.Cached || miss.LastAccess != nil {
+		t.Fatalf("unexpected miss: %+v", miss)
+	}
+
+	c.Format = "tar.gz"
+	tarball, err := c.Stat(context.Background(), "foo/bar", "dev")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if !tarball.Cached || tarball.Size != 7 {
+		t.Fatalf("expected the tar.gz cache to be reported, got %+v", tarball)
+	}
+}
+
+func TestDownload_KnownCommitKeepsLocalZip(t *testing.T) {
+	var downloads int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/download", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-GHH-Commit", "abc1234")
+		if r.Header.Get("X-GHH-Known-Commit") == "abc1234" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddInt32(&downloads, 1)
+		w.Header().Set("Content-Length", "7")
+		_, _ = w.Write([]byte("zipdata"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	c := NewClient(server.URL, "", server.Client())
+	c.ProgressInterval = 10 * time.Millisecond
+	zipPath := filepath.Join(t.TempDir(), "repo.zip")
+
+	if err := c.Download(context.Background(), "foo/bar", "main", zipPath, ""); err != nil {
+		t.Fatalf("first Download: %v", err)
+	}
+	commit, err := os.ReadFile(zipPath + ".commit.txt")
+	if err != nil || strings.TrimSpace(string(commit)) != "abc1234" {
+		t.Fatalf("commit file: %q err=%v", commit, err)
+	}
+
+	if err := c.Download(context.Background(), "foo/bar", "main", zipPath, ""); err != nil {
+		t.Fatalf("second Download: %v", err)
+	}
+	if downloads != 1 {
+		t.Fatalf("expected 1 full download, got %d", downloads)
+	}
+	data, err := os.ReadFile(zipPath)
+	if err != nil || string(data) != "zipdata" {
+		t.Fatalf("local zip not kept: %q err=%v", data, err)
+	}
+}
+
+func TestDownload_TarGzExtracts(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	content := []byte("hello")
+	_ = tw.WriteHeader(&tar.Header{Name: "repo-main/", Typeflag: tar.TypeDir, Mode: 0o755})
+	_ = tw.WriteHeader(&tar.Header{Name: "repo-main/README.md", Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(content))})
+	_, _ = tw.Write(content)
+	_ = tw.Close()
+	_ = gz.Close()
+
+	var gotFormat string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotFormat = r.URL.Query().Get("format")
+		w.Header().Set("X-GHH-Commit", "abc1234")
+		_, _ = w.Write(buf.Bytes())
+	}))
+	t.Cleanup(server.Close)
+
+	c := NewClient(server.URL, "", server.Client())
+	c.Format = "tar.gz"
+	dir := t.TempDir()
+	if err := c.Download(context.Background(), "foo/bar", "main", filepath.Join(dir, "bar.tar.gz"), dir); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if gotFormat != "tar.gz" {
+		t.Fatalf("format query = %q", gotFormat)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "repo-main", "README.md"))
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("extracted file: %q err=%v", data, err)
+	}
+}
+
+func TestDownload_RemoveZipAfterExtract(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("repo-main/README.md")
+	_, _ = w.Write([]byte("hello"))
+	_ = zw.Close()
+	archive := buf.Bytes()
+
+	tests := []struct {
+		name     string
+		body     []byte
+		remove   bool
+		wantErr  bool
+		wantZip  bool
+		wantFile bool
+	}{
+		{name: "removed after extraction", body: archive, remove: true, wantFile: true},
+		{name: "kept by default", body: archive, wantZip: true, wantFile: true},
+		{name: "kept when extraction fails", body: []byte("not a zip"), remove: true, wantErr: true, wantZip: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-GHH-Commit", "abc1234")
+				_, _ = w.Write(tt.body)
+			}))
+			t.Cleanup(server.Close)
+
+			c := NewClient(server.URL, "", server.Client())
+			c.RetryMax = 1
+			c.RemoveZipAfterExtract = tt.remove
+			dir := t.TempDir()
+			zipPath := filepath.Join(dir, "bar.zip")
+			err := c.Download(context.Background(), "foo/bar", "main", zipPath, dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Download err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if _, err := os.Stat(zipPath); (err == nil) != tt.wantZip {
+				t.Errorf("zip exists=%v, want %v", err == nil, tt.wantZip)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "repo-main", "README.md")); (err == nil) != tt.wantFile {
+				t.Errorf("extracted file exists=%v, want %v", err == nil, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestExtractZip_Symlinks(t *testing.T) {
+	type entry struct{ name, body string }
+	tests := []struct {
+		name    string
+		links   []entry
+		wantErr bool
+	}{
+		{name: "in-tree link", links: []entry{{"repo/link.txt", "docs/readme.txt"}}},
+		{name: "escaping link", links: []entry{{"repo/evil", "../../outside"}}, wantErr: true},
+		{name: "absolute link", links: []entry{{"repo/evil", "/etc/passwd"}}, wantErr: true},
+		{name: "escape through another link", links: []entry{{"dot", "."}, {"evil", "dot/.."}}, wantErr: true},
+		{name: "escape through a later link", links: []entry{{"x", "y/.."}, {"y", "."}}, wantErr: true},
+		{name: "dangling link", links: []entry{{"repo/missing", "nope.txt"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			zw := zip.NewWriter(&buf)
+			w, _ := zw.Create("repo/docs/readme.txt")
+			_, _ = w.Write([]byte("hello"))
+			for _, l := range tt.links {
+				hdr := &zip.FileHeader{Name: l.name, Method: zip.Store}
+				hdr.SetMode(os.ModeSymlink | 0o777)
+				w, err := zw.CreateHeader(hdr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, _ = w.Write([]byte(l.body))
+			}
+			_ = zw.Close()
+
+			dest := filepath.Join(t.TempDir(), "out")
+			err := extractZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()), dest, (&Client{}).extractLimits())
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "illegal symlink target") {
+					t.Fatalf("expected illegal symlink error, got %v", err)
+				}
+				for _, l := range tt.links {
+					if _, err := os.Lstat(filepath.Join(dest, l.name)); !os.IsNotExist(err) {
+						t.Errorf("link %s left behind after failure: %v", l.name, err)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractZip: %v", err)
+			}
+			link := filepath.Join(dest, "repo", "link.txt")
+			target, err := os.Readlink(link)
+			if err != nil || target != "docs/readme.txt" {
+				t.Fatalf("readlink=%q err=%v", target, err)
+			}
+			data, err := os.ReadFile(link)
+			if err != nil || string(data) != "hello" {
+				t.Fatalf("read through link=%q err=%v", data, err)
+			}
+		})
+	}
+}
+
+func TestExtractZip_Limits(t *testing.T) {
+	type entry struct {
+		name string
+		size int
+	}
+	tests := []struct {
+		name    string
+		entries []entry
+		wantErr bool
+	}{
+		{name: "within limits", entries: []entry{{name: "a", size: 5}, {name: "b", size: 5}}},
+		{name: "file over per-file cap", entries: []entry{{name: "a", size: 20}}, wantErr: true},
+		{name: "archive over total cap", entries: []entry{{name: "a", size: 8}, {name: "b", size: 8}}, wantErr: true},
+	}
+	limits := extractLimits{total: 15, perFile: 10}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			zw := zip.NewWriter(&buf)
+			for _, e := range tt.entries {
+				w, _ := zw.Create(e.name)
+				_, _ = w.Write(bytes.Repeat([]byte("x"), e.size))
+			}
+			_ = zw.Close()
+
+			dest := t.TempDir()
+			err := extractZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()), dest, limits)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("extractZip: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrExtractLimit) {
+				t.Fatalf("expected ErrExtractLimit, got %v", err)
+			}
+			left, _ := os.ReadDir(dest)
+			if len(left) != 0 {
+				t.Fatalf("partial output not cleaned up: %v", left)
+			}
+		})
+	}
+}
+
+func TestListEntries_JSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/dir/list" || r.URL.Query().Get("path") != "repos" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`[
+			{"name":"b.zip","path":"repos/b.zip","size":30},
+			{"name":"z","path":"repos/z","is_dir":true},
+			{"name":"a.zip","path":"repos/a.zip","size":12},
+			{"name":"c","path":"repos/c","is_dir":true}
+		]`))
+	}))
+	t.Cleanup(server.Close)
+
+	c := NewClient(server.URL, "", server.Client())
+	entries, err := c.ListEntries(context.Background(), "repos")
+	if err != nil {
+		t.Fatalf("ListEntries: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeListJSON(&buf, "repos", entries); err != nil {
+		t.Fatalf("writeListJSON: %v", err)
+	}
+	var out struct {
+		Path      string  `json:"path"`
+		Entries   []Entry `json:"entries"`
+		TotalSize int64   `json:"total_size"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+	}
+	var names []string
+	for _, e := range out.Entries {
+		names = append(names, e.Name)
+	}
+	if got := strings.Join(names, ","); got != "c,z,a.zip,b.zip" {
+		t.Fatalf("order=%s, want directories first then by name", got)
+	}
+	if out.TotalSize != 42 || out.Path != "repos" {
+		t.Fatalf("unexpected summary: path=%s total_size=%d", out.Path, out.TotalSize)
+	}
+}
+
+func TestListTree_Recursive(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("recursive") != "true" {
+			http.Error(w, "expected recursive=true", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"path":"repos","truncated":true,"entries":[
+			{"name":"main.zip","path":"repos/o/r/main.zip","size":5},
+			{"name":"o","path":"repos/o","is_dir":true},
+			{"name":"r","path":"repos/o/r","is_dir":true}
+		]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	c := NewClient(server.URL, "", server.Client())
+	entries, truncated, err := c.ListTree(context.Background(), "repos")
+	if err != nil {
+		t.Fatalf("ListTree: %v", err)
+	}
+	if !truncated || len(entries) != 3 {
+		t.Fatalf("entries=%d truncated=%v", len(entries), truncated)
+	}
+
+	var buf bytes.Buffer
+	if err := writeTreeJSON(&buf, "repos", entries, truncated); err != nil {
+		t.Fatalf("writeTreeJSON: %v", err)
+	}
+	var out struct {
+		Entries   []Entry `json:"entries"`
+		TotalSize int64   `json:"total_size"`
+		Truncated bool    `json:"truncated"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+	}
+	var paths []string
+	for _, e := range out.Entries {
+		paths = append(paths, e.Path)
+	}
+	if got := strings.Join(paths, ","); got != "repos/o,repos/o/r,repos/o/r/main.zip" {
+		t.Fatalf("order=%s, want path order", got)
+	}
+	if !out.Truncated || out.TotalSize != 5 {
+		t.Fatalf("truncated=%v total_size=%d", out.Truncated, out.TotalSize)
+	}
+}
+
+func TestMove(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/dir/move" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		if got["to"] == "taken" {
+			http.Error(w, "move: already exists", http.StatusConflict)
+			return
+		}
+		_, _ = w.Write([]byte("moved"))
+	}))
+	t.Cleanup(server.Close)
+
+	c := NewClient(server.URL, "", server.Client())
+	if err := c.Move(context.Background(), "uploads/a", "uploads/b"); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	if got["from"] != "uploads/a" || got["to"] != "uploads/b" {
+		t.Fatalf("unexpected body: %v", got)
+	}
+	var httpErr *HTTPError
+	if err := c.Move(context.Background(), "uploads/b", "taken"); !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409 HTTPError, got %v", err)
+	}
+}
+
+func TestUpload_ZipsDirectory(t *testing.T) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("alpha"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "sub", "b.txt"), []byte("beta"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]string{}
+	var gotPath string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/upload", func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Query().Get("path")
+		body, _ := io.ReadAll(r.Body)
+		zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			rc, _ := f.Open()
+			b, _ := io.ReadAll(rc)
+			_ = rc.Close()
+			got[f.Name] = string(b)
+		}
+		_, _ = w.Write([]byte("uploaded"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	c := NewClient(server.URL, "", server.Client())
+	if err := c.Upload(context.Background(), src, "artifacts/build-1"); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if gotPath != "artifacts/build-1" {
+		t.Fatalf("unexpected path: %q", gotPath)
+	}
+	if len(got) != 2 || got["a.txt"] != "alpha" || got["sub/b.txt"] != "beta" {
+		t.Fatalf("unexpected archive contents: %v", got)
+	}
+}
+
+func TestDownload_ChecksumMismatch(t *testing.T) {
+	good := []byte("zip-bytes-from-cache")
+	sum := sha256.Sum256(good)
+	var attempts int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/download", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Header().Set("X-GHH-SHA256", hex.EncodeToString(sum[:]))
+		// Deliberately corrupt the stream: same length, one flipped byte.
+		corrupt := append([]byte{}, good...)
+		corrupt[3] ^= 0xff
+		_, _ = w.Write(corrupt)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	c := NewClient(server.URL, "", server.Client())
+	c.RetryMax = 1
+	c.RetryBackoff = time.Millisecond
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "repo.zip")
+	err := c.Download(context.Background(), "foo/bar", "main", zipPath, "")
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Fatalf("expected mismatch to be retried once, got %d attempts", got)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Fatalf("expected partial files to be removed, found %d entries", len(entries))
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	var gotUser string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/cache/stats", func(w http.ResponseWriter, r *http.Request) {
+		gotUser = r.Header.Get("X-GHH-User")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"user":"alice","entries":[{"user":"alice","repo":"o/r","branch":"main","size":42,"mod_time":"2024-03-01T10:00:00Z"}],"total_size":42,"total_count":1}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	c := NewClient(server.URL, "", server.Client())
+	c.User = "alice"
+	st, err := c.CacheStats(context.Background())
+	if err != nil {
+		t.Fatalf("CacheStats: %v", err)
+	}
+	if gotUser != "alice" {
+		t.Fatalf("expected user header alice, got %q", gotUser)
+	}
+	if st.TotalCount != 1 || st.TotalSize != 42 || len(st.Entries) != 1 || st.Entries[0].Repo != "o/r" {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
+
+func TestClearCache(t *testing.T) {
+	var gotUser, gotMethod string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/cache", func(w http.ResponseWriter, r *http.Request) {
+		gotUser = r.Header.Get("X-GHH-User")
+		gotMethod = r.Method
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"user":"alice","freed_bytes":1234}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	c := NewClient(server.URL, "", server.Client())
+	c.User = "alice"
+	freed, err := c.ClearCache(context.Background())
+	if err != nil {
+		t.Fatalf("ClearCache: %v", err)
+	}
+	if gotMethod != http.MethodDelete || gotUser != "alice" {
+		t.Fatalf("unexpected request: method=%s user=%q", gotMethod, gotUser)
+	}
+	if freed != 1234 {
+		t.Fatalf("freed=%d, want 1234", freed)
+	}
+}
+
+func TestDirAndBranchCalls_Retry(t *testing.T) {
+	calls := []struct {
+		name    string
+		pattern string
+		call    func(c *Client) error
+	}{
+		{"ListDir", "/api/v1/dir/list", func(c *Client) error {
+			return c.ListDir(context.Background(), "u/tmp", ListRaw, false, "")
+		}},
+		{"DeleteDir", "/api/v1/dir", func(c *Client) error {
+			return c.DeleteDir(context.Background(), "u/tmp", true)
+		}},
+		{"SwitchBranch", "/api/v1/branch/switch", func(c *Client) error {
+			return c.SwitchBranch(context.Background(), "owner/repo", "dev")
+		}},
+	}
+	statuses := []struct {
+		name     string
+		status   int
+		wantErr  bool
+		attempts int32
+	}{
+		{"503 then success", http.StatusServiceUnavailable, false, 2},
+		{"504 then success", http.StatusGatewayTimeout, false, 2},
+		{"500 is not retried", http.StatusInternalServerError, true, 1},
+		{"404 is not retried", http.StatusNotFound, true, 1},
+	}
+
+	for _, call := range calls {
+		for _, st := range statuses {
+			t.Run(call.name+"/"+st.name, func(t *testing.T) {
+				var attempts int32
+				var bodies []string
+				mux := http.NewServeMux()
+				mux.HandleFunc(call.pattern, func(w http.ResponseWriter, r *http.Request) {
+					b, _ := io.ReadAll(r.Body)
+					bodies = append(bodies, string(b))
+					if atomic.AddInt32(&attempts, 1) == 1 {
+						http.Error(w, "temporary", st.status)
+						return
+					}
+					_, _ = w.Write([]byte("[]"))
+				})
+				server := httptest.NewServer(mux)
+				t.Cleanup(server.Close)
+
+				c := NewClient(server.URL, "", server.Client())
+				c.RetryMax = 2
+				c.RetryBackoff = time.Millisecond
+
+				err := call.call(c)
+				if (err != nil) != st.wantErr {
+					t.Fatalf("err = %v, wantErr %v", err, st.wantErr)
+				}
+				if attempts != st.attempts {
+					t.Fatalf("expected %d attempts, got %d", st.attempts, attempts)
+				}
+				for _, b := range bodies[1:] {
+					if b != bodies[0] {
+						t.Fatalf("retried request body changed: %q vs %q", b, bodies[0])
+					}
+				}
+			})
+		}
+	}
+}
@@ -5,14 +5,45 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Config holds client configuration loaded from YAML (preferred) or JSON (fallback).
 type Config struct {
-	BaseURL string `json:"base_url"`
-	Token   string `json:"token"`
-	User    string `json:"user"`
+	BaseURL   string    `json:"base_url" yaml:"base_url"`
+	Token     string    `json:"token" yaml:"token"`
+	User      string    `json:"user" yaml:"user"`
+	Endpoints Endpoints `json:"endpoints" yaml:"endpoints"`
+}
+
+// Endpoints overrides the client's API path templates; empty fields keep the
+// built-in defaults.
+type Endpoints struct {
+	Download     string `json:"download" yaml:"download"`
+	BranchSwitch string `json:"branch_switch" yaml:"branch_switch"`
+	DirList      string `json:"dir_list" yaml:"dir_list"`
+	DirDelete    string `json:"dir_delete" yaml:"dir_delete"`
+}
+
+// UnmarshalYAML rejects unknown endpoint names so a typo does not silently
+// fall back to the built-in template.
+func (e *Endpoints) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: endpoints must be a mapping", n.Line)
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		switch key := n.Content[i]; key.Value {
+		case "download", "branch_switch", "dir_list", "dir_delete":
+		default:
+			return fmt.Errorf("line %d: unknown endpoint %q", key.Line, key.Value)
+		}
+	}
+	type plain Endpoints
+	return n.Decode((*plain)(e))
 }
 
 func Default() Config {
@@ -22,9 +53,127 @@ func Default() Config {
 	}
 }
 
+// ErrExists is returned by WriteTemplate when the target file already exists.
+var ErrExists = errors.New("config file already exists")
+
+// DefaultPath returns the config location used when neither --config nor
+// GHH_CONFIG is set: <user config dir>/ghh/config.yaml. It returns "" when the
+// user config dir cannot be determined.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "ghh", "config.yaml")
+}
+
+const yamlTemplate = `base_url: "http://localhost:8080"
+# Auth token sent as a Bearer token; GHH_TOKEN or --token override it.
+token: ""
+# User name for grouping the server cache; GHH_USER or --user override it.
+user: ""
+# Optional API path overrides, e.g. for a server behind a path prefix:
+# endpoints:
+#   download: "/api/v1/download"
+#   branch_switch: "/api/v1/branch/switch"
+#   dir_list: "/api/v1/dir/list"
+#   dir_delete: "/api/v1/dir"
+`
+
+// Template returns a starter config for path: YAML for .yml/.yaml paths,
+// JSON otherwise, so Load reads it back the same way.
+func Template(path string) []byte {
+	if isYAML(path) {
+		return []byte(yamlTemplate)
+	}
+	b, _ := json.MarshalIndent(Default(), "", "  ")
+	return append(b, '\n')
+}
+
+// WriteTemplate writes Template(path) to path, creating parent directories.
+// An existing file is kept and ErrExists returned unless force is set. The
+// file is only readable by its owner because it holds a token.
+func WriteTemplate(path string, force bool) error {
+	if path == "" {
+		return errors.New("no config path")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%s: %w", path, ErrExists)
+		}
+		return err
+	}
+	if _, err := f.Write(Template(path)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// MaskToken hides all but the last four characters of a token; short tokens
+// are hidden completely.
+func MaskToken(token string) string {
+	if token == "" {
+		return ""
+	}
+	if len(token) <= 8 {
+		return "****"
+	}
+	return "****" + token[len(token)-4:]
+}
+
 // Load loads config from YAML (.yml/.yaml) or JSON path. If the file
 // does not exist or path is empty, returns default config and no error.
+// String values may reference environment variables as ${VAR} or $VAR;
+// see ExpandEnv.
 func Load(path string) (Config, error) {
+	cfg, err := loadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	ExpandEnvFields(&cfg)
+	return cfg, nil
+}
+
+// ExpandEnv replaces ${VAR} and $VAR in s with the environment value, or
+// with "" when the variable is unset. $$ stands for a literal dollar sign.
+func ExpandEnv(s string) string {
+	return os.Expand(s, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		return os.Getenv(name)
+	})
+}
+
+// ExpandEnvFields applies ExpandEnv to every string field of the struct v
+// points to, including nested structs.
+func ExpandEnvFields(v interface{}) {
+	expandValue(reflect.ValueOf(v).Elem())
+}
+
+func expandValue(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(ExpandEnv(v.String()))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			expandValue(v.Field(i))
+		}
+	}
+}
+
+func loadFile(path string) (Config, error) {
 	if path == "" {
 		return Default(), nil
 	}
@@ -68,43 +217,15 @@ func isYAML(path string) bool {
 	return strings.HasSuffix(lower, ".yaml") || strings.HasSuffix(lower, ".yml")
 }
 
-// Minimal YAML parser for the limited schema of Config.
-// Supports:
-//
-//	base_url: "..."
-//	token: "..."
-//	user: "..."
+// parseYAMLConfig decodes a YAML config on top of the defaults. Keys that are
+// missing or empty keep their default values.
 func parseYAMLConfig(s string) (Config, error) {
 	cfg := Default()
-	for _, raw := range strings.Split(s, "\n") {
-		line := strings.TrimRight(raw, "\r")
-		t := strings.TrimSpace(line)
-		if t == "" || strings.HasPrefix(t, "#") {
-			continue
-		}
-		// Key: value pairs
-		kv := strings.SplitN(strings.TrimSpace(line), ":", 2)
-		if len(kv) != 2 {
-			continue
-		}
-		k := strings.TrimSpace(kv[0])
-		v := strings.TrimSpace(kv[1])
-		v = strings.Trim(v, "\"'")
-		// root level
-		switch k {
-		case "base_url":
-			if v != "" {
-				cfg.BaseURL = v
-			}
-		case "token":
-			if v != "" {
-				cfg.Token = v
-			}
-		case "user":
-			if v != "" {
-				cfg.User = v
-			}
-		}
+	if err := yaml.Unmarshal([]byte(s), &cfg); err != nil {
+		return Config{}, err
+	}
+	if strings.TrimSpace(cfg.BaseURL) == "" {
+		cfg.BaseURL = Default().BaseURL
 	}
 	return cfg, nil
 }
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteTemplate(t *testing.T) {
+	for _, name := range []string{"config.yaml", "config.json"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "nested", name)
+			if err := WriteTemplate(path, false); err != nil {
+				t.Fatalf("WriteTemplate: %v", err)
+			}
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load template: %v", err)
+			}
+			if cfg != Default() {
+				t.Errorf("template should load as the defaults, got %+v", cfg)
+			}
+			if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+				t.Errorf("expected mode 0600, got %v (%v)", info.Mode().Perm(), err)
+			}
+
+			if err := os.WriteFile(path, []byte(`base_url: "http://hub:8080"`), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := WriteTemplate(path, false); !errors.Is(err, ErrExists) {
+				t.Fatalf("expected ErrExists, got %v", err)
+			}
+			if b, _ := os.ReadFile(path); string(b) != `base_url: "http://hub:8080"` {
+				t.Errorf("existing file was modified: %q", b)
+			}
+			if err := WriteTemplate(path, true); err != nil {
+				t.Fatalf("WriteTemplate with force: %v", err)
+			}
+			if b, _ := os.ReadFile(path); string(b) != string(Template(path)) {
+				t.Errorf("force should overwrite the file, got %q", b)
+			}
+		})
+	}
+}
+
+func TestMaskToken(t *testing.T) {
+	tests := map[string]string{
+		"":                "",
+		"short":           "****",
+		"ghp_abcdef12345": "****2345",
+	}
+	for in, want := range tests {
+		if got := MaskToken(in); got != want {
+			t.Errorf("MaskToken(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseYAMLConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    Config
+		wantErr bool
+	}{
+		{
+			name: "flat only",
+			in:   "base_url: \"http://hub:8080\"\ntoken: abc\nuser: 'bob'\n",
+			want: Config{BaseURL: "http://hub:8080", Token: "abc", User: "bob"},
+		},
+		{
+			name: "nested endpoints",
+			in: `base_url: http://hub:8080
+endpoints:
+  download: "/v2/download"
+  branch_switch: /v2/branch/{repo}/switch
+  # comment inside the section
+  dir_list: /v2/dir/{path}
+  dir_delete: /v2/dir/{path}  # inline comment
+user: bob
+`,
+			want: Config{
+				BaseURL: "http://hub:8080",
+				User:    "bob",
+				Endpoints: Endpoints{
+					Download:     "/v2/download",
+					BranchSwitch: "/v2/branch/{repo}/switch",
+					DirList:      "/v2/dir/{path}",
+					DirDelete:    "/v2/dir/{path}",
+				},
+			},
+		},
+		{
+			name: "root key after section",
+			in:   "endpoints:\n  download: /d\ntoken: t\n",
+			want: Config{BaseURL: Default().BaseURL, Token: "t", Endpoints: Endpoints{Download: "/d"}},
+		},
+		{
+			name: "quoted colons, anchors and block scalars",
+			in: `token: "a:b:c"
+base: &base http://hub:8080
+base_url: *base
+user: >-
+  folded
+  name
+`,
+			want: Config{BaseURL: "http://hub:8080", Token: "a:b:c", User: "folded name"},
+		},
+		{
+			name: "empty base_url keeps default",
+			in:   "base_url: \"\"\ntoken: t # trailing comment\n",
+			want: Config{BaseURL: Default().BaseURL, Token: "t"},
+		},
+		{
+			name:    "unknown endpoint",
+			in:      "endpoints:\n  upload: /u\n",
+			wantErr: true,
+		},
+		{
+			name:    "inline endpoints value",
+			in:      "endpoints: /x\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAMLConfig(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadJSONEndpoints(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"base_url":"http://hub","endpoints":{"dir_list":"/v2/list"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.BaseURL != "http://hub" || cfg.Endpoints.DirList != "/v2/list" {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("GHH_TEST_TOKEN", "secret")
+	t.Setenv("GHH_TEST_HOST", "example.com")
+	cases := []struct {
+		in, want string
+	}{
+		{"${GHH_TEST_TOKEN}", "secret"},
+		{"$GHH_TEST_TOKEN", "secret"},
+		{"https://${GHH_TEST_HOST}:8080", "https://example.com:8080"},
+		{"${GHH_TEST_UNSET_VAR}", ""},
+		{"pa$$word", "pa$word"},
+		{"$${GHH_TEST_TOKEN}", "${GHH_TEST_TOKEN}"},
+		{"plain", "plain"},
+	}
+	for _, tc := range cases {
+		if got := ExpandEnv(tc.in); got != tc.want {
+			t.Errorf("ExpandEnv(%q)=%q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestLoadExpandsEnv(t *testing.T) {
+	t.Setenv("GHH_TEST_TOKEN", "secret")
+	t.Setenv("GHH_TEST_HOST", "ghh.example.com")
+	files := map[string]string{
+		"config.yaml": "base_url: \"https://${GHH_TEST_HOST}\"\ntoken: \"${GHH_TEST_TOKEN}\"\nuser: \"$GHH_TEST_UNSET_VAR\"\nendpoints:\n  download: \"/api/$$v1/download\"\n",
+		"config.json": `{"base_url":"https://${GHH_TEST_HOST}","token":"${GHH_TEST_TOKEN}","user":"$GHH_TEST_UNSET_VAR","endpoints":{"download":"/api/$$v1/download"}}`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.BaseURL != "https://ghh.example.com" || cfg.Token != "secret" {
+				t.Errorf("base_url=%q token=%q", cfg.BaseURL, cfg.Token)
+			}
+			if cfg.User != "" {
+				t.Errorf("unset variable should expand to empty, got %q", cfg.User)
+			}
+			if cfg.Endpoints.Download != "/api/$v1/download" {
+				t.Errorf("endpoints.download=%q", cfg.Endpoints.Download)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github-hub/internal/quality/storage"
+)
+
+// httpStatusFor 把存储层错误映射为 HTTP 状态码：
+// 不存在返回 404，冲突返回 409，其余（如数据库故障）返回 500
+func httpStatusFor(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, storage.ErrEventNotFound), errors.Is(err, storage.ErrCheckNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeStorageError 按错误类型返回状态码；404/409 直接返回错误信息，
+// 500 只返回 fallback，避免把数据库错误细节暴露给调用方
+func writeStorageError(w http.ResponseWriter, err error, fallback string) {
+	status := httpStatusFor(err)
+	msg := fallback
+	if status != http.StatusInternalServerError {
+		msg = err.Error()
+	}
+	http.Error(w, msg, status)
+}
@@ -1,8 +1,15 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -13,7 +20,9 @@ import (
 	"github-hub/internal/quality/handlers"
 	"github-hub/internal/quality/logger"
 	"github-hub/internal/quality/models"
+	"github-hub/internal/quality/notify"
 	"github-hub/internal/quality/storage"
+	"github-hub/internal/version"
 )
 
 // Server 质量引擎服务器
@@ -23,6 +32,27 @@ type Server struct {
 	pushHandler *handlers.PushHandler
 	qualityDir  string
 	startTime   time.Time
+	dbType      string
+	dbHost      string
+	dbName      string
+
+	// acceptedEvents 允许处理的事件键集合，为空表示全部接受
+	acceptedEvents map[models.EventKey]bool
+
+	// notifier 事件完成时的出站通知投递器，为空表示不发送通知
+	notifier *notify.Dispatcher
+
+	// webhookSecret Webhook 签名密钥，为空表示不校验 X-Hub-Signature-256
+	webhookSecret []byte
+
+	// workers 异步处理 Webhook 和模拟事件的 worker 池
+	workers *workerPool
+
+	// outputPreviewBytes 事件详情、列表和检查列表中每个检查 output 的最大字节数，0 表示不截断
+	outputPreviewBytes int
+
+	// recordSkipped 为 true 时被过滤的 push/PR 事件也以 skipped 状态保存，便于审计
+	recordSkipped bool
 }
 
 // NewServerWithStorage 使用提供的存储创建新的质量引擎服务器
@@ -31,13 +61,165 @@ func NewServerWithStorage(store storage.Storage) (*Server, error) {
 	prHandler := handlers.NewPRHandler(store)
 	pushHandler := handlers.NewPushHandler(store)
 
-	return &Server{
+	server := &Server{
 		storage:     store,
 		prHandler:   prHandler,
 		pushHandler: pushHandler,
 		qualityDir:  "/usr/local/share/quality-data",
 		startTime:   time.Now(),
-	}, nil
+		workers:     newWorkerPool(DefaultWorkers, DefaultQueueSize),
+
+		outputPreviewBytes: DefaultOutputPreviewBytes,
+	}
+
+	// 重新提交重启前已保存、但还没来得及处理的 Webhook 事件
+	server.recoverPendingEvents(context.Background(), DefaultStalePendingAge)
+	return server, nil
+}
+
+// DefaultStalePendingAge 启动时超过该时长仍为 pending 且没有检查项的事件视为重启前未处理完的事件
+// Webhook 保存事件后通常在毫秒级内由 worker 创建检查项，留出余量以免抢走其他实例队列中的事件
+const DefaultStalePendingAge = time.Minute
+
+// recoverPendingEvents 启动恢复：把超过 grace 仍为 pending、且还没有检查项的事件重新提交到 worker 池，返回提交的数量
+// 这些事件已由 Webhook 保存，但进程在 worker 处理前退出；已有检查项的事件已处理过，由 CI 继续推进，直接跳过
+func (s *Server) recoverPendingEvents(ctx context.Context, grace time.Duration) int {
+	events, err := s.storage.ListPendingEvents(ctx, grace)
+	if err != nil {
+		logger.Warnf("Failed to list stale pending events: %v", err)
+		return 0
+	}
+
+	recovered := 0
+	for _, event := range events {
+		if len(event.QualityChecks) > 0 {
+			continue
+		}
+		if !s.submitStoredEvent(ctx, event.ID) {
+			logger.Warnf("Event queue is full, leaving remaining stale pending events for the next restart")
+			break
+		}
+		recovered++
+	}
+	if recovered > 0 {
+		logger.Infof("Re-queued %d pending event(s) left unprocessed before restart", recovered)
+	}
+	return recovered
+}
+
+// submitStoredEvent 把已保存的事件放入 worker 池处理，队列已满或池已停止时返回 false
+func (s *Server) submitStoredEvent(ctx context.Context, id int) bool {
+	return s.workers.submit(func() { s.processStoredEvent(ctx, id) })
+}
+
+// processStoredEvent 按当前流水线为已保存的 pending 事件创建质量检查项
+// 事件已有检查项（已处理过，或被其他实例抢先处理）时跳过
+func (s *Server) processStoredEvent(ctx context.Context, id int) {
+	log := logger.FromContext(ctx)
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("Panic in event processing: %v", r)
+		}
+	}()
+
+	event, err := s.storage.GetEvent(ctx, id)
+	if err != nil {
+		log.Warnf("Failed to load event %d for processing: %v", id, err)
+		return
+	}
+	if len(event.QualityChecks) > 0 {
+		return
+	}
+	checks := models.CreateChecksForRepository(event.EventID, event.Repository, event.ChangedFiles)
+	if err := s.storage.AttachQualityChecks(ctx, id, checks); err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			return
+		}
+		log.Errorf("Failed to create quality checks for event %d: %v", id, err)
+		return
+	}
+	log.Infof("Created event #%d (%s) with %d quality checks", id, event.EventID, len(checks))
+}
+
+// SetWorkers 替换异步事件处理的 worker 池，旧池中已入队的事件处理完后退出
+// workers 为并发处理数，queueSize 为等待队列容量，非正数使用默认值
+func (s *Server) SetWorkers(workers, queueSize int) {
+	old := s.workers
+	s.workers = newWorkerPool(workers, queueSize)
+	if old != nil {
+		old.stop()
+	}
+}
+
+// Close 停止接收异步事件，并等待已入队的事件处理完毕
+// 之后提交的事件会被拒绝（Webhook 返回 503），重复调用是安全的
+func (s *Server) Close() {
+	s.workers.stop()
+}
+
+// writeQueueFull 事件队列已满时返回 503，提示调用方稍后重试
+func writeQueueFull(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "1")
+	http.Error(w, "event queue is full, retry later", http.StatusServiceUnavailable)
+}
+
+// SetDatabaseInfo 设置状态接口展示的数据库信息（由启动时解析的 DSN 提供）
+func (s *Server) SetDatabaseInfo(dbType, host, name string) {
+	s.dbType = dbType
+	s.dbHost = host
+	s.dbName = name
+}
+
+// SetNotifier 设置事件完成（completed/failed）时使用的出站通知投递器
+func (s *Server) SetNotifier(d *notify.Dispatcher) {
+	s.notifier = d
+}
+
+// SetWebhookSecret 设置 Webhook 签名密钥，设置后请求必须携带有效的 X-Hub-Signature-256
+func (s *Server) SetWebhookSecret(secret string) {
+	s.webhookSecret = []byte(secret)
+}
+
+// DefaultOutputPreviewBytes 事件和检查列表响应中每个检查 output 默认保留的字节数
+const DefaultOutputPreviewBytes = 4096
+
+// SetOutputPreviewBytes 设置响应中 output 保留的字节数，超出部分只保留末尾并标记 output_truncated
+// 0 表示返回完整输出；完整输出始终可通过 GET /api/quality-checks/{id}/output 获取
+func (s *Server) SetOutputPreviewBytes(n int) {
+	s.outputPreviewBytes = n
+}
+
+// SetRecordSkipped 设置是否保存被过滤跳过的 push/PR 事件及跳过原因
+func (s *Server) SetRecordSkipped(enable bool) {
+	s.recordSkipped = enable
+}
+
+// verifySignature 校验 GitHub 的 X-Hub-Signature-256（sha256=<hex HMAC>）
+func (s *Server) verifySignature(body []byte, header string) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, s.webhookSecret)
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// SetAcceptedEvents 设置允许处理的事件键，如 "push"、"pull_request.opened"
+// 纯类型匹配该类型的所有 action，传入空列表表示全部接受
+func (s *Server) SetAcceptedEvents(keys []string) {
+	accepted := make(map[models.EventKey]bool)
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key != "" {
+			accepted[models.EventKey(key)] = true
+		}
+	}
+	s.acceptedEvents = accepted
 }
 
 // RegisterRoutes 注册路由
@@ -47,14 +229,21 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 
 	// API 端点
 	mux.HandleFunc("/api/events", s.handleEvents)
+	mux.HandleFunc("/api/events/ingest", s.handleIngestEvents)
+	mux.HandleFunc("/api/events/validate", s.handleValidateEvent)
 	mux.HandleFunc("/api/repositories", s.handleRepositories)
+	mux.HandleFunc("/api/repositories/latest", s.handleLatestEvents)
 	mux.HandleFunc("/api/mock/events", s.handleMockEvents)
 	mux.HandleFunc("/api/mock/simulate/", s.handleMockSimulate)
 	mux.HandleFunc("/api/custom-test", s.handleCustomTest)
+	mux.HandleFunc("/api/pipeline/preview", s.handlePipelinePreview)
 	mux.HandleFunc("/api/login", s.handleLogin)
 	mux.HandleFunc("/api/logout", s.handleLogout)
 	mux.HandleFunc("/api/check-login", s.handleCheckLogin)
 	mux.HandleFunc("/api/status", s.handleStatus)
+	mux.HandleFunc("/api/version", s.handleVersion)
+	mux.HandleFunc("/api/admin/notifications/dead-letter", s.handleDeadLetterNotifications)
+	mux.HandleFunc("/api/admin/latency", s.handleLatency)
 
 	// 动态路由处理
 	mux.HandleFunc("/api/", s.handleDynamicRoutes)
@@ -67,6 +256,7 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 
 // handleWebhook 处理Webhook事件
 func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	reqLog := logger.FromContext(r.Context())
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -79,73 +269,142 @@ func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	// 配置了密钥时校验签名
+	if len(s.webhookSecret) > 0 && !s.verifySignature(body, r.Header.Get("X-Hub-Signature-256")) {
+		reqLog.Warn("Rejected webhook with invalid signature")
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
 	// 解析请求体
 	var payload map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+	if err := json.Unmarshal(body, &payload); err != nil {
 		http.Error(w, "invalid JSON payload", http.StatusBadRequest)
 		return
 	}
 
-	logger.Infof("DEBUG: Received event: %s", eventType)
-
-	// 事件过滤逻辑
-	shouldProcess := false
-
-	if eventType == "push" {
-		// Push事件过滤：只处理main分支
-		shouldProcess = models.ShouldProcessPushEvent(payload)
-		if shouldProcess {
-			logger.Infof("Processing push event")
-		} else {
-			logger.Infof("Skipping push event")
-		}
+	eventKey := models.NewEventKey(eventType, payload)
+	eventLog := reqLog.WithField("event_key", string(eventKey))
+	eventLog.Infof("DEBUG: Received event: %s", eventType)
 
-	} else if eventType == "pull_request" {
-		// PR事件过滤：只处理非main分支合入main分支的事件
-		shouldProcess = models.ShouldProcessPREvent(payload)
-		if shouldProcess {
-			logger.Infof("Processing PR event")
-		} else {
-			logger.Infof("Skipping PR event")
+	// 按事件键过滤（如只处理 pull_request.opened|synchronize|reopened），再按分支过滤
+	if skipReason := s.skipReason(eventType, eventKey, payload); skipReason != "" {
+		eventLog.Infof("Skipping event: %s", skipReason)
+		if s.recordSkipped {
+			s.recordSkippedEvent(r.Context(), eventType, payload, skipReason)
 		}
-	}
-
-	if !shouldProcess {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"status": "skipped",
-			"event":  eventType,
+			"status":    "skipped",
+			"event":     eventType,
+			"event_key": eventKey,
+			"reason":    skipReason,
 		})
 		return
 	}
+	eventLog.Infof("Processing %s event", eventType)
 
-	// 异步处理事件
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				logger.Infof("ERROR: Panic in event processing: %v", r)
-			}
-		}()
+	// 先以 pending 状态保存事件再入队，进程在 worker 处理前退出时由启动恢复重新提交
+	event, err := models.NewGitHubEvent(payload, models.EventType(eventType))
+	if err != nil {
+		eventLog.Warnf("Rejecting invalid %s event: %v", eventType, err)
+		http.Error(w, "invalid event: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.storage.CreateEvent(r.Context(), event); err != nil {
+		writeStorageError(w, err, "failed to save event")
+		return
+	}
 
-		// 根据事件类型处理
-		if eventType == "push" {
-			s.pushHandler.Handle(payload)
-		} else if eventType == "pull_request" {
-			s.prHandler.Handle(payload)
-		} else {
-			logger.Infof("WARN: Unknown event type: %s", eventType)
+	// 放入队列由 worker 池异步处理，队列已满时返回 503
+	// 处理发生在响应返回之后，因此使用不随请求取消的 context
+	ctx := context.WithoutCancel(r.Context())
+	if !s.submitStoredEvent(ctx, event.ID) {
+		eventLog.Warn("Event queue is full, rejecting webhook")
+		// 调用方会重新投递，删掉刚保存的事件以免重复
+		if err := s.storage.DeleteEvent(ctx, event.ID); err != nil {
+			eventLog.Warnf("Failed to delete rejected event %d: %v", event.ID, err)
 		}
-	}()
+		writeQueueFull(w)
+		return
+	}
 
 	// 返回响应
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status": "received",
-		"event":  eventType,
+		"status":    "received",
+		"event":     eventType,
+		"event_key": eventKey,
+		"id":        event.ID,
+		"event_id":  event.EventID,
 	})
 }
 
+// skipReason 返回 Webhook 事件被跳过的原因，先按允许的事件键过滤，再按分支过滤；应当处理时返回空字符串
+func (s *Server) skipReason(eventType string, eventKey models.EventKey, payload map[string]interface{}) string {
+	if len(s.acceptedEvents) > 0 && !eventKey.MatchesAny(s.acceptedEvents) {
+		return fmt.Sprintf("event key %s is not in the accepted set", eventKey)
+	}
+	return eventSkipReason(eventType, payload)
+}
+
+// eventSkipReason 按事件类型返回跳过原因，应当处理时返回空字符串
+// push 只处理 main 分支，PR 只处理非 main 分支合入 main 的事件，其他类型一律跳过
+func eventSkipReason(eventType string, payload map[string]interface{}) string {
+	switch eventType {
+	case "push":
+		return models.PushEventSkipReason(payload)
+	case "pull_request":
+		return models.PREventSkipReason(payload)
+	default:
+		return fmt.Sprintf("unsupported event type %s", eventType)
+	}
+}
+
+// maxSkipReasonLength skip_reason 列的最大长度（VARCHAR(255) 按字符计）
+const maxSkipReasonLength = 255
+
+// truncateSkipReason 把原因截断到 maxSkipReasonLength 个字符，不会切断多字节字符
+func truncateSkipReason(reason string) string {
+	count := 0
+	for i := range reason {
+		if count == maxSkipReasonLength {
+			return reason[:i]
+		}
+		count++
+	}
+	return reason
+}
+
+// recordSkippedEvent 以 skipped 状态保存被过滤的 push/PR 事件，不创建质量检查项
+// 其他事件类型或缺少必填字段时只记录日志；保存失败不影响 Webhook 响应
+func (s *Server) recordSkippedEvent(ctx context.Context, eventType string, payload map[string]interface{}, reason string) {
+	log := logger.FromContext(ctx)
+	if eventType != string(models.EventTypePush) && eventType != string(models.EventTypePullRequest) {
+		return
+	}
+	event, err := models.NewGitHubEvent(payload, models.EventType(eventType))
+	if err != nil {
+		log.Warnf("Not recording skipped event: %v", err)
+		return
+	}
+	reason = truncateSkipReason(reason)
+	now := models.Now()
+	event.EventStatus = models.EventStatusSkipped
+	event.SkipReason = &reason
+	event.ProcessedAt = &now
+	if err := s.storage.CreateEvent(ctx, event); err != nil {
+		log.Warnf("Failed to record skipped event: %v", err)
+	}
+}
+
 // handleEvents 处理事件列表请求
 func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
@@ -161,130 +420,311 @@ func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
 // handleGetEvents 处理获取事件列表
 func (s *Server) handleGetEvents(w http.ResponseWriter, r *http.Request) {
 	// 获取查询参数
-	eventType := r.URL.Query().Get("event_type")
-	status := r.URL.Query().Get("status")
-	branch := r.URL.Query().Get("branch")
-	repository := r.URL.Query().Get("repository")
-
-	// 分页参数
-	pageStr := r.URL.Query().Get("page")
-	pageSizeStr := r.URL.Query().Get("page_size")
-
-	// 默认分页参数
-	page := 1
-	pageSize := 20
-	if pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
+	filter := storage.EventFilter{
+		EventType:  r.URL.Query().Get("event_type"),
+		Status:     r.URL.Query().Get("status"),
+		Branch:     r.URL.Query().Get("branch"),
+		Repository: r.URL.Query().Get("repository"),
+		Pusher:     r.URL.Query().Get("pusher"),
+		Author:     r.URL.Query().Get("author"),
+	}
+
+	// 时间范围参数（RFC3339），按 created_at 过滤
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		t, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			http.Error(w, "invalid from parameter, expected RFC3339", http.StatusBadRequest)
+			return
 		}
+		filter.From = t
 	}
-	if pageSizeStr != "" {
-		if ps, err := strconv.Atoi(pageSizeStr); err == nil && ps > 0 && ps <= 100 {
-			pageSize = ps
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		t, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			http.Error(w, "invalid to parameter, expected RFC3339", http.StatusBadRequest)
+			return
 		}
+		filter.To = t
 	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		http.Error(w, "from must not be after to", http.StatusBadRequest)
+		return
+	}
+
+	// 列表默认只返回检查项摘要，include_checks=true 时额外返回完整检查项；
+	// include=summary_only 显式要求只返回摘要，优先于 include_checks
+	includeChecks, _ := strconv.ParseBool(r.URL.Query().Get("include_checks"))
+	switch include := r.URL.Query().Get("include"); include {
+	case "":
+	case "summary_only":
+		includeChecks = false
+	default:
+		http.Error(w, fmt.Sprintf("invalid include parameter %q, expected summary_only", include), http.StatusBadRequest)
+		return
+	}
+
+	loc, err := parseTimezone(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, pageSize := parsePagination(r)
 
 	// 如果没有过滤条件，使用数据库分页查询（性能优化）
-	if eventType == "" && status == "" && branch == "" && repository == "" {
+	if filter.IsEmpty() {
 		offset := (page - 1) * pageSize
-		events, total, err := s.storage.ListEventsPaginated(offset, pageSize)
+		events, total, err := s.storage.ListEventsPaginated(r.Context(), offset, pageSize)
 		if err != nil {
 			http.Error(w, "failed to list events", http.StatusInternalServerError)
 			return
 		}
 
-		totalPages := (total + pageSize - 1) / pageSize
-		if totalPages == 0 {
-			totalPages = 1
+		if events == nil {
+			events = []*models.GitHubEvent{}
 		}
 
 		// 格式化响应
 		response := map[string]interface{}{
-			"success": true,
-			"data":    events,
-			"pagination": map[string]interface{}{
-				"page":        page,
-				"page_size":   pageSize,
-				"total":       total,
-				"total_pages": totalPages,
-			},
+			"success":    true,
+			"data":       eventListView(events, includeChecks, s.outputPreviewBytes),
+			"pagination": buildPagination(page, pageSize, total),
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+		writeJSONInZone(w, response, loc)
 		return
 	}
 
-	// 如果有过滤条件，使用原有的内存过滤方式
-	events, err := s.storage.ListEvents()
+	// 有过滤条件时整体下推到存储层
+	filteredEvents, err := s.storage.ListEventsFiltered(r.Context(), filter)
 	if err != nil {
 		http.Error(w, "failed to list events", http.StatusInternalServerError)
 		return
 	}
 
-	// 过滤事件
-	filteredEvents := []*models.GitHubEvent{}
-	for _, event := range events {
-		// 按事件类型过滤
-		if eventType != "" && string(event.EventType) != eventType {
-			continue
-		}
-		// 按状态过滤
-		if status != "" && string(event.EventStatus) != status {
-			continue
-		}
-		// 按分支过滤
-		if branch != "" && event.Branch != branch {
-			continue
-		}
-		// 按仓库过滤
-		if repository != "" && event.Repository != repository {
-			continue
-		}
-		filteredEvents = append(filteredEvents, event)
-	}
-
-	// 计算分页信息
+	// 计算分页信息；超出范围的页码不再回退到最后一页，而是返回空数据并标记 page_out_of_range
 	totalEvents := len(filteredEvents)
-	totalPages := (totalEvents + pageSize - 1) / pageSize
-	if page > totalPages && totalPages > 0 {
-		page = totalPages
-	}
-
-	// 计算起止索引
 	start := (page - 1) * pageSize
 	end := start + pageSize
 	if end > totalEvents {
 		end = totalEvents
 	}
-	if start > totalEvents {
-		start = totalEvents
-	}
 
 	// 获取当前页数据
-	var pagedEvents []*models.GitHubEvent
+	pagedEvents := []*models.GitHubEvent{}
 	if start < totalEvents {
 		pagedEvents = filteredEvents[start:end]
 	}
 
 	// 格式化响应
 	response := map[string]interface{}{
-		"success":     true,
-		"data":        pagedEvents,
-		"pagination": map[string]interface{}{
-			"page":        page,
-			"page_size":   pageSize,
-			"total":       totalEvents,
-			"total_pages": totalPages,
-		},
+		"success":    true,
+		"data":       eventListView(pagedEvents, includeChecks, s.outputPreviewBytes),
+		"pagination": buildPagination(page, pageSize, totalEvents),
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeJSONInZone(w, response, loc)
+}
+
+// eventListItem 列表视图中的事件，附带按状态统计的检查摘要
+type eventListItem struct {
+	models.GitHubEvent
+	ChecksSummary map[string]int `json:"checks_summary"`
+}
+
+// eventListView 构造列表响应数据；每个事件都带检查摘要，includeChecks 为 false 时省略完整检查项
+func eventListView(events []*models.GitHubEvent, includeChecks bool, outputBytes int) []eventListItem {
+	items := make([]eventListItem, 0, len(events))
+	for _, event := range events {
+		summary := map[string]int{"total": len(event.QualityChecks)}
+		for _, check := range event.QualityChecks {
+			summary[string(check.CheckStatus)]++
+		}
+		item := eventListItem{GitHubEvent: *event, ChecksSummary: summary}
+		if includeChecks {
+			item.QualityChecks = previewChecks(event.QualityChecks, outputBytes)
+		} else {
+			item.QualityChecks = nil
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// previewChecks 返回 output 截断到 maxBytes 的检查副本，存储中的数据不会被修改
+func previewChecks(checks []models.PRQualityCheck, maxBytes int) []models.PRQualityCheck {
+	if checks == nil {
+		return nil
+	}
+	out := make([]models.PRQualityCheck, len(checks))
+	for i, check := range checks {
+		if check.Output != nil {
+			preview, truncated := models.PreviewCheckOutput(*check.Output, maxBytes)
+			check.Output = &preview
+			check.OutputTruncated = truncated
+		}
+		out[i] = check
+	}
+	return out
+}
+
+// 分页参数的默认值和上限
+const (
+	// defaultPageSize 未指定 page_size 时的每页条数
+	defaultPageSize = 20
+	// maxPageSize 允许的最大 page_size，超出时使用默认值
+	maxPageSize = 100
+)
+
+// parsePagination 解析 page 和 page_size 参数，缺失或非法时使用默认值
+func parsePagination(r *http.Request) (page, pageSize int) {
+	page, pageSize = 1, defaultPageSize
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 0 {
+		page = p
+	}
+	if ps, err := strconv.Atoi(r.URL.Query().Get("page_size")); err == nil && ps > 0 && ps <= maxPageSize {
+		pageSize = ps
+	}
+	return page, pageSize
+}
+
+// buildPagination 构造分页信息
+// matched_total 为过滤后命中的事件总数；page_out_of_range 表示请求的页码超出 total_pages，
+// 客户端据此区分“没有匹配数据”（matched_total 为 0）与“页码越界”。
+func buildPagination(page, pageSize, matchedTotal int) map[string]interface{} {
+	totalPages := (matchedTotal + pageSize - 1) / pageSize
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	return map[string]interface{}{
+		"page":              page,
+		"page_size":         pageSize,
+		"total":             matchedTotal,
+		"matched_total":     matchedTotal,
+		"total_pages":       totalPages,
+		"page_out_of_range": page > totalPages,
+	}
+}
+
+const (
+	// defaultIngestBatchSize 流式导入时每个事务写入的默认事件数
+	defaultIngestBatchSize = 100
+	// maxIngestBatchSize 流式导入允许的最大批次大小
+	maxIngestBatchSize = 1000
+)
+
+// ingestResult 流式导入中单行的处理结果
+type ingestResult struct {
+	Line    int    `json:"line"`
+	Status  string `json:"status"`
+	ID      int    `json:"id,omitempty"`
+	EventID string `json:"event_id,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// handleIngestEvents 处理 NDJSON 流式导入请求
+// POST /api/events/ingest?batch_size=N
+// 请求体每行一个简化格式的事件（需包含 event_type），按批次在事务中写入，
+// 并以 NDJSON 逐行返回处理结果，最后一行为汇总信息，内存占用与批次大小相关而与请求体大小无关。
+func (s *Server) handleIngestEvents(w http.ResponseWriter, r *http.Request) {
+	reqLog := logger.FromContext(r.Context())
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	batchSize := defaultIngestBatchSize
+	if bs := r.URL.Query().Get("batch_size"); bs != "" {
+		n, err := strconv.Atoi(bs)
+		if err != nil || n <= 0 || n > maxIngestBatchSize {
+			http.Error(w, fmt.Sprintf("invalid batch_size, expected 1-%d", maxIngestBatchSize), http.StatusBadRequest)
+			return
+		}
+		batchSize = n
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+
+	inserted, failed := 0, 0
+	var batch []*models.GitHubEvent
+	var batchLines []int
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		err := s.storage.CreateEvents(r.Context(), batch)
+		for i, event := range batch {
+			if err != nil {
+				failed++
+				enc.Encode(ingestResult{Line: batchLines[i], Status: "error", Error: err.Error()})
+				continue
+			}
+			inserted++
+			enc.Encode(ingestResult{Line: batchLines[i], Status: "ok", ID: event.ID, EventID: event.EventID})
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		batch = batch[:0]
+		batchLines = batchLines[:0]
+	}
+
+	dec := json.NewDecoder(r.Body)
+	line := 0
+	for {
+		var eventData map[string]interface{}
+		err := dec.Decode(&eventData)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line++
+		if err != nil {
+			// 解码器无法从语法错误中恢复，记录失败后停止读取
+			flush()
+			failed++
+			enc.Encode(ingestResult{Line: line, Status: "error", Error: fmt.Sprintf("invalid JSON: %v", err)})
+			break
+		}
+
+		eventType, _ := eventData["event_type"].(string)
+		if eventType != string(models.EventTypePush) && eventType != string(models.EventTypePullRequest) {
+			failed++
+			enc.Encode(ingestResult{Line: line, Status: "error", Error: "missing or unsupported event_type"})
+			continue
+		}
+		event, err := models.NewGitHubEvent(eventData, models.EventType(eventType))
+		if err != nil {
+			failed++
+			enc.Encode(ingestResult{Line: line, Status: "error", Error: err.Error()})
+			continue
+		}
+		event.QualityChecks = models.CreateChecksForRepository(event.EventID, event.Repository, event.ChangedFiles)
+
+		batch = append(batch, event)
+		batchLines = append(batchLines, line)
+		if len(batch) >= batchSize {
+			flush()
+		}
+	}
+	flush()
+
+	reqLog.WithFields(map[string]interface{}{
+		"inserted": inserted,
+		"failed":   failed,
+	}).Infof("NDJSON ingest finished")
+	enc.Encode(map[string]interface{}{
+		"summary":  true,
+		"inserted": inserted,
+		"failed":   failed,
+	})
 }
 
 // handleCustomTest 处理自定义测试请求
 func (s *Server) handleCustomTest(w http.ResponseWriter, r *http.Request) {
+	reqLog := logger.FromContext(r.Context())
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -313,6 +753,59 @@ func (s *Server) handleCustomTest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 校验必填的字符串字段，缺失或类型错误时返回 400 并指明字段
+	required, ok := customTestFields[eventTypeStr]
+	if !ok {
+		http.Error(w, "unsupported event type", http.StatusBadRequest)
+		return
+	}
+	fields, err := stringFields(request.Payload, required)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if eventTypeStr == "pull_request" {
+		if raw, present := request.Payload["pr_number"]; !present || raw == nil {
+			http.Error(w, "pr_number is required", http.StatusBadRequest)
+			return
+		} else if n, ok := models.IntValue(raw); !ok || n <= 0 {
+			http.Error(w, "pr_number must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+	changedFiles, ok := request.Payload["changed_files"].(string)
+	if _, present := request.Payload["changed_files"]; present && !ok {
+		http.Error(w, "changed_files must be a string", http.StatusBadRequest)
+		return
+	}
+
+	// 调用方提供 event_id 时按该 ID 幂等创建，重复提交直接返回已有事件
+	eventID, err := customTestEventID(request.Payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if eventID != "" {
+		existing, err := s.storage.GetEventByEventID(r.Context(), eventID)
+		if err == nil {
+			reqLog.Infof("Custom test event already exists: ID=%d, event_id=%s", existing.ID, existing.EventID)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success":    true,
+				"event_type": string(existing.EventType),
+				"event_id":   existing.EventID,
+				"existing":   true,
+				"data":       existing,
+				"message":    "事件已存在，未重复创建",
+			})
+			return
+		}
+		if !errors.Is(err, storage.ErrEventNotFound) {
+			writeStorageError(w, err, "failed to look up event")
+			return
+		}
+	}
+
 	// 构建GitHub Webhook格式的payload
 	webhookPayload := map[string]interface{}{}
 
@@ -320,54 +813,42 @@ func (s *Server) handleCustomTest(w http.ResponseWriter, r *http.Request) {
 	switch eventTypeStr {
 	case "push":
 		// 构建push事件格式
-		webhookPayload["ref"] = "refs/heads/" + request.Payload["branch"].(string)
+		webhookPayload["ref"] = "refs/heads/" + fields["branch"]
 		webhookPayload["repository"] = map[string]interface{}{
-			"full_name": request.Payload["repository"].(string),
+			"full_name": fields["repository"],
 		}
 		webhookPayload["pusher"] = map[string]interface{}{
-			"name": request.Payload["pusher"].(string),
+			"name": fields["pusher"],
 		}
-		webhookPayload["after"] = request.Payload["commit_sha"].(string)
+		webhookPayload["after"] = fields["commit_sha"]
 	case "pull_request":
 		// 构建PR事件格式
-		webhookPayload["action"] = request.Payload["pr_action"].(string)
+		webhookPayload["action"] = fields["pr_action"]
 		webhookPayload["number"] = toFloat64(request.Payload["pr_number"])
 		webhookPayload["pull_request"] = map[string]interface{}{
-			"title": request.Payload["pr_title"].(string),
+			"title": fields["pr_title"],
 			"user": map[string]interface{}{
-				"login": request.Payload["pr_author"].(string),
+				"login": fields["pr_author"],
 			},
 			"head": map[string]interface{}{
-				"ref": request.Payload["source_branch"].(string),
+				"ref": fields["source_branch"],
 			},
 			"base": map[string]interface{}{
-				"ref": request.Payload["target_branch"].(string),
+				"ref": fields["target_branch"],
 			},
 		}
 		webhookPayload["repository"] = map[string]interface{}{
-			"full_name": request.Payload["repository"].(string),
+			"full_name": fields["repository"],
 		}
-	default:
-		http.Error(w, "unsupported event type", http.StatusBadRequest)
-		return
 	}
 
-	// 事件过滤逻辑
-	shouldProcess := false
-
-	if eventTypeStr == "push" {
-		// Push事件过滤：只处理main分支
-		shouldProcess = models.ShouldProcessPushEvent(webhookPayload)
-	} else if eventTypeStr == "pull_request" {
-		// PR事件过滤：只处理非main分支合入main分支的事件
-		shouldProcess = models.ShouldProcessPREvent(webhookPayload)
-	}
-
-	if !shouldProcess {
+	// 事件过滤逻辑：push 只处理 main 分支，PR 只处理非 main 分支合入 main 的事件
+	if reason := eventSkipReason(eventTypeStr, webhookPayload); reason != "" {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"status":  "skipped",
 			"event":   eventTypeStr,
+			"reason":  reason,
 			"message": "事件被跳过（非main分支或不满足处理条件）",
 		})
 		return
@@ -376,43 +857,41 @@ func (s *Server) handleCustomTest(w http.ResponseWriter, r *http.Request) {
 	// 准备事件数据
 	eventData := map[string]interface{}{
 		"event_type": eventTypeStr,
-		"repository": request.Payload["repository"].(string),
+	}
+	for name, value := range fields {
+		eventData[name] = value
 	}
 
 	if eventTypeStr == "push" {
-		eventData["branch"] = request.Payload["branch"].(string)
-		eventData["commit_sha"] = request.Payload["commit_sha"].(string)
-		eventData["pusher"] = request.Payload["pusher"].(string)
-		eventData["changed_files"] = request.Payload["changed_files"].(string)
+		eventData["changed_files"] = changedFiles
 	} else if eventTypeStr == "pull_request" {
 		eventData["pr_number"] = toInt(request.Payload["pr_number"])
-		eventData["pr_action"] = request.Payload["pr_action"].(string)
-		eventData["pr_title"] = request.Payload["pr_title"].(string)
-		eventData["pr_author"] = request.Payload["pr_author"].(string)
-		eventData["source_branch"] = request.Payload["source_branch"].(string)
-		eventData["target_branch"] = request.Payload["target_branch"].(string)
 	}
 
 	// 创建GitHubEvent
 	eventType := models.EventType(eventTypeStr)
 	event, err := models.NewGitHubEvent(eventData, eventType)
 	if err != nil {
-		logger.Infof("ERROR: Error creating event: %v", err)
+		reqLog.Infof("ERROR: Error creating event: %v", err)
 		http.Error(w, "failed to create event: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if eventID != "" {
+		event.EventID = eventID
+	}
 
 	// 为事件创建质量检查项
-	event.QualityChecks = models.CreateChecksForEvent(event.EventID)
+	event.ChangedFiles = models.ChangedFiles(request.Payload)
+	event.QualityChecks = models.CreateChecksForRepository(event.EventID, event.Repository, event.ChangedFiles)
 
 	// 保存事件
-	if err := s.storage.CreateEvent(event); err != nil {
-		logger.Infof("ERROR: Failed to create event: %v", err)
-		http.Error(w, "failed to save event", http.StatusInternalServerError)
+	if err := s.storage.CreateEvent(r.Context(), event); err != nil {
+		reqLog.Infof("ERROR: Failed to create event: %v", err)
+		writeStorageError(w, err, "failed to save event")
 		return
 	}
 
-	logger.Infof("Custom test event created: ID=%d, event_id=%s", event.ID, event.EventID)
+	reqLog.Infof("Custom test event created: ID=%d, event_id=%s", event.ID, event.EventID)
 
 	// 返回成功响应
 	response := map[string]interface{}{
@@ -426,6 +905,49 @@ func (s *Server) handleCustomTest(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// customTestFields 各事件类型的自定义测试必须提供的字符串字段
+var customTestFields = map[string][]string{
+	"push":         {"repository", "branch", "commit_sha", "pusher"},
+	"pull_request": {"repository", "pr_action", "pr_title", "pr_author", "source_branch", "target_branch"},
+}
+
+// maxEventIDLength event_id 列的最大长度
+const maxEventIDLength = 36
+
+// customTestEventID 取出调用方提供的可选 event_id，未提供时返回空字符串
+func customTestEventID(payload map[string]interface{}) (string, error) {
+	raw, ok := payload["event_id"]
+	if !ok || raw == nil {
+		return "", nil
+	}
+	id, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("event_id must be a string")
+	}
+	id = strings.TrimSpace(id)
+	if id == "" || len(id) > maxEventIDLength {
+		return "", fmt.Errorf("event_id must be 1-%d characters", maxEventIDLength)
+	}
+	return id, nil
+}
+
+// stringFields 按顺序取出 payload 中的字符串字段，第一个缺失或类型不对的字段作为错误返回
+func stringFields(payload map[string]interface{}, names []string) (map[string]string, error) {
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		raw, ok := payload[name]
+		if !ok || raw == nil {
+			return nil, fmt.Errorf("%s is required", name)
+		}
+		v, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string", name)
+		}
+		values[name] = v
+	}
+	return values, nil
+}
+
 // toFloat64 安全地将 interface{} 转换为 float64
 func toFloat64(v interface{}) float64 {
 	switch val := v.(type) {
@@ -456,26 +978,10 @@ func toFloat64(v interface{}) float64 {
 
 // toInt 安全地将 interface{} 转换为 int
 func toInt(v interface{}) int {
-	switch val := v.(type) {
-	case int:
-		return val
-	case int64:
-		return int(val)
-	case float64:
-		return int(val)
-	case float32:
-		return int(val)
-	case string:
-		// 尝试解析字符串
-		var i int
-		if _, err := fmt.Sscanf(val, "%d", &i); err == nil {
-			return i
-		}
-		return 0
-	default:
-		return 0
-	}
+	i, _ := models.IntValue(v)
+	return i
 }
+
 // handleDynamicRoutes 处理动态路由
 func (s *Server) handleDynamicRoutes(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
@@ -507,101 +1013,448 @@ func (s *Server) handleDynamicRoutes(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// PUT /api/events/{id}/quality-checks/batch - 批量更新质量检查状态
-	if r.Method == http.MethodPut && len(path) > len("/api/events/") && path[len(path)-len("/quality-checks/batch"):] == "/quality-checks/batch" {
-		idStr := path[len("/api/events/") : len(path)-len("/quality-checks/batch")]
-		if id, err := strconv.Atoi(idStr); err == nil {
-			s.handleBatchUpdateQualityChecks(w, r, id)
-			return
-		}
+	// POST /api/events/{id}/rerun - 重新运行整条流水线
+	if r.Method == http.MethodPost && strings.HasPrefix(path, "/api/events/") && strings.HasSuffix(path, "/rerun") {
+		idStr := path[len("/api/events/") : len(path)-len("/rerun")]
+		if id, err := strconv.Atoi(idStr); err == nil {
+			s.handleRerunEvent(w, r, id)
+			return
+		}
+	}
+
+	// POST /api/events/{id}/replay - 用保存的 payload 重新走一遍 Webhook 处理
+	if r.Method == http.MethodPost && strings.HasPrefix(path, "/api/events/") && strings.HasSuffix(path, "/replay") {
+		idStr := path[len("/api/events/") : len(path)-len("/replay")]
+		if id, err := strconv.Atoi(idStr); err == nil {
+			s.handleReplayEvent(w, r, id)
+			return
+		}
+	}
+
+	// PUT /api/events/{id}/quality-checks/batch - 批量更新质量检查状态
+	if r.Method == http.MethodPut && len(path) > len("/api/events/") && path[len(path)-len("/quality-checks/batch"):] == "/quality-checks/batch" {
+		idStr := path[len("/api/events/") : len(path)-len("/quality-checks/batch")]
+		if id, err := strconv.Atoi(idStr); err == nil {
+			s.handleBatchUpdateQualityChecks(w, r, id)
+			return
+		}
+	}
+
+	// GET /api/events/{eventID}/quality-checks - 获取质量检查列表
+	if r.Method == http.MethodGet && len(path) > len("/api/events/") && path[len(path)-len("/quality-checks"):] == "/quality-checks" {
+		eventIDStr := path[len("/api/events/") : len(path)-len("/quality-checks")]
+		if eventIDStr != "" {
+			s.handleQualityChecks(w, r, eventIDStr)
+			return
+		}
+	}
+
+	// GET /api/quality-checks/{id}/output - 获取质量检查完整输出
+	if r.Method == http.MethodGet && strings.HasPrefix(path, "/api/quality-checks/") && strings.HasSuffix(path, "/output") {
+		idStr := path[len("/api/quality-checks/") : len(path)-len("/output")]
+		if id, err := strconv.Atoi(idStr); err == nil {
+			s.handleCheckOutput(w, r, id)
+			return
+		}
+	}
+
+	// POST /api/quality-checks/{id}/output/append - 追加质量检查输出
+	if r.Method == http.MethodPost && strings.HasPrefix(path, "/api/quality-checks/") && strings.HasSuffix(path, "/output/append") {
+		idStr := path[len("/api/quality-checks/") : len(path)-len("/output/append")]
+		if id, err := strconv.Atoi(idStr); err == nil {
+			s.handleAppendCheckOutput(w, r, id)
+			return
+		}
+	}
+
+	// PUT /api/quality-checks/{id} - 更新质量检查
+	if r.Method == http.MethodPut && len(path) > len("/api/quality-checks/") {
+		idStr := path[len("/api/quality-checks/"):]
+		if id, err := strconv.Atoi(idStr); err == nil {
+			s.handleQualityCheckUpdate(w, r, id)
+			return
+		}
+	}
+
+	http.NotFound(w, r)
+}
+
+// handleDeleteEvent 处理删除单个事件
+func (s *Server) handleDeleteEvent(w http.ResponseWriter, r *http.Request, id int) {
+	reqLog := logger.FromContext(r.Context())
+	if err := s.storage.DeleteEvent(r.Context(), id); err != nil {
+		writeStorageError(w, err, "failed to delete event")
+		reqLog.Infof("ERROR: Failed to delete event %d: %v", id, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "事件删除成功",
+		"id":      id,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// confirmDeleteAllHeader 清空所有事件时必须携带的确认请求头，值为 yes
+const confirmDeleteAllHeader = "X-Confirm-Delete-All"
+
+// handleDeleteAllEvents 处理删除所有事件，未携带确认请求头时返回 400
+func (s *Server) handleDeleteAllEvents(w http.ResponseWriter, r *http.Request) {
+	if !strings.EqualFold(strings.TrimSpace(r.Header.Get(confirmDeleteAllHeader)), "yes") {
+		http.Error(w, "deleting all events requires header "+confirmDeleteAllHeader+": yes", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.storage.DeleteAllEvents(r.Context()); err != nil {
+		http.Error(w, "failed to delete all events", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "数据库清空成功",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleEventDetail 处理事件详情请求
+func (s *Server) handleEventDetail(w http.ResponseWriter, r *http.Request, id int) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	loc, err := parseTimezone(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	event, err := s.storage.GetEvent(r.Context(), id)
+	if err != nil {
+		writeStorageError(w, err, "failed to get event")
+		return
+	}
+
+	view := *event
+	view.QualityChecks = previewChecks(event.QualityChecks, s.outputPreviewBytes)
+	writeJSONInZone(w, map[string]interface{}{
+		"success": true,
+		"data":    view,
+	}, loc)
+}
+
+// handleRerunEvent 处理重新运行事件流水线：检查全部重置为 pending，事件回到 pending 且 run_count 加 1
+// 返回重置后的事件
+func (s *Server) handleRerunEvent(w http.ResponseWriter, r *http.Request, id int) {
+	reqLog := logger.FromContext(r.Context())
+	loc, err := parseTimezone(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.storage.RerunEvent(r.Context(), id); err != nil {
+		writeStorageError(w, err, "failed to rerun event")
+		reqLog.Infof("ERROR: Failed to rerun event %d: %v", id, err)
+		return
+	}
+
+	event, err := s.storage.GetEvent(r.Context(), id)
+	if err != nil {
+		writeStorageError(w, err, "failed to get event")
+		return
+	}
+	reqLog.Infof("Event %d reset for rerun (run_count=%d)", id, event.RunCount)
+
+	writeJSONInZone(w, map[string]interface{}{
+		"success": true,
+		"data":    event,
+	}, loc)
+}
+
+// handleReplayEvent 解码事件保存的原始 payload，像实时 Webhook 一样先过滤再交给 push/PR 处理器，
+// 默认创建一个新事件；in_place=true 时按当前流水线为原事件重新生成检查并重置为 pending
+func (s *Server) handleReplayEvent(w http.ResponseWriter, r *http.Request, id int) {
+	reqLog := logger.FromContext(r.Context())
+	loc, err := parseTimezone(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	inPlace, _ := strconv.ParseBool(r.URL.Query().Get("in_place"))
+
+	event, err := s.storage.GetEvent(r.Context(), id)
+	if err != nil {
+		writeStorageError(w, err, "failed to get event")
+		return
+	}
+	if event.EventType != models.EventTypePush && event.EventType != models.EventTypePullRequest {
+		http.Error(w, fmt.Sprintf("cannot replay %s events", event.EventType), http.StatusBadRequest)
+		return
+	}
+
+	// 配置了键白名单时保存的只是裁剪后的 payload，重放会丢失字段，直接拒绝
+	if len(models.PayloadAllowList()) > 0 {
+		http.Error(w, "cannot replay: stored payloads are trimmed to the -payload-keys allow-list", http.StatusConflict)
+		return
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		http.Error(w, "stored payload is not a JSON object", http.StatusUnprocessableEntity)
+		return
+	}
+	// 脱敏替换过的值无法还原，重放结果可能与原始投递不同，在响应中提示
+	var warning string
+	if bytes.Contains(event.Payload, []byte(models.RedactedSecret)) {
+		warning = "stored payload contains redacted values; the replay uses them as stored"
+	}
+
+	eventType := string(event.EventType)
+	eventKey := models.NewEventKey(eventType, payload)
+	if reason := s.skipReason(eventType, eventKey, payload); reason != "" {
+		reqLog.Infof("Replay of event %d skipped: %s", id, reason)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":   true,
+			"status":    "skipped",
+			"event_key": eventKey,
+			"reason":    reason,
+		})
+		return
+	}
+
+	if inPlace {
+		replayed, err := models.NewGitHubEvent(payload, event.EventType)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid stored payload: %v", err), http.StatusUnprocessableEntity)
+			return
+		}
+		checks := models.CreateChecksForRepository(event.EventID, replayed.Repository, replayed.ChangedFiles)
+		if err := s.storage.ReplayEventInPlace(r.Context(), id, checks); err != nil {
+			writeStorageError(w, err, "failed to replay event")
+			reqLog.Infof("ERROR: Failed to replay event %d in place: %v", id, err)
+			return
+		}
+		updated, err := s.storage.GetEvent(r.Context(), id)
+		if err != nil {
+			writeStorageError(w, err, "failed to get event")
+			return
+		}
+		reqLog.Infof("Event %d replayed in place with %d checks", id, len(checks))
+		resp := map[string]interface{}{
+			"success":       true,
+			"status":        "replayed",
+			"replayed_from": event.EventID,
+			"data":          updated,
+		}
+		if warning != "" {
+			resp["warning"] = warning
+		}
+		writeJSONInZone(w, resp, loc)
+		return
+	}
+
+	var result map[string]interface{}
+	if event.EventType == models.EventTypePush {
+		result = s.pushHandler.Handle(r.Context(), payload)
+	} else {
+		result = s.prHandler.Handle(r.Context(), payload)
+	}
+	if result["status"] != "processed" {
+		reqLog.Infof("ERROR: Failed to replay event %d: %v", id, result["error"])
+		http.Error(w, fmt.Sprintf("failed to replay event: %v", result["error"]), http.StatusInternalServerError)
+		return
+	}
+
+	newEventID, _ := result["event_id"].(string)
+	replayed, err := s.storage.GetEventByEventID(r.Context(), newEventID)
+	if err != nil {
+		writeStorageError(w, err, "failed to get replayed event")
+		return
+	}
+	reqLog.Infof("Event %d replayed as event %d", id, replayed.ID)
+	resp := map[string]interface{}{
+		"success":       true,
+		"status":        "replayed",
+		"replayed_from": event.EventID,
+		"data":          replayed,
+	}
+	if warning != "" {
+		resp["warning"] = warning
+	}
+	writeJSONInZone(w, resp, loc)
+}
+
+// handleRepositories 处理仓库列表请求
+func (s *Server) handleRepositories(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
-	// GET /api/events/{eventID}/quality-checks - 获取质量检查列表
-	if r.Method == http.MethodGet && len(path) > len("/api/events/") && path[len(path)-len("/quality-checks"):] == "/quality-checks" {
-		eventIDStr := path[len("/api/events/") : len(path)-len("/quality-checks")]
-		if eventIDStr != "" {
-			s.handleQualityChecks(w, r, eventIDStr)
-			return
-		}
+	loc, err := parseTimezone(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// PUT /api/quality-checks/{id} - 更新质量检查
-	if r.Method == http.MethodPut && len(path) > len("/api/quality-checks/") {
-		idStr := path[len("/api/quality-checks/"):]
-		if id, err := strconv.Atoi(idStr); err == nil {
-			s.handleQualityCheckUpdate(w, r, id)
-			return
-		}
+	repos, err := s.storage.ListRepositories(r.Context())
+	if err != nil {
+		writeStorageError(w, err, "failed to list repositories")
+		return
 	}
 
-	http.NotFound(w, r)
+	writeJSONInZone(w, map[string]interface{}{
+		"success": true,
+		"data":    repos,
+	}, loc)
 }
 
-// handleDeleteEvent 处理删除单个事件
-func (s *Server) handleDeleteEvent(w http.ResponseWriter, r *http.Request, id int) {
-	if err := s.storage.DeleteEvent(id); err != nil {
-		http.Error(w, "failed to delete event", http.StatusInternalServerError)
-		logger.Infof("ERROR: Failed to delete event %d: %v", id, err)
+// handleLatestEvents 返回每个仓库最新的一个事件，供概览页使用
+// 与事件列表一样默认只带检查摘要，include_checks=true 时返回完整检查项
+func (s *Server) handleLatestEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	response := map[string]interface{}{
-		"success": true,
-		"message": "事件删除成功",
-		"id":      id,
+	loc, err := parseTimezone(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	includeChecks, _ := strconv.ParseBool(r.URL.Query().Get("include_checks"))
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	events, err := s.storage.LatestEventPerRepository(r.Context())
+	if err != nil {
+		writeStorageError(w, err, "failed to list latest events")
+		return
+	}
+
+	writeJSONInZone(w, map[string]interface{}{
+		"success": true,
+		"data":    eventListView(events, includeChecks, s.outputPreviewBytes),
+	}, loc)
 }
 
-// handleDeleteAllEvents 处理删除所有事件
-func (s *Server) handleDeleteAllEvents(w http.ResponseWriter, r *http.Request) {
-	if err := s.storage.DeleteAllEvents(); err != nil {
-		http.Error(w, "failed to delete all events", http.StatusInternalServerError)
+// handlePipelinePreview 预览示例事件将创建的检查项，不写入存储
+// 请求体: {"repository", "branch", "changed_files"}，changed_files 可为数组或逗号分隔字符串
+func (s *Server) handlePipelinePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+	repository, _ := request["repository"].(string)
+	if strings.TrimSpace(repository) == "" {
+		http.Error(w, "missing repository", http.StatusBadRequest)
 		return
 	}
+	branch, _ := request["branch"].(string)
+	changedFiles := models.ChangedFiles(request)
+
+	pipeline := models.PipelineFor(repository)
+	checks := []map[string]interface{}{}
+	for _, check := range pipeline.CreateChecksForChanges("", changedFiles) {
+		checks = append(checks, map[string]interface{}{
+			"check_type":  check.CheckType,
+			"stage":       check.Stage,
+			"stage_order": check.StageOrder,
+			"check_order": check.CheckOrder,
+		})
+	}
+	skippedStages := []models.StageType{}
+	for _, stage := range pipeline.Stages {
+		if stage.SkippedFor(changedFiles) {
+			skippedStages = append(skippedStages, stage.Stage)
+		}
+	}
+	if changedFiles == nil {
+		changedFiles = []string{}
+	}
 
 	response := map[string]interface{}{
 		"success": true,
-		"message": "数据库清空成功",
+		"data": map[string]interface{}{
+			"repository":     repository,
+			"branch":         branch,
+			"changed_files":  changedFiles,
+			"pipeline":       pipeline.Name,
+			"fingerprint":    models.PipelineFingerprint(),
+			"checks":         checks,
+			"skipped_stages": skippedStages,
+		},
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-// handleEventDetail 处理事件详情请求
-func (s *Server) handleEventDetail(w http.ResponseWriter, r *http.Request, id int) {
-	if r.Method != http.MethodGet {
+// handleValidateEvent 按 webhook 的提取规则校验 payload，不创建事件
+// POST /api/events/validate，事件类型取自 X-GitHub-Event 头、event_type 查询参数或简化格式中的 event_type 字段
+func (s *Server) handleValidateEvent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	event, err := s.storage.GetEvent(id)
-	if err != nil {
-		http.Error(w, "event not found", http.StatusNotFound)
+	var payload map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid JSON payload", http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"data":    event,
-	})
-}
-
-// handleRepositories 处理仓库列表请求
-func (s *Server) handleRepositories(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	eventType := r.Header.Get("X-GitHub-Event")
+	if eventType == "" {
+		eventType = r.URL.Query().Get("event_type")
+	}
+	if eventType == "" {
+		eventType, _ = payload["event_type"].(string)
+	}
+	if eventType == "" {
+		http.Error(w, "missing event type: set X-GitHub-Event or event_type", http.StatusBadRequest)
 		return
 	}
 
-	// 简化实现：返回空列表
+	fields, err := models.ValidateEventData(payload, models.EventType(eventType))
+	problems := fields.Problems
+	if problems == nil {
+		problems = []string{}
+	}
+	fields.Problems = nil
+
+	// 不支持的事件类型等问题不一定产生错误，任何 problem 都视为校验不通过
+	valid := err == nil && len(problems) == 0
+	eventKey := models.NewEventKey(eventType, payload)
+	skipReason := s.skipReason(eventType, eventKey, payload)
+	wouldProcess := valid && skipReason == ""
+
+	data := map[string]interface{}{
+		"valid":         valid,
+		"event_type":    eventType,
+		"event_key":     eventKey,
+		"would_process": wouldProcess,
+		"fields":        fields,
+		"problems":      problems,
+	}
+	if skipReason != "" {
+		data["skip_reason"] = skipReason
+	}
 	response := map[string]interface{}{
 		"success": true,
-		"data":    []interface{}{},
+		"data":    data,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -615,18 +1468,60 @@ func (s *Server) handleQualityChecks(w http.ResponseWriter, r *http.Request, eve
 		return
 	}
 
-	checks, err := s.storage.ListQualityChecksByEventID(eventID)
+	loc, err := parseTimezone(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// 未指定 page 或 page_size 时返回全部检查项，保持原有行为
+	query := r.URL.Query()
+	if !query.Has("page") && !query.Has("page_size") {
+		checks, err := s.storage.ListQualityChecksByEventID(r.Context(), eventID)
+		if err != nil {
+			checks = []models.PRQualityCheck{}
+		}
+
+		response := map[string]interface{}{
+			"success": true,
+			"data":    previewChecks(checks, s.outputPreviewBytes),
+		}
+
+		writeJSONInZone(w, response, loc)
+		return
+	}
+
+	page, pageSize := parsePagination(r)
+	checks, total, err := s.storage.ListQualityChecksByEventIDPaginated(r.Context(), eventID, (page-1)*pageSize, pageSize)
 	if err != nil {
+		http.Error(w, "failed to list quality checks", http.StatusInternalServerError)
+		return
+	}
+	if checks == nil {
 		checks = []models.PRQualityCheck{}
 	}
 
 	response := map[string]interface{}{
-		"success": true,
-		"data":    checks,
+		"success":    true,
+		"data":       previewChecks(checks, s.outputPreviewBytes),
+		"pagination": buildPagination(page, pageSize, total),
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeJSONInZone(w, response, loc)
+}
+
+// handleCheckOutput 以纯文本返回质量检查的完整输出，供前端展开检查时按需加载
+func (s *Server) handleCheckOutput(w http.ResponseWriter, r *http.Request, id int) {
+	check, err := s.storage.GetQualityCheck(r.Context(), id)
+	if err != nil {
+		writeStorageError(w, err, "failed to get quality check")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if check.Output != nil {
+		io.Copy(w, strings.NewReader(*check.Output))
+	}
 }
 
 // handleQualityCheckUpdate 处理质量检查更新请求
@@ -636,9 +1531,9 @@ func (s *Server) handleQualityCheckUpdate(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	check, err := s.storage.GetQualityCheck(id)
+	check, err := s.storage.GetQualityCheck(r.Context(), id)
 	if err != nil {
-		http.Error(w, "quality check not found", http.StatusNotFound)
+		writeStorageError(w, err, "failed to get quality check")
 		return
 	}
 
@@ -672,7 +1567,8 @@ func (s *Server) handleQualityCheckUpdate(w http.ResponseWriter, r *http.Request
 	}
 
 	if updateData.Output != nil {
-		check.Output = updateData.Output
+		output := models.TruncateCheckOutput(*updateData.Output)
+		check.Output = &output
 	}
 
 	if updateData.StartedAt != nil {
@@ -703,8 +1599,8 @@ func (s *Server) handleQualityCheckUpdate(w http.ResponseWriter, r *http.Request
 
 	check.UpdatedAt = now
 
-	if err := s.storage.UpdateQualityCheck(check); err != nil {
-		http.Error(w, "failed to update quality check", http.StatusInternalServerError)
+	if err := s.storage.UpdateQualityCheck(r.Context(), check); err != nil {
+		writeStorageError(w, err, "failed to update quality check")
 		return
 	}
 
@@ -715,8 +1611,56 @@ func (s *Server) handleQualityCheckUpdate(w http.ResponseWriter, r *http.Request
 	})
 }
 
+// maxOutputChunkBytes 单次追加输出请求体的上限
+const maxOutputChunkBytes = 1 << 20
+
+// handleAppendCheckOutput 处理追加质量检查输出请求
+// 请求体为纯文本（可使用 chunked 传输），追加到已有输出末尾，超过上限时保留末尾部分；
+// complete=true 表示输出流结束，未设置 completed_at 时记为当前时间
+func (s *Server) handleAppendCheckOutput(w http.ResponseWriter, r *http.Request, id int) {
+	complete := false
+	if v := r.URL.Query().Get("complete"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "invalid complete value", http.StatusBadRequest)
+			return
+		}
+		complete = b
+	}
+
+	chunk, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxOutputChunkBytes))
+	if err != nil {
+		http.Error(w, "output chunk too large or unreadable", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	check, err := s.storage.AppendQualityCheckOutput(r.Context(), id, string(chunk))
+	if err != nil {
+		writeStorageError(w, err, "failed to append quality check output")
+		return
+	}
+
+	if complete && check.CompletedAt == nil {
+		now := models.Now()
+		check.CompletedAt = &now
+		check.UpdatedAt = now
+		if err := s.storage.UpdateQualityCheck(r.Context(), check); err != nil {
+			writeStorageError(w, err, "failed to update quality check")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"complete": complete,
+		"data":     check,
+	})
+}
+
 // handleMockEvents 处理Mock事件列表请求
 func (s *Server) handleMockEvents(w http.ResponseWriter, r *http.Request) {
+	reqLog := logger.FromContext(r.Context())
 	if r.Method != http.MethodGet {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -726,7 +1670,7 @@ func (s *Server) handleMockEvents(w http.ResponseWriter, r *http.Request) {
 	mockDataPath := filepath.Join(s.qualityDir, "github_webhook_payload_mock.json")
 	mockData, err := os.ReadFile(mockDataPath)
 	if err != nil {
-		logger.Infof("DEBUG: Failed to read mock data file: %v", err)
+		reqLog.Infof("DEBUG: Failed to read mock data file: %v", err)
 		// 如果文件不存在，返回空数组
 		response := map[string]interface{}{
 			"success": true,
@@ -739,7 +1683,7 @@ func (s *Server) handleMockEvents(w http.ResponseWriter, r *http.Request) {
 
 	var mockEvents []map[string]interface{}
 	if err := json.Unmarshal(mockData, &mockEvents); err != nil {
-		logger.Infof("ERROR: Failed to parse mock data: %v", err)
+		reqLog.Infof("ERROR: Failed to parse mock data: %v", err)
 		http.Error(w, "failed to parse mock data", http.StatusInternalServerError)
 		return
 	}
@@ -755,6 +1699,7 @@ func (s *Server) handleMockEvents(w http.ResponseWriter, r *http.Request) {
 
 // handleMockSimulate 处理模拟事件请求
 func (s *Server) handleMockSimulate(w http.ResponseWriter, r *http.Request) {
+	reqLog := logger.FromContext(r.Context())
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -770,22 +1715,22 @@ func (s *Server) handleMockSimulate(w http.ResponseWriter, r *http.Request) {
 
 	// 从JSON文件读取mock数据
 	mockDataPath := filepath.Join(s.qualityDir, "github_webhook_payload_mock.json")
-	logger.Infof("DEBUG: Reading mock data from: %s", mockDataPath)
+	reqLog.Infof("DEBUG: Reading mock data from: %s", mockDataPath)
 	mockData, err := os.ReadFile(mockDataPath)
 	if err != nil {
-		logger.Infof("DEBUG: Failed to read mock data file: %v", err)
+		reqLog.Infof("DEBUG: Failed to read mock data file: %v", err)
 		http.Error(w, "failed to read mock data", http.StatusInternalServerError)
 		return
 	}
-	logger.Infof("DEBUG: Successfully read mock data file: %d bytes", len(mockData))
+	reqLog.Infof("DEBUG: Successfully read mock data file: %d bytes", len(mockData))
 
 	var mockEvents []map[string]interface{}
 	if err := json.Unmarshal(mockData, &mockEvents); err != nil {
-		logger.Infof("ERROR: Failed to parse mock data: %v", err)
+		reqLog.Infof("ERROR: Failed to parse mock data: %v", err)
 		http.Error(w, "failed to parse mock data", http.StatusInternalServerError)
 		return
 	}
-	logger.Infof("DEBUG: Successfully parsed mock data: %d events", len(mockEvents))
+	reqLog.Infof("DEBUG: Successfully parsed mock data: %d events", len(mockEvents))
 
 	// 查找匹配的mock数据
 	var selectedMockData map[string]interface{}
@@ -795,25 +1740,25 @@ func (s *Server) handleMockSimulate(w http.ResponseWriter, r *http.Request) {
 		simpleEventType = "pull_request"
 		action = strings.TrimPrefix(eventTypeStr, "pull_request.")
 	}
-	logger.Infof("DEBUG: Looking for event type: %s, simple type: %s, action: %s", eventTypeStr, simpleEventType, action)
+	reqLog.Infof("DEBUG: Looking for event type: %s, simple type: %s, action: %s", eventTypeStr, simpleEventType, action)
 
 	for i, mockEvent := range mockEvents {
 		if mockEventType, ok := mockEvent["event_type"].(string); ok {
-			logger.Infof("DEBUG: Checking event %d: type=%s", i, mockEventType)
+			reqLog.Infof("DEBUG: Checking event %d: type=%s", i, mockEventType)
 			if mockEventType == simpleEventType {
 				// 对于PR事件，检查action是否匹配
 				if simpleEventType == "pull_request" && action != "" {
 					if mockAction, ok := mockEvent["pr_action"].(string); ok {
-						logger.Infof("DEBUG: Checking PR action: %s vs %s", mockAction, action)
+						reqLog.Infof("DEBUG: Checking PR action: %s vs %s", mockAction, action)
 						if mockAction == action {
 							selectedMockData = mockEvent
-							logger.Infof("DEBUG: Found matching PR event with action: %s", action)
+							reqLog.Infof("DEBUG: Found matching PR event with action: %s", action)
 							break
 						}
 					}
 				} else {
 					selectedMockData = mockEvent
-					logger.Infof("DEBUG: Found matching event: %s", mockEventType)
+					reqLog.Infof("DEBUG: Found matching event: %s", mockEventType)
 					break
 				}
 			}
@@ -824,28 +1769,35 @@ func (s *Server) handleMockSimulate(w http.ResponseWriter, r *http.Request) {
 	if selectedMockData == nil {
 		selectedMockData = make(map[string]interface{})
 		selectedMockData["event_type"] = eventTypeStr
-		logger.Debugf("No matching mock data found, using empty data")
+		reqLog.Debugf("No matching mock data found, using empty data")
 	} else {
-		logger.Infof("DEBUG: Selected mock data: %+v", selectedMockData)
+		reqLog.Infof("DEBUG: Selected mock data: %+v", selectedMockData)
 	}
 
-	// 异步处理事件
-	go func() {
+	// 放入队列由 worker 池异步处理，队列已满时返回 503
+	// 处理发生在响应返回之后，因此使用不随请求取消的 context
+	ctx := context.WithoutCancel(r.Context())
+	queued := s.workers.submit(func() {
 		defer func() {
 			if r := recover(); r != nil {
-				logger.Infof("ERROR: Panic in mock event processing: %v", r)
+				reqLog.Infof("ERROR: Panic in mock event processing: %v", r)
 			}
 		}()
 
 		// 根据事件类型处理
 		if simpleEventType == "pull_request" {
-			s.prHandler.Handle(selectedMockData)
+			s.prHandler.Handle(ctx, selectedMockData)
 		} else if simpleEventType == "push" {
-			s.pushHandler.Handle(selectedMockData)
+			s.pushHandler.Handle(ctx, selectedMockData)
 		} else {
-			logger.Infof("WARN: Unknown mock event type: %s", eventTypeStr)
+			reqLog.Infof("WARN: Unknown mock event type: %s", eventTypeStr)
 		}
-	}()
+	})
+	if !queued {
+		reqLog.Warn("Event queue is full, rejecting mock event")
+		writeQueueFull(w)
+		return
+	}
 
 	// 返回响应
 	w.Header().Set("Content-Type", "application/json")
@@ -909,8 +1861,25 @@ func (s *Server) handleCheckLogin(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleVersion 返回当前运行的构建信息，无需登录
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"data":    version.Current(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // handleStatus 处理系统状态请求
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	reqLog := logger.FromContext(r.Context())
 	if r.Method != http.MethodGet {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -920,32 +1889,60 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 	uptime := time.Since(s.startTime)
 	uptimeStr := formatUptime(uptime)
 
+	// 检查数据库连接
+	statusCode := http.StatusOK
+	serviceStatus := "healthy"
+	databaseStatus := "connected"
+	pingErr := s.storage.Ping(r.Context())
+	if pingErr != nil {
+		statusCode = http.StatusServiceUnavailable
+		serviceStatus = "unhealthy"
+		databaseStatus = "disconnected"
+		reqLog.Errorf("Database health check failed: %v", pingErr)
+	}
+
 	// 获取事件统计（使用优化的统计查询）
-	totalEvents, pendingEvents, err := s.storage.GetEventStats()
+	totalEvents, pendingEvents, err := s.storage.GetEventStats(r.Context())
 	if err != nil {
 		totalEvents = 0
 		pendingEvents = 0
 	}
 
-	response := map[string]interface{}{
-		"success": true,
-		"data": map[string]interface{}{
-			"service_status":  "healthy",
-			"database_status": "connected",
-			"total_events":    totalEvents,
-			"pending_events":  pendingEvents,
-			"db_type":         "MySQL",
-			"db_host":         "quality-mysql",
-			"db_name":         "github_hub",
-			"version":         "1.0.0",
-			"uptime":          uptimeStr,
-		},
+	data := map[string]interface{}{
+		"service_status":  serviceStatus,
+		"database_status": databaseStatus,
+		"total_events":    totalEvents,
+		"pending_events":  pendingEvents,
+		"version":         version.String(),
+		"uptime":          uptimeStr,
+		"started_at":      s.startTime.In(models.TimeZone()).Format(time.RFC3339),
+		"uptime_seconds":  int64(uptime / time.Second),
+	}
+	// 状态接口不需要认证，驱动错误可能带有数据库地址和用户名，只返回固定文案，完整错误见日志
+	if pingErr != nil {
+		data["database_error"] = "database ping failed"
+	}
+	if s.dbType != "" {
+		data["db_type"] = s.dbType
+	}
+	if s.dbHost != "" {
+		data["db_host"] = s.dbHost
+	}
+	if s.dbName != "" {
+		data["db_name"] = s.dbName
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": pingErr == nil,
+		"data":    data,
+	})
 }
 
+// maxEventErrorMessageBytes 事件 error_message 的最大长度，与 MySQL TEXT 列的容量一致
+const maxEventErrorMessageBytes = 65535
+
 // handleUpdateEventStatus 处理更新事件状态请求
 func (s *Server) handleUpdateEventStatus(w http.ResponseWriter, r *http.Request, id int) {
 	if r.Method != http.MethodPut {
@@ -954,22 +1951,49 @@ func (s *Server) handleUpdateEventStatus(w http.ResponseWriter, r *http.Request,
 	}
 
 	// 检查事件是否存在
-	event, err := s.storage.GetEvent(id)
+	event, err := s.storage.GetEvent(r.Context(), id)
 	if err != nil {
-		http.Error(w, "event not found", http.StatusNotFound)
+		writeStorageError(w, err, "failed to get event")
 		return
 	}
 
 	// 解析请求体
 	var updateData struct {
-		EventStatus string `json:"event_status"`
-		ProcessedAt string `json:"processed_at"` // 可选，ISO 8601 格式
+		EventStatus  string  `json:"event_status"`
+		ProcessedAt  string  `json:"processed_at"`  // 可选，ISO 8601 格式
+		ErrorMessage *string `json:"error_message"` // 可选，空字符串表示清空
+		RetryCount   *int    `json:"retry_count"`   // 可选，不能为负数
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
 		http.Error(w, "invalid JSON payload", http.StatusBadRequest)
 		return
 	}
+	if updateData.RetryCount != nil && *updateData.RetryCount < 0 {
+		http.Error(w, "retry_count must not be negative", http.StatusBadRequest)
+		return
+	}
+	if updateData.ErrorMessage != nil && len(*updateData.ErrorMessage) > maxEventErrorMessageBytes {
+		http.Error(w, fmt.Sprintf("error_message exceeds %d bytes", maxEventErrorMessageBytes), http.StatusBadRequest)
+		return
+	}
+
+	// 错误信息和重试次数先于状态写入，事件完成通知中即可带上失败原因
+	if updateData.ErrorMessage != nil || updateData.RetryCount != nil {
+		if err := s.storage.UpdateEventError(r.Context(), id, updateData.ErrorMessage, updateData.RetryCount); err != nil {
+			writeStorageError(w, err, "failed to update event error")
+			return
+		}
+		if updateData.ErrorMessage != nil {
+			event.ErrorMessage = updateData.ErrorMessage
+			if *updateData.ErrorMessage == "" {
+				event.ErrorMessage = nil
+			}
+		}
+		if updateData.RetryCount != nil {
+			event.RetryCount = *updateData.RetryCount
+		}
+	}
 
 	// 如果提供了 event_status，则更新
 	if updateData.EventStatus != "" {
@@ -994,12 +2018,16 @@ func (s *Server) handleUpdateEventStatus(w http.ResponseWriter, r *http.Request,
 			processedAt = &now
 		}
 
-		if err := s.storage.UpdateEventStatus(id, newStatus, processedAt); err != nil {
-			http.Error(w, "failed to update event status", http.StatusInternalServerError)
+		if err := s.storage.UpdateEventStatus(r.Context(), id, newStatus, processedAt); err != nil {
+			writeStorageError(w, err, "failed to update event status")
 			return
 		}
 		event.EventStatus = newStatus
 		event.ProcessedAt = processedAt
+
+		if newStatus == models.EventStatusCompleted || newStatus == models.EventStatusFailed {
+			s.notifyCompletion(event)
+		}
 	}
 
 	// 返回更新后的事件
@@ -1019,9 +2047,9 @@ func (s *Server) handleBatchUpdateQualityChecks(w http.ResponseWriter, r *http.R
 	}
 
 	// 检查事件是否存在
-	event, err := s.storage.GetEvent(eventID)
+	event, err := s.storage.GetEvent(r.Context(), eventID)
 	if err != nil {
-		http.Error(w, "event not found", http.StatusNotFound)
+		writeStorageError(w, err, "failed to get event")
 		return
 	}
 
@@ -1083,7 +2111,8 @@ func (s *Server) handleBatchUpdateQualityChecks(w http.ResponseWriter, r *http.R
 		}
 
 		if update.Output != nil {
-			check.Output = update.Output
+			output := models.TruncateCheckOutput(*update.Output)
+			check.Output = &output
 		}
 
 		if update.StartedAt != nil {
@@ -1121,8 +2150,8 @@ func (s *Server) handleBatchUpdateQualityChecks(w http.ResponseWriter, r *http.R
 	}
 
 	// 批量更新
-	if err := s.storage.BatchUpdateQualityChecks(checksToUpdate); err != nil {
-		http.Error(w, "failed to update quality checks", http.StatusInternalServerError)
+	if err := s.storage.BatchUpdateQualityChecks(r.Context(), checksToUpdate); err != nil {
+		writeStorageError(w, err, "failed to update quality checks")
 		return
 	}
 
@@ -1135,6 +2164,65 @@ func (s *Server) handleBatchUpdateQualityChecks(w http.ResponseWriter, r *http.R
 	})
 }
 
+// notifyCompletion 将事件完成通知加入出站队列，投递在后台进行，不阻塞请求处理
+func (s *Server) notifyCompletion(event *models.GitHubEvent) {
+	if s.notifier == nil {
+		return
+	}
+	payload := map[string]interface{}{
+		"id":           event.ID,
+		"event_id":     event.EventID,
+		"event_type":   event.EventType,
+		"event_status": event.EventStatus,
+		"repository":   event.Repository,
+		"branch":       event.Branch,
+		"commit_sha":   event.CommitSHA,
+		"pr_number":    event.PRNumber,
+		"processed_at": event.ProcessedAt,
+	}
+	if err := s.notifier.Enqueue(payload); err != nil {
+		logger.Errorf("Failed to enqueue completion notification for event %d: %v", event.ID, err)
+	}
+}
+
+// handleDeadLetterNotifications 返回多次投递失败后进入死信列表的通知
+// GET /api/admin/notifications/dead-letter
+func (s *Server) handleDeadLetterNotifications(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	deadLetters := []notify.Notification{}
+	pending := 0
+	if s.notifier != nil {
+		deadLetters = s.notifier.DeadLetters()
+		pending = len(s.notifier.Pending())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    deadLetters,
+		"pending": pending,
+	})
+}
+
+// handleLatency 返回日志中间件统计的各路由延迟分位数
+// GET /api/admin/latency
+func (s *Server) handleLatency(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    logger.LatencySnapshot(),
+	})
+}
+
 // formatUptime 格式化运行时间
 func formatUptime(d time.Duration) string {
 	if d < time.Minute {
@@ -1153,3 +2241,63 @@ func formatUptime(d time.Duration) string {
 		return fmt.Sprintf("%d天%d小时", days, hours)
 	}
 }
+
+// parseTimezone 解析 tz 查询参数，未指定时返回 nil
+func parseTimezone(r *http.Request) (*time.Location, error) {
+	tz := r.URL.Query().Get("tz")
+	if tz == "" {
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tz parameter: %w", err)
+	}
+	return loc, nil
+}
+
+// writeJSONInZone 输出 JSON 响应，指定时区时将所有 *_at 时间字段转换到该时区
+func writeJSONInZone(w http.ResponseWriter, v interface{}, loc *time.Location) {
+	w.Header().Set("Content-Type", "application/json")
+	if loc == nil {
+		json.NewEncoder(w).Encode(v)
+		return
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	// LocalTime.MarshalJSON 输出服务端配置的时区（models.SetTimeZone），请求指定的时区在输出阶段重新渲染
+	decoder := json.NewDecoder(strings.NewReader(string(raw)))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(convertTimeFields(generic, loc))
+}
+
+// convertTimeFields 递归转换 JSON 中以 _at 结尾的时间字段
+func convertTimeFields(v interface{}, loc *time.Location) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, item := range val {
+			if str, ok := item.(string); ok && strings.HasSuffix(key, "_at") {
+				if t, err := time.Parse(time.RFC3339, str); err == nil {
+					val[key] = t.In(loc).Format("2006-01-02T15:04:05-07:00")
+				}
+				continue
+			}
+			val[key] = convertTimeFields(item, loc)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = convertTimeFields(item, loc)
+		}
+	}
+	return v
+}
@@ -2,14 +2,25 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github-hub/internal/quality/models"
+	"github-hub/internal/quality/notify"
 	"github-hub/internal/quality/storage"
+	"github-hub/internal/version"
 )
 
 func setupTestServer(t *testing.T) (*Server, storage.Storage) {
@@ -18,6 +29,7 @@ func setupTestServer(t *testing.T) (*Server, storage.Storage) {
 	if err != nil {
 		t.Fatalf("Failed to create server: %v", err)
 	}
+	t.Cleanup(server.Close)
 	return server, store
 }
 
@@ -34,7 +46,7 @@ func TestHandleQualityCheckUpdate(t *testing.T) {
 		CreatedAt:   models.Now(),
 		UpdatedAt:   models.Now(),
 	}
-	store.CreateEvent(event)
+	store.CreateEvent(context.Background(), event)
 
 	check := &models.PRQualityCheck{
 		GitHubEventID: event.EventID,
@@ -47,7 +59,7 @@ func TestHandleQualityCheckUpdate(t *testing.T) {
 		CreatedAt:     models.Now(),
 		UpdatedAt:     models.Now(),
 	}
-	store.CreateQualityCheck(check)
+	store.CreateQualityCheck(context.Background(), check)
 
 	tests := []struct {
 		name           string
@@ -167,7 +179,7 @@ func TestHandleQualityCheckUpdate_AllFields(t *testing.T) {
 		CreatedAt:   models.Now(),
 		UpdatedAt:   models.Now(),
 	}
-	store.CreateEvent(event)
+	store.CreateEvent(context.Background(), event)
 
 	check := &models.PRQualityCheck{
 		GitHubEventID: event.EventID,
@@ -180,7 +192,7 @@ func TestHandleQualityCheckUpdate_AllFields(t *testing.T) {
 		CreatedAt:     models.Now(),
 		UpdatedAt:     models.Now(),
 	}
-	store.CreateQualityCheck(check)
+	store.CreateQualityCheck(context.Background(), check)
 
 	payload := map[string]interface{}{
 		"check_status":     "passed",
@@ -234,7 +246,7 @@ func TestHandleBatchUpdateQualityChecks(t *testing.T) {
 		CreatedAt:     models.Now(),
 		UpdatedAt:     models.Now(),
 	}
-	store.CreateEvent(event)
+	store.CreateEvent(context.Background(), event)
 
 	checkIDs := make([]int, len(event.QualityChecks))
 	for i, qc := range event.QualityChecks {
@@ -284,7 +296,7 @@ func TestHandleBatchUpdateQualityChecks(t *testing.T) {
 		t.Error("expected success to be true")
 	}
 
-	check1, _ := store.GetQualityCheck(checkIDs[0])
+	check1, _ := store.GetQualityCheck(context.Background(), checkIDs[0])
 	if check1.CheckStatus != models.QualityCheckStatusPassed {
 		t.Errorf("expected check %d status 'passed', got '%s'", checkIDs[0], check1.CheckStatus)
 	}
@@ -292,12 +304,12 @@ func TestHandleBatchUpdateQualityChecks(t *testing.T) {
 		t.Errorf("expected check %d output 'Compilation successful', got %v", checkIDs[0], check1.Output)
 	}
 
-	check2, _ := store.GetQualityCheck(checkIDs[1])
+	check2, _ := store.GetQualityCheck(context.Background(), checkIDs[1])
 	if check2.CheckStatus != models.QualityCheckStatusPassed {
 		t.Errorf("expected check %d status 'passed', got '%s'", checkIDs[1], check2.CheckStatus)
 	}
 
-	check3, _ := store.GetQualityCheck(checkIDs[2])
+	check3, _ := store.GetQualityCheck(context.Background(), checkIDs[2])
 	if check3.CheckStatus != models.QualityCheckStatusFailed {
 		t.Errorf("expected check %d status 'failed', got '%s'", checkIDs[2], check3.CheckStatus)
 	}
@@ -319,7 +331,7 @@ func TestHandleEventStatusUpdate(t *testing.T) {
 		CreatedAt:   models.Now(),
 		UpdatedAt:   models.Now(),
 	}
-	store.CreateEvent(event)
+	store.CreateEvent(context.Background(), event)
 
 	payload := map[string]interface{}{
 		"event_status": "completed",
@@ -336,16 +348,1859 @@ func TestHandleEventStatusUpdate(t *testing.T) {
 		t.Fatalf("expected status %d, got %d. Body: %s", http.StatusOK, rec.Code, rec.Body.String())
 	}
 
-	updatedEvent, _ := store.GetEvent(event.ID)
+	updatedEvent, _ := store.GetEvent(context.Background(), event.ID)
 	if updatedEvent.EventStatus != models.EventStatusCompleted {
 		t.Errorf("expected event status 'completed', got '%s'", updatedEvent.EventStatus)
 	}
 }
 
+// TestHandleEventStatusUpdate_ErrorAndRetry 测试状态更新同时记录错误信息和重试次数
+func TestHandleEventStatusUpdate_ErrorAndRetry(t *testing.T) {
+	server, store := setupTestServer(t)
+
+	event := &models.GitHubEvent{
+		EventID:     "test-event-error",
+		EventType:   models.EventTypePush,
+		EventStatus: models.EventStatusPending,
+		Repository:  "test/repo",
+		Branch:      "main",
+		Payload:     []byte(`{}`),
+		CreatedAt:   models.Now(),
+		UpdatedAt:   models.Now(),
+	}
+	store.CreateEvent(context.Background(), event)
+
+	tests := []struct {
+		name         string
+		body         string
+		wantCode     int
+		wantStatus   models.EventStatus
+		wantError    string // 空表示没有错误信息
+		wantRetryCnt int
+	}{
+		{
+			name:         "mark failed with context",
+			body:         `{"event_status":"failed","error_message":"runner lost connection","retry_count":2}`,
+			wantCode:     http.StatusOK,
+			wantStatus:   models.EventStatusFailed,
+			wantError:    "runner lost connection",
+			wantRetryCnt: 2,
+		},
+		{
+			name:         "retry count only keeps the message",
+			body:         `{"retry_count":3}`,
+			wantCode:     http.StatusOK,
+			wantStatus:   models.EventStatusFailed,
+			wantError:    "runner lost connection",
+			wantRetryCnt: 3,
+		},
+		{
+			name:         "empty message clears it",
+			body:         `{"event_status":"completed","error_message":""}`,
+			wantCode:     http.StatusOK,
+			wantStatus:   models.EventStatusCompleted,
+			wantRetryCnt: 3,
+		},
+		{
+			name:         "negative retry count",
+			body:         `{"retry_count":-1}`,
+			wantCode:     http.StatusBadRequest,
+			wantStatus:   models.EventStatusCompleted,
+			wantRetryCnt: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/api/events/"+strconv.Itoa(event.ID)+"/status", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			server.handleUpdateEventStatus(rec, req, event.ID)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d. Body: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+
+			stored, _ := store.GetEvent(context.Background(), event.ID)
+			gotError := ""
+			if stored.ErrorMessage != nil {
+				gotError = *stored.ErrorMessage
+			}
+			if stored.EventStatus != tt.wantStatus || gotError != tt.wantError || stored.RetryCount != tt.wantRetryCnt {
+				t.Errorf("stored status=%s error=%q retry_count=%d, want %s %q %d",
+					stored.EventStatus, gotError, stored.RetryCount, tt.wantStatus, tt.wantError, tt.wantRetryCnt)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Data map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+			if msg, _ := response.Data["error_message"].(string); msg != tt.wantError {
+				t.Errorf("response error_message=%q, want %q", msg, tt.wantError)
+			}
+			if n, _ := response.Data["retry_count"].(float64); int(n) != tt.wantRetryCnt {
+				t.Errorf("response retry_count=%v, want %d", response.Data["retry_count"], tt.wantRetryCnt)
+			}
+		})
+	}
+}
+
+func TestHandleEventStatusUpdate_NotifiesDeadLetter(t *testing.T) {
+	server, store := setupTestServer(t)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer receiver.Close()
+	dispatcher, err := notify.NewDispatcher(receiver.URL, "", receiver.Client())
+	if err != nil {
+		t.Fatalf("NewDispatcher: %v", err)
+	}
+	dispatcher.MaxAttempts = 1
+	dispatcher.Start()
+	defer dispatcher.Stop()
+	server.SetNotifier(dispatcher)
+
+	event := &models.GitHubEvent{
+		EventID:     "test-event-notify",
+		EventType:   models.EventTypePush,
+		EventStatus: models.EventStatusPending,
+		Repository:  "test/repo",
+		Branch:      "main",
+		Payload:     []byte(`{}`),
+		CreatedAt:   models.Now(),
+		UpdatedAt:   models.Now(),
+	}
+	store.CreateEvent(context.Background(), event)
+
+	body := []byte(`{"event_status":"completed"}`)
+	req := httptest.NewRequest(http.MethodPut, "/api/events/"+strconv.Itoa(event.ID)+"/status", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	server.handleUpdateEventStatus(rec, req, event.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d. Body: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(dispatcher.DeadLetters()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/admin/notifications/dead-letter", nil)
+	rec = httptest.NewRecorder()
+	server.handleDeadLetterNotifications(rec, req)
+
+	var response struct {
+		Data []notify.Notification `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Data) != 1 {
+		t.Fatalf("expected 1 dead-letter notification, got %d", len(response.Data))
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(response.Data[0].Payload, &payload); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if payload["event_id"] != "test-event-notify" || payload["event_status"] != "completed" {
+		t.Errorf("unexpected notification payload: %v", payload)
+	}
+}
+
+// TestHandleVersion 测试版本端点返回构建信息的各个字段
+func TestHandleVersion(t *testing.T) {
+	server, _ := setupTestServer(t)
+	origV, origC, origD := version.Version, version.Commit, version.BuildDate
+	defer func() { version.Version, version.Commit, version.BuildDate = origV, origC, origD }()
+	version.Version, version.Commit, version.BuildDate = "v1.2.3", "abc123", "2024-01-01T00:00:00Z"
+
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/version", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var response struct {
+		Success bool              `json:"success"`
+		Data    map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	want := map[string]string{
+		"version":    "v1.2.3",
+		"commit":     "abc123",
+		"build_date": "2024-01-01T00:00:00Z",
+		"string":     "v1.2.3 (commit=abc123, date=2024-01-01T00:00:00Z)",
+	}
+	if !response.Success {
+		t.Error("expected success")
+	}
+	for key, value := range want {
+		if response.Data[key] != value {
+			t.Errorf("%s=%q, want %q", key, response.Data[key], value)
+		}
+	}
+}
+
+func TestHandleStatus_DatabaseHealth(t *testing.T) {
+	store := storage.NewMockStorage()
+	server, err := NewServerWithStorage(store)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	server.SetDatabaseInfo("MySQL", "db.internal:3306", "github_hub")
+
+	tests := []struct {
+		name           string
+		pingErr        error
+		expectedStatus int
+		wantDBStatus   string
+	}{
+		{
+			name:           "database reachable",
+			expectedStatus: http.StatusOK,
+			wantDBStatus:   "connected",
+		},
+		{
+			name:           "database unreachable",
+			pingErr:        errors.New("dial tcp 10.0.0.5:3306 as quality_user: connection refused"),
+			expectedStatus: http.StatusServiceUnavailable,
+			wantDBStatus:   "disconnected",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store.SetPingError(tt.pingErr)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/status", nil)
+			rec := httptest.NewRecorder()
+			server.handleStatus(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d. Body: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+
+			var response map[string]interface{}
+			json.Unmarshal(rec.Body.Bytes(), &response)
+			data := response["data"].(map[string]interface{})
+
+			if data["database_status"] != tt.wantDBStatus {
+				t.Errorf("expected database_status '%s', got '%v'", tt.wantDBStatus, data["database_status"])
+			}
+			if data["db_host"] != "db.internal:3306" || data["db_name"] != "github_hub" {
+				t.Errorf("unexpected db info: host=%v name=%v", data["db_host"], data["db_name"])
+			}
+			if response["success"] != (tt.pingErr == nil) {
+				t.Errorf("expected success=%v, got %v", tt.pingErr == nil, response["success"])
+			}
+			if tt.pingErr != nil {
+				if data["database_error"] != "database ping failed" {
+					t.Errorf("expected a fixed database_error, got '%v'", data["database_error"])
+				}
+				if strings.Contains(rec.Body.String(), "10.0.0.5") || strings.Contains(rec.Body.String(), "quality_user") {
+					t.Errorf("driver error details leaked into the response: %s", rec.Body.String())
+				}
+			}
+			startedAt, err := time.Parse(time.RFC3339, fmt.Sprint(data["started_at"]))
+			if err != nil || !startedAt.Equal(server.startTime.Truncate(time.Second)) {
+				t.Errorf("expected started_at %s in RFC3339, got %v", server.startTime, data["started_at"])
+			}
+			if secs, ok := data["uptime_seconds"].(float64); !ok || secs < 0 {
+				t.Errorf("expected numeric uptime_seconds, got %v", data["uptime_seconds"])
+			}
+		})
+	}
+}
+
+func TestHandleGetEvents_DateRange(t *testing.T) {
+	server, store := setupTestServer(t)
+
+	day1 := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	day2 := time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)
+	for i, createdAt := range []time.Time{day1, day1.Add(2 * time.Hour), day2} {
+		branch := "main"
+		if i == 2 {
+			branch = "dev"
+		}
+		store.CreateEvent(context.Background(), &models.GitHubEvent{
+			EventID:     "range-event-" + strconv.Itoa(i),
+			EventType:   models.EventTypePush,
+			EventStatus: models.EventStatusPending,
+			Repository:  "test/repo",
+			Branch:      branch,
+			CreatedAt:   models.FromTime(createdAt),
+			UpdatedAt:   models.FromTime(createdAt),
+		})
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		wantCount      int
+	}{
+		{
+			name:           "first day only",
+			query:          "?from=2024-03-01T00:00:00Z&to=2024-03-01T23:59:59Z",
+			expectedStatus: http.StatusOK,
+			wantCount:      2,
+		},
+		{
+			name:           "from only",
+			query:          "?from=2024-03-02T00:00:00Z",
+			expectedStatus: http.StatusOK,
+			wantCount:      1,
+		},
+		{
+			name:           "to only with offset",
+			query:          "?to=2024-03-01T19:00:00%2B08:00",
+			expectedStatus: http.StatusOK,
+			wantCount:      1,
+		},
+		{
+			name:           "range combined with branch",
+			query:          "?from=2024-03-01T00:00:00Z&to=2024-03-02T23:59:59Z&branch=dev",
+			expectedStatus: http.StatusOK,
+			wantCount:      1,
+		},
+		{
+			name:           "invalid from",
+			query:          "?from=2024-03-01",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid to",
+			query:          "?to=yesterday",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "from after to",
+			query:          "?from=2024-03-02T00:00:00Z&to=2024-03-01T00:00:00Z",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/events"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			server.handleGetEvents(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d. Body: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Data []models.GitHubEvent `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(response.Data) != tt.wantCount {
+				t.Errorf("expected %d events, got %d", tt.wantCount, len(response.Data))
+			}
+		})
+	}
+}
+
+func TestHandleGetEvents_IncludeChecks(t *testing.T) {
+	server, store := setupTestServer(t)
+
+	checks := models.CreateChecksForEvent("test-event-summary")
+	checks[0].CheckStatus = models.QualityCheckStatusPassed
+	checks[1].CheckStatus = models.QualityCheckStatusFailed
+	store.CreateEvent(context.Background(), &models.GitHubEvent{
+		EventID:       "test-event-summary",
+		EventType:     models.EventTypePush,
+		EventStatus:   models.EventStatusProcessing,
+		Repository:    "test/repo",
+		Branch:        "main",
+		QualityChecks: checks,
+		Payload:       []byte(`{}`),
+		CreatedAt:     models.Now(),
+		UpdatedAt:     models.Now(),
+	})
+
+	tests := []struct {
+		name        string
+		query       string
+		wantChecks  bool
+		wantSummary bool
+	}{
+		{"summary by default", "", false, true},
+		{"summary when false", "?include_checks=false", false, true},
+		{"full checks when requested", "?include_checks=true", true, true},
+		{"filtered list honours the flag", "?repository=test/repo", false, true},
+		{"summary_only overrides include_checks", "?include_checks=true&include=summary_only", false, true},
+		{"summary_only on filtered list", "?repository=test/repo&include=summary_only", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/events"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			server.handleEvents(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", rec.Code)
+			}
+			var response struct {
+				Data []struct {
+					QualityChecks []models.PRQualityCheck `json:"quality_checks"`
+					ChecksSummary map[string]int          `json:"checks_summary"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if len(response.Data) != 1 {
+				t.Fatalf("expected 1 event, got %d", len(response.Data))
+			}
+			event := response.Data[0]
+			if gotChecks := len(event.QualityChecks) == len(checks); gotChecks != tt.wantChecks {
+				t.Errorf("quality_checks=%d, want full checks=%v", len(event.QualityChecks), tt.wantChecks)
+			}
+			if (event.ChecksSummary != nil) != tt.wantSummary {
+				t.Fatalf("checks_summary=%v, want present=%v", event.ChecksSummary, tt.wantSummary)
+			}
+			if tt.wantSummary {
+				want := map[string]int{"total": len(checks), "passed": 1, "failed": 1, "pending": len(checks) - 2}
+				for k, v := range want {
+					if event.ChecksSummary[k] != v {
+						t.Errorf("checks_summary[%s]=%d, want %d", k, event.ChecksSummary[k], v)
+					}
+				}
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/events?include=everything", nil)
+	rec := httptest.NewRecorder()
+	server.handleEvents(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for unknown include, got %d", rec.Code)
+	}
+}
+
+func TestHandleGetEvents_EmptyPagination(t *testing.T) {
+	server, store := setupTestServer(t)
+
+	for i := 0; i < 3; i++ {
+		store.CreateEvent(context.Background(), &models.GitHubEvent{
+			EventID:     "page-event-" + strconv.Itoa(i),
+			EventType:   models.EventTypePush,
+			EventStatus: models.EventStatusPending,
+			Repository:  "test/repo",
+			Branch:      "main",
+			CreatedAt:   models.Now(),
+			UpdatedAt:   models.Now(),
+		})
+	}
+
+	tests := []struct {
+		name             string
+		query            string
+		wantCount        int
+		wantMatchedTotal int
+		wantOutOfRange   bool
+	}{
+		{
+			name:             "filter matches nothing",
+			query:            "?repository=other/repo",
+			wantCount:        0,
+			wantMatchedTotal: 0,
+			wantOutOfRange:   false,
+		},
+		{
+			name:             "filtered page beyond range",
+			query:            "?repository=test/repo&page=5&page_size=2",
+			wantCount:        0,
+			wantMatchedTotal: 3,
+			wantOutOfRange:   true,
+		},
+		{
+			name:             "unfiltered page beyond range",
+			query:            "?page=3&page_size=2",
+			wantCount:        0,
+			wantMatchedTotal: 3,
+			wantOutOfRange:   true,
+		},
+		{
+			name:             "last page in range",
+			query:            "?repository=test/repo&page=2&page_size=2",
+			wantCount:        1,
+			wantMatchedTotal: 3,
+			wantOutOfRange:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/events"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			server.handleGetEvents(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d. Body: %s", rec.Code, rec.Body.String())
+			}
+
+			var response struct {
+				Data       []models.GitHubEvent `json:"data"`
+				Pagination struct {
+					MatchedTotal   int  `json:"matched_total"`
+					PageOutOfRange bool `json:"page_out_of_range"`
+				} `json:"pagination"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.Data == nil {
+				t.Errorf("expected data to be an empty array, got null")
+			}
+			if len(response.Data) != tt.wantCount {
+				t.Errorf("expected %d events, got %d", tt.wantCount, len(response.Data))
+			}
+			if response.Pagination.MatchedTotal != tt.wantMatchedTotal {
+				t.Errorf("expected matched_total %d, got %d", tt.wantMatchedTotal, response.Pagination.MatchedTotal)
+			}
+			if response.Pagination.PageOutOfRange != tt.wantOutOfRange {
+				t.Errorf("expected page_out_of_range %v, got %v", tt.wantOutOfRange, response.Pagination.PageOutOfRange)
+			}
+		})
+	}
+}
+
+func TestHandleIngestEvents(t *testing.T) {
+	server, store := setupTestServer(t)
+
+	var body bytes.Buffer
+	for i := 0; i < 5; i++ {
+		body.WriteString(`{"event_type":"push","repository":"test/repo","branch":"main","commit_sha":"sha` + strconv.Itoa(i) + `"}` + "\n")
+	}
+	body.WriteString(`{"event_type":"pull_request","repository":"test/repo","source_branch":"feature","target_branch":"main","pr_number":7}` + "\n")
+	body.WriteString(`{"event_type":"unknown","repository":"test/repo","branch":"main"}` + "\n")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/events/ingest?batch_size=2", &body)
+	rec := httptest.NewRecorder()
+	server.handleIngestEvents(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d. Body: %s", rec.Code, rec.Body.String())
+	}
+
+	dec := json.NewDecoder(rec.Body)
+	var okLines, errLines int
+	var summary map[string]interface{}
+	for dec.More() {
+		var line map[string]interface{}
+		if err := dec.Decode(&line); err != nil {
+			t.Fatalf("failed to decode result line: %v", err)
+		}
+		if line["summary"] == true {
+			summary = line
+			continue
+		}
+		switch line["status"] {
+		case "ok":
+			okLines++
+		case "error":
+			errLines++
+		}
+	}
+	if okLines != 6 || errLines != 1 {
+		t.Errorf("expected 6 ok and 1 error lines, got %d ok and %d error", okLines, errLines)
+	}
+	if summary == nil || summary["inserted"] != float64(6) || summary["failed"] != float64(1) {
+		t.Errorf("unexpected summary: %v", summary)
+	}
+
+	events, err := store.ListEvents(context.Background())
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if len(events) != 6 {
+		t.Errorf("expected 6 stored events, got %d", len(events))
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/events/ingest?batch_size=0", bytes.NewReader(nil))
+	rec = httptest.NewRecorder()
+	server.handleIngestEvents(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid batch_size, got %d", rec.Code)
+	}
+}
+
+func TestHandleEventDetail_Timezone(t *testing.T) {
+	server, store := setupTestServer(t)
+
+	createdAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	event := &models.GitHubEvent{
+		EventID:     "tz-event",
+		EventType:   models.EventTypePush,
+		EventStatus: models.EventStatusPending,
+		Repository:  "test/repo",
+		Branch:      "main",
+		CreatedAt:   models.FromTime(createdAt),
+		UpdatedAt:   models.FromTime(createdAt),
+	}
+	store.CreateEvent(context.Background(), event)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		wantCreatedAt  string
+	}{
+		{
+			name:           "default Shanghai",
+			expectedStatus: http.StatusOK,
+			wantCreatedAt:  "2024-03-01T18:00:00+08:00",
+		},
+		{
+			name:           "America/New_York",
+			query:          "?tz=America/New_York",
+			expectedStatus: http.StatusOK,
+			wantCreatedAt:  "2024-03-01T05:00:00-05:00",
+		},
+		{
+			name:           "UTC",
+			query:          "?tz=UTC",
+			expectedStatus: http.StatusOK,
+			wantCreatedAt:  "2024-03-01T10:00:00+00:00",
+		},
+		{
+			name:           "unknown zone",
+			query:          "?tz=Mars/Olympus",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/events/"+strconv.Itoa(event.ID)+tt.query, nil)
+			rec := httptest.NewRecorder()
+			server.handleEventDetail(rec, req, event.ID)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d. Body: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response map[string]interface{}
+			json.Unmarshal(rec.Body.Bytes(), &response)
+			data := response["data"].(map[string]interface{})
+			if data["created_at"] != tt.wantCreatedAt {
+				t.Errorf("expected created_at '%s', got '%v'", tt.wantCreatedAt, data["created_at"])
+			}
+		})
+	}
+}
+
+func TestHandleWebhook_AcceptedEvents(t *testing.T) {
+	tests := []struct {
+		name           string
+		accepted       []string
+		action         string
+		expectedStatus int
+		wantStatus     string
+	}{
+		{
+			name:           "opened is processed",
+			accepted:       []string{"pull_request.opened", "pull_request.synchronize", "pull_request.reopened"},
+			action:         "opened",
+			expectedStatus: http.StatusAccepted,
+			wantStatus:     "received",
+		},
+		{
+			name:           "closed is skipped",
+			accepted:       []string{"pull_request.opened", "pull_request.synchronize", "pull_request.reopened"},
+			action:         "closed",
+			expectedStatus: http.StatusOK,
+			wantStatus:     "skipped",
+		},
+		{
+			name:           "bare type accepts every action",
+			accepted:       []string{"pull_request"},
+			action:         "closed",
+			expectedStatus: http.StatusAccepted,
+			wantStatus:     "received",
+		},
+		{
+			name:           "empty set accepts everything",
+			action:         "closed",
+			expectedStatus: http.StatusAccepted,
+			wantStatus:     "received",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := setupTestServer(t)
+			server.SetAcceptedEvents(tt.accepted)
+
+			payload := map[string]interface{}{
+				"action": tt.action,
+				"repository": map[string]interface{}{
+					"full_name": "test/repo",
+				},
+				"pull_request": map[string]interface{}{
+					"number": 1,
+					"head":   map[string]interface{}{"ref": "feature", "sha": "abc123"},
+					"base":   map[string]interface{}{"ref": "main"},
+				},
+			}
+			body, _ := json.Marshal(payload)
+			req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+			req.Header.Set("X-GitHub-Event", "pull_request")
+			rec := httptest.NewRecorder()
+			server.handleWebhook(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d. Body: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+
+			var response map[string]interface{}
+			json.Unmarshal(rec.Body.Bytes(), &response)
+			if response["status"] != tt.wantStatus {
+				t.Errorf("expected status '%s', got '%v'", tt.wantStatus, response["status"])
+			}
+			if response["event_key"] != "pull_request."+tt.action {
+				t.Errorf("expected event_key 'pull_request.%s', got '%v'", tt.action, response["event_key"])
+			}
+		})
+	}
+}
+
+func TestHandleWebhook_Signature(t *testing.T) {
+	const secret = "s3cret"
+	body := []byte(`{"action":"opened","repository":{"full_name":"test/repo"},"pull_request":{"number":1,"head":{"ref":"feature","sha":"abc123"},"base":{"ref":"main"}}}`)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	valid := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name           string
+		signature      string
+		expectedStatus int
+	}{
+		{name: "valid signature", signature: valid, expectedStatus: http.StatusAccepted},
+		{name: "missing signature", expectedStatus: http.StatusUnauthorized},
+		{name: "wrong signature", signature: "sha256=" + strings.Repeat("0", 64), expectedStatus: http.StatusUnauthorized},
+		{name: "sha1 signature", signature: "sha1=" + strings.Repeat("0", 40), expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := setupTestServer(t)
+			server.SetWebhookSecret(secret)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+			req.Header.Set("X-GitHub-Event", "pull_request")
+			if tt.signature != "" {
+				req.Header.Set("X-Hub-Signature-256", tt.signature)
+			}
+			rec := httptest.NewRecorder()
+			server.handleWebhook(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d. Body: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlePipelinePreview(t *testing.T) {
+	set := &models.PipelineSet{
+		Pipelines: map[string]*models.PipelineConfig{
+			"web": {Stages: []models.PipelineStage{
+				{Stage: models.StageTypeBasicCI, Checks: []models.QualityCheckType{models.QualityCheckTypeCodeLint, models.QualityCheckTypeUnitTest}},
+				{Stage: models.StageTypeDeployment, Checks: []models.QualityCheckType{models.QualityCheckTypeDeployment}, IgnorePaths: []string{"docs/", "*.md"}},
+				{Stage: models.StageTypeSpecializedTests, Checks: []models.QualityCheckType{models.QualityCheckTypeApiTest}, IgnorePaths: []string{"docs/", "*.md"}},
+			}},
+		},
+		Repositories: map[string]string{"myorg/*": "web"},
+	}
+	if err := set.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	models.SetPipelineSet(set)
+	defer models.SetPipelineSet(nil)
+
+	tests := []struct {
+		name        string
+		body        string
+		wantChecks  int
+		wantSkipped int
+	}{
+		{"docs-only change", `{"repository":"myorg/site","branch":"main","changed_files":["docs/guide.md","README.md"]}`, 2, 2},
+		{"code change", `{"repository":"myorg/site","branch":"main","changed_files":"docs/guide.md, src/app.go"}`, 4, 0},
+		{"unknown files", `{"repository":"myorg/site","branch":"main"}`, 4, 0},
+		{"default pipeline", `{"repository":"other/repo","changed_files":["docs/guide.md"]}`, 9, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, store := setupTestServer(t)
+			req := httptest.NewRequest(http.MethodPost, "/api/pipeline/preview", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			server.handlePipelinePreview(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d. Body: %s", rec.Code, rec.Body.String())
+			}
+			var response struct {
+				Data struct {
+					Checks        []map[string]interface{} `json:"checks"`
+					SkippedStages []string                 `json:"skipped_stages"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if len(response.Data.Checks) != tt.wantChecks || len(response.Data.SkippedStages) != tt.wantSkipped {
+				t.Errorf("checks=%d skipped=%v, want %d/%d", len(response.Data.Checks), response.Data.SkippedStages, tt.wantChecks, tt.wantSkipped)
+			}
+			if events, _ := store.ListEvents(context.Background()); len(events) != 0 {
+				t.Errorf("preview persisted %d events", len(events))
+			}
+		})
+	}
+}
+
+// TestHandleValidateEvent 测试校验接口返回提取字段且不创建事件
+func TestHandleValidateEvent(t *testing.T) {
+	tests := []struct {
+		name         string
+		header       string
+		body         string
+		wantStatus   int
+		wantValid    bool
+		wantProcess  bool
+		wantProblems int
+	}{
+		{"webhook push", "push", `{"ref":"refs/heads/main","repository":{"full_name":"o/r"},"head_commit":{"id":"abc"}}`, http.StatusOK, true, true, 0},
+		{"push to feature branch", "push", `{"ref":"refs/heads/feature","repository":{"full_name":"o/r"}}`, http.StatusOK, true, false, 0},
+		{"simplified without header", "", `{"event_type":"push","repository":"o/r","branch":"main"}`, http.StatusOK, true, true, 0},
+		{"missing fields", "pull_request", `{"pull_request":{"number":1}}`, http.StatusOK, false, false, 2},
+		{"pull request missing number", "pull_request", `{"repository":{"full_name":"o/r"},"pull_request":{"head":{"ref":"feature"},"base":{"ref":"main"}}}`, http.StatusOK, false, false, 1},
+		{"unsupported event type", "issues", `{"event_type":"issues","repository":"o/r","branch":"main"}`, http.StatusOK, false, false, 2},
+		{"no event type", "", `{"ref":"refs/heads/main"}`, http.StatusBadRequest, false, false, 0},
+		{"invalid json", "push", `{`, http.StatusBadRequest, false, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, store := setupTestServer(t)
+			req := httptest.NewRequest(http.MethodPost, "/api/events/validate", strings.NewReader(tt.body))
+			if tt.header != "" {
+				req.Header.Set("X-GitHub-Event", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			server.handleValidateEvent(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d. Body: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var response struct {
+				Data struct {
+					Valid        bool                   `json:"valid"`
+					WouldProcess bool                   `json:"would_process"`
+					Fields       models.ExtractedFields `json:"fields"`
+					Problems     []string               `json:"problems"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+			if response.Data.Valid != tt.wantValid || response.Data.WouldProcess != tt.wantProcess {
+				t.Errorf("valid=%v would_process=%v, want %v/%v", response.Data.Valid, response.Data.WouldProcess, tt.wantValid, tt.wantProcess)
+			}
+			if len(response.Data.Problems) != tt.wantProblems {
+				t.Errorf("expected %d problems, got %v", tt.wantProblems, response.Data.Problems)
+			}
+			if tt.wantValid && response.Data.Fields.Repository != "o/r" {
+				t.Errorf("expected repository o/r, got %q", response.Data.Fields.Repository)
+			}
+			if events, _ := store.ListEvents(context.Background()); len(events) != 0 {
+				t.Errorf("expected no events to be created, got %d", len(events))
+			}
+		})
+	}
+}
+
 func strPtr(s string) *string {
 	return &s
 }
 
-func floatPtr(f float64) *float64 {
-	return &f
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestHTTPStatusFor(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, http.StatusOK},
+		{"event not found", storage.ErrEventNotFound, http.StatusNotFound},
+		{"check not found", storage.ErrCheckNotFound, http.StatusNotFound},
+		{"wrapped not found", fmt.Errorf("lookup: %w", storage.ErrEventNotFound), http.StatusNotFound},
+		{"conflict", fmt.Errorf("event x already exists: %w", storage.ErrConflict), http.StatusConflict},
+		{"generic", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := httpStatusFor(tt.err); got != tt.want {
+				t.Errorf("httpStatusFor(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleEventDetail_StorageErrors(t *testing.T) {
+	server, store := setupTestServer(t)
+	mock := store.(*storage.MockStorage)
+
+	tests := []struct {
+		name           string
+		getErr         error
+		expectedStatus int
+	}{
+		{"missing event", nil, http.StatusNotFound},
+		{"database failure", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.SetGetError(tt.getErr)
+			defer mock.SetGetError(nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/events/42", nil)
+			rec := httptest.NewRecorder()
+			server.handleEventDetail(rec, req, 42)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if strings.Contains(rec.Body.String(), "connection refused") {
+				t.Errorf("response leaks storage error: %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleDeleteEvent_NotFound(t *testing.T) {
+	server, _ := setupTestServer(t)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/events/42", nil)
+	rec := httptest.NewRecorder()
+	server.handleDeleteEvent(rec, req, 42)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+// TestHandleDeleteAllEvents_RequiresConfirmation 测试清空事件必须携带确认请求头
+func TestHandleDeleteAllEvents_RequiresConfirmation(t *testing.T) {
+	server, mockStorage := setupTestServer(t)
+	event := &models.GitHubEvent{EventID: "delete-all-1", EventType: models.EventTypePush, Repository: "test/repo"}
+	if err := mockStorage.CreateEvent(context.Background(), event); err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+		wantLeft   int
+	}{
+		{"missing header", "", http.StatusBadRequest, 1},
+		{"wrong value", "true", http.StatusBadRequest, 1},
+		{"confirmed", "yes", http.StatusOK, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/api/events", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Confirm-Delete-All", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			server.handleEvents(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			events, err := mockStorage.ListEvents(context.Background())
+			if err != nil {
+				t.Fatalf("ListEvents failed: %v", err)
+			}
+			if len(events) != tt.wantLeft {
+				t.Errorf("expected %d events left, got %d", tt.wantLeft, len(events))
+			}
+		})
+	}
+}
+
+// TestHandleWebhook_QueueFull 测试 worker 池忙且队列已满时 Webhook 返回 503，队列有空位后恢复 202
+func TestHandleWebhook_QueueFull(t *testing.T) {
+	server, store := setupTestServer(t)
+	server.SetWorkers(1, 1)
+
+	// 占住唯一的 worker，再填满容量为 1 的队列
+	started := make(chan struct{})
+	release := make(chan struct{})
+	if !server.workers.submit(func() { close(started); <-release }) {
+		t.Fatal("failed to submit blocking job")
+	}
+	<-started
+	if !server.workers.submit(func() {}) {
+		t.Fatal("failed to fill the queue")
+	}
+
+	send := func() *httptest.ResponseRecorder {
+		payload := map[string]interface{}{
+			"ref":        "refs/heads/main",
+			"after":      "abc123",
+			"repository": map[string]interface{}{"full_name": "test/repo"},
+			"pusher":     map[string]interface{}{"name": "alice"},
+		}
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+		req.Header.Set("X-GitHub-Event", "push")
+		rec := httptest.NewRecorder()
+		server.handleWebhook(rec, req)
+		return rec
+	}
+
+	rec := send()
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("expected a Retry-After header")
+	}
+
+	close(release)
+	server.SetWorkers(1, 1) // 等待旧池排空
+	if rec := send(); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202 after the queue drained, got %d: %s", rec.Code, rec.Body.String())
+	}
+	server.Close()
+	if total, _, _ := store.GetEventStats(context.Background()); total != 1 {
+		t.Errorf("expected 1 processed event, got %d", total)
+	}
+
+	// 关闭后提交不能向已关闭的队列发送，而是按队列已满处理
+	if rec := send(); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503 after Close, got %d: %s", rec.Code, rec.Body.String())
+	}
+	server.Close()
+}
+
+// TestHandleCustomTest_Validation 测试自定义测试对缺失或类型错误的字段返回 400 而不是 panic
+func TestHandleCustomTest_Validation(t *testing.T) {
+	server, _ := setupTestServer(t)
+
+	push := func(overrides map[string]interface{}) map[string]interface{} {
+		payload := map[string]interface{}{
+			"event_type": "push",
+			"repository": "test/repo",
+			"branch":     "main",
+			"commit_sha": "abc123",
+			"pusher":     "alice",
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(payload, k)
+				continue
+			}
+			payload[k] = v
+		}
+		return payload
+	}
+
+	tests := []struct {
+		name       string
+		payload    map[string]interface{}
+		wantStatus int
+		wantBody   string
+	}{
+		{"valid push", push(nil), http.StatusOK, "event_id"},
+		{"valid push with changed files", push(map[string]interface{}{"changed_files": "README.md"}), http.StatusOK, "event_id"},
+		{"missing branch", push(map[string]interface{}{"branch": nil}), http.StatusBadRequest, "branch is required"},
+		{"branch wrong type", push(map[string]interface{}{"branch": 42}), http.StatusBadRequest, "branch must be a string"},
+		{"pusher wrong type", push(map[string]interface{}{"pusher": []interface{}{"alice"}}), http.StatusBadRequest, "pusher must be a string"},
+		{"changed_files wrong type", push(map[string]interface{}{"changed_files": []interface{}{"a.go"}}), http.StatusBadRequest, "changed_files must be a string"},
+		{"pull request missing title", map[string]interface{}{
+			"event_type":    "pull_request",
+			"repository":    "test/repo",
+			"pr_action":     "opened",
+			"pr_number":     1,
+			"pr_author":     "bob",
+			"source_branch": "feature",
+			"target_branch": "main",
+		}, http.StatusBadRequest, "pr_title is required"},
+		{"pull request missing number", map[string]interface{}{
+			"event_type":    "pull_request",
+			"repository":    "test/repo",
+			"pr_action":     "opened",
+			"pr_title":      "Add feature",
+			"pr_author":     "bob",
+			"source_branch": "feature",
+			"target_branch": "main",
+		}, http.StatusBadRequest, "pr_number is required"},
+		{"pull request non-numeric number", map[string]interface{}{
+			"event_type":    "pull_request",
+			"repository":    "test/repo",
+			"pr_action":     "opened",
+			"pr_number":     "abc",
+			"pr_title":      "Add feature",
+			"pr_author":     "bob",
+			"source_branch": "feature",
+			"target_branch": "main",
+		}, http.StatusBadRequest, "pr_number must be a positive integer"},
+		{"unsupported event type", map[string]interface{}{"event_type": "release"}, http.StatusBadRequest, "unsupported event type"},
+		{"event_id wrong type", push(map[string]interface{}{"event_id": 7}), http.StatusBadRequest, "event_id must be a string"},
+		{"event_id too long", push(map[string]interface{}{"event_id": strings.Repeat("x", 37)}), http.StatusBadRequest, "event_id must be 1-36 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]interface{}{"payload": tt.payload})
+			req := httptest.NewRequest(http.MethodPost, "/api/custom-test", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+			server.handleCustomTest(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("expected body to contain %q, got %s", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+// TestHandleRerunEvent 测试重新运行事件流水线
+func TestHandleRerunEvent(t *testing.T) {
+	server, mockStorage := setupTestServer(t)
+
+	output := "old output"
+	processed := models.Now()
+	event := &models.GitHubEvent{
+		EventID:       "rerun-event",
+		EventType:     models.EventTypePush,
+		EventStatus:   models.EventStatusCompleted,
+		Repository:    "test/repo",
+		Branch:        "main",
+		ProcessedAt:   &processed,
+		QualityChecks: models.CreateChecksForEvent("rerun-event"),
+		Payload:       []byte(`{}`),
+	}
+	for i := range event.QualityChecks {
+		event.QualityChecks[i].CheckStatus = models.QualityCheckStatusPassed
+		event.QualityChecks[i].Output = &output
+	}
+	if err := mockStorage.CreateEvent(context.Background(), event); err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/events/"+strconv.Itoa(event.ID)+"/rerun", nil)
+	rec := httptest.NewRecorder()
+	server.handleDynamicRoutes(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var response struct {
+		Success bool               `json:"success"`
+		Data    models.GitHubEvent `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !response.Success || response.Data.RunCount != 1 || response.Data.EventStatus != models.EventStatusPending {
+		t.Fatalf("unexpected response: success=%v run_count=%d status=%s", response.Success, response.Data.RunCount, response.Data.EventStatus)
+	}
+	if response.Data.ProcessedAt != nil {
+		t.Errorf("expected processed_at to be cleared, got %v", response.Data.ProcessedAt)
+	}
+	for _, c := range response.Data.QualityChecks {
+		if c.CheckStatus != models.QualityCheckStatusPending || c.Output != nil {
+			t.Errorf("check %s not reset: status=%s output=%v", c.CheckType, c.CheckStatus, c.Output)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/events/9999/rerun", nil)
+	rec = httptest.NewRecorder()
+	server.handleDynamicRoutes(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for missing event, got %d", rec.Code)
+	}
+}
+
+func TestHandleAppendCheckOutput(t *testing.T) {
+	server, store := setupTestServer(t)
+	check := &models.PRQualityCheck{
+		GitHubEventID: "append-event",
+		CheckType:     models.QualityCheckTypeCompilation,
+		CheckStatus:   models.QualityCheckStatusRunning,
+	}
+	store.CreateQualityCheck(context.Background(), check)
+	path := "/api/quality-checks/" + strconv.Itoa(check.ID) + "/output/append"
+
+	for _, step := range []struct {
+		chunk string
+		query string
+	}{
+		{chunk: "building...\n"},
+		{chunk: "build ok\n", query: "?complete=true"},
+	} {
+		req := httptest.NewRequest(http.MethodPost, path+step.query, strings.NewReader(step.chunk))
+		rec := httptest.NewRecorder()
+		server.handleDynamicRoutes(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("append %q: expected status 200, got %d: %s", step.chunk, rec.Code, rec.Body.String())
+		}
+	}
+
+	got, err := store.GetQualityCheck(context.Background(), check.ID)
+	if err != nil {
+		t.Fatalf("GetQualityCheck failed: %v", err)
+	}
+	if got.Output == nil || *got.Output != "building...\nbuild ok\n" {
+		t.Errorf("expected concatenated output, got %v", got.Output)
+	}
+	if got.CompletedAt == nil {
+		t.Error("expected complete=true to set completed_at")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/quality-checks/9999/output/append", strings.NewReader("x"))
+	rec := httptest.NewRecorder()
+	server.handleDynamicRoutes(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown check, got %d", rec.Code)
+	}
+}
+
+// TestRecoverPendingEvents_AfterRestart 测试进程在 worker 处理前退出后，重启时重新提交已保存的 Webhook 事件，
+// 已有检查项和刚创建的 pending 事件不受影响
+func TestRecoverPendingEvents_AfterRestart(t *testing.T) {
+	store := storage.NewMockStorage()
+	first, err := NewServerWithStorage(store)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	first.SetWorkers(1, 1)
+
+	// 占住唯一的 worker，Webhook 事件只能停在队列里，模拟进程在处理前被杀掉
+	started := make(chan struct{})
+	release := make(chan struct{})
+	if !first.workers.submit(func() { close(started); <-release }) {
+		t.Fatal("failed to submit blocking job")
+	}
+	<-started
+	t.Cleanup(func() {
+		close(release)
+		first.Close()
+	})
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"ref":         "refs/heads/main",
+		"repository":  map[string]interface{}{"full_name": "test/repo"},
+		"head_commit": map[string]interface{}{"id": "abc123"},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+	req.Header.Set("X-GitHub-Event", "push")
+	rec := httptest.NewRecorder()
+	first.handleWebhook(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var accepted struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &accepted); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	stranded, err := store.GetEvent(context.Background(), accepted.ID)
+	if err != nil {
+		t.Fatalf("webhook event was not stored before queueing: %v", err)
+	}
+	if stranded.EventStatus != models.EventStatusPending || len(stranded.QualityChecks) != 0 {
+		t.Fatalf("expected a pending event without checks, got status=%s checks=%d", stranded.EventStatus, len(stranded.QualityChecks))
+	}
+	old := models.LocalTime{Time: time.Now().Add(-time.Hour)}
+	stranded.CreatedAt = old
+
+	// 已由 CI 推进的事件和刚保存的事件不应被重新提交
+	inProgress := &models.GitHubEvent{
+		EventID:       "in-progress",
+		EventType:     models.EventTypePush,
+		EventStatus:   models.EventStatusPending,
+		Repository:    "test/repo",
+		Payload:       []byte(`{}`),
+		QualityChecks: models.CreateChecksForEvent("in-progress"),
+		CreatedAt:     old,
+		UpdatedAt:     old,
+	}
+	inProgress.QualityChecks[0].CheckStatus = models.QualityCheckStatusPassed
+	fresh := &models.GitHubEvent{
+		EventID:     "fresh",
+		EventType:   models.EventTypePush,
+		EventStatus: models.EventStatusPending,
+		Repository:  "test/repo",
+		Payload:     []byte(`{}`),
+		CreatedAt:   models.Now(),
+		UpdatedAt:   models.Now(),
+	}
+	for _, event := range []*models.GitHubEvent{inProgress, fresh} {
+		if err := store.CreateEvent(context.Background(), event); err != nil {
+			t.Fatalf("CreateEvent failed: %v", err)
+		}
+	}
+	inProgressChecks := len(inProgress.QualityChecks)
+
+	// 重启：新服务在构造时恢复遗留事件，Close 等待恢复任务处理完毕
+	second, err := NewServerWithStorage(store)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	second.Close()
+
+	if checks, _ := store.ListQualityChecksByEventID(context.Background(), stranded.EventID); len(checks) == 0 {
+		t.Error("expected the stranded webhook event to be processed after restart")
+	}
+	if checks, _ := store.ListQualityChecksByEventID(context.Background(), "in-progress"); len(checks) != inProgressChecks {
+		t.Errorf("expected the in-progress event to keep %d checks, got %d", inProgressChecks, len(checks))
+	}
+	if checks, _ := store.ListQualityChecksByEventID(context.Background(), "fresh"); len(checks) != 0 {
+		t.Errorf("expected the fresh pending event to be left alone, got %d checks", len(checks))
+	}
+}
+
+// TestHandleCustomTest_IdempotentEventID 测试重复提交相同 event_id 返回已有事件
+func TestHandleCustomTest_IdempotentEventID(t *testing.T) {
+	server, store := setupTestServer(t)
+
+	payload := map[string]interface{}{
+		"event_type": "push",
+		"event_id":   "retry-safe-1",
+		"repository": "test/repo",
+		"branch":     "main",
+		"commit_sha": "abc123",
+		"pusher":     "alice",
+	}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		body, _ := json.Marshal(map[string]interface{}{"payload": payload})
+		req := httptest.NewRequest(http.MethodPost, "/api/custom-test", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		server.handleCustomTest(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("attempt %d: expected status 200, got %d: %s", attempt, rec.Code, rec.Body.String())
+		}
+		var resp map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if resp["event_id"] != "retry-safe-1" {
+			t.Errorf("attempt %d: expected event_id retry-safe-1, got %v", attempt, resp["event_id"])
+		}
+		if existing, _ := resp["existing"].(bool); existing != (attempt == 2) {
+			t.Errorf("attempt %d: unexpected existing=%v", attempt, resp["existing"])
+		}
+	}
+
+	events, _ := store.ListEvents(context.Background())
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event after retry, got %d", len(events))
+	}
+}
+
+// TestHandleReplayEvent 测试用保存的 push payload 重放事件，默认新建事件，in_place 时重置原事件
+func TestHandleReplayEvent(t *testing.T) {
+	server, store := setupTestServer(t)
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+
+	store.CreateEvent(context.Background(), &models.GitHubEvent{
+		EventID:     "stale-1",
+		EventType:   models.EventTypePush,
+		EventStatus: models.EventStatusFailed,
+		Repository:  "test/repo",
+		Branch:      "main",
+		Payload: []byte(`{"ref":"refs/heads/main","repository":{"full_name":"test/repo"},` +
+			`"head_commit":{"id":"abc123"},"pusher":{"name":"alice"},"commits":[{"added":["src/main.go"]}]}`),
+	})
+	store.CreateEvent(context.Background(), &models.GitHubEvent{
+		EventID:     "feature-1",
+		EventType:   models.EventTypePush,
+		EventStatus: models.EventStatusSkipped,
+		Repository:  "test/repo",
+		Branch:      "feature",
+		Payload:     []byte(`{"ref":"refs/heads/feature","repository":{"full_name":"test/repo"}}`),
+	})
+	original, _ := store.GetEventByEventID(context.Background(), "stale-1")
+	skipped, _ := store.GetEventByEventID(context.Background(), "feature-1")
+
+	replay := func(path string) (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		var body map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec.Code, body
+	}
+
+	code, body := replay(fmt.Sprintf("/api/events/%d/replay", original.ID))
+	if code != http.StatusOK || body["status"] != "replayed" || body["replayed_from"] != "stale-1" {
+		t.Fatalf("unexpected replay response %d: %v", code, body)
+	}
+	data := body["data"].(map[string]interface{})
+	newEventID, _ := data["event_id"].(string)
+	replayed, err := store.GetEventByEventID(context.Background(), newEventID)
+	if err != nil || newEventID == "stale-1" {
+		t.Fatalf("expected a new event, got %q: %v", newEventID, err)
+	}
+	if replayed.Repository != "test/repo" || replayed.CommitSHA == nil || *replayed.CommitSHA != "abc123" ||
+		replayed.EventStatus != models.EventStatusPending || len(replayed.QualityChecks) == 0 {
+		t.Errorf("replayed event not built from the stored payload: %+v", replayed)
+	}
+	if original.EventStatus != models.EventStatusFailed || original.RunCount != 0 {
+		t.Errorf("original event must be left alone, got status=%s run_count=%d", original.EventStatus, original.RunCount)
+	}
+
+	code, body = replay(fmt.Sprintf("/api/events/%d/replay?in_place=true", original.ID))
+	if code != http.StatusOK || body["status"] != "replayed" {
+		t.Fatalf("unexpected in-place response %d: %v", code, body)
+	}
+	if original.EventStatus != models.EventStatusPending || original.RunCount != 1 || len(original.QualityChecks) == 0 {
+		t.Errorf("in-place replay did not reset the event: status=%s run_count=%d checks=%d",
+			original.EventStatus, original.RunCount, len(original.QualityChecks))
+	}
+	checks, _ := store.ListQualityChecksByEventID(context.Background(), "stale-1")
+	if len(checks) != len(original.QualityChecks) {
+		t.Errorf("expected %d stored checks after in-place replay, got %d", len(original.QualityChecks), len(checks))
+	}
+
+	code, body = replay(fmt.Sprintf("/api/events/%d/replay", skipped.ID))
+	if reason, _ := body["reason"].(string); code != http.StatusOK || body["status"] != "skipped" || !strings.HasPrefix(reason, `push to branch "feature"`) {
+		t.Errorf("expected the filtered payload to be skipped, got %d: %v", code, body)
+	}
+
+	if code, _ := replay("/api/events/9999/replay"); code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing event, got %d", code)
+	}
+
+	// 保存时被脱敏的 payload 可以重放，但响应中会提示
+	store.CreateEvent(context.Background(), &models.GitHubEvent{
+		EventID:     "redacted-1",
+		EventType:   models.EventTypePush,
+		EventStatus: models.EventStatusFailed,
+		Repository:  "test/repo",
+		Branch:      "main",
+		Payload: []byte(`{"ref":"refs/heads/main","repository":{"full_name":"test/repo"},` +
+			`"head_commit":{"id":"abc123","message":"` + models.RedactedSecret + `"},"pusher":{"name":"alice"}}`),
+	})
+	redacted, _ := store.GetEventByEventID(context.Background(), "redacted-1")
+	code, body = replay(fmt.Sprintf("/api/events/%d/replay", redacted.ID))
+	if warning, _ := body["warning"].(string); code != http.StatusOK || body["status"] != "replayed" || warning == "" {
+		t.Errorf("expected a replay with a redaction warning, got %d: %v", code, body)
+	}
+
+	// 配置了 payload 白名单时保存的 payload 不完整，拒绝重放
+	models.SetPayloadAllowList([]string{"ref", "repository.full_name"})
+	t.Cleanup(func() { models.SetPayloadAllowList(nil) })
+	if code, _ := replay(fmt.Sprintf("/api/events/%d/replay", original.ID)); code != http.StatusConflict {
+		t.Errorf("expected 409 while a payload allow-list is configured, got %d", code)
+	}
+}
+
+// TestHandleWebhook_SkipReason 测试跳过的事件返回原因，开启记录后以 skipped 状态保存
+func TestHandleWebhook_SkipReason(t *testing.T) {
+	tests := []struct {
+		name       string
+		eventType  string
+		payload    map[string]interface{}
+		record     bool
+		wantReason string
+		wantStored bool
+	}{
+		{
+			name:       "push to feature branch",
+			eventType:  "push",
+			payload:    map[string]interface{}{"ref": "refs/heads/feature", "repository": map[string]interface{}{"full_name": "test/repo"}},
+			wantReason: `push to branch "feature"`,
+		},
+		{
+			name:      "pr not targeting main is recorded",
+			eventType: "pull_request",
+			payload: map[string]interface{}{
+				"action":     "opened",
+				"number":     7,
+				"repository": map[string]interface{}{"full_name": "test/repo"},
+				"pull_request": map[string]interface{}{
+					"head": map[string]interface{}{"ref": "feature"},
+					"base": map[string]interface{}{"ref": "develop"},
+				},
+			},
+			record:     true,
+			wantReason: `pull request targets "develop"`,
+			wantStored: true,
+		},
+		{
+			name:       "unsupported type is never recorded",
+			eventType:  "issues",
+			payload:    map[string]interface{}{"action": "opened"},
+			record:     true,
+			wantReason: "unsupported event type issues",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, store := setupTestServer(t)
+			server.SetRecordSkipped(tt.record)
+
+			body, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+			req.Header.Set("X-GitHub-Event", tt.eventType)
+			rec := httptest.NewRecorder()
+			server.handleWebhook(rec, req)
+
+			var resp map[string]interface{}
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			reason, _ := resp["reason"].(string)
+			if resp["status"] != "skipped" || !strings.Contains(reason, tt.wantReason) {
+				t.Fatalf("expected skipped with reason %q, got %s", tt.wantReason, rec.Body.String())
+			}
+
+			events, _ := store.ListEvents(context.Background())
+			if got := len(events) == 1; got != tt.wantStored {
+				t.Fatalf("expected stored=%v, got %d events", tt.wantStored, len(events))
+			}
+			if tt.wantStored {
+				event := events[0]
+				if event.EventStatus != models.EventStatusSkipped || event.SkipReason == nil || *event.SkipReason != reason {
+					t.Errorf("unexpected stored event: status=%s skip_reason=%v", event.EventStatus, event.SkipReason)
+				}
+				if len(event.QualityChecks) != 0 {
+					t.Errorf("expected no quality checks for a skipped event, got %d", len(event.QualityChecks))
+				}
+			}
+		})
+	}
+}
+
+// TestTruncateSkipReason 测试跳过原因按字符截断，不会切断多字节字符
+func TestTruncateSkipReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		reason string
+		want   string
+	}{
+		{"short", "push to branch", "push to branch"},
+		{"exact length", strings.Repeat("a", maxSkipReasonLength), strings.Repeat("a", maxSkipReasonLength)},
+		{"ascii overflow", strings.Repeat("a", maxSkipReasonLength+10), strings.Repeat("a", maxSkipReasonLength)},
+		{"multibyte overflow", strings.Repeat("分支", maxSkipReasonLength), strings.Repeat("分支", maxSkipReasonLength/2) + "分"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateSkipReason(tt.reason)
+			if got != tt.want {
+				t.Errorf("truncateSkipReason() = %q (%d runes), want %d runes", got, utf8.RuneCountInString(got), utf8.RuneCountInString(tt.want))
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("truncated reason is not valid UTF-8: %q", got)
+			}
+		})
+	}
+}
+
+// TestCheckOutputPreview 测试事件详情和检查列表截断 output，完整输出通过单独的接口获取
+func TestCheckOutputPreview(t *testing.T) {
+	server, store := setupTestServer(t)
+	server.SetOutputPreviewBytes(64)
+
+	event := &models.GitHubEvent{
+		EventID:       "output-preview",
+		EventType:     models.EventTypePush,
+		EventStatus:   models.EventStatusPending,
+		Payload:       []byte(`{}`),
+		QualityChecks: models.CreateChecksForEvent("output-preview"),
+	}
+	fullLog := strings.Repeat("line of build output\n", 20) + "FAILED: tests"
+	short := "ok"
+	event.QualityChecks[0].Output = &fullLog
+	event.QualityChecks[1].Output = &short
+	if err := store.CreateEvent(context.Background(), event); err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	checkPreview := func(name string, checks []models.PRQualityCheck) {
+		byID := map[int]models.PRQualityCheck{}
+		for _, c := range checks {
+			byID[c.ID] = c
+		}
+		long, ok := byID[event.QualityChecks[0].ID]
+		if !ok || !long.OutputTruncated || len(*long.Output) > 64 || !strings.HasSuffix(*long.Output, "FAILED: tests") {
+			t.Errorf("%s: expected a truncated tail of the long output, got %+v", name, long)
+		}
+		brief, ok := byID[event.QualityChecks[1].ID]
+		if !ok || brief.OutputTruncated || brief.Output == nil || *brief.Output != short {
+			t.Errorf("%s: expected the short output unchanged, got %+v", name, brief)
+		}
+	}
+
+	var detail struct {
+		Data models.GitHubEvent `json:"data"`
+	}
+	rec := get(fmt.Sprintf("/api/events/%d", event.ID))
+	if err := json.Unmarshal(rec.Body.Bytes(), &detail); err != nil {
+		t.Fatalf("invalid detail response: %v", err)
+	}
+	checkPreview("detail", detail.Data.QualityChecks)
+
+	var list struct {
+		Data []models.PRQualityCheck `json:"data"`
+	}
+	rec = get("/api/events/output-preview/quality-checks")
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("invalid checks response: %v", err)
+	}
+	checkPreview("checks", list.Data)
+
+	if stored, _ := store.GetQualityCheck(context.Background(), event.QualityChecks[0].ID); *stored.Output != fullLog {
+		t.Error("preview must not modify the stored output")
+	}
+
+	rec = get(fmt.Sprintf("/api/quality-checks/%d/output", event.QualityChecks[0].ID))
+	if rec.Code != http.StatusOK || rec.Body.String() != fullLog {
+		t.Errorf("expected the full output, got %d with %d bytes", rec.Code, rec.Body.Len())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("expected text/plain, got %s", ct)
+	}
+	if rec := get("/api/quality-checks/9999/output"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing check, got %d", rec.Code)
+	}
+}
+
+// TestHandleQualityChecks_Pagination 测试检查项列表在指定 page/page_size 时分页，未指定时返回全部
+func TestHandleQualityChecks_Pagination(t *testing.T) {
+	server, store := setupTestServer(t)
+	event := &models.GitHubEvent{
+		EventID:       "paged-checks",
+		EventType:     models.EventTypePush,
+		EventStatus:   models.EventStatusPending,
+		Payload:       []byte(`{}`),
+		QualityChecks: models.CreateChecksForEvent("paged-checks"),
+	}
+	if err := store.CreateEvent(context.Background(), event); err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+	total := len(event.QualityChecks)
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+
+	tests := []struct {
+		name           string
+		query          string
+		wantLen        int
+		wantPagination bool
+	}{
+		{name: "no paging params returns all", query: "", wantLen: total},
+		{name: "page size only", query: "?page_size=2", wantLen: 2, wantPagination: true},
+		{name: "second page", query: "?page=2&page_size=2", wantLen: 2, wantPagination: true},
+		{name: "out of range", query: "?page=100&page_size=2", wantLen: 0, wantPagination: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/events/paged-checks/quality-checks"+tt.query, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var resp struct {
+				Data       []models.PRQualityCheck `json:"data"`
+				Pagination map[string]interface{}  `json:"pagination"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+			if len(resp.Data) != tt.wantLen {
+				t.Errorf("got %d checks, want %d", len(resp.Data), tt.wantLen)
+			}
+			if (resp.Pagination != nil) != tt.wantPagination {
+				t.Fatalf("pagination present=%v, want %v", resp.Pagination != nil, tt.wantPagination)
+			}
+			if tt.wantPagination && int(resp.Pagination["total"].(float64)) != total {
+				t.Errorf("pagination total=%v, want %d", resp.Pagination["total"], total)
+			}
+		})
+	}
+}
+
+// TestHandleLatestEvents 测试仓库最新事件接口对每个仓库只返回一条
+func TestHandleLatestEvents(t *testing.T) {
+	server, store := setupTestServer(t)
+	for i, repo := range []string{"org/a", "org/b", "org/a"} {
+		event := &models.GitHubEvent{
+			EventID:       fmt.Sprintf("latest-%d", i),
+			EventType:     models.EventTypePush,
+			EventStatus:   models.EventStatusPending,
+			Repository:    repo,
+			Payload:       []byte(`{}`),
+			QualityChecks: models.CreateChecksForEvent(fmt.Sprintf("latest-%d", i)),
+		}
+		if err := store.CreateEvent(context.Background(), event); err != nil {
+			t.Fatalf("CreateEvent failed: %v", err)
+		}
+	}
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/repositories/latest", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var response struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	var got []string
+	for _, e := range response.Data {
+		got = append(got, fmt.Sprintf("%v@%v", e["event_id"], e["repository"]))
+		if _, ok := e["checks_summary"]; !ok {
+			t.Errorf("expected a check summary for %v", e["event_id"])
+		}
+	}
+	if strings.Join(got, ",") != "latest-2@org/a,latest-1@org/b" {
+		t.Errorf("latest events = %v", got)
+	}
+}
+
+// TestHandleRepositories 测试仓库列表返回存储中的仓库汇总
+func TestHandleRepositories(t *testing.T) {
+	server, store := setupTestServer(t)
+	for i, repo := range []string{"org/a", "org/b", "org/a"} {
+		event := &models.GitHubEvent{
+			EventID:     fmt.Sprintf("repos-%d", i),
+			EventType:   models.EventTypePush,
+			EventStatus: models.EventStatusPending,
+			Repository:  repo,
+			Payload:     []byte(`{}`),
+			CreatedAt:   models.Now(),
+		}
+		if err := store.CreateEvent(context.Background(), event); err != nil {
+			t.Fatalf("CreateEvent failed: %v", err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	server.handleRepositories(rec, httptest.NewRequest(http.MethodGet, "/api/repositories", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Data []storage.RepoSummary `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	counts := map[string]int{}
+	for _, repo := range resp.Data {
+		counts[repo.Repository] = repo.EventCount
+		if repo.LastSeenAt.IsZero() {
+			t.Errorf("expected last_seen_at for %s", repo.Repository)
+		}
+	}
+	if len(counts) != 2 || counts["org/a"] != 2 || counts["org/b"] != 1 {
+		t.Errorf("unexpected repositories: %+v", resp.Data)
+	}
+}
+
+// TestHandleGetEvents_PusherAuthorFilter 测试按 pusher/author 过滤事件列表
+func TestHandleGetEvents_PusherAuthorFilter(t *testing.T) {
+	server, store := setupTestServer(t)
+	alice, bob := "alice", "bob"
+	for i, e := range []struct {
+		eventType models.EventType
+		pusher    *string
+		author    *string
+	}{
+		{models.EventTypePush, &alice, nil},
+		{models.EventTypePush, &bob, nil},
+		{models.EventTypePush, nil, nil},
+		{models.EventTypePullRequest, nil, &alice},
+	} {
+		store.CreateEvent(context.Background(), &models.GitHubEvent{
+			EventID:     "who-" + strconv.Itoa(i),
+			EventType:   e.eventType,
+			EventStatus: models.EventStatusPending,
+			Repository:  "test/repo",
+			Pusher:      e.pusher,
+			Author:      e.author,
+			CreatedAt:   models.Now(),
+		})
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"pusher=alice", []string{"who-0"}},
+		{"author=alice", []string{"who-3"}},
+		{"pusher=carol", []string{}},
+		{"pusher=", []string{"who-3", "who-2", "who-1", "who-0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			server.handleGetEvents(rec, httptest.NewRequest(http.MethodGet, "/api/events?"+tt.query, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var resp struct {
+				Data []models.GitHubEvent `json:"data"`
+			}
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			got := []string{}
+			for _, e := range resp.Data {
+				got = append(got, e.EventID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
 }
@@ -0,0 +1,73 @@
+package api
+
+import "sync"
+
+const (
+	// DefaultWorkers 异步处理 Webhook 事件的默认 worker 数
+	DefaultWorkers = 16
+	// DefaultQueueSize 等待处理的事件队列默认容量，队列满时 Webhook 返回 503
+	DefaultQueueSize = 256
+)
+
+// workerPool 固定数量的 worker 从有界队列中取任务执行，避免突发请求时为每个事件启动 goroutine
+type workerPool struct {
+	jobs chan func()
+	wg   sync.WaitGroup
+
+	// mu 保护 closed，保证 stop 关闭队列后 submit 不会再向其发送
+	mu     sync.RWMutex
+	closed bool
+}
+
+// newWorkerPool 创建并启动 worker 池，workers 和 queueSize 非正数时使用默认值
+func newWorkerPool(workers, queueSize int) *workerPool {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	p := &workerPool{jobs: make(chan func(), queueSize)}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			for job := range p.jobs {
+				job()
+			}
+		}()
+	}
+	return p
+}
+
+// submit 把任务放入队列，队列已满或池已停止时立即返回 false
+func (p *workerPool) submit(job func()) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return false
+	}
+	select {
+	case p.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop 关闭队列并等待已入队的任务执行完毕，重复调用是安全的
+func (p *workerPool) stop() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
@@ -0,0 +1,294 @@
+// 质量引擎 gRPC 接口，与 REST API 共用同一个 Storage
+//
+// 修改后重新生成代码（在仓库根目录执行）：
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//     --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+//     internal/quality/grpcapi/qualitypb/quality.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: internal/quality/grpcapi/qualitypb/quality.proto
+
+package qualitypb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	QualityService_CreateEvent_FullMethodName       = "/quality.v1.QualityService/CreateEvent"
+	QualityService_GetEvent_FullMethodName          = "/quality.v1.QualityService/GetEvent"
+	QualityService_ListEvents_FullMethodName        = "/quality.v1.QualityService/ListEvents"
+	QualityService_UpdateCheck_FullMethodName       = "/quality.v1.QualityService/UpdateCheck"
+	QualityService_BatchUpdateChecks_FullMethodName = "/quality.v1.QualityService/BatchUpdateChecks"
+)
+
+// QualityServiceClient is the client API for QualityService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// QualityService 事件和质量检查的核心操作
+type QualityServiceClient interface {
+	// CreateEvent 按简化格式或 GitHub webhook 格式的 payload 创建事件及其检查项
+	CreateEvent(ctx context.Context, in *CreateEventRequest, opts ...grpc.CallOption) (*Event, error)
+	// GetEvent 按 id 获取事件（含检查项）
+	GetEvent(ctx context.Context, in *GetEventRequest, opts ...grpc.CallOption) (*Event, error)
+	// ListEvents 按 id 降序分页列出事件
+	ListEvents(ctx context.Context, in *ListEventsRequest, opts ...grpc.CallOption) (*ListEventsResponse, error)
+	// UpdateCheck 更新单个检查项，只修改设置了的字段
+	UpdateCheck(ctx context.Context, in *CheckUpdate, opts ...grpc.CallOption) (*QualityCheck, error)
+	// BatchUpdateChecks 在一个事务中更新同一事件的多个检查项
+	BatchUpdateChecks(ctx context.Context, in *BatchUpdateChecksRequest, opts ...grpc.CallOption) (*BatchUpdateChecksResponse, error)
+}
+
+type qualityServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewQualityServiceClient(cc grpc.ClientConnInterface) QualityServiceClient {
+	return &qualityServiceClient{cc}
+}
+
+func (c *qualityServiceClient) CreateEvent(ctx context.Context, in *CreateEventRequest, opts ...grpc.CallOption) (*Event, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Event)
+	err := c.cc.Invoke(ctx, QualityService_CreateEvent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *qualityServiceClient) GetEvent(ctx context.Context, in *GetEventRequest, opts ...grpc.CallOption) (*Event, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Event)
+	err := c.cc.Invoke(ctx, QualityService_GetEvent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *qualityServiceClient) ListEvents(ctx context.Context, in *ListEventsRequest, opts ...grpc.CallOption) (*ListEventsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListEventsResponse)
+	err := c.cc.Invoke(ctx, QualityService_ListEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *qualityServiceClient) UpdateCheck(ctx context.Context, in *CheckUpdate, opts ...grpc.CallOption) (*QualityCheck, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QualityCheck)
+	err := c.cc.Invoke(ctx, QualityService_UpdateCheck_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *qualityServiceClient) BatchUpdateChecks(ctx context.Context, in *BatchUpdateChecksRequest, opts ...grpc.CallOption) (*BatchUpdateChecksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchUpdateChecksResponse)
+	err := c.cc.Invoke(ctx, QualityService_BatchUpdateChecks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// QualityServiceServer is the server API for QualityService service.
+// All implementations must embed UnimplementedQualityServiceServer
+// for forward compatibility.
+//
+// QualityService 事件和质量检查的核心操作
+type QualityServiceServer interface {
+	// CreateEvent 按简化格式或 GitHub webhook 格式的 payload 创建事件及其检查项
+	CreateEvent(context.Context, *CreateEventRequest) (*Event, error)
+	// GetEvent 按 id 获取事件（含检查项）
+	GetEvent(context.Context, *GetEventRequest) (*Event, error)
+	// ListEvents 按 id 降序分页列出事件
+	ListEvents(context.Context, *ListEventsRequest) (*ListEventsResponse, error)
+	// UpdateCheck 更新单个检查项，只修改设置了的字段
+	UpdateCheck(context.Context, *CheckUpdate) (*QualityCheck, error)
+	// BatchUpdateChecks 在一个事务中更新同一事件的多个检查项
+	BatchUpdateChecks(context.Context, *BatchUpdateChecksRequest) (*BatchUpdateChecksResponse, error)
+	mustEmbedUnimplementedQualityServiceServer()
+}
+
+// UnimplementedQualityServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedQualityServiceServer struct{}
+
+func (UnimplementedQualityServiceServer) CreateEvent(context.Context, *CreateEventRequest) (*Event, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateEvent not implemented")
+}
+func (UnimplementedQualityServiceServer) GetEvent(context.Context, *GetEventRequest) (*Event, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEvent not implemented")
+}
+func (UnimplementedQualityServiceServer) ListEvents(context.Context, *ListEventsRequest) (*ListEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListEvents not implemented")
+}
+func (UnimplementedQualityServiceServer) UpdateCheck(context.Context, *CheckUpdate) (*QualityCheck, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateCheck not implemented")
+}
+func (UnimplementedQualityServiceServer) BatchUpdateChecks(context.Context, *BatchUpdateChecksRequest) (*BatchUpdateChecksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchUpdateChecks not implemented")
+}
+func (UnimplementedQualityServiceServer) mustEmbedUnimplementedQualityServiceServer() {}
+func (UnimplementedQualityServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeQualityServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to QualityServiceServer will
+// result in compilation errors.
+type UnsafeQualityServiceServer interface {
+	mustEmbedUnimplementedQualityServiceServer()
+}
+
+func RegisterQualityServiceServer(s grpc.ServiceRegistrar, srv QualityServiceServer) {
+	// If the following call pancis, it indicates UnimplementedQualityServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&QualityService_ServiceDesc, srv)
+}
+
+func _QualityService_CreateEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateEventRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QualityServiceServer).CreateEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: QualityService_CreateEvent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QualityServiceServer).CreateEvent(ctx, req.(*CreateEventRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QualityService_GetEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEventRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QualityServiceServer).GetEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: QualityService_GetEvent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QualityServiceServer).GetEvent(ctx, req.(*GetEventRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QualityService_ListEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QualityServiceServer).ListEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: QualityService_ListEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QualityServiceServer).ListEvents(ctx, req.(*ListEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QualityService_UpdateCheck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckUpdate)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QualityServiceServer).UpdateCheck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: QualityService_UpdateCheck_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QualityServiceServer).UpdateCheck(ctx, req.(*CheckUpdate))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QualityService_BatchUpdateChecks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchUpdateChecksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QualityServiceServer).BatchUpdateChecks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: QualityService_BatchUpdateChecks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QualityServiceServer).BatchUpdateChecks(ctx, req.(*BatchUpdateChecksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// QualityService_ServiceDesc is the grpc.ServiceDesc for QualityService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var QualityService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "quality.v1.QualityService",
+	HandlerType: (*QualityServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateEvent",
+			Handler:    _QualityService_CreateEvent_Handler,
+		},
+		{
+			MethodName: "GetEvent",
+			Handler:    _QualityService_GetEvent_Handler,
+		},
+		{
+			MethodName: "ListEvents",
+			Handler:    _QualityService_ListEvents_Handler,
+		},
+		{
+			MethodName: "UpdateCheck",
+			Handler:    _QualityService_UpdateCheck_Handler,
+		},
+		{
+			MethodName: "BatchUpdateChecks",
+			Handler:    _QualityService_BatchUpdateChecks_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "internal/quality/grpcapi/qualitypb/quality.proto",
+}
@@ -0,0 +1,264 @@
+// Package grpcapi 提供质量引擎的 gRPC 接口，与 REST API 共用同一个 Storage
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github-hub/internal/quality/grpcapi/qualitypb"
+	"github-hub/internal/quality/logger"
+	"github-hub/internal/quality/models"
+	"github-hub/internal/quality/storage"
+)
+
+const (
+	// defaultPageSize 未指定 page_size 时的每页条数，与 REST API 一致
+	defaultPageSize = 20
+	// maxPageSize 允许的最大 page_size，超出时使用默认值
+	maxPageSize = 100
+)
+
+// Server 实现 qualitypb.QualityServiceServer
+type Server struct {
+	qualitypb.UnimplementedQualityServiceServer
+
+	storage storage.Storage
+}
+
+// NewServer 使用提供的存储创建 gRPC 服务实现
+func NewServer(store storage.Storage) *Server {
+	return &Server{storage: store}
+}
+
+// Register 把服务注册到 grpc.Server
+func (s *Server) Register(gs *grpc.Server) {
+	qualitypb.RegisterQualityServiceServer(gs, s)
+}
+
+// CreateEvent 按与 NDJSON 导入相同的规则创建事件及其检查项
+func (s *Server) CreateEvent(ctx context.Context, req *qualitypb.CreateEventRequest) (*qualitypb.Event, error) {
+	eventType := models.EventType(req.GetEventType())
+	if eventType != models.EventTypePush && eventType != models.EventTypePullRequest {
+		return nil, status.Error(codes.InvalidArgument, "missing or unsupported event_type")
+	}
+	var eventData map[string]interface{}
+	if err := json.Unmarshal([]byte(req.GetPayloadJson()), &eventData); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid payload_json: %v", err)
+	}
+	event, err := models.NewGitHubEvent(eventData, eventType)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	event.QualityChecks = models.CreateChecksForRepository(event.EventID, event.Repository, event.ChangedFiles)
+	if err := s.storage.CreateEvent(ctx, event); err != nil {
+		return nil, storageError(err, "failed to create event")
+	}
+	logger.FromContext(ctx).Infof("gRPC created event %d (%s)", event.ID, event.EventID)
+	return eventToProto(event), nil
+}
+
+// GetEvent 按 id 获取事件（含检查项）
+func (s *Server) GetEvent(ctx context.Context, req *qualitypb.GetEventRequest) (*qualitypb.Event, error) {
+	event, err := s.storage.GetEvent(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, storageError(err, "failed to get event")
+	}
+	return eventToProto(event), nil
+}
+
+// ListEvents 分页列出事件，page 和 page_size 的默认值与 REST API 一致
+func (s *Server) ListEvents(ctx context.Context, req *qualitypb.ListEventsRequest) (*qualitypb.ListEventsResponse, error) {
+	page, pageSize := int(req.GetPage()), int(req.GetPageSize())
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > maxPageSize {
+		pageSize = defaultPageSize
+	}
+	events, total, err := s.storage.ListEventsPaginated(ctx, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, storageError(err, "failed to list events")
+	}
+	resp := &qualitypb.ListEventsResponse{Total: int32(total)}
+	for _, event := range events {
+		resp.Events = append(resp.Events, eventToProto(event))
+	}
+	return resp, nil
+}
+
+// UpdateCheck 更新单个检查项；设置了状态但没有完成时间时，完成时间记为当前时间
+func (s *Server) UpdateCheck(ctx context.Context, req *qualitypb.CheckUpdate) (*qualitypb.QualityCheck, error) {
+	check, err := s.storage.GetQualityCheck(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, storageError(err, "failed to get quality check")
+	}
+	now := models.Now()
+	if err := applyCheckUpdate(check, req); err != nil {
+		return nil, err
+	}
+	if req.CheckStatus != nil && req.CompletedAt == nil {
+		check.CompletedAt = &now
+	}
+	check.UpdatedAt = now
+	if err := s.storage.UpdateQualityCheck(ctx, check); err != nil {
+		return nil, storageError(err, "failed to update quality check")
+	}
+	return checkToProto(check), nil
+}
+
+// BatchUpdateChecks 更新同一事件的多个检查项；设置了完成时间但没有持续时间时按开始时间计算
+func (s *Server) BatchUpdateChecks(ctx context.Context, req *qualitypb.BatchUpdateChecksRequest) (*qualitypb.BatchUpdateChecksResponse, error) {
+	if len(req.GetChecks()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "checks is required")
+	}
+	event, err := s.storage.GetEvent(ctx, int(req.GetEventId()))
+	if err != nil {
+		return nil, storageError(err, "failed to get event")
+	}
+	existing := make(map[int]models.PRQualityCheck, len(event.QualityChecks))
+	for _, check := range event.QualityChecks {
+		existing[check.ID] = check
+	}
+
+	now := models.Now()
+	checks := make([]models.PRQualityCheck, 0, len(req.GetChecks()))
+	for _, update := range req.GetChecks() {
+		check, ok := existing[int(update.GetId())]
+		if !ok {
+			return nil, status.Errorf(codes.NotFound, "quality check with id %d not found", update.GetId())
+		}
+		if err := applyCheckUpdate(&check, update); err != nil {
+			return nil, err
+		}
+		if update.CompletedAt != nil && update.DurationSeconds == nil && check.StartedAt != nil {
+			duration := check.CompletedAt.ToTime().Sub(check.StartedAt.ToTime()).Seconds()
+			check.DurationSeconds = &duration
+		}
+		check.UpdatedAt = now
+		checks = append(checks, check)
+	}
+	if err := s.storage.BatchUpdateQualityChecks(ctx, checks); err != nil {
+		return nil, storageError(err, "failed to update quality checks")
+	}
+
+	resp := &qualitypb.BatchUpdateChecksResponse{}
+	for i := range checks {
+		resp.Checks = append(resp.Checks, checkToProto(&checks[i]))
+	}
+	return resp, nil
+}
+
+// applyCheckUpdate 把 update 中设置了的字段写入 check，与 REST 更新接口的字段规则一致
+func applyCheckUpdate(check *models.PRQualityCheck, update *qualitypb.CheckUpdate) error {
+	if update.CheckStatus != nil {
+		st, err := models.ParseQualityCheckStatus(update.GetCheckStatus())
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid check_status for check %d", update.GetId())
+		}
+		check.CheckStatus = st
+	}
+	if update.ErrorMessage != nil {
+		msg := update.GetErrorMessage()
+		check.ErrorMessage = &msg
+	}
+	if update.Output != nil {
+		output := models.TruncateCheckOutput(update.GetOutput())
+		check.Output = &output
+	}
+	if update.StartedAt != nil {
+		lt := models.FromTime(update.GetStartedAt().AsTime())
+		check.StartedAt = &lt
+	}
+	if update.CompletedAt != nil {
+		lt := models.FromTime(update.GetCompletedAt().AsTime())
+		check.CompletedAt = &lt
+	}
+	if update.DurationSeconds != nil {
+		duration := update.GetDurationSeconds()
+		check.DurationSeconds = &duration
+	}
+	return nil
+}
+
+// storageError 把存储层错误映射为 gRPC 状态码：不存在返回 NotFound，冲突返回 Aborted，
+// 其余返回 Internal 且只带 fallback，避免把数据库错误细节暴露给调用方
+func storageError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, storage.ErrEventNotFound), errors.Is(err, storage.ErrCheckNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, storage.ErrConflict):
+		return status.Error(codes.Aborted, err.Error())
+	default:
+		logger.Errorf("%s: %v", fallback, err)
+		return status.Error(codes.Internal, fallback)
+	}
+}
+
+// eventToProto 把事件模型转换为 protobuf 消息
+func eventToProto(event *models.GitHubEvent) *qualitypb.Event {
+	pb := &qualitypb.Event{
+		Id:           int64(event.ID),
+		EventId:      event.EventID,
+		EventType:    string(event.EventType),
+		EventStatus:  string(event.EventStatus),
+		Repository:   event.Repository,
+		Branch:       event.Branch,
+		TargetBranch: event.TargetBranch,
+		CommitSha:    event.CommitSHA,
+		Action:       event.Action,
+		Pusher:       event.Pusher,
+		Author:       event.Author,
+		PayloadJson:  string(event.Payload),
+		ChangedFiles: event.ChangedFiles,
+		CreatedAt:    timestamp(&event.CreatedAt),
+		UpdatedAt:    timestamp(&event.UpdatedAt),
+		ProcessedAt:  timestamp(event.ProcessedAt),
+		RunCount:     int32(event.RunCount),
+		SkipReason:   event.SkipReason,
+		ErrorMessage: event.ErrorMessage,
+		RetryCount:   int32(event.RetryCount),
+	}
+	if event.PRNumber != nil {
+		n := int64(*event.PRNumber)
+		pb.PrNumber = &n
+	}
+	for i := range event.QualityChecks {
+		pb.QualityChecks = append(pb.QualityChecks, checkToProto(&event.QualityChecks[i]))
+	}
+	return pb
+}
+
+// checkToProto 把检查项模型转换为 protobuf 消息
+func checkToProto(check *models.PRQualityCheck) *qualitypb.QualityCheck {
+	return &qualitypb.QualityCheck{
+		Id:              int64(check.ID),
+		GithubEventId:   check.GitHubEventID,
+		CheckType:       string(check.CheckType),
+		CheckStatus:     string(check.CheckStatus),
+		Stage:           string(check.Stage),
+		StageOrder:      int32(check.StageOrder),
+		CheckOrder:      int32(check.CheckOrder),
+		StartedAt:       timestamp(check.StartedAt),
+		CompletedAt:     timestamp(check.CompletedAt),
+		DurationSeconds: check.DurationSeconds,
+		ErrorMessage:    check.ErrorMessage,
+		Output:          check.Output,
+		RetryCount:      int32(check.RetryCount),
+		CreatedAt:       timestamp(&check.CreatedAt),
+		UpdatedAt:       timestamp(&check.UpdatedAt),
+	}
+}
+
+// timestamp 转换可选时间，nil 或零值返回 nil
+func timestamp(lt *models.LocalTime) *timestamppb.Timestamp {
+	if lt == nil || lt.IsZero() {
+		return nil
+	}
+	return timestamppb.New(lt.ToTime())
+}
@@ -0,0 +1,185 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github-hub/internal/quality/grpcapi/qualitypb"
+	"github-hub/internal/quality/storage"
+)
+
+// newTestClient 启动进程内 gRPC 服务并返回连接到它的客户端
+func newTestClient(t *testing.T) qualitypb.QualityServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	NewServer(storage.NewMockStorage()).Register(gs)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return qualitypb.NewQualityServiceClient(conn)
+}
+
+func pushPayload(t *testing.T, repo string) string {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{
+		"event_type": "push",
+		"repository": repo,
+		"branch":     "main",
+		"commit_sha": "abc123",
+		"pusher":     "alice",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// TestQualityService_CreateAndGetEvent 测试通过 gRPC 客户端创建事件后能取回事件及其检查项
+func TestQualityService_CreateAndGetEvent(t *testing.T) {
+	client := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	created, err := client.CreateEvent(ctx, &qualitypb.CreateEventRequest{EventType: "push", PayloadJson: pushPayload(t, "test/repo")})
+	if err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+	if created.GetId() == 0 || created.GetEventStatus() != "pending" || len(created.GetQualityChecks()) == 0 {
+		t.Fatalf("unexpected created event: %+v", created)
+	}
+
+	got, err := client.GetEvent(ctx, &qualitypb.GetEventRequest{Id: created.GetId()})
+	if err != nil {
+		t.Fatalf("GetEvent: %v", err)
+	}
+	if got.GetEventId() != created.GetEventId() || got.GetRepository() != "test/repo" || got.GetCommitSha() != "abc123" {
+		t.Errorf("GetEvent = %+v, want event %s", got, created.GetEventId())
+	}
+	if len(got.GetQualityChecks()) != len(created.GetQualityChecks()) {
+		t.Errorf("expected %d checks, got %d", len(created.GetQualityChecks()), len(got.GetQualityChecks()))
+	}
+
+	list, err := client.ListEvents(ctx, &qualitypb.ListEventsRequest{})
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if list.GetTotal() != 1 || len(list.GetEvents()) != 1 {
+		t.Errorf("ListEvents = %+v, want 1 event", list)
+	}
+}
+
+// TestQualityService_Errors 测试参数错误和不存在的资源映射为对应的 gRPC 状态码
+func TestQualityService_Errors(t *testing.T) {
+	client := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{name: "unsupported event type", call: func() error {
+			_, err := client.CreateEvent(ctx, &qualitypb.CreateEventRequest{EventType: "issue", PayloadJson: "{}"})
+			return err
+		}, want: codes.InvalidArgument},
+		{name: "invalid payload", call: func() error {
+			_, err := client.CreateEvent(ctx, &qualitypb.CreateEventRequest{EventType: "push", PayloadJson: "not json"})
+			return err
+		}, want: codes.InvalidArgument},
+		{name: "missing event", call: func() error {
+			_, err := client.GetEvent(ctx, &qualitypb.GetEventRequest{Id: 999})
+			return err
+		}, want: codes.NotFound},
+		{name: "missing check", call: func() error {
+			_, err := client.UpdateCheck(ctx, &qualitypb.CheckUpdate{Id: 999, CheckStatus: proto.String("passed")})
+			return err
+		}, want: codes.NotFound},
+		{name: "empty batch", call: func() error {
+			_, err := client.BatchUpdateChecks(ctx, &qualitypb.BatchUpdateChecksRequest{EventId: 1})
+			return err
+		}, want: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(tt.call()); got != tt.want {
+				t.Errorf("code = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestQualityService_UpdateChecks 测试单个和批量更新检查项
+func TestQualityService_UpdateChecks(t *testing.T) {
+	client := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	event, err := client.CreateEvent(ctx, &qualitypb.CreateEventRequest{EventType: "push", PayloadJson: pushPayload(t, "test/repo")})
+	if err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+	checks := event.GetQualityChecks()
+	if len(checks) < 2 {
+		t.Fatalf("expected at least 2 checks, got %d", len(checks))
+	}
+
+	updated, err := client.UpdateCheck(ctx, &qualitypb.CheckUpdate{Id: checks[0].GetId(), CheckStatus: proto.String("passed"), Output: proto.String("ok")})
+	if err != nil {
+		t.Fatalf("UpdateCheck: %v", err)
+	}
+	if updated.GetCheckStatus() != "passed" || updated.GetOutput() != "ok" || updated.GetCompletedAt() == nil {
+		t.Errorf("unexpected updated check: %+v", updated)
+	}
+	if _, err := client.UpdateCheck(ctx, &qualitypb.CheckUpdate{Id: checks[0].GetId(), CheckStatus: proto.String("bogus")}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("invalid status: code = %s, want InvalidArgument", status.Code(err))
+	}
+
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	batch, err := client.BatchUpdateChecks(ctx, &qualitypb.BatchUpdateChecksRequest{
+		EventId: event.GetId(),
+		Checks: []*qualitypb.CheckUpdate{{
+			Id:          checks[1].GetId(),
+			CheckStatus: proto.String("failed"),
+			StartedAt:   timestamppb.New(started),
+			CompletedAt: timestamppb.New(started.Add(90 * time.Second)),
+		}},
+	})
+	if err != nil {
+		t.Fatalf("BatchUpdateChecks: %v", err)
+	}
+	if len(batch.GetChecks()) != 1 || batch.GetChecks()[0].GetCheckStatus() != "failed" || batch.GetChecks()[0].GetDurationSeconds() != 90 {
+		t.Errorf("unexpected batch result: %+v", batch.GetChecks())
+	}
+
+	got, err := client.GetEvent(ctx, &qualitypb.GetEventRequest{Id: event.GetId()})
+	if err != nil {
+		t.Fatalf("GetEvent: %v", err)
+	}
+	statuses := map[int64]string{}
+	for _, check := range got.GetQualityChecks() {
+		statuses[check.GetId()] = check.GetCheckStatus()
+	}
+	if statuses[checks[0].GetId()] != "passed" || statuses[checks[1].GetId()] != "failed" {
+		t.Errorf("stored statuses = %v", statuses)
+	}
+}
@@ -0,0 +1,14 @@
+package handlers
+
+import "strings"
+
+// countChangedFiles 统计逗号分隔的变更文件数，忽略首尾空白和空项
+func countChangedFiles(s string) int {
+	count := 0
+	for _, file := range strings.Split(s, ",") {
+		if strings.TrimSpace(file) != "" {
+			count++
+		}
+	}
+	return count
+}
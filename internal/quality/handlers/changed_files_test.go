@@ -0,0 +1,28 @@
+package handlers
+
+import "testing"
+
+// TestCountChangedFiles 测试变更文件计数忽略空白和空项
+func TestCountChangedFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want int
+	}{
+		{"empty", "", 0},
+		{"whitespace only", "   ", 0},
+		{"single", "a.py", 1},
+		{"multiple", "a.py,b.py,c.go", 3},
+		{"trailing comma", "a.py, b.py,", 2},
+		{"surrounding spaces", "  a.py ,  b.py  ", 2},
+		{"only commas", " , ,", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countChangedFiles(tt.in); got != tt.want {
+				t.Errorf("countChangedFiles(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
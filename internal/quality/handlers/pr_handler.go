@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"log"
 
 	"github-hub/internal/quality/models"
@@ -19,8 +20,8 @@ func NewPRHandler(storage storage.Storage) *PRHandler {
 	}
 }
 
-// Handle 处理PR事件
-func (h *PRHandler) Handle(eventData map[string]interface{}) map[string]interface{} {
+// Handle 处理PR事件，ctx 用于存储调用
+func (h *PRHandler) Handle(ctx context.Context, eventData map[string]interface{}) map[string]interface{} {
 	log.Println("Processing PR event")
 
 	// 检测数据格式
@@ -43,9 +44,8 @@ func (h *PRHandler) Handle(eventData map[string]interface{}) map[string]interfac
 		if repo, ok := eventData["repository"].(string); ok {
 			repository = repo
 		}
-		if pn, ok := eventData["pr_number"].(float64); ok {
-			pnInt := int(pn)
-			prNumber = &pnInt
+		if pn, ok := models.IntValue(eventData["pr_number"]); ok {
+			prNumber = &pn
 		}
 		if title, ok := eventData["pr_title"].(string); ok {
 			prTitle = title
@@ -70,16 +70,7 @@ func (h *PRHandler) Handle(eventData map[string]interface{}) map[string]interfac
 			author = a
 		}
 		if changedFiles, ok := eventData["changed_files"].(string); ok {
-			if changedFiles != "" {
-				// 简单计算文件数量
-				count := 1
-				for i := 0; i < len(changedFiles); i++ {
-					if changedFiles[i] == ',' {
-						count++
-					}
-				}
-				changedFilesCount = count
-			}
+			changedFilesCount = countChangedFiles(changedFiles)
 		}
 
 		log.Printf("PR #%v: %s", prNumber, prTitle)
@@ -191,10 +182,10 @@ func (h *PRHandler) Handle(eventData map[string]interface{}) map[string]interfac
 	}
 
 	// 为事件创建质量检查项
-	event.QualityChecks = models.CreateChecksForEvent(event.EventID)
+	event.QualityChecks = models.CreateChecksForRepository(event.EventID, event.Repository, event.ChangedFiles)
 
 	// 保存事件到存储
-	if err := h.storage.CreateEvent(event); err != nil {
+	if err := h.storage.CreateEvent(ctx, event); err != nil {
 		log.Printf("Error saving event: %v", err)
 		return map[string]interface{}{
 			"status": "error",
@@ -213,6 +204,7 @@ func (h *PRHandler) Handle(eventData map[string]interface{}) map[string]interfac
 
 	return map[string]interface{}{
 		"status":        "processed",
+		"event_id":      event.EventID,
 		"repository":    repository,
 		"pr_number":     prNumber,
 		"pr_title":      prTitle,
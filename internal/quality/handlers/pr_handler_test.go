@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"testing"
 
 	"github-hub/internal/quality/storage"
@@ -24,7 +25,7 @@ func TestPRHandler_Handle_SimplifiedFormat(t *testing.T) {
 		"changed_files": "file1.py,file2.js",
 	}
 
-	result := handler.Handle(eventData)
+	result := handler.Handle(context.Background(), eventData)
 
 	if result["status"] != "processed" {
 		t.Errorf("expected status 'processed', got '%v'", result["status"])
@@ -42,7 +43,7 @@ func TestPRHandler_Handle_SimplifiedFormat(t *testing.T) {
 	}
 
 	// 验证事件被保存
-	events, err := mockStorage.ListEvents()
+	events, err := mockStorage.ListEvents(context.Background())
 	if err != nil {
 		t.Fatalf("ListEvents failed: %v", err)
 	}
@@ -60,7 +61,7 @@ func TestPRHandler_Handle_SimplifiedFormat(t *testing.T) {
 	}
 
 	// 验证质量检查被创建
-	checks, err := mockStorage.ListQualityChecksByEventID(events[0].EventID)
+	checks, err := mockStorage.ListQualityChecksByEventID(context.Background(), events[0].EventID)
 	if err != nil {
 		t.Fatalf("ListQualityChecksByEventID failed: %v", err)
 	}
@@ -103,7 +104,7 @@ func TestPRHandler_Handle_WebhookFormat(t *testing.T) {
 		},
 	}
 
-	result := handler.Handle(eventData)
+	result := handler.Handle(context.Background(), eventData)
 
 	if result["status"] != "processed" {
 		t.Errorf("expected status 'processed', got '%v'", result["status"])
@@ -118,7 +119,7 @@ func TestPRHandler_Handle_WebhookFormat(t *testing.T) {
 	}
 
 	// 验证事件被保存
-	events, err := mockStorage.ListEvents()
+	events, err := mockStorage.ListEvents(context.Background())
 	if err != nil {
 		t.Fatalf("ListEvents failed: %v", err)
 	}
@@ -168,7 +169,7 @@ func TestPRHandler_Handle_StorageError(t *testing.T) {
 	}
 
 	// 这个测试主要验证错误不会导致 panic
-	result := handler.Handle(eventData)
+	result := handler.Handle(context.Background(), eventData)
 
 	// 由于使用了有效的 mock，不应该有错误
 	if result["status"] != "processed" {
@@ -190,7 +191,7 @@ func TestPRHandler_Handle_DefaultValues(t *testing.T) {
 		"target_branch": "main",
 	}
 
-	result := handler.Handle(eventData)
+	result := handler.Handle(context.Background(), eventData)
 
 	if result["action"] != "opened" {
 		t.Errorf("expected default action 'opened', got '%v'", result["action"])
@@ -213,7 +214,7 @@ func TestPRHandler_Handle_MissingRequiredFields(t *testing.T) {
 		"target_branch": "main",
 	}
 
-	result := handler.Handle(eventData)
+	result := handler.Handle(context.Background(), eventData)
 
 	if result["status"] != "error" {
 		t.Errorf("expected status 'error', got '%v'", result["status"])
@@ -262,11 +263,16 @@ func TestPRHandler_Handle_ChangedFilesCount(t *testing.T) {
 				"changed_files": tt.changedFiles,
 			}
 
-			result := handler.Handle(eventData)
+			result := handler.Handle(context.Background(), eventData)
 
 			if result["changed_files"] != tt.expectedCount {
 				t.Errorf("expected changed_files count %d, got %v", tt.expectedCount, result["changed_files"])
 			}
+
+			events, _ := mockStorage.ListEvents(context.Background())
+			if len(events) != 1 || len(events[0].ChangedFiles) != tt.expectedCount {
+				t.Errorf("expected %d stored changed files, got %+v", tt.expectedCount, events)
+			}
 		})
 	}
 }
@@ -306,7 +312,7 @@ func TestPRHandler_Handle_WebhookFormatWithoutPullRequest(t *testing.T) {
 		},
 	}
 
-	result := handler.Handle(eventData)
+	result := handler.Handle(context.Background(), eventData)
 
 	// 应该成功处理
 	if result["status"] != "processed" {
@@ -314,7 +320,7 @@ func TestPRHandler_Handle_WebhookFormatWithoutPullRequest(t *testing.T) {
 	}
 
 	// 验证事件被保存
-	events, err := mockStorage.ListEvents()
+	events, err := mockStorage.ListEvents(context.Background())
 	if err != nil {
 		t.Fatalf("ListEvents failed: %v", err)
 	}
@@ -337,14 +343,14 @@ func TestPRHandler_Handle_PreserveEventID(t *testing.T) {
 		"target_branch": "main",
 	}
 
-	result := handler.Handle(eventData)
+	result := handler.Handle(context.Background(), eventData)
 
 	if result["status"] != "processed" {
 		t.Fatalf("expected status 'processed', got '%v'", result["status"])
 	}
 
 	// 验证事件有非空的 EventID
-	events, _ := mockStorage.ListEvents()
+	events, _ := mockStorage.ListEvents(context.Background())
 	if len(events) == 0 {
 		t.Fatal("expected 1 event")
 	}
@@ -353,3 +359,41 @@ func TestPRHandler_Handle_PreserveEventID(t *testing.T) {
 		t.Error("expected non-empty EventID")
 	}
 }
+
+// TestPRHandler_Handle_PRNumberForms 测试 pr_number 为字符串或整数时都能正确解析
+func TestPRHandler_Handle_PRNumberForms(t *testing.T) {
+	tests := []struct {
+		name     string
+		prNumber interface{}
+	}{
+		{"json number", float64(42)},
+		{"int", 42},
+		{"string", "42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := storage.NewMockStorage()
+			handler := NewPRHandler(mockStorage)
+
+			result := handler.Handle(context.Background(), map[string]interface{}{
+				"event_type":    "pull_request",
+				"repository":    "test/repo",
+				"pr_number":     tt.prNumber,
+				"pr_title":      "Test PR",
+				"pr_action":     "opened",
+				"source_branch": "feature",
+				"target_branch": "main",
+				"pr_author":     "testuser",
+			})
+			if result["status"] != "processed" {
+				t.Fatalf("expected status 'processed', got %v", result)
+			}
+
+			events, _ := mockStorage.ListEvents(context.Background())
+			if len(events) != 1 || events[0].PRNumber == nil || *events[0].PRNumber != 42 {
+				t.Fatalf("expected stored pr_number 42, got %+v", events)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"log"
 
 	"github-hub/internal/quality/models"
@@ -19,8 +20,8 @@ func NewPushHandler(storage storage.Storage) *PushHandler {
 	}
 }
 
-// Handle 处理Push事件
-func (h *PushHandler) Handle(eventData map[string]interface{}) map[string]interface{} {
+// Handle 处理Push事件，ctx 用于存储调用
+func (h *PushHandler) Handle(ctx context.Context, eventData map[string]interface{}) map[string]interface{} {
 	log.Println("Processing Push event")
 
 	// 检测数据格式
@@ -50,16 +51,7 @@ func (h *PushHandler) Handle(eventData map[string]interface{}) map[string]interf
 			pusher = p
 		}
 		if changedFiles, ok := eventData["changed_files"].(string); ok {
-			if changedFiles != "" {
-				// 简单计算文件数量
-				count := 1
-				for i := 0; i < len(changedFiles); i++ {
-					if changedFiles[i] == ',' {
-						count++
-					}
-				}
-				changedFilesCount = count
-			}
+			changedFilesCount = countChangedFiles(changedFiles)
 		}
 
 		log.Printf("Repository: %s", repository)
@@ -141,10 +133,10 @@ func (h *PushHandler) Handle(eventData map[string]interface{}) map[string]interf
 	}
 
 	// 为事件创建质量检查项
-	event.QualityChecks = models.CreateChecksForEvent(event.EventID)
+	event.QualityChecks = models.CreateChecksForRepository(event.EventID, event.Repository, event.ChangedFiles)
 
 	// 保存事件到存储
-	if err := h.storage.CreateEvent(event); err != nil {
+	if err := h.storage.CreateEvent(ctx, event); err != nil {
 		log.Printf("Error saving event: %v", err)
 		return map[string]interface{}{
 			"status": "error",
@@ -163,6 +155,7 @@ func (h *PushHandler) Handle(eventData map[string]interface{}) map[string]interf
 
 	return map[string]interface{}{
 		"status":        "processed",
+		"event_id":      event.EventID,
 		"repository":    repository,
 		"branch":        branch,
 		"commit_sha":    commitSHA,
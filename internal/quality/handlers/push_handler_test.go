@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"strings"
 	"testing"
 
 	"github-hub/internal/quality/storage"
@@ -20,7 +22,7 @@ func TestPushHandler_Handle_SimplifiedFormat(t *testing.T) {
 		"changed_files": "file1.py,file2.js,file3.go",
 	}
 
-	result := handler.Handle(eventData)
+	result := handler.Handle(context.Background(), eventData)
 
 	if result["status"] != "processed" {
 		t.Errorf("expected status 'processed', got '%v'", result["status"])
@@ -48,7 +50,7 @@ func TestPushHandler_Handle_SimplifiedFormat(t *testing.T) {
 	}
 
 	// 验证事件被保存
-	events, err := mockStorage.ListEvents()
+	events, err := mockStorage.ListEvents(context.Background())
 	if err != nil {
 		t.Fatalf("ListEvents failed: %v", err)
 	}
@@ -71,7 +73,7 @@ func TestPushHandler_Handle_SimplifiedFormat(t *testing.T) {
 	}
 
 	// 验证质量检查被创建
-	checks, err := mockStorage.ListQualityChecksByEventID(event.EventID)
+	checks, err := mockStorage.ListQualityChecksByEventID(context.Background(), event.EventID)
 	if err != nil {
 		t.Fatalf("ListQualityChecksByEventID failed: %v", err)
 	}
@@ -111,7 +113,7 @@ func TestPushHandler_Handle_WebhookFormat(t *testing.T) {
 		},
 	}
 
-	result := handler.Handle(eventData)
+	result := handler.Handle(context.Background(), eventData)
 
 	if result["status"] != "processed" {
 		t.Errorf("expected status 'processed', got '%v'", result["status"])
@@ -140,7 +142,7 @@ func TestPushHandler_Handle_WebhookFormat(t *testing.T) {
 	}
 
 	// 验证事件被保存
-	events, err := mockStorage.ListEvents()
+	events, err := mockStorage.ListEvents(context.Background())
 	if err != nil {
 		t.Fatalf("ListEvents failed: %v", err)
 	}
@@ -181,7 +183,7 @@ func TestPushHandler_Handle_RefsWithoutPrefix(t *testing.T) {
 		},
 	}
 
-	result := handler.Handle(eventData)
+	result := handler.Handle(context.Background(), eventData)
 
 	if result["status"] != "processed" {
 		t.Errorf("expected status 'processed', got '%v'", result["status"])
@@ -203,7 +205,7 @@ func TestPushHandler_Handle_MissingRequiredFields(t *testing.T) {
 		"repository": "test/repo",
 	}
 
-	result := handler.Handle(eventData)
+	result := handler.Handle(context.Background(), eventData)
 
 	if result["status"] != "error" {
 		t.Errorf("expected status 'error', got '%v'", result["status"])
@@ -228,7 +230,7 @@ func TestPushHandler_Handle_EmptyChangedFiles(t *testing.T) {
 		"changed_files": "",
 	}
 
-	result := handler.Handle(eventData)
+	result := handler.Handle(context.Background(), eventData)
 
 	if result["status"] != "processed" {
 		t.Errorf("expected status 'processed', got '%v'", result["status"])
@@ -253,7 +255,7 @@ func TestPushHandler_Handle_SingleChangedFile(t *testing.T) {
 		"changed_files": "single.py",
 	}
 
-	result := handler.Handle(eventData)
+	result := handler.Handle(context.Background(), eventData)
 
 	if result["status"] != "processed" {
 		t.Errorf("expected status 'processed', got '%v'", result["status"])
@@ -307,7 +309,7 @@ func TestPushHandler_Handle_WebhookWithMultipleCommits(t *testing.T) {
 		},
 	}
 
-	result := handler.Handle(eventData)
+	result := handler.Handle(context.Background(), eventData)
 
 	if result["status"] != "processed" {
 		t.Errorf("expected status 'processed', got '%v'", result["status"])
@@ -321,6 +323,16 @@ func TestPushHandler_Handle_WebhookWithMultipleCommits(t *testing.T) {
 	if result["changed_files"] != expectedFilesCount {
 		t.Errorf("expected changed_files count %d, got %v", expectedFilesCount, result["changed_files"])
 	}
+
+	// 验证变更文件路径随事件保存
+	events, _ := mockStorage.ListEvents(context.Background())
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	want := "file1.py,file2.py,file3.py,file4.js,file5.js"
+	if got := strings.Join(events[0].ChangedFiles, ","); got != want {
+		t.Errorf("expected stored changed files %s, got %s", want, got)
+	}
 }
 
 // TestPushHandler_Handle_WebhookWithoutCommits 测试没有 commits 字段的 webhook
@@ -342,7 +354,7 @@ func TestPushHandler_Handle_WebhookWithoutCommits(t *testing.T) {
 		// 没有 commits 字段
 	}
 
-	result := handler.Handle(eventData)
+	result := handler.Handle(context.Background(), eventData)
 
 	if result["status"] != "processed" {
 		t.Errorf("expected status 'processed', got '%v'", result["status"])
@@ -366,14 +378,14 @@ func TestPushHandler_Handle_PreserveEventID(t *testing.T) {
 		"pusher":      "user",
 	}
 
-	result := handler.Handle(eventData)
+	result := handler.Handle(context.Background(), eventData)
 
 	if result["status"] != "processed" {
 		t.Fatalf("expected status 'processed', got '%v'", result["status"])
 	}
 
 	// 验证事件有非空的 EventID
-	events, _ := mockStorage.ListEvents()
+	events, _ := mockStorage.ListEvents(context.Background())
 	if len(events) == 0 {
 		t.Fatal("expected 1 event")
 	}
@@ -396,9 +408,9 @@ func TestPushHandler_Handle_ActionDefaultValue(t *testing.T) {
 		"pusher":      "user",
 	}
 
-	handler.Handle(eventData)
+	handler.Handle(context.Background(), eventData)
 
-	events, _ := mockStorage.ListEvents()
+	events, _ := mockStorage.ListEvents(context.Background())
 	if len(events) == 0 {
 		t.Fatal("expected 1 event")
 	}
@@ -422,14 +434,14 @@ func TestPushHandler_Handle_QualityChecksCreated(t *testing.T) {
 		"pusher":      "user",
 	}
 
-	handler.Handle(eventData)
+	handler.Handle(context.Background(), eventData)
 
-	events, _ := mockStorage.ListEvents()
+	events, _ := mockStorage.ListEvents(context.Background())
 	if len(events) == 0 {
 		t.Fatal("expected 1 event")
 	}
 
-	checks, err := mockStorage.ListQualityChecksByEventID(events[0].EventID)
+	checks, err := mockStorage.ListQualityChecksByEventID(context.Background(), events[0].EventID)
 	if err != nil {
 		t.Fatalf("ListQualityChecksByEventID failed: %v", err)
 	}
@@ -0,0 +1,20 @@
+package logger
+
+import "context"
+
+// ctxKey context 中保存 Logger 的键
+type ctxKey struct{}
+
+// IntoContext 返回携带 Logger 的 context
+func IntoContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext 返回 context 中的 Logger，不存在时返回 DefaultLogger
+// LoggingMiddleware 注入的 Logger 带有当前请求的 request_id
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*Logger); ok && l != nil {
+		return l
+	}
+	return DefaultLogger
+}
@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFromContext_DefaultsToDefaultLogger(t *testing.T) {
+	if FromContext(context.Background()) != DefaultLogger {
+		t.Fatal("expected DefaultLogger for a context without a logger")
+	}
+	l := WithField("k", "v")
+	if FromContext(IntoContext(context.Background(), l)) != l {
+		t.Fatal("expected the logger stored in the context")
+	}
+}
+
+func TestLoggingMiddleware_InjectsRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	SetColor(false)
+	defer func() {
+		SetOutput(nil)
+		SetColor(true)
+	}()
+
+	handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Info("inside handler")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/events", nil)
+	req.Header.Set("X-Request-ID", "req-42")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.Contains(line, "inside handler") {
+			if !strings.Contains(line, "request_id=req-42") {
+				t.Fatalf("handler log line lacks request_id: %q", line)
+			}
+			return
+		}
+	}
+	t.Fatalf("handler log line not found in %q", buf.String())
+}
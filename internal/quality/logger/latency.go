@@ -0,0 +1,136 @@
+package logger
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyBuckets 延迟直方图的桶上界（毫秒），最后一个桶收集所有更大的值
+var latencyBuckets = []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// RouteLatency 单个路由的延迟统计
+type RouteLatency struct {
+	Method string  `json:"method"`
+	Route  string  `json:"route"`
+	Count  int64   `json:"count"`
+	P50Ms  float64 `json:"p50_ms"`
+	P95Ms  float64 `json:"p95_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+// histogram 固定桶的延迟直方图
+type histogram struct {
+	counts []int64
+	count  int64
+	max    float64
+}
+
+func (h *histogram) observe(ms float64) {
+	idx := sort.SearchFloat64s(latencyBuckets, ms)
+	h.counts[idx]++
+	h.count++
+	if ms > h.max {
+		h.max = ms
+	}
+}
+
+// percentile 估算分位数：定位目标所在桶后在桶内线性插值
+func (h *histogram) percentile(p float64) float64 {
+	if h.count == 0 {
+		return 0
+	}
+	rank := p * float64(h.count)
+	var seen int64
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		if float64(seen+c) >= rank {
+			lower := 0.0
+			if i > 0 {
+				lower = latencyBuckets[i-1]
+			}
+			upper := h.max
+			if i < len(latencyBuckets) && latencyBuckets[i] < upper {
+				upper = latencyBuckets[i]
+			}
+			if upper < lower {
+				return upper
+			}
+			return lower + (upper-lower)*(rank-float64(seen))/float64(c)
+		}
+		seen += c
+	}
+	return h.max
+}
+
+var (
+	latencyMu    sync.Mutex
+	latencyStats = map[string]*histogram{}
+)
+
+// ObserveLatency 记录一次请求耗时，path 会先经过 TemplatePath 归一化
+func ObserveLatency(method, path string, d time.Duration) {
+	key := method + " " + TemplatePath(path)
+	ms := float64(d) / float64(time.Millisecond)
+
+	latencyMu.Lock()
+	defer latencyMu.Unlock()
+	h, ok := latencyStats[key]
+	if !ok {
+		h = &histogram{counts: make([]int64, len(latencyBuckets)+1)}
+		latencyStats[key] = h
+	}
+	h.observe(ms)
+}
+
+// LatencySnapshot 返回所有路由的延迟统计，按 method 与 route 排序
+func LatencySnapshot() []RouteLatency {
+	latencyMu.Lock()
+	defer latencyMu.Unlock()
+
+	result := make([]RouteLatency, 0, len(latencyStats))
+	for key, h := range latencyStats {
+		method, route, _ := strings.Cut(key, " ")
+		result = append(result, RouteLatency{
+			Method: method,
+			Route:  route,
+			Count:  h.count,
+			P50Ms:  h.percentile(0.50),
+			P95Ms:  h.percentile(0.95),
+			P99Ms:  h.percentile(0.99),
+			MaxMs:  h.max,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Route != result[j].Route {
+			return result[i].Route < result[j].Route
+		}
+		return result[i].Method < result[j].Method
+	})
+	return result
+}
+
+// TemplatePath 将路径中的数字 ID 段替换为 {id}，避免每个资源 ID 产生独立的统计项
+// 例如 /api/events/42/status -> /api/events/{id}/status
+func TemplatePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if seg != "" && isDigits(seg) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTemplatePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/events", "/api/events"},
+		{"/api/events/42", "/api/events/{id}"},
+		{"/api/events/42/quality-checks/batch", "/api/events/{id}/quality-checks/batch"},
+		{"/api/mock/simulate/push", "/api/mock/simulate/push"},
+	}
+	for _, tt := range tests {
+		if got := TemplatePath(tt.path); got != tt.want {
+			t.Errorf("TemplatePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestLoggingMiddleware_RecordsLatency(t *testing.T) {
+	handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(3 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/latency-test/"+id, nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	var found *RouteLatency
+	for _, rl := range LatencySnapshot() {
+		if rl.Method == http.MethodGet && rl.Route == "/api/latency-test/{id}" {
+			rl := rl
+			found = &rl
+		}
+	}
+	if found == nil {
+		t.Fatal("expected latency entry for /api/latency-test/{id}")
+	}
+	if found.Count != 5 {
+		t.Errorf("expected count 5, got %d", found.Count)
+	}
+	if found.P50Ms < 2 || found.P95Ms < found.P50Ms || found.P99Ms < found.P95Ms || found.MaxMs < found.P99Ms {
+		t.Errorf("unexpected percentiles: %+v", *found)
+	}
+}
@@ -59,6 +59,12 @@ type Config struct {
 	EnableCaller bool
 	// 是否显示堆栈跟踪（ERROR 及以上）
 	EnableStack bool
+	// DEBUG/INFO 采样率：同一调用点超过每秒突发额度后只记录 1/N，<= 1 表示不采样
+	SampleRate int
+	// 采样开启时每个调用点每秒的突发额度，默认 DefaultSampleBurst
+	SampleBurst int
+	// 需要脱敏的字段名（不区分大小写），nil 表示 DefaultRedactKeys，空切片表示不脱敏
+	RedactKeys []string
 }
 
 // Logger 日志记录器
@@ -72,6 +78,8 @@ type Logger struct {
 	enableStack   bool
 	fields        map[string]interface{}
 	requestID     string
+	sampler       *sampler
+	redact        map[string]bool
 }
 
 // NewLogger 创建新的日志记录器
@@ -87,6 +95,8 @@ func NewLogger(config Config) *Logger {
 		enableCaller: config.EnableCaller,
 		enableStack:  config.EnableStack,
 		fields:       make(map[string]interface{}),
+		sampler:      newSampler(config.SampleRate, config.SampleBurst),
+		redact:       newRedactSet(config.RedactKeys),
 	}
 }
 
@@ -112,6 +122,16 @@ func SetJSONFormat(enable bool) {
 	DefaultLogger.mu.Unlock()
 }
 
+// SetOutput 设置全局日志输出目标，nil 表示 os.Stdout
+func SetOutput(out io.Writer) {
+	if out == nil {
+		out = os.Stdout
+	}
+	DefaultLogger.mu.Lock()
+	DefaultLogger.out = out
+	DefaultLogger.mu.Unlock()
+}
+
 // SetColor 设置颜色输出
 func SetColor(enable bool) {
 	DefaultLogger.mu.Lock()
@@ -121,6 +141,22 @@ func SetColor(enable bool) {
 
 // WithField 添加全局字段 - 返回新的 Logger 实例
 func (l *Logger) WithField(key string, value interface{}) *Logger {
+	newLogger := l.clone()
+	newLogger.fields[key] = value
+	return newLogger
+}
+
+// WithFields 添加多个字段 - 返回新的 Logger 实例
+func (l *Logger) WithFields(fields map[string]interface{}) *Logger {
+	newLogger := l.clone()
+	for k, v := range fields {
+		newLogger.fields[k] = v
+	}
+	return newLogger
+}
+
+// clone 复制 Logger 的配置和字段
+func (l *Logger) clone() *Logger {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
@@ -133,13 +169,14 @@ func (l *Logger) WithField(key string, value interface{}) *Logger {
 		enableStack:  l.enableStack,
 		fields:       make(map[string]interface{}),
 		requestID:    l.requestID,
+		sampler:      l.sampler,
+		redact:       l.redact,
 	}
 
 	// 复制现有字段
 	for k, v := range l.fields {
 		newLogger.fields[k] = v
 	}
-	newLogger.fields[key] = value
 	return newLogger
 }
 
@@ -163,12 +200,17 @@ func (l *Logger) log(level Level, msg string, fields map[string]interface{}, sta
 	}
 
 	l.mu.RLock()
+	out := l.out
 	enableCaller := l.enableCaller
 	jsonFormat := l.jsonFormat
 	requestID := l.requestID
+	sampler := l.sampler
 	l.mu.RUnlock()
 
 	now := time.Now()
+	if sampler != nil && level < WARN && !sampler.allow(level, callSite(), now) {
+		return
+	}
 	record := make(map[string]interface{})
 
 	// 基础字段
@@ -183,15 +225,16 @@ func (l *Logger) log(level Level, msg string, fields map[string]interface{}, sta
 
 	// 添加预存字段
 	l.mu.RLock()
+	redact := l.redact
 	for k, v := range l.fields {
-		record[k] = v
+		record[k] = redactValue(redact, k, v)
 	}
 	l.mu.RUnlock()
 
 	// 添加额外字段
 	for k, v := range fields {
 		if _, exists := record[k]; !exists {
-			record[k] = v
+			record[k] = redactValue(redact, k, v)
 		}
 	}
 
@@ -214,25 +257,25 @@ func (l *Logger) log(level Level, msg string, fields map[string]interface{}, sta
 	if jsonFormat {
 		jsonData, err := json.Marshal(record)
 		if err != nil {
-			fmt.Fprintf(l.out, "{\"level\":\"ERROR\",\"message\":\"failed to marshal log: %v\"}\n", err)
+			fmt.Fprintf(out, "{\"level\":\"ERROR\",\"message\":\"failed to marshal log: %v\"}\n", err)
 			return
 		}
-		fmt.Fprintln(l.out, string(jsonData))
+		fmt.Fprintln(out, string(jsonData))
 	} else {
-		l.logText(level, record)
+		l.logText(out, level, record)
 	}
 }
 
 // logText 文本格式日志输出
-func (l *Logger) logText(level Level, record map[string]interface{}) {
+func (l *Logger) logText(out io.Writer, level Level, record map[string]interface{}) {
 	l.mu.RLock()
 	enableColor := l.enableColor
 	l.mu.RUnlock()
 
 	// 颜色
-	color := levelColors[level]
+	color, reset := levelColors[level], ColorReset
 	if !enableColor {
-		color = ""
+		color, reset = "", ""
 	}
 
 	// 时间戳
@@ -248,7 +291,7 @@ func (l *Logger) logText(level Level, record map[string]interface{}) {
 
 	// 构建输出
 	output := fmt.Sprintf("%s[%s %s]%s %-5s | %s",
-		color, date, clock, ColorReset, levelName, msg)
+		color, date, clock, reset, levelName, msg)
 
 	// 添加 request_id
 	if requestID, ok := record["request_id"]; ok {
@@ -263,7 +306,7 @@ func (l *Logger) logText(level Level, record map[string]interface{}) {
 		output += fmt.Sprintf(" | %s=%v", k, v)
 	}
 
-	fmt.Fprintln(l.out, output)
+	fmt.Fprintln(out, output)
 }
 
 // getStack 获取堆栈信息
@@ -458,28 +501,7 @@ func WithField(key string, value interface{}) *Logger {
 
 // WithFields 添加多个全局字段
 func WithFields(fields map[string]interface{}) *Logger {
-	l := DefaultLogger
-	newLogger := &Logger{
-		out:          l.out,
-		level:        l.level,
-		jsonFormat:   l.jsonFormat,
-		enableColor:  l.enableColor,
-		enableCaller: l.enableCaller,
-		enableStack:  l.enableStack,
-		fields:       make(map[string]interface{}),
-		requestID:    l.requestID,
-	}
-
-	l.mu.RLock()
-	for k, v := range l.fields {
-		newLogger.fields[k] = v
-	}
-	l.mu.RUnlock()
-
-	for k, v := range fields {
-		newLogger.fields[k] = v
-	}
-	return newLogger
+	return DefaultLogger.WithFields(fields)
 }
 
 // WithRequest 设置请求 ID
@@ -2,8 +2,11 @@
 package logger
 
 import (
+	"crypto/rand"
 	"fmt"
+	mathrand "math/rand"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -25,6 +28,13 @@ func (w *responseWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Flush 实现 http.Flusher，保证流式响应经过日志中间件后仍能及时输出
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 // LoggingMiddleware HTTP 请求日志中间件
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -45,6 +55,10 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 		// 设置响应头
 		w.Header().Set("X-Request-ID", requestID)
 
+		// 注入请求级 Logger，处理器通过 FromContext 获取，日志自动带 request_id
+		logger := WithRequest(requestID)
+		r = r.WithContext(IntoContext(r.Context(), logger))
+
 		// 记录请求开始（使用基本日志避免中间件自身的性能问题）
 		Debugf("HTTP %s %s started | request_id=%s | remote=%s",
 			r.Method, r.URL.Path, requestID, r.RemoteAddr)
@@ -63,6 +77,7 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 
 		// 计算耗时
 		duration := time.Since(start)
+		ObserveLatency(r.Method, r.URL.Path, duration)
 
 		// 记录请求完成
 		logLevel := INFO
@@ -76,24 +91,87 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 			r.Method, r.URL.Path, rw.statusCode, rw.size, duration.Milliseconds())
 
 		// 使用带请求 ID 的日志记录器
-		logger := WithRequest(requestID)
 		logger.log(logLevel, msg, nil, "")
 	})
 }
 
-// generateRequestID 生成请求 ID
-func generateRequestID() string {
-	return fmt.Sprintf("%d-%s", time.Now().UnixNano(), randomString(8))
+// crockford 是 ULID 使用的 Crockford Base32 字母表（不含 I L O U）
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// requestIDGen 生成 ULID 风格的请求 ID：48 位毫秒时间戳 + 80 位随机数，
+// 同一毫秒内随机部分单调递增，因此 ID 全局唯一且按字典序即时间序
+type requestIDGen struct {
+	mu      sync.Mutex
+	lastMs  int64
+	entropy [10]byte
 }
 
-// randomString 生成随机字符串
-func randomString(n int) string {
-	const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
-	b := make([]byte, n)
-	for i := range b {
-		b[i] = letters[time.Now().UnixNano()%int64(len(letters))]
+var requestIDs requestIDGen
+
+// next 返回下一个 26 字符的请求 ID
+func (g *requestIDGen) next(now time.Time) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := now.UnixMilli()
+	if ms <= g.lastMs {
+		// 同一毫秒（或时钟回拨）：沿用上次的时间戳并递增随机部分
+		ms = g.lastMs
+		if !incrementBytes(g.entropy[:]) {
+			ms++
+			fillRandom(g.entropy[:])
+		}
+	} else {
+		fillRandom(g.entropy[:])
 	}
-	return string(b)
+	g.lastMs = ms
+
+	var out [26]byte
+	for i := 9; i >= 0; i-- {
+		out[i] = crockford[ms&0x1f]
+		ms >>= 5
+	}
+	encodeBase32(out[10:], g.entropy[:])
+	return string(out[:])
+}
+
+// incrementBytes 将大端字节序列加一，溢出时返回 false
+func incrementBytes(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeBase32 将 src 的比特流按 5 位一组写入 dst（len(dst)*5 需等于 len(src)*8）
+func encodeBase32(dst, src []byte) {
+	var buf uint
+	var bits uint
+	j := 0
+	for _, c := range src {
+		buf = buf<<8 | uint(c)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			dst[j] = crockford[(buf>>bits)&0x1f]
+			j++
+		}
+	}
+}
+
+// fillRandom 用 crypto/rand 填充 b，失败时退回 math/rand
+func fillRandom(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		mathrand.Read(b)
+	}
+}
+
+// generateRequestID 生成请求 ID
+func generateRequestID() string {
+	return requestIDs.next(time.Now())
 }
 
 // RequestIDMiddleware 简单的请求 ID 中间件（只添加 ID，不记录日志）
@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestGenerateRequestID_UniqueAndSorted 测试批量生成的请求 ID 唯一且按生成顺序递增
+func TestGenerateRequestID_UniqueAndSorted(t *testing.T) {
+	const n = 10000
+	ids := make([]string, n)
+	seen := make(map[string]bool, n)
+	for i := range ids {
+		id := generateRequestID()
+		if len(id) != 26 {
+			t.Fatalf("expected 26-char id, got %q", id)
+		}
+		if strings.Trim(id, crockford) != "" {
+			t.Fatalf("id %q contains characters outside the alphabet", id)
+		}
+		if seen[id] {
+			t.Fatalf("duplicate id %q after %d ids", id, i)
+		}
+		seen[id] = true
+		ids[i] = id
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Error("expected ids to be lexically sorted in generation order")
+	}
+}
+
+// TestRequestIDGen_Monotonic 测试同一毫秒和时钟回拨时 ID 仍然递增
+func TestRequestIDGen_Monotonic(t *testing.T) {
+	var g requestIDGen
+	now := time.UnixMilli(1700000000000)
+
+	first := g.next(now)
+	second := g.next(now)
+	third := g.next(now.Add(-time.Second))
+	if !(first < second && second < third) {
+		t.Errorf("expected increasing ids, got %s %s %s", first, second, third)
+	}
+	if first[:10] != third[:10] {
+		t.Errorf("expected timestamp prefix to be kept on clock rollback, got %s and %s", first[:10], third[:10])
+	}
+
+	// 随机部分溢出时时间戳前进一毫秒
+	for i := range g.entropy {
+		g.entropy[i] = 0xff
+	}
+	overflow := g.next(now)
+	if overflow[:10] <= third[:10] {
+		t.Errorf("expected timestamp to advance on overflow, got %s after %s", overflow, third)
+	}
+}
+
+// TestRequestIDEntropy_Distribution 测试随机部分的字符分布大致均匀
+func TestRequestIDEntropy_Distribution(t *testing.T) {
+	const samples = 10000
+	counts := make(map[byte]int)
+	var raw [10]byte
+	var out [16]byte
+	for i := 0; i < samples; i++ {
+		fillRandom(raw[:])
+		encodeBase32(out[:], raw[:])
+		for _, c := range out {
+			counts[c]++
+		}
+	}
+
+	if len(counts) != len(crockford) {
+		t.Fatalf("expected all %d characters to appear, got %d", len(crockford), len(counts))
+	}
+	expected := float64(samples*len(out)) / float64(len(crockford))
+	for c, n := range counts {
+		if dev := (float64(n) - expected) / expected; dev > 0.1 || dev < -0.1 {
+			t.Errorf("character %q appeared %d times, expected about %.0f", c, n, expected)
+		}
+	}
+}
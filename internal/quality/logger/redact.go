@@ -0,0 +1,39 @@
+package logger
+
+import "strings"
+
+// RedactedValue 替换敏感字段值的占位符
+const RedactedValue = "***"
+
+// DefaultRedactKeys 默认脱敏的字段名（不区分大小写）
+var DefaultRedactKeys = []string{"password", "token", "authorization", "dsn", "secret"}
+
+// newRedactSet 构建小写字段名集合，nil 表示使用 DefaultRedactKeys
+func newRedactSet(keys []string) map[string]bool {
+	if keys == nil {
+		keys = DefaultRedactKeys
+	}
+	set := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+			set[k] = true
+		}
+	}
+	return set
+}
+
+// redactValue 字段名命中脱敏集合时返回占位符，否则原样返回
+func redactValue(redact map[string]bool, key string, value interface{}) interface{} {
+	if redact[strings.ToLower(key)] {
+		return RedactedValue
+	}
+	return value
+}
+
+// SetRedactKeys 设置全局日志的脱敏字段名，nil 恢复默认，空切片表示不脱敏
+func SetRedactKeys(keys []string) {
+	set := newRedactSet(keys)
+	DefaultLogger.mu.Lock()
+	DefaultLogger.redact = set
+	DefaultLogger.mu.Unlock()
+}
@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// TestLogger_RedactFields 测试字段名不区分大小写地命中脱敏集合
+func TestLogger_RedactFields(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(Config{Out: &buf, Level: DEBUG, JSONFormat: true})
+
+	l.WithField("Token", "abc").InfoWithFields("login", map[string]interface{}{
+		"PASSWORD":      "hunter2",
+		"Authorization": "Bearer xyz",
+		"user":          "alice",
+	})
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("invalid JSON log: %v", err)
+	}
+	for _, k := range []string{"Token", "PASSWORD", "Authorization"} {
+		if record[k] != RedactedValue {
+			t.Errorf("expected %s to be redacted, got %v", k, record[k])
+		}
+	}
+	if record["user"] != "alice" {
+		t.Errorf("expected user to be kept, got %v", record["user"])
+	}
+}
+
+// TestNewRedactSet 测试自定义和关闭脱敏
+func TestNewRedactSet(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		key  string
+		want bool
+	}{
+		{"default dsn", nil, "DSN", true},
+		{"default keeps others", nil, "repo", false},
+		{"custom key", []string{" Cookie "}, "cookie", true},
+		{"custom replaces defaults", []string{"cookie"}, "token", false},
+		{"empty disables", []string{}, "password", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redactValue(newRedactSet(tt.keys), tt.key, "v") == RedactedValue
+			if got != tt.want {
+				t.Errorf("expected %v for %q, got %v", tt.want, tt.key, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingWriter_RotatesPastThreshold(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "quality.log")
+	w := NewRotatingWriter(path, 1, 2)
+	defer w.Close()
+
+	chunk := bytes.Repeat([]byte("x"), 600<<10)
+	for i := 0; i < 4; i++ {
+		if _, err := w.Write(chunk); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("expected %s: %v", filepath.Base(name), err)
+		}
+		if info.Size() > 1<<20 {
+			t.Errorf("%s is %d bytes, over the 1MB threshold", filepath.Base(name), info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 backups, found %s.3", filepath.Base(path))
+	}
+}
+
+func TestSetOutput(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	SetColor(false)
+	defer func() {
+		SetOutput(nil)
+		SetColor(true)
+	}()
+
+	Info("to the buffer")
+	if out := buf.String(); !strings.Contains(out, "to the buffer") || strings.Contains(out, "\033[") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// DefaultSampleBurst 采样开启时每个调用点每秒不经采样直接输出的日志条数
+const DefaultSampleBurst = 10
+
+// sampler 按调用点对 DEBUG/INFO 日志限流：每个（级别, 调用点）一个令牌桶，
+// 每秒补充 burst 个令牌；令牌耗尽后只输出每 rate 条中的 1 条。
+// WARN 及以上级别不采样
+type sampler struct {
+	rate  int
+	burst int
+
+	mu      sync.Mutex
+	buckets map[sampleKey]*sampleBucket
+}
+
+type sampleKey struct {
+	level Level
+	pc    uintptr
+}
+
+type sampleBucket struct {
+	tokens float64
+	last   time.Time
+	over   int // 令牌耗尽后到达的条数
+}
+
+// newSampler 创建采样器，rate <= 1 表示不采样（返回 nil）
+func newSampler(rate, burst int) *sampler {
+	if rate <= 1 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = DefaultSampleBurst
+	}
+	return &sampler{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[sampleKey]*sampleBucket),
+	}
+}
+
+// allow 判断该条日志是否输出
+func (s *sampler) allow(level Level, pc uintptr, now time.Time) bool {
+	if s == nil || level >= WARN {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := sampleKey{level: level, pc: pc}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &sampleBucket{tokens: float64(s.burst), last: now}
+		s.buckets[key] = b
+	}
+
+	// 按流逝时间补充令牌
+	b.tokens += now.Sub(b.last).Seconds() * float64(s.burst)
+	if b.tokens > float64(s.burst) {
+		b.tokens = float64(s.burst)
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true
+	}
+	b.over++
+	return b.over%s.rate == 0
+}
+
+// loggerFile 是 logger.go 的路径，用于在调用栈中跳过日志 API 自身的帧
+var loggerFile = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return file[:len(file)-len("sample.go")] + "logger.go"
+}()
+
+// callSite 返回调用日志 API 的代码位置，全局函数和实例方法都解析到真正的调用者
+func callSite() uintptr {
+	var pcs [8]uintptr
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if frame.File != loggerFile || !more {
+			return frame.PC
+		}
+	}
+}
+
+// SetSampling 设置全局日志采样率，rate <= 1 表示关闭采样
+func SetSampling(rate int) {
+	s := newSampler(rate, DefaultSampleBurst)
+	DefaultLogger.mu.Lock()
+	DefaultLogger.sampler = s
+	DefaultLogger.mu.Unlock()
+}
@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSampler_Allow 测试令牌耗尽后按 1/N 采样，时间流逝后额度恢复
+func TestSampler_Allow(t *testing.T) {
+	s := newSampler(5, 2)
+	now := time.Unix(1700000000, 0)
+
+	allowed := 0
+	for i := 0; i < 12; i++ {
+		if s.allow(DEBUG, 1, now) {
+			allowed++
+		}
+	}
+	// 2 条突发额度 + 剩余 10 条中的 1/5
+	if allowed != 4 {
+		t.Errorf("expected 4 allowed lines, got %d", allowed)
+	}
+
+	// 其他调用点和级别独立计数
+	if !s.allow(DEBUG, 2, now) || !s.allow(INFO, 1, now) {
+		t.Error("expected separate buckets per call site and level")
+	}
+
+	// 一秒后令牌补满
+	later := now.Add(time.Second)
+	if !s.allow(DEBUG, 1, later) || !s.allow(DEBUG, 1, later) {
+		t.Error("expected burst to be refilled after one second")
+	}
+
+	// WARN 及以上永不采样
+	for i := 0; i < 20; i++ {
+		if !s.allow(ERROR, 1, now) {
+			t.Fatal("expected ERROR lines to always be emitted")
+		}
+	}
+}
+
+// TestNewSampler_Disabled 测试 rate <= 1 时不采样
+func TestNewSampler_Disabled(t *testing.T) {
+	for _, rate := range []int{-1, 0, 1} {
+		if s := newSampler(rate, 0); s != nil {
+			t.Errorf("rate %d: expected nil sampler", rate)
+		}
+	}
+	if s := newSampler(3, 0); s.burst != DefaultSampleBurst {
+		t.Errorf("expected default burst %d, got %d", DefaultSampleBurst, s.burst)
+	}
+}
+
+// TestLogger_SamplingPerCallSite 测试 Logger 按调用点独立采样
+func TestLogger_SamplingPerCallSite(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(Config{Out: &buf, Level: DEBUG, SampleRate: 1000, SampleBurst: 3})
+
+	for i := 0; i < 10; i++ {
+		l.Debug("hot")
+	}
+	for i := 0; i < 10; i++ {
+		l.WithField("k", "v").Info("other")
+	}
+	for i := 0; i < 5; i++ {
+		l.Warn("warn")
+	}
+
+	out := buf.String()
+	if n := strings.Count(out, "hot"); n != 3 {
+		t.Errorf("expected 3 sampled DEBUG lines, got %d", n)
+	}
+	if n := strings.Count(out, "other"); n != 3 {
+		t.Errorf("expected 3 sampled INFO lines from a derived logger, got %d", n)
+	}
+	if n := strings.Count(out, "warn"); n != 5 {
+		t.Errorf("expected all 5 WARN lines, got %d", n)
+	}
+}
@@ -1,6 +1,9 @@
 package models
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // EventStatus 事件状态枚举
 type EventStatus string
@@ -22,6 +25,35 @@ const (
 	EventTypePullRequest EventType = "pull_request"
 )
 
+// EventKey 归一化的事件键，格式为 "<type>.<action>"，无 action 时仅为 "<type>"
+type EventKey string
+
+// NewEventKey 根据 X-GitHub-Event 和 payload 中的 action 构造事件键
+// 支持 GitHub webhook 格式（action）和简化格式（pr_action）
+func NewEventKey(eventType string, payload map[string]interface{}) EventKey {
+	action, _ := payload["action"].(string)
+	if action == "" {
+		action, _ = payload["pr_action"].(string)
+	}
+	if action == "" {
+		return EventKey(eventType)
+	}
+	return EventKey(eventType + "." + action)
+}
+
+// Type 返回事件键中的事件类型部分
+func (k EventKey) Type() string {
+	if i := strings.Index(string(k), "."); i >= 0 {
+		return string(k)[:i]
+	}
+	return string(k)
+}
+
+// MatchesAny 判断事件键是否命中集合；集合中的纯类型（如 "push"）匹配该类型的所有 action
+func (k EventKey) MatchesAny(accepted map[EventKey]bool) bool {
+	return accepted[k] || accepted[EventKey(k.Type())]
+}
+
 // QualityCheckStatus 质量检查状态枚举
 type QualityCheckStatus string
 
@@ -69,6 +101,18 @@ func ParseQualityCheckStatus(status string) (QualityCheckStatus, error) {
 	}
 }
 
+// ParseQualityCheckType 解析质量检查类型字符串
+func ParseQualityCheckType(checkType string) (QualityCheckType, error) {
+	switch QualityCheckType(checkType) {
+	case QualityCheckTypeCompilation, QualityCheckTypeCodeLint, QualityCheckTypeSecurityScan,
+		QualityCheckTypeUnitTest, QualityCheckTypeDeployment, QualityCheckTypeApiTest,
+		QualityCheckTypeModuleE2E, QualityCheckTypeAgentE2E, QualityCheckTypeAiE2E:
+		return QualityCheckType(checkType), nil
+	default:
+		return "", fmt.Errorf("invalid quality check type: %s", checkType)
+	}
+}
+
 // ParseEventStatus 解析事件状态字符串
 func ParseEventStatus(status string) (EventStatus, error) {
 	switch EventStatus(status) {
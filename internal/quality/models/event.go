@@ -3,6 +3,8 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
 )
@@ -22,10 +24,15 @@ type GitHubEvent struct {
 	Pusher       *string        `json:"pusher,omitempty"`
 	Author       *string        `json:"author,omitempty"`
 	Payload      json.RawMessage `json:"payload"`
+	ChangedFiles []string       `json:"changed_files,omitempty"` // 变更文件路径，用于选择专项测试
 	QualityChecks []PRQualityCheck `json:"quality_checks,omitempty"`
 	CreatedAt    LocalTime      `json:"created_at"`
 	UpdatedAt    LocalTime      `json:"updated_at"`
 	ProcessedAt  *LocalTime     `json:"processed_at,omitempty"`
+	RunCount     int            `json:"run_count"` // 整条流水线被重新运行的次数
+	SkipReason   *string        `json:"skip_reason,omitempty"` // 事件被过滤跳过的原因，仅 skipped 事件有值
+	ErrorMessage *string        `json:"error_message,omitempty"` // 执行方上报的最近一次失败原因
+	RetryCount   int            `json:"retry_count"`             // 执行方上报的重试次数
 }
 
 // PRQualityCheck PR质量检查模型
@@ -42,13 +49,29 @@ type PRQualityCheck struct {
 	DurationSeconds *float64         `json:"duration_seconds,omitempty"`
 	ErrorMessage  *string            `json:"error_message,omitempty"`
 	Output        *string            `json:"output,omitempty"`
+	OutputTruncated bool             `json:"output_truncated,omitempty"` // 响应中的 output 被截断，仅用于接口输出，不持久化
 	RetryCount    int                `json:"retry_count"`
 	CreatedAt     LocalTime          `json:"created_at"`
 	UpdatedAt     LocalTime          `json:"updated_at"`
 }
 
-// NewGitHubEvent 创建新的GitHub事件
-func NewGitHubEvent(eventData interface{}, eventType EventType) (*GitHubEvent, error) {
+// ExtractedFields 从事件数据中提取的字段，与 NewGitHubEvent 写入事件的字段一致
+type ExtractedFields struct {
+	Format       string   `json:"format"` // simplified 或 webhook
+	Repository   string   `json:"repository"`
+	Branch       string   `json:"branch"`
+	TargetBranch *string  `json:"target_branch,omitempty"`
+	CommitSHA    *string  `json:"commit_sha,omitempty"`
+	PRNumber     *int     `json:"pr_number,omitempty"`
+	Action       *string  `json:"action,omitempty"`
+	Pusher       *string  `json:"pusher,omitempty"`
+	Author       *string  `json:"author,omitempty"`
+	Problems     []string `json:"problems,omitempty"`
+}
+
+// ValidateEventData 按 NewGitHubEvent 的规则提取字段并检查必填项，不创建事件
+// 校验失败时返回已提取的字段（Problems 列出每个问题）和与 NewGitHubEvent 相同的错误
+func ValidateEventData(eventData interface{}, eventType EventType) (ExtractedFields, error) {
 	// 检测数据格式
 	var isSimplifiedFormat bool
 	var repository, branch string
@@ -58,7 +81,7 @@ func NewGitHubEvent(eventData interface{}, eventType EventType) (*GitHubEvent, e
 	// 尝试将eventData转换为map
 	eventMap, ok := eventData.(map[string]interface{})
 	if !ok {
-		return nil, fmt.Errorf("invalid event data format")
+		return ExtractedFields{Problems: []string{"payload is not a JSON object"}}, fmt.Errorf("invalid event data format")
 	}
 
 	// 检查是否为简化格式
@@ -97,9 +120,8 @@ func NewGitHubEvent(eventData interface{}, eventType EventType) (*GitHubEvent, e
 			if sha, ok := eventMap["commit_sha"].(string); ok {
 				commitSHA = &sha
 			}
-			if pn, ok := eventMap["pr_number"].(float64); ok {
-				pnInt := int(pn)
-				prNumber = &pnInt
+			if pn, ok := IntValue(eventMap["pr_number"]); ok {
+				prNumber = &pn
 			}
 			if prAction, ok := eventMap["pr_action"].(string); ok {
 				action = &prAction
@@ -166,9 +188,11 @@ func NewGitHubEvent(eventData interface{}, eventType EventType) (*GitHubEvent, e
 					targetBranch = &ref
 				}
 			}
-			if pn, ok := pr["number"].(float64); ok {
-				pnInt := int(pn)
-				prNumber = &pnInt
+			// webhook 的 PR 编号同时出现在顶层 number 和 pull_request.number
+			if pn, ok := IntValue(pr["number"]); ok {
+				prNumber = &pn
+			} else if pn, ok := IntValue(eventMap["number"]); ok {
+				prNumber = &pn
 			}
 			if a, ok := eventMap["action"].(string); ok {
 				action = &a
@@ -184,15 +208,56 @@ func NewGitHubEvent(eventData interface{}, eventType EventType) (*GitHubEvent, e
 		}
 	}
 
+	fields := ExtractedFields{
+		Format:       "webhook",
+		Repository:   repository,
+		Branch:       branch,
+		TargetBranch: targetBranch,
+		CommitSHA:    commitSHA,
+		PRNumber:     prNumber,
+		Action:       action,
+		Pusher:       pusher,
+		Author:       author,
+	}
+	if isSimplifiedFormat {
+		fields.Format = "simplified"
+	}
+	if eventType != EventTypePush && eventType != EventTypePullRequest {
+		fields.Problems = append(fields.Problems, fmt.Sprintf("unsupported event type %q", eventType))
+	}
+	if repository == "" {
+		fields.Problems = append(fields.Problems, "missing repository")
+	}
+	if branch == "" {
+		fields.Problems = append(fields.Problems, "missing branch")
+	}
+	// PR 编号缺失或不是正整数时不能入库，否则会被存成 0
+	missingPRNumber := eventType == EventTypePullRequest && (prNumber == nil || *prNumber <= 0)
+	if missingPRNumber {
+		fields.Problems = append(fields.Problems, "missing pr_number")
+	}
 	if repository == "" || branch == "" {
-		return nil, fmt.Errorf("missing required fields: repository or branch")
+		return fields, fmt.Errorf("missing required fields: repository or branch")
+	}
+	if missingPRNumber {
+		return fields, fmt.Errorf("missing required field: pr_number")
+	}
+	return fields, nil
+}
+
+// NewGitHubEvent 创建新的GitHub事件
+func NewGitHubEvent(eventData interface{}, eventType EventType) (*GitHubEvent, error) {
+	fields, err := ValidateEventData(eventData, eventType)
+	if err != nil {
+		return nil, err
 	}
+	eventMap := eventData.(map[string]interface{})
 
 	// 生成EventID
 	eventID := uuid.New().String()[:16]
 
-	// 序列化payload
-	payloadBytes, err := json.Marshal(eventData)
+	// 序列化payload（先替换疑似密钥，配置了键白名单时只保留白名单中的键）
+	payloadBytes, err := json.Marshal(ProjectPayload(RedactSecrets(eventMap), PayloadAllowList()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal payload: %w", err)
 	}
@@ -204,15 +269,16 @@ func NewGitHubEvent(eventData interface{}, eventType EventType) (*GitHubEvent, e
 		EventID:      eventID,
 		EventType:    eventType,
 		EventStatus:  EventStatusPending,
-		Repository:   repository,
-		Branch:       branch,
-		TargetBranch: targetBranch,
-		CommitSHA:    commitSHA,
-		PRNumber:     prNumber,
-		Action:       action,
-		Pusher:       pusher,
-		Author:       author,
+		Repository:   fields.Repository,
+		Branch:       fields.Branch,
+		TargetBranch: fields.TargetBranch,
+		CommitSHA:    fields.CommitSHA,
+		PRNumber:     fields.PRNumber,
+		Action:       fields.Action,
+		Pusher:       fields.Pusher,
+		Author:       fields.Author,
 		Payload:      payloadBytes,
+		ChangedFiles: ChangedFiles(eventMap),
 		QualityChecks: []PRQualityCheck{},
 		CreatedAt:    now,
 		UpdatedAt:    now,
@@ -220,98 +286,86 @@ func NewGitHubEvent(eventData interface{}, eventType EventType) (*GitHubEvent, e
 	}, nil
 }
 
-// CreateChecksForEvent 为事件创建所有质量检查项
+// CreateChecksForEvent 为事件创建内置默认流水线的所有质量检查项
 func CreateChecksForEvent(githubEventID string) []PRQualityCheck {
-	checks := []PRQualityCheck{}
-	now := Now()
+	return DefaultPipeline().CreateChecks(githubEventID)
+}
 
-	// 基础CI流水线阶段
-	basicCIChecks := []struct {
-		CheckType QualityCheckType
-		Order     int
-	}{
-		{QualityCheckTypeCompilation, 1},
-		{QualityCheckTypeCodeLint, 2},
-		{QualityCheckTypeSecurityScan, 3},
-		{QualityCheckTypeUnitTest, 4},
-	}
+// CreateChecksForRepository 按仓库对应的流水线和变更文件为事件创建质量检查项
+func CreateChecksForRepository(githubEventID, repository string, changedFiles []string) []PRQualityCheck {
+	return PipelineFor(repository).CreateChecksForChanges(githubEventID, changedFiles)
+}
 
-	for _, check := range basicCIChecks {
-		checks = append(checks, PRQualityCheck{
-			ID:            0, // 将由存储层分配
-			GitHubEventID: githubEventID,
-			CheckType:     check.CheckType,
-			CheckStatus:   QualityCheckStatusPending,
-			Stage:         StageTypeBasicCI,
-			StageOrder:    1,
-			CheckOrder:    check.Order,
-			StartedAt:     nil,
-			CompletedAt:   nil,
-			DurationSeconds: nil,
-			ErrorMessage:  nil,
-			Output:        nil,
-			RetryCount:    0,
-			CreatedAt:     now,
-			UpdatedAt:     now,
-		})
+// IntValue 将 JSON 数字、Go 整数或整数字符串（如 "42"）转换为 int，其他类型或无法解析时返回 false
+func IntValue(v interface{}) (int, bool) {
+	switch val := v.(type) {
+	case int:
+		return val, true
+	case int64:
+		return int(val), true
+	case float64:
+		return int(val), true
+	case float32:
+		return int(val), true
+	case string:
+		i, err := strconv.Atoi(strings.TrimSpace(val))
+		return i, err == nil
+	default:
+		return 0, false
 	}
+}
 
-	// 部署阶段
-	checks = append(checks, PRQualityCheck{
-		ID:            0,
-		GitHubEventID: githubEventID,
-		CheckType:     QualityCheckTypeDeployment,
-		CheckStatus:   QualityCheckStatusPending,
-		Stage:         StageTypeDeployment,
-		StageOrder:    2,
-		CheckOrder:    1,
-		StartedAt:     nil,
-		CompletedAt:   nil,
-		DurationSeconds: nil,
-		ErrorMessage:  nil,
-		Output:        nil,
-		RetryCount:    0,
-		CreatedAt:     now,
-		UpdatedAt:     now,
-	})
-
-	// 专项测试流水线阶段
-	specializedChecks := []struct {
-		CheckType QualityCheckType
-		Order     int
-	}{
-		{QualityCheckTypeApiTest, 1},
-		{QualityCheckTypeModuleE2E, 2},
-		{QualityCheckTypeAgentE2E, 3},
-		{QualityCheckTypeAiE2E, 4},
+// ChangedFiles 从事件数据中提取变更文件路径，按首次出现顺序去重
+// 支持简化格式的 changed_files（逗号分隔字符串或字符串数组）和 GitHub push 的 commits[].added/modified/removed
+func ChangedFiles(eventData map[string]interface{}) []string {
+	var files []string
+	seen := map[string]bool{}
+	add := func(file string) {
+		file = strings.TrimSpace(file)
+		if file != "" && !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
 	}
-
-	for _, check := range specializedChecks {
-		checks = append(checks, PRQualityCheck{
-			ID:            0,
-			GitHubEventID: githubEventID,
-			CheckType:     check.CheckType,
-			CheckStatus:   QualityCheckStatusPending,
-			Stage:         StageTypeSpecializedTests,
-			StageOrder:    3,
-			CheckOrder:    check.Order,
-			StartedAt:     nil,
-			CompletedAt:   nil,
-			DurationSeconds: nil,
-			ErrorMessage:  nil,
-			Output:        nil,
-			RetryCount:    0,
-			CreatedAt:     now,
-			UpdatedAt:     now,
-		})
+	addAll := func(v interface{}) {
+		switch list := v.(type) {
+		case string:
+			for _, file := range strings.Split(list, ",") {
+				add(file)
+			}
+		case []interface{}:
+			for _, item := range list {
+				if file, ok := item.(string); ok {
+					add(file)
+				}
+			}
+		}
 	}
 
-	return checks
+	addAll(eventData["changed_files"])
+	if commits, ok := eventData["commits"].([]interface{}); ok {
+		for _, c := range commits {
+			commit, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, key := range []string{"added", "modified", "removed"} {
+				addAll(commit[key])
+			}
+		}
+	}
+	return files
 }
 
 // ShouldProcessPushEvent 判断是否应该处理push事件
 // 支持GitHub webhook格式和简化格式
 func ShouldProcessPushEvent(eventData map[string]interface{}) bool {
+	return PushEventSkipReason(eventData) == ""
+}
+
+// PushEventSkipReason 返回 push 事件被跳过的原因，应当处理时返回空字符串
+// 只处理 main 分支的 push
+func PushEventSkipReason(eventData map[string]interface{}) string {
 	var branch string
 
 	// 尝试从简化格式获取分支 (GitHub Actions格式)
@@ -326,13 +380,25 @@ func ShouldProcessPushEvent(eventData map[string]interface{}) bool {
 		}
 	}
 
-	// 处理main分支的push事件
-	return branch == "main"
+	switch branch {
+	case "main":
+		return ""
+	case "":
+		return "push event has no branch"
+	default:
+		return fmt.Sprintf("push to branch %q, only main is processed", branch)
+	}
 }
 
 // ShouldProcessPREvent 判断是否应该处理PR事件
 // 支持GitHub webhook格式和简化格式
 func ShouldProcessPREvent(eventData map[string]interface{}) bool {
+	return PREventSkipReason(eventData) == ""
+}
+
+// PREventSkipReason 返回 PR 事件被跳过的原因，应当处理时返回空字符串
+// 只处理非 main 分支合入 main 分支的 PR
+func PREventSkipReason(eventData map[string]interface{}) string {
 	var headBranch, baseBranch string
 
 	// 尝试从简化格式获取分支 (GitHub Actions格式)
@@ -369,6 +435,12 @@ func ShouldProcessPREvent(eventData map[string]interface{}) bool {
 		}
 	}
 
-	// 只处理非main分支合入main分支的PR
-	return headBranch != "main" && baseBranch == "main"
+	switch {
+	case baseBranch != "main":
+		return fmt.Sprintf("pull request targets %q, only main is processed", baseBranch)
+	case headBranch == "main":
+		return "pull request source branch is main"
+	default:
+		return ""
+	}
 }
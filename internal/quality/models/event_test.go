@@ -229,6 +229,61 @@ func TestNewGitHubEvent_PayloadSerialization(t *testing.T) {
 	}
 }
 
+// TestNewGitHubEvent_PayloadAllowList 测试配置键白名单后 payload 只保留指定键
+func TestNewGitHubEvent_PayloadAllowList(t *testing.T) {
+	SetPayloadAllowList([]string{"ref", "repository"})
+	defer SetPayloadAllowList(nil)
+
+	eventData := map[string]interface{}{
+		"ref": "refs/heads/main",
+		"repository": map[string]interface{}{
+			"full_name": "owner/repo",
+		},
+		"head_commit": map[string]interface{}{
+			"id": "abc123",
+		},
+		"pusher": map[string]interface{}{
+			"name":  "alice",
+			"email": "alice@example.com",
+		},
+		"sender": map[string]interface{}{"login": "alice"},
+	}
+
+	event, err := NewGitHubEvent(eventData, EventTypePush)
+	if err != nil {
+		t.Fatalf("NewGitHubEvent failed: %v", err)
+	}
+
+	// 解析字段不受白名单影响
+	if event.CommitSHA == nil || *event.CommitSHA != "abc123" {
+		t.Errorf("expected commit sha to be parsed from full payload, got %v", event.CommitSHA)
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		t.Fatalf("Failed to unmarshal payload: %v", err)
+	}
+	if len(payload) != 2 || payload["ref"] != "refs/heads/main" || payload["repository"] == nil {
+		t.Errorf("expected only ref and repository in payload, got %v", payload)
+	}
+}
+
+func TestProjectPayload_NestedKeys(t *testing.T) {
+	data := map[string]interface{}{
+		"repository": map[string]interface{}{"full_name": "owner/repo", "private": true},
+		"pusher":     map[string]interface{}{"name": "alice"},
+	}
+
+	got := ProjectPayload(data, []string{"repository.full_name", "missing.key"})
+	raw, _ := json.Marshal(got)
+	if string(raw) != `{"repository":{"full_name":"owner/repo"}}` {
+		t.Errorf("unexpected projection: %s", raw)
+	}
+	if len(data["repository"].(map[string]interface{})) != 2 {
+		t.Errorf("projection must not modify the original payload")
+	}
+}
+
 // TestCreateChecksForEvent 测试质量检查项创建
 func TestCreateChecksForEvent(t *testing.T) {
 	eventID := "test-event-123"
@@ -366,6 +421,9 @@ func TestShouldProcessPushEvent(t *testing.T) {
 			if got != tt.want {
 				t.Errorf("ShouldProcessPushEvent() = %v, want %v", got, tt.want)
 			}
+			if reason := PushEventSkipReason(tt.eventData); (reason == "") != tt.want {
+				t.Errorf("PushEventSkipReason() = %q, want empty=%v", reason, tt.want)
+			}
 		})
 	}
 }
@@ -458,6 +516,9 @@ func TestShouldProcessPREvent(t *testing.T) {
 			if got != tt.want {
 				t.Errorf("ShouldProcessPREvent() = %v, want %v", got, tt.want)
 			}
+			if reason := PREventSkipReason(tt.eventData); (reason == "") != tt.want {
+				t.Errorf("PREventSkipReason() = %q, want empty=%v", reason, tt.want)
+			}
 		})
 	}
 }
@@ -523,6 +584,44 @@ func TestQualityCheckTypeValues(t *testing.T) {
 	}
 }
 
+// TestNewEventKey 测试事件键归一化与匹配
+func TestNewEventKey(t *testing.T) {
+	accepted := map[EventKey]bool{
+		"push":                     true,
+		"pull_request.opened":      true,
+		"pull_request.synchronize": true,
+	}
+
+	tests := []struct {
+		name      string
+		eventType string
+		payload   map[string]interface{}
+		wantKey   EventKey
+		wantMatch bool
+	}{
+		{"push without action", "push", map[string]interface{}{"ref": "refs/heads/main"}, "push", true},
+		{"webhook pr opened", "pull_request", map[string]interface{}{"action": "opened"}, "pull_request.opened", true},
+		{"webhook pr closed", "pull_request", map[string]interface{}{"action": "closed"}, "pull_request.closed", false},
+		{"simplified pr action", "pull_request", map[string]interface{}{"pr_action": "synchronize"}, "pull_request.synchronize", true},
+		{"unknown type", "issues", map[string]interface{}{"action": "opened"}, "issues.opened", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := NewEventKey(tt.eventType, tt.payload)
+			if key != tt.wantKey {
+				t.Errorf("expected key '%s', got '%s'", tt.wantKey, key)
+			}
+			if key.Type() != tt.eventType {
+				t.Errorf("expected type '%s', got '%s'", tt.eventType, key.Type())
+			}
+			if got := key.MatchesAny(accepted); got != tt.wantMatch {
+				t.Errorf("MatchesAny() = %v, want %v", got, tt.wantMatch)
+			}
+		})
+	}
+}
+
 // TestGitHubEvent_Timestamps 测试时间戳
 func TestGitHubEvent_Timestamps(t *testing.T) {
 	eventData := map[string]interface{}{
@@ -573,3 +672,167 @@ func TestPRQualityCheck_DefaultValues(t *testing.T) {
 		}
 	}
 }
+
+// TestValidateEventData_MatchesNewGitHubEvent 测试校验结果与创建事件时提取的字段一致
+func TestValidateEventData_MatchesNewGitHubEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      interface{}
+		eventType EventType
+		format    string
+		problems  []string
+	}{
+		{
+			name:      "simplified push",
+			data:      map[string]interface{}{"event_type": "push", "repository": "o/r", "branch": "main", "commit_sha": "abc", "pusher": "alice"},
+			eventType: EventTypePush,
+			format:    "simplified",
+		},
+		{
+			name: "webhook pull request",
+			data: map[string]interface{}{
+				"action":     "synchronize",
+				"repository": map[string]interface{}{"full_name": "o/r"},
+				"pull_request": map[string]interface{}{
+					"number": float64(7),
+					"head":   map[string]interface{}{"ref": "feature", "sha": "def"},
+					"base":   map[string]interface{}{"ref": "main"},
+					"user":   map[string]interface{}{"login": "bob"},
+				},
+			},
+			eventType: EventTypePullRequest,
+			format:    "webhook",
+		},
+		{
+			name: "webhook pull request with top-level number",
+			data: map[string]interface{}{
+				"number":     float64(8),
+				"repository": map[string]interface{}{"full_name": "o/r"},
+				"pull_request": map[string]interface{}{
+					"head": map[string]interface{}{"ref": "feature"},
+					"base": map[string]interface{}{"ref": "main"},
+				},
+			},
+			eventType: EventTypePullRequest,
+			format:    "webhook",
+		},
+		{
+			name: "webhook pull request missing number",
+			data: map[string]interface{}{
+				"repository": map[string]interface{}{"full_name": "o/r"},
+				"pull_request": map[string]interface{}{
+					"head": map[string]interface{}{"ref": "feature"},
+				},
+			},
+			eventType: EventTypePullRequest,
+			format:    "webhook",
+			problems:  []string{"missing pr_number"},
+		},
+		{
+			name:      "missing branch",
+			data:      map[string]interface{}{"repository": map[string]interface{}{"full_name": "o/r"}},
+			eventType: EventTypePush,
+			format:    "webhook",
+			problems:  []string{"missing branch"},
+		},
+		{
+			name:      "missing both",
+			data:      map[string]interface{}{},
+			eventType: EventTypePullRequest,
+			format:    "webhook",
+			problems:  []string{"missing repository", "missing branch", "missing pr_number"},
+		},
+		{
+			name:      "not an object",
+			data:      []interface{}{"x"},
+			eventType: EventTypePush,
+			problems:  []string{"payload is not a JSON object"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, verr := ValidateEventData(tt.data, tt.eventType)
+			event, nerr := NewGitHubEvent(tt.data, tt.eventType)
+
+			if (verr == nil) != (nerr == nil) || (verr != nil && verr.Error() != nerr.Error()) {
+				t.Fatalf("errors differ: validate=%v new=%v", verr, nerr)
+			}
+			if fields.Format != tt.format {
+				t.Errorf("expected format %q, got %q", tt.format, fields.Format)
+			}
+			if len(fields.Problems) != len(tt.problems) {
+				t.Fatalf("expected problems %v, got %v", tt.problems, fields.Problems)
+			}
+			for i := range tt.problems {
+				if fields.Problems[i] != tt.problems[i] {
+					t.Errorf("expected problems %v, got %v", tt.problems, fields.Problems)
+				}
+			}
+			if nerr != nil {
+				return
+			}
+
+			got, _ := json.Marshal(fields)
+			want, _ := json.Marshal(ExtractedFields{
+				Format:       tt.format,
+				Repository:   event.Repository,
+				Branch:       event.Branch,
+				TargetBranch: event.TargetBranch,
+				CommitSHA:    event.CommitSHA,
+				PRNumber:     event.PRNumber,
+				Action:       event.Action,
+				Pusher:       event.Pusher,
+				Author:       event.Author,
+			})
+			if string(got) != string(want) {
+				t.Errorf("fields differ from event:\n got  %s\n want %s", got, want)
+			}
+		})
+	}
+}
+
+// TestNewGitHubEvent_PRNumberForms 测试简化格式的 pr_number 支持数字和整数字符串，缺失或无法解析时拒绝创建
+func TestNewGitHubEvent_PRNumberForms(t *testing.T) {
+	tests := []struct {
+		name     string
+		prNumber interface{}
+		want     int // 0 表示 pr_number 缺失，应返回错误
+	}{
+		{"json number", float64(42), 42},
+		{"int", 42, 42},
+		{"string", "42", 42},
+		{"padded string", " 42 ", 42},
+		{"non-numeric string", "abc", 0},
+		{"missing", nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventData := map[string]interface{}{
+				"event_type":    "pull_request",
+				"repository":    "test/repo",
+				"source_branch": "feature",
+				"target_branch": "main",
+				"pr_author":     "contributor",
+			}
+			if tt.prNumber != nil {
+				eventData["pr_number"] = tt.prNumber
+			}
+
+			event, err := NewGitHubEvent(eventData, EventTypePullRequest)
+			if tt.want == 0 {
+				if err == nil {
+					t.Fatalf("expected an error for missing pr_number, got event with pr_number %v", event.PRNumber)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewGitHubEvent failed: %v", err)
+			}
+			if event.PRNumber == nil || *event.PRNumber != tt.want {
+				t.Errorf("expected pr_number %d, got %v", tt.want, event.PRNumber)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// EnumLabels 阶段和检查类型枚举值对应的展示名称，前端直接使用，不再自行映射
+// 阶段 deployment 与检查类型 deployment 共用同一个名称
+var EnumLabels = map[string]string{
+	string(StageTypeBasicCI):          "Basic CI",
+	string(StageTypeDeployment):       "Deployment",
+	string(StageTypeSpecializedTests): "Specialized Tests",
+
+	string(QualityCheckTypeCompilation):  "Compilation",
+	string(QualityCheckTypeCodeLint):     "Code Lint",
+	string(QualityCheckTypeSecurityScan): "Security Scan",
+	string(QualityCheckTypeUnitTest):     "Unit Test",
+	string(QualityCheckTypeApiTest):      "API Test",
+	string(QualityCheckTypeModuleE2E):    "Module E2E",
+	string(QualityCheckTypeAgentE2E):     "Agent E2E",
+	string(QualityCheckTypeAiE2E):        "AI E2E",
+}
+
+// StageLabel 返回阶段的展示名称
+func StageLabel(stage StageType) string {
+	return enumLabel(string(stage))
+}
+
+// CheckTypeLabel 返回检查类型的展示名称
+func CheckTypeLabel(checkType QualityCheckType) string {
+	return enumLabel(string(checkType))
+}
+
+// enumLabel 查找展示名称；未登记的值按下划线拆词并首字母大写，如 smoke_test → Smoke Test
+func enumLabel(value string) string {
+	if label, ok := EnumLabels[value]; ok {
+		return label
+	}
+	words := strings.FieldsFunc(value, func(r rune) bool { return r == '_' || r == '-' })
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// MarshalJSON 在原始枚举字段之外输出计算得到的 stage_label 和 check_label，两者不持久化
+func (c PRQualityCheck) MarshalJSON() ([]byte, error) {
+	type plain PRQualityCheck
+	return json.Marshal(struct {
+		plain
+		StageLabel string `json:"stage_label"`
+		CheckLabel string `json:"check_label"`
+	}{
+		plain:      plain(c),
+		StageLabel: StageLabel(c.Stage),
+		CheckLabel: CheckTypeLabel(c.CheckType),
+	})
+}
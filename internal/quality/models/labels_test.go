@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPRQualityCheck_MarshalJSONLabels(t *testing.T) {
+	tests := []struct {
+		name      string
+		stage     StageType
+		checkType QualityCheckType
+		wantStage string
+		wantCheck string
+	}{
+		{"basic ci", StageTypeBasicCI, QualityCheckTypeCodeLint, "Basic CI", "Code Lint"},
+		{"deployment", StageTypeDeployment, QualityCheckTypeDeployment, "Deployment", "Deployment"},
+		{"specialized tests", StageTypeSpecializedTests, QualityCheckTypeAiE2E, "Specialized Tests", "AI E2E"},
+		{"unregistered values", StageType("smoke_checks"), QualityCheckType("perf-test"), "Smoke Checks", "Perf Test"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := PRQualityCheck{ID: 3, Stage: tt.stage, CheckType: tt.checkType, CheckStatus: QualityCheckStatusPassed}
+			raw, err := json.Marshal(check)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			var got map[string]interface{}
+			if err := json.Unmarshal(raw, &got); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if got["stage_label"] != tt.wantStage || got["check_label"] != tt.wantCheck {
+				t.Errorf("labels = %v / %v, want %s / %s", got["stage_label"], got["check_label"], tt.wantStage, tt.wantCheck)
+			}
+			if got["stage"] != string(tt.stage) || got["check_type"] != string(tt.checkType) || got["id"] != float64(3) {
+				t.Errorf("raw fields missing or changed: %s", raw)
+			}
+
+			var back PRQualityCheck
+			if err := json.Unmarshal(raw, &back); err != nil || back.Stage != tt.stage || back.CheckType != tt.checkType {
+				t.Errorf("round trip = %+v, %v", back, err)
+			}
+		})
+	}
+}
+
+func TestEnumLabels_CoverAllStagesAndCheckTypes(t *testing.T) {
+	for _, stage := range []StageType{StageTypeBasicCI, StageTypeDeployment, StageTypeSpecializedTests} {
+		if _, ok := EnumLabels[string(stage)]; !ok {
+			t.Errorf("missing label for stage %s", stage)
+		}
+	}
+	for _, checkType := range []QualityCheckType{
+		QualityCheckTypeCompilation, QualityCheckTypeCodeLint, QualityCheckTypeSecurityScan,
+		QualityCheckTypeUnitTest, QualityCheckTypeDeployment, QualityCheckTypeApiTest,
+		QualityCheckTypeModuleE2E, QualityCheckTypeAgentE2E, QualityCheckTypeAiE2E,
+	} {
+		if _, ok := EnumLabels[string(checkType)]; !ok {
+			t.Errorf("missing label for check type %s", checkType)
+		}
+	}
+}
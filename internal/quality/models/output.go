@@ -0,0 +1,41 @@
+package models
+
+import "unicode/utf8"
+
+// MaxCheckOutputBytes 质量检查 output 的最大字节数，与 MySQL TEXT 列的上限一致
+const MaxCheckOutputBytes = 65535
+
+// OutputTruncatedMarker 输出被截断时加在开头的标记
+const OutputTruncatedMarker = "...[output truncated]\n"
+
+// TruncateCheckOutput 输出超过 MaxCheckOutputBytes 时保留末尾部分（日志的结尾通常是失败原因），
+// 并在开头加上截断标记；截断点对齐到 UTF-8 字符边界
+func TruncateCheckOutput(output string) string {
+	truncated, _ := PreviewCheckOutput(output, MaxCheckOutputBytes)
+	return truncated
+}
+
+// PreviewCheckOutput 按 TruncateCheckOutput 的规则把输出截断到 maxBytes 以内，并返回是否发生截断
+// maxBytes 不大于 0 时原样返回；maxBytes 小于截断标记长度时只返回截断到 maxBytes 的标记
+func PreviewCheckOutput(output string, maxBytes int) (string, bool) {
+	if maxBytes <= 0 || len(output) <= maxBytes {
+		return output, false
+	}
+	if maxBytes <= len(OutputTruncatedMarker) {
+		return OutputTruncatedMarker[:maxBytes], true
+	}
+	keep := maxBytes - len(OutputTruncatedMarker)
+	start := len(output) - keep
+	for start < len(output) && !utf8.RuneStart(output[start]) {
+		start++
+	}
+	return OutputTruncatedMarker + output[start:], true
+}
+
+// AppendCheckOutput 把 chunk 追加到已有输出之后，并按上限截断
+func AppendCheckOutput(existing *string, chunk string) string {
+	if existing == nil {
+		return TruncateCheckOutput(chunk)
+	}
+	return TruncateCheckOutput(*existing + chunk)
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestAppendCheckOutput(t *testing.T) {
+	if got := AppendCheckOutput(nil, "a"); got != "a" {
+		t.Errorf("expected first chunk as-is, got %q", got)
+	}
+	existing := "a"
+	if got := AppendCheckOutput(&existing, "b"); got != "ab" {
+		t.Errorf("expected ab, got %q", got)
+	}
+
+	big := strings.Repeat("x", MaxCheckOutputBytes-1)
+	got := AppendCheckOutput(&big, "界tail")
+	if len(got) > MaxCheckOutputBytes {
+		t.Errorf("expected at most %d bytes, got %d", MaxCheckOutputBytes, len(got))
+	}
+	if !strings.HasPrefix(got, OutputTruncatedMarker) || !strings.HasSuffix(got, "界tail") {
+		t.Errorf("expected marker and the newest output to be kept, got ...%q", got[len(got)-16:])
+	}
+	if !utf8.ValidString(got) {
+		t.Error("truncated output is not valid UTF-8")
+	}
+}
+
+func TestPreviewCheckOutput(t *testing.T) {
+	tests := []struct {
+		name          string
+		output        string
+		maxBytes      int
+		wantTruncated bool
+	}{
+		{"fits", "short log", 100, false},
+		{"exact limit", strings.Repeat("x", 100), 100, false},
+		{"too long", strings.Repeat("x", 200), 100, true},
+		{"multibyte boundary", strings.Repeat("日志", 50), 100, true},
+		{"disabled", strings.Repeat("x", 200), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := PreviewCheckOutput(tt.output, tt.maxBytes)
+			if truncated != tt.wantTruncated {
+				t.Fatalf("truncated = %v, want %v", truncated, tt.wantTruncated)
+			}
+			if !truncated {
+				if got != tt.output {
+					t.Errorf("expected output unchanged, got %q", got)
+				}
+				return
+			}
+			if len(got) > tt.maxBytes || !strings.HasPrefix(got, OutputTruncatedMarker) || !utf8.ValidString(got) {
+				t.Errorf("unexpected preview (%d bytes): %q", len(got), got)
+			}
+			if !strings.HasSuffix(tt.output, strings.TrimPrefix(got, OutputTruncatedMarker)) {
+				t.Error("expected the preview to keep the end of the output")
+			}
+		})
+	}
+}
+
+// TestPreviewCheckOutput_LimitBelowMarker 测试 maxBytes 小于截断标记长度时结果仍不超过 maxBytes
+func TestPreviewCheckOutput_LimitBelowMarker(t *testing.T) {
+	output := strings.Repeat("x", 200)
+	for _, maxBytes := range []int{1, 10, len(OutputTruncatedMarker) - 1, len(OutputTruncatedMarker)} {
+		got, truncated := PreviewCheckOutput(output, maxBytes)
+		if !truncated {
+			t.Errorf("maxBytes=%d: expected truncated", maxBytes)
+		}
+		if len(got) > maxBytes {
+			t.Errorf("maxBytes=%d: preview is %d bytes: %q", maxBytes, len(got), got)
+		}
+		if !strings.HasPrefix(OutputTruncatedMarker, got) {
+			t.Errorf("maxBytes=%d: expected a prefix of the truncation marker, got %q", maxBytes, got)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"strings"
+	"sync"
+)
+
+// payloadAllowList 持久化 payload 时保留的键，为空表示保存完整 payload
+var (
+	payloadAllowMu   sync.RWMutex
+	payloadAllowList []string
+)
+
+// SetPayloadAllowList 设置持久化 payload 时保留的键
+// 支持用点号表示嵌套键，如 "repository.full_name"；传入空列表恢复保存完整 payload
+func SetPayloadAllowList(keys []string) {
+	cleaned := make([]string, 0, len(keys))
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key != "" {
+			cleaned = append(cleaned, key)
+		}
+	}
+
+	payloadAllowMu.Lock()
+	defer payloadAllowMu.Unlock()
+	payloadAllowList = cleaned
+}
+
+// PayloadAllowList 返回当前配置的 payload 键白名单
+func PayloadAllowList() []string {
+	payloadAllowMu.RLock()
+	defer payloadAllowMu.RUnlock()
+	return append([]string(nil), payloadAllowList...)
+}
+
+// ProjectPayload 按白名单裁剪 payload，只保留列出的键；keys 为空时原样返回
+// 嵌套键只保留路径上的对象和目标值，原始数据不会被修改
+func ProjectPayload(data map[string]interface{}, keys []string) map[string]interface{} {
+	if len(keys) == 0 {
+		return data
+	}
+
+	result := map[string]interface{}{}
+	for _, key := range keys {
+		copyPath(data, result, strings.Split(key, "."))
+	}
+	return result
+}
+
+// copyPath 将 src 中 path 指向的值复制到 dst 的相同位置
+func copyPath(src, dst map[string]interface{}, path []string) {
+	value, ok := src[path[0]]
+	if !ok {
+		return
+	}
+	if len(path) == 1 {
+		dst[path[0]] = value
+		return
+	}
+
+	child, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	next, ok := dst[path[0]].(map[string]interface{})
+	if !ok {
+		next = map[string]interface{}{}
+		dst[path[0]] = next
+	}
+	copyPath(child, next, path[1:])
+}
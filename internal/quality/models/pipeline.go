@@ -0,0 +1,262 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PipelineStage 流水线中的一个阶段及其按顺序执行的检查项
+// IgnorePaths 为路径选择规则：所有变更文件都命中这些模式时跳过该阶段
+// 模式以 "/" 结尾表示目录前缀，不含 "/" 时匹配文件名，否则按 path.Match 匹配完整路径
+type PipelineStage struct {
+	Stage       StageType          `json:"stage"`
+	Checks      []QualityCheckType `json:"checks"`
+	IgnorePaths []string           `json:"ignore_paths,omitempty"`
+}
+
+// SkippedFor 判断该阶段是否因路径选择规则被跳过；变更文件未知时从不跳过
+func (st PipelineStage) SkippedFor(changedFiles []string) bool {
+	if len(st.IgnorePaths) == 0 || len(changedFiles) == 0 {
+		return false
+	}
+	for _, file := range changedFiles {
+		if !matchAnyPath(st.IgnorePaths, file) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchAnyPath 判断文件路径是否命中任一模式
+func matchAnyPath(patterns []string, file string) bool {
+	for _, pattern := range patterns {
+		switch {
+		case strings.HasSuffix(pattern, "/"):
+			if strings.HasPrefix(file, pattern) {
+				return true
+			}
+		case !strings.Contains(pattern, "/"):
+			if ok, _ := path.Match(pattern, path.Base(file)); ok {
+				return true
+			}
+		default:
+			if ok, _ := path.Match(pattern, file); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PipelineConfig 命名的检查流水线，阶段顺序即 StageOrder
+type PipelineConfig struct {
+	Name   string          `json:"name"`
+	Stages []PipelineStage `json:"stages"`
+}
+
+// PipelineSet 流水线配置文件的内容
+// Repositories 将仓库全名（支持 path.Match 通配符，如 "myorg/*"）映射到流水线名称
+// Default 为未匹配仓库使用的流水线名称，为空表示内置默认流水线
+type PipelineSet struct {
+	Default      string                     `json:"default"`
+	Pipelines    map[string]*PipelineConfig `json:"pipelines"`
+	Repositories map[string]string          `json:"repositories"`
+}
+
+// pipelineSet 当前生效的流水线配置，为空表示所有仓库使用内置默认流水线
+var (
+	pipelineMu  sync.RWMutex
+	pipelineSet *PipelineSet
+)
+
+// DefaultPipeline 返回内置默认流水线：基础CI、部署、专项测试三个阶段
+func DefaultPipeline() *PipelineConfig {
+	return &PipelineConfig{
+		Name: "default",
+		Stages: []PipelineStage{
+			{
+				Stage: StageTypeBasicCI,
+				Checks: []QualityCheckType{
+					QualityCheckTypeCompilation,
+					QualityCheckTypeCodeLint,
+					QualityCheckTypeSecurityScan,
+					QualityCheckTypeUnitTest,
+				},
+			},
+			{
+				Stage:  StageTypeDeployment,
+				Checks: []QualityCheckType{QualityCheckTypeDeployment},
+			},
+			{
+				Stage: StageTypeSpecializedTests,
+				Checks: []QualityCheckType{
+					QualityCheckTypeApiTest,
+					QualityCheckTypeModuleE2E,
+					QualityCheckTypeAgentE2E,
+					QualityCheckTypeAiE2E,
+				},
+			},
+		},
+	}
+}
+
+// LoadPipelineSet 从 JSON 文件加载并校验流水线配置
+func LoadPipelineSet(file string) (*PipelineSet, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read pipeline config: %w", err)
+	}
+	var set PipelineSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse pipeline config: %w", err)
+	}
+	if err := set.Validate(); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// Validate 校验流水线引用、阶段、检查类型和仓库通配符
+func (s *PipelineSet) Validate() error {
+	for name, p := range s.Pipelines {
+		if p == nil || len(p.Stages) == 0 {
+			return fmt.Errorf("pipeline %q has no stages", name)
+		}
+		if p.Name == "" {
+			p.Name = name
+		}
+		for _, stage := range p.Stages {
+			switch stage.Stage {
+			case StageTypeBasicCI, StageTypeDeployment, StageTypeSpecializedTests:
+			default:
+				return fmt.Errorf("pipeline %q: invalid stage: %s", name, stage.Stage)
+			}
+			for _, check := range stage.Checks {
+				if _, err := ParseQualityCheckType(string(check)); err != nil {
+					return fmt.Errorf("pipeline %q: %w", name, err)
+				}
+			}
+			for _, pattern := range stage.IgnorePaths {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return fmt.Errorf("pipeline %q: invalid ignore path %q: %w", name, pattern, err)
+				}
+			}
+		}
+	}
+	if s.Default != "" && s.Pipelines[s.Default] == nil {
+		return fmt.Errorf("default pipeline %q is not defined", s.Default)
+	}
+	for pattern, name := range s.Repositories {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid repository pattern %q: %w", pattern, err)
+		}
+		if s.Pipelines[name] == nil {
+			return fmt.Errorf("repository %q uses undefined pipeline %q", pattern, name)
+		}
+	}
+	return nil
+}
+
+// SetPipelineSet 设置当前生效的流水线配置，传入 nil 恢复内置默认流水线
+func SetPipelineSet(set *PipelineSet) {
+	pipelineMu.Lock()
+	defer pipelineMu.Unlock()
+	pipelineSet = set
+}
+
+// PipelineFingerprint 返回当前生效配置的指纹，未加载配置时为 "default"
+func PipelineFingerprint() string {
+	pipelineMu.RLock()
+	defer pipelineMu.RUnlock()
+	if pipelineSet == nil {
+		return "default"
+	}
+	return pipelineSet.Fingerprint()
+}
+
+// Fingerprint 返回配置内容的 sha256 摘要（前 12 位），用于确认重新加载后生效的配置
+func (s *PipelineSet) Fingerprint() string {
+	// map 按键排序编码，相同配置得到相同指纹
+	data, _ := json.Marshal(s)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// PipelineFor 返回仓库应使用的流水线
+// 精确匹配优先，其次是最长的通配符匹配，都未命中时使用配置的默认流水线或内置默认流水线
+func PipelineFor(repo string) *PipelineConfig {
+	pipelineMu.RLock()
+	defer pipelineMu.RUnlock()
+
+	if pipelineSet == nil {
+		return DefaultPipeline()
+	}
+	if name, ok := pipelineSet.Repositories[repo]; ok {
+		return pipelineSet.Pipelines[name]
+	}
+
+	patterns := make([]string, 0, len(pipelineSet.Repositories))
+	for pattern := range pipelineSet.Repositories {
+		if strings.ContainsAny(pattern, "*?[") {
+			patterns = append(patterns, pattern)
+		}
+	}
+	// 更长的通配符更具体；长度相同时按字典序保证结果稳定
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, repo); ok {
+			return pipelineSet.Pipelines[pipelineSet.Repositories[pattern]]
+		}
+	}
+
+	if pipelineSet.Default != "" {
+		return pipelineSet.Pipelines[pipelineSet.Default]
+	}
+	return DefaultPipeline()
+}
+
+// CreateChecks 按流水线定义为事件创建质量检查项
+func (p *PipelineConfig) CreateChecks(githubEventID string) []PRQualityCheck {
+	return p.CreateChecksForChanges(githubEventID, nil)
+}
+
+// CreateChecksForChanges 按流水线定义和变更文件创建质量检查项，跳过路径选择规则排除的阶段
+// 被跳过的阶段不重新编号，StageOrder 始终对应配置中的阶段位置
+func (p *PipelineConfig) CreateChecksForChanges(githubEventID string, changedFiles []string) []PRQualityCheck {
+	checks := []PRQualityCheck{}
+	now := Now()
+
+	for i, stage := range p.Stages {
+		if stage.SkippedFor(changedFiles) {
+			continue
+		}
+		for j, checkType := range stage.Checks {
+			checks = append(checks, PRQualityCheck{
+				ID:            0, // 将由存储层分配
+				GitHubEventID: githubEventID,
+				CheckType:     checkType,
+				CheckStatus:   QualityCheckStatusPending,
+				Stage:         stage.Stage,
+				StageOrder:    i + 1,
+				CheckOrder:    j + 1,
+				RetryCount:    0,
+				CreatedAt:     now,
+				UpdatedAt:     now,
+			})
+		}
+	}
+
+	return checks
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPipelineFor(t *testing.T) {
+	set := &PipelineSet{
+		Pipelines: map[string]*PipelineConfig{
+			"frontend": {Stages: []PipelineStage{
+				{Stage: StageTypeBasicCI, Checks: []QualityCheckType{QualityCheckTypeCodeLint, QualityCheckTypeUnitTest}},
+			}},
+			"org": {Stages: []PipelineStage{
+				{Stage: StageTypeBasicCI, Checks: []QualityCheckType{QualityCheckTypeCompilation}},
+				{Stage: StageTypeDeployment, Checks: []QualityCheckType{QualityCheckTypeDeployment}},
+			}},
+		},
+		Repositories: map[string]string{
+			"myorg/frontend": "frontend",
+			"myorg/*":        "org",
+		},
+	}
+	if err := set.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	SetPipelineSet(set)
+	defer SetPipelineSet(nil)
+
+	tests := []struct {
+		repo string
+		want string
+	}{
+		{"myorg/frontend", "frontend"},
+		{"myorg/backend", "org"},
+		{"other/repo", "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.repo, func(t *testing.T) {
+			if got := PipelineFor(tt.repo).Name; got != tt.want {
+				t.Errorf("PipelineFor(%q) = %q, want %q", tt.repo, got, tt.want)
+			}
+		})
+	}
+
+	checks := CreateChecksForRepository("evt-1", "myorg/backend", nil)
+	if len(checks) != 2 {
+		t.Fatalf("expected 2 checks, got %d", len(checks))
+	}
+	if checks[1].CheckType != QualityCheckTypeDeployment || checks[1].StageOrder != 2 || checks[1].CheckOrder != 1 {
+		t.Errorf("unexpected second check: %+v", checks[1])
+	}
+
+	set.Default = "org"
+	if got := PipelineFor("other/repo").Name; got != "org" {
+		t.Errorf("configured default: got %q, want org", got)
+	}
+}
+
+func TestLoadPipelineSet_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown pipeline", `{"pipelines":{},"repositories":{"a/b":"missing"}}`},
+		{"unknown check", `{"pipelines":{"p":{"stages":[{"stage":"basic_ci","checks":["fuzz"]}]}}}`},
+		{"unknown stage", `{"pipelines":{"p":{"stages":[{"stage":"release","checks":["compilation"]}]}}}`},
+		{"bad pattern", `{"pipelines":{"p":{"stages":[{"stage":"basic_ci","checks":["compilation"]}]}},"repositories":{"a/[":"p"}}`},
+		{"bad json", `{`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "pipelines.json")
+			if err := os.WriteFile(file, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadPipelineSet(file); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestLoadPipelineSet_Example(t *testing.T) {
+	set, err := LoadPipelineSet("../../../configs/pipelines.example.json")
+	if err != nil {
+		t.Fatalf("LoadPipelineSet: %v", err)
+	}
+	if set.Pipelines["frontend"].Name != "frontend" {
+		t.Errorf("pipeline name not filled from key: %q", set.Pipelines["frontend"].Name)
+	}
+}
+
+func TestPipelineStage_SkippedFor(t *testing.T) {
+	stage := PipelineStage{Stage: StageTypeSpecializedTests, IgnorePaths: []string{"docs/", "*.md", "assets/*.png"}}
+	tests := []struct {
+		name  string
+		files []string
+		want  bool
+	}{
+		{"docs only", []string{"docs/a/guide.txt", "README.md", "assets/logo.png"}, true},
+		{"code change", []string{"docs/guide.md", "main.go"}, false},
+		{"nested png not matched", []string{"assets/img/logo.png"}, false},
+		{"unknown files", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stage.SkippedFor(tt.files); got != tt.want {
+				t.Errorf("SkippedFor(%v) = %v, want %v", tt.files, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	simplified := ChangedFiles(map[string]interface{}{"changed_files": " a.go, b.md,,a.go "})
+	if got := strings.Join(simplified, ","); got != "a.go,b.md" {
+		t.Errorf("simplified format: %s", got)
+	}
+
+	webhook := ChangedFiles(map[string]interface{}{
+		"commits": []interface{}{
+			map[string]interface{}{"added": []interface{}{"new.go"}, "modified": []interface{}{"main.go"}},
+			map[string]interface{}{"removed": []interface{}{"old.go"}, "modified": []interface{}{"main.go"}},
+		},
+	})
+	if got := strings.Join(webhook, ","); got != "new.go,main.go,old.go" {
+		t.Errorf("webhook format: %s", got)
+	}
+}
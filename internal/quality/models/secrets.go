@@ -0,0 +1,186 @@
+package models
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// RedactedSecret 替换 payload 中疑似密钥的占位符
+const RedactedSecret = "[REDACTED]"
+
+// DefaultSecretPatterns 默认识别的密钥格式
+var DefaultSecretPatterns = []string{
+	`gh[pousr]_[A-Za-z0-9]{36,}`,   // GitHub token
+	`github_pat_[A-Za-z0-9_]{22,}`, // GitHub fine-grained token
+	`AKIA[0-9A-Z]{16}`,             // AWS access key ID
+	`xox[baprs]-[A-Za-z0-9-]{10,}`, // Slack token
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+}
+
+const (
+	// minEntropyTokenLen 参与熵检测的最短连续字符数
+	minEntropyTokenLen = 32
+	// minTokenEntropy 判定为高熵字符串的香农熵阈值（比特/字符），十六进制的 commit SHA 最多 4 比特，不会命中
+	minTokenEntropy = 4.5
+)
+
+// entropyCandidate 熵检测的候选片段：不含 / 和 .，避免把文件路径和 URL 当作密钥
+var entropyCandidate = regexp.MustCompile(`[A-Za-z0-9+=_\-]{32,}`)
+
+// SecretRedactor 在持久化前把 payload 字符串字段中的疑似密钥替换为 [REDACTED]
+type SecretRedactor struct {
+	patterns    []*regexp.Regexp
+	highEntropy bool
+}
+
+// NewSecretRedactor 编译密钥正则；highEntropy 为 true 时额外替换高熵的长字符串
+func NewSecretRedactor(patterns []string, highEntropy bool) (*SecretRedactor, error) {
+	r := &SecretRedactor{highEntropy: highEntropy}
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// LoadSecretPatterns 从文件读取密钥正则，每行一个，空行和 # 开头的行被忽略
+func LoadSecretPatterns(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open secret patterns: %w", err)
+	}
+	defer f.Close()
+
+	var patterns []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read secret patterns: %w", err)
+	}
+	return patterns, nil
+}
+
+var (
+	secretRedactorMu sync.RWMutex
+	secretRedactor   = mustDefaultSecretRedactor()
+)
+
+func mustDefaultSecretRedactor() *SecretRedactor {
+	r, err := NewSecretRedactor(DefaultSecretPatterns, true)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// SetSecretRedactor 设置全局 payload 密钥脱敏器，nil 表示不脱敏
+func SetSecretRedactor(r *SecretRedactor) {
+	secretRedactorMu.Lock()
+	defer secretRedactorMu.Unlock()
+	secretRedactor = r
+}
+
+// RedactSecrets 使用全局脱敏器处理 payload
+func RedactSecrets(data map[string]interface{}) map[string]interface{} {
+	secretRedactorMu.RLock()
+	r := secretRedactor
+	secretRedactorMu.RUnlock()
+	return r.Redact(data)
+}
+
+// Redact 返回脱敏后的 payload 副本，原始数据不会被修改；r 为 nil 时原样返回
+func (r *SecretRedactor) Redact(data map[string]interface{}) map[string]interface{} {
+	if r == nil || (len(r.patterns) == 0 && !r.highEntropy) {
+		return data
+	}
+	return r.redactValue(data).(map[string]interface{})
+}
+
+func (r *SecretRedactor) redactValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, child := range val {
+			out[k] = r.redactValue(child)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, child := range val {
+			out[i] = r.redactValue(child)
+		}
+		return out
+	case string:
+		return r.RedactString(val)
+	default:
+		return v
+	}
+}
+
+// RedactString 替换字符串中命中正则或高熵检测的片段
+func (r *SecretRedactor) RedactString(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, RedactedSecret)
+	}
+	if r.highEntropy && len(s) >= minEntropyTokenLen {
+		s = entropyCandidate.ReplaceAllStringFunc(s, func(token string) string {
+			if looksLikeSecret(token) {
+				return RedactedSecret
+			}
+			return token
+		})
+	}
+	return s
+}
+
+// looksLikeSecret 同时包含大小写字母和数字且香农熵超过阈值的片段视为密钥
+func looksLikeSecret(token string) bool {
+	var upper, lower, digit bool
+	for _, c := range token {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= '0' && c <= '9':
+			digit = true
+		}
+	}
+	return upper && lower && digit && shannonEntropy(token) >= minTokenEntropy
+}
+
+// shannonEntropy 计算字符串每个字符的香农熵（比特）
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, c := range s {
+		counts[c]++
+	}
+	n := float64(len(s))
+	var h float64
+	for _, c := range counts {
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
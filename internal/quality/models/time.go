@@ -5,29 +5,52 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 )
 
-// LocalTime 是一个自定义时间类型，用于处理JSON序列化时使用本地时区（Asia/Shanghai）
+// LocalTime 是一个自定义时间类型，JSON 序列化和数据库读写统一使用 SetTimeZone 配置的时区（默认 Asia/Shanghai）
 type LocalTime struct {
 	time.Time
 }
 
-// 定义上海时区
-var shanghaiLoc *time.Location
+// DefaultTimeZone 未配置时使用的时区
+const DefaultTimeZone = "Asia/Shanghai"
 
-func init() {
-	var err error
-	shanghaiLoc, err = time.LoadLocation("Asia/Shanghai")
+var (
+	timeZoneMu sync.RWMutex
+	timeZone   = defaultLocation()
+)
+
+// defaultLocation 加载上海时区，失败时使用固定的 +8 小时偏移
+func defaultLocation() *time.Location {
+	loc, err := time.LoadLocation(DefaultTimeZone)
 	if err != nil {
-		// 如果加载失败，使用固定的 +8 小时偏移
-		shanghaiLoc = time.FixedZone("CST", 8*60*60)
+		return time.FixedZone("CST", 8*60*60)
+	}
+	return loc
+}
+
+// SetTimeZone 设置 LocalTime 使用的时区，nil 表示恢复默认的上海时区
+func SetTimeZone(loc *time.Location) {
+	if loc == nil {
+		loc = defaultLocation()
 	}
+	timeZoneMu.Lock()
+	defer timeZoneMu.Unlock()
+	timeZone = loc
+}
+
+// TimeZone 返回 LocalTime 当前使用的时区
+func TimeZone() *time.Location {
+	timeZoneMu.RLock()
+	defer timeZoneMu.RUnlock()
+	return timeZone
 }
 
 // Now 返回当前本地时间
 func Now() LocalTime {
-	return LocalTime{time.Now().In(shanghaiLoc)}
+	return LocalTime{time.Now().In(TimeZone())}
 }
 
 // ParseLocalTime 解析字符串为 LocalTime
@@ -50,7 +73,7 @@ func ParseLocalTime(s string) (LocalTime, error) {
 	var t time.Time
 	var err error
 	for _, format := range formats {
-		t, err = time.ParseInLocation(format, s, shanghaiLoc)
+		t, err = time.ParseInLocation(format, s, TimeZone())
 		if err == nil {
 			return LocalTime{t}, nil
 		}
@@ -59,7 +82,7 @@ func ParseLocalTime(s string) (LocalTime, error) {
 	// 如果所有格式都失败，尝试直接解析
 	t, err = time.Parse(time.RFC3339, s)
 	if err == nil {
-		return LocalTime{t.In(shanghaiLoc)}, nil
+		return LocalTime{t.In(TimeZone())}, nil
 	}
 
 	return LocalTime{}, fmt.Errorf("unable to parse time: %s", s)
@@ -71,11 +94,10 @@ func (lt LocalTime) MarshalJSON() ([]byte, error) {
 		return []byte("null"), nil
 	}
 
-	// 转换为上海时区
-	localTime := lt.Time.In(shanghaiLoc)
+	// 转换为配置的时区
+	localTime := lt.Time.In(TimeZone())
 
-	// 格式化为带时区偏移的格式 (类似 RFC3339 但不使用 Z)
-	// 上海时区是 UTC+8，所以格式为: 2006-01-02T15:04:05+08:00
+	// 格式化为带时区偏移的格式 (类似 RFC3339 但不使用 Z)，如上海时区为: 2006-01-02T15:04:05+08:00
 	formatted := localTime.Format("2006-01-02T15:04:05-07:00")
 	return json.Marshal(formatted)
 }
@@ -106,7 +128,7 @@ func (lt *LocalTime) UnmarshalJSON(data []byte) error {
 	for _, format := range formats {
 		t, err = time.Parse(format, s)
 		if err == nil {
-			lt.Time = t.In(shanghaiLoc)
+			lt.Time = t.In(TimeZone())
 			return nil
 		}
 	}
@@ -127,7 +149,7 @@ func (lt *LocalTime) Scan(value interface{}) error {
 	}
 
 	if t, ok := value.(time.Time); ok {
-		lt.Time = t.In(shanghaiLoc)
+		lt.Time = t.In(TimeZone())
 		return nil
 	}
 
@@ -139,7 +161,7 @@ func (lt LocalTime) String() string {
 	if lt.Time.IsZero() {
 		return ""
 	}
-	return lt.Time.In(shanghaiLoc).Format("2006-01-02 15:04:05")
+	return lt.Time.In(TimeZone()).Format("2006-01-02 15:04:05")
 }
 
 // Format 按指定格式返回字符串
@@ -147,7 +169,7 @@ func (lt LocalTime) Format(layout string) string {
 	if lt.Time.IsZero() {
 		return ""
 	}
-	return lt.Time.In(shanghaiLoc).Format(layout)
+	return lt.Time.In(TimeZone()).Format(layout)
 }
 
 // IsZero 判断是否为零值
@@ -162,5 +184,5 @@ func (lt LocalTime) ToTime() time.Time {
 
 // FromTime 从标准 time.Time 创建 LocalTime
 func FromTime(t time.Time) LocalTime {
-	return LocalTime{t.In(shanghaiLoc)}
+	return LocalTime{t.In(TimeZone())}
 }
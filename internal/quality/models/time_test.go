@@ -0,0 +1,65 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// TestSetTimeZone 测试 LocalTime 的序列化、解析和数据库读取都使用配置的时区
+func TestSetTimeZone(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+	SetTimeZone(ny)
+	t.Cleanup(func() { SetTimeZone(nil) })
+
+	utc := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	lt := FromTime(utc)
+
+	data, err := json.Marshal(lt)
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+	if got, want := string(data), `"2024-01-15T07:00:00-05:00"`; got != want {
+		t.Errorf("MarshalJSON = %s, want %s", got, want)
+	}
+	if got, want := lt.String(), "2024-01-15 07:00:00"; got != want {
+		t.Errorf("String = %s, want %s", got, want)
+	}
+
+	var decoded LocalTime
+	if err := json.Unmarshal([]byte(`"2024-01-15T12:00:00Z"`), &decoded); err != nil {
+		t.Fatalf("UnmarshalJSON failed: %v", err)
+	}
+	if decoded.Location() != ny || !decoded.Equal(utc) {
+		t.Errorf("UnmarshalJSON = %v, want %v in America/New_York", decoded.Time, utc)
+	}
+
+	var scanned LocalTime
+	if err := scanned.Scan(driver.Value(utc)); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if scanned.Location() != ny {
+		t.Errorf("Scan location = %v, want America/New_York", scanned.Location())
+	}
+
+	if loc := Now().Location(); loc != ny {
+		t.Errorf("Now location = %v, want America/New_York", loc)
+	}
+
+	parsed, err := ParseLocalTime("2024-01-15 07:00:00")
+	if err != nil {
+		t.Fatalf("ParseLocalTime failed: %v", err)
+	}
+	if !parsed.Equal(utc) {
+		t.Errorf("ParseLocalTime = %v, want %v", parsed.Time, utc)
+	}
+
+	SetTimeZone(nil)
+	if got := TimeZone().String(); got != DefaultTimeZone && got != "CST" {
+		t.Errorf("SetTimeZone(nil) should restore the default, got %s", got)
+	}
+}
@@ -0,0 +1,302 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github-hub/internal/quality/logger"
+)
+
+const (
+	// DefaultMaxAttempts 默认最大投递次数，超过后进入死信列表
+	DefaultMaxAttempts = 5
+	// DefaultBaseBackoff 默认首次重试等待时间，之后按指数翻倍
+	DefaultBaseBackoff = 2 * time.Second
+	// maxBackoff 单次重试等待时间上限
+	maxBackoff = 10 * time.Minute
+)
+
+// Notification 待投递的出站通知
+type Notification struct {
+	ID          int64           `json:"id"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	LastError   string          `json:"last_error,omitempty"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// state 持久化到磁盘的队列状态
+type state struct {
+	NextID     int64          `json:"next_id"`
+	Pending    []Notification `json:"pending"`
+	DeadLetter []Notification `json:"dead_letter"`
+}
+
+// Dispatcher 后台投递出站通知，失败时按指数退避重试，超过最大次数后进入死信列表
+// 入队操作不会阻塞调用方；配置 statePath 时队列会持久化到该文件，重启后继续投递
+type Dispatcher struct {
+	URL         string
+	MaxAttempts int
+	BaseBackoff time.Duration
+
+	client    *http.Client
+	statePath string
+
+	mu    sync.Mutex
+	state state
+	wake  chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// NewDispatcher 创建通知投递器，statePath 为空时仅在内存中保存队列
+func NewDispatcher(url, statePath string, client *http.Client) (*Dispatcher, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	d := &Dispatcher{
+		URL:         url,
+		MaxAttempts: DefaultMaxAttempts,
+		BaseBackoff: DefaultBaseBackoff,
+		client:      client,
+		statePath:   statePath,
+		wake:        make(chan struct{}, 1),
+	}
+	if statePath != "" {
+		data, err := os.ReadFile(statePath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read notification state: %w", err)
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &d.state); err != nil {
+				return nil, fmt.Errorf("failed to parse notification state: %w", err)
+			}
+		}
+	}
+	return d, nil
+}
+
+// Start 启动后台投递协程
+func (d *Dispatcher) Start() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return
+	}
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+	go d.run(d.stop, d.done)
+}
+
+// Stop 停止后台投递协程，未投递的通知保留在队列中
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	stop, done := d.stop, d.done
+	d.stop, d.done = nil, nil
+	d.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// Enqueue 将通知加入队列，立即返回
+func (d *Dispatcher) Enqueue(payload interface{}) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	now := time.Now()
+	d.mu.Lock()
+	d.state.NextID++
+	d.state.Pending = append(d.state.Pending, Notification{
+		ID:          d.state.NextID,
+		Payload:     raw,
+		NextAttempt: now,
+		CreatedAt:   now,
+	})
+	d.persistLocked()
+	d.mu.Unlock()
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Pending 返回待投递通知的副本
+func (d *Dispatcher) Pending() []Notification {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Notification{}, d.state.Pending...)
+}
+
+// DeadLetters 返回死信列表的副本
+func (d *Dispatcher) DeadLetters() []Notification {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Notification{}, d.state.DeadLetter...)
+}
+
+func (d *Dispatcher) run(stop, done chan struct{}) {
+	defer close(done)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-d.wake:
+		case <-timer.C:
+		}
+
+		wait := d.dispatchDue(stop)
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+	}
+}
+
+// dispatchDue 投递所有到期的通知，返回距离下一条通知到期的等待时间
+func (d *Dispatcher) dispatchDue(stop chan struct{}) time.Duration {
+	for {
+		select {
+		case <-stop:
+			return time.Hour
+		default:
+		}
+
+		d.mu.Lock()
+		now := time.Now()
+		next := time.Hour
+		idx := -1
+		for i, n := range d.state.Pending {
+			if !n.NextAttempt.After(now) {
+				idx = i
+				break
+			}
+			if wait := n.NextAttempt.Sub(now); wait < next {
+				next = wait
+			}
+		}
+		if idx < 0 {
+			d.mu.Unlock()
+			return next
+		}
+		n := d.state.Pending[idx]
+		d.mu.Unlock()
+
+		err := d.deliver(n.Payload)
+
+		d.mu.Lock()
+		d.finishLocked(n.ID, err)
+		d.persistLocked()
+		d.mu.Unlock()
+	}
+}
+
+// finishLocked 根据投递结果更新队列：成功则移除，失败则安排重试或移入死信列表
+func (d *Dispatcher) finishLocked(id int64, err error) {
+	for i := range d.state.Pending {
+		if d.state.Pending[i].ID != id {
+			continue
+		}
+		if err == nil {
+			d.state.Pending = append(d.state.Pending[:i], d.state.Pending[i+1:]...)
+			return
+		}
+
+		n := &d.state.Pending[i]
+		n.Attempts++
+		n.LastError = err.Error()
+		if n.Attempts >= d.maxAttempts() {
+			logger.WithFields(map[string]interface{}{
+				"notification_id": n.ID,
+				"attempts":        n.Attempts,
+				"error":           n.LastError,
+			}).Warn("Notification moved to dead-letter")
+			d.state.DeadLetter = append(d.state.DeadLetter, *n)
+			d.state.Pending = append(d.state.Pending[:i], d.state.Pending[i+1:]...)
+			return
+		}
+		n.NextAttempt = time.Now().Add(d.backoff(n.Attempts))
+		return
+	}
+}
+
+func (d *Dispatcher) deliver(payload json.RawMessage) error {
+	resp, err := d.client.Post(d.URL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("receiver returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (d *Dispatcher) maxAttempts() int {
+	if d.MaxAttempts <= 0 {
+		return DefaultMaxAttempts
+	}
+	return d.MaxAttempts
+}
+
+// backoff 返回第 attempts 次失败后的等待时间：BaseBackoff * 2^(attempts-1)
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	base := d.BaseBackoff
+	if base <= 0 {
+		base = DefaultBaseBackoff
+	}
+	wait := base
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
+
+// persistLocked 将队列状态写入磁盘（先写临时文件再重命名）
+func (d *Dispatcher) persistLocked() {
+	if d.statePath == "" {
+		return
+	}
+	data, err := json.Marshal(d.state)
+	if err != nil {
+		logger.Errorf("Failed to marshal notification state: %v", err)
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(d.statePath), ".notify-*.json")
+	if err != nil {
+		logger.Errorf("Failed to persist notification state: %v", err)
+		return
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil {
+		os.Remove(tmp.Name())
+		logger.Errorf("Failed to persist notification state: %v %v", werr, cerr)
+		return
+	}
+	if err := os.Rename(tmp.Name(), d.statePath); err != nil {
+		os.Remove(tmp.Name())
+		logger.Errorf("Failed to persist notification state: %v", err)
+	}
+}
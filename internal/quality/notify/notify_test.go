@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFor 轮询直到条件满足或超时
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("condition not met before timeout")
+}
+
+func TestDispatcher_RetriesUntilDelivered(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	d, err := NewDispatcher(receiver.URL, "", receiver.Client())
+	if err != nil {
+		t.Fatalf("NewDispatcher: %v", err)
+	}
+	d.BaseBackoff = time.Millisecond
+	d.MaxAttempts = 5
+	d.Start()
+	defer d.Stop()
+
+	if err := d.Enqueue(map[string]interface{}{"event_id": "abc"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	waitFor(t, func() bool { return len(d.Pending()) == 0 })
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("expected 3 delivery attempts, got %d", got)
+	}
+	if dl := d.DeadLetters(); len(dl) != 0 {
+		t.Errorf("expected empty dead-letter list, got %d entries", len(dl))
+	}
+}
+
+func TestDispatcher_DeadLetterAfterMaxAttempts(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	statePath := filepath.Join(t.TempDir(), "notify.json")
+	d, err := NewDispatcher(receiver.URL, statePath, receiver.Client())
+	if err != nil {
+		t.Fatalf("NewDispatcher: %v", err)
+	}
+	d.BaseBackoff = time.Millisecond
+	d.MaxAttempts = 3
+	d.Start()
+
+	if err := d.Enqueue(map[string]interface{}{"event_id": "xyz"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	waitFor(t, func() bool { return len(d.DeadLetters()) == 1 })
+	d.Stop()
+
+	dl := d.DeadLetters()[0]
+	if dl.Attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", dl.Attempts)
+	}
+	if dl.LastError == "" {
+		t.Error("expected last_error to be recorded")
+	}
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("expected 3 delivery attempts, got %d", got)
+	}
+
+	// 死信列表应持久化，重启后仍可查询
+	reloaded, err := NewDispatcher(receiver.URL, statePath, receiver.Client())
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if len(reloaded.DeadLetters()) != 1 || len(reloaded.Pending()) != 0 {
+		t.Errorf("unexpected reloaded state: dead=%d pending=%d", len(reloaded.DeadLetters()), len(reloaded.Pending()))
+	}
+}
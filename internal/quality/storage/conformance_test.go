@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github-hub/internal/quality/models"
+)
+
+// conformanceStorages 返回需要做一致性校验的存储实现
+// MySQL 仅在设置 QUALITY_TEST_MYSQL_DSN 时参与（库表需已初始化，测试会清空事件）
+func conformanceStorages(t *testing.T) map[string]Storage {
+	t.Helper()
+	storages := map[string]Storage{"mock": NewMockStorage()}
+
+	dsn := os.Getenv("QUALITY_TEST_MYSQL_DSN")
+	if dsn == "" {
+		return storages
+	}
+	s, err := NewMySQLStorage(dsn)
+	if err != nil {
+		t.Fatalf("NewMySQLStorage failed: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if err := s.DeleteAllEvents(context.Background()); err != nil {
+		t.Fatalf("DeleteAllEvents failed: %v", err)
+	}
+	storages["mysql"] = s
+	return storages
+}
+
+// eventIDs 提取事件的 event_id 序列，用于比较排序
+func eventIDs(events []*models.GitHubEvent) []string {
+	ids := make([]string, len(events))
+	for i, e := range events {
+		ids[i] = e.EventID
+	}
+	return ids
+}
+
+// TestStorageConformance_ListOrdering 测试各存储对同一批写入返回相同的列表顺序
+func TestStorageConformance_ListOrdering(t *testing.T) {
+	base := models.Now()
+	// 故意打乱 created_at，确保排序依据是 id 而不是时间
+	offsets := []int{3, 0, 4, 1, 2}
+	want := []string{"order-4", "order-3", "order-2", "order-1", "order-0"}
+
+	for name, s := range conformanceStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			for i, off := range offsets {
+				created := models.LocalTime{Time: base.Add(time.Duration(off) * time.Minute)}
+				event := &models.GitHubEvent{
+					EventID:     fmt.Sprintf("order-%d", i),
+					EventType:   models.EventTypePush,
+					EventStatus: models.EventStatusPending,
+					Repository:  "test/repo",
+					Branch:      "main",
+					Payload:     []byte(`{}`),
+					CreatedAt:   created,
+					UpdatedAt:   created,
+				}
+				if err := s.CreateEvent(context.Background(), event); err != nil {
+					t.Fatalf("CreateEvent failed: %v", err)
+				}
+			}
+
+			all, err := s.ListEvents(context.Background())
+			if err != nil {
+				t.Fatalf("ListEvents failed: %v", err)
+			}
+			assertOrder(t, "ListEvents", eventIDs(all), want)
+
+			page, total, err := s.ListEventsPaginated(context.Background(), 1, 3)
+			if err != nil {
+				t.Fatalf("ListEventsPaginated failed: %v", err)
+			}
+			if total != len(want) {
+				t.Errorf("expected total %d, got %d", len(want), total)
+			}
+			assertOrder(t, "ListEventsPaginated", eventIDs(page), want[1:4])
+
+			from := base.Add(-time.Hour)
+			to := base.Add(time.Hour)
+			ranged, err := s.ListEventsFiltered(context.Background(), EventFilter{From: from, To: to})
+			if err != nil {
+				t.Fatalf("ListEventsFiltered failed: %v", err)
+			}
+			assertOrder(t, "ListEventsFiltered", eventIDs(ranged), want)
+		})
+	}
+}
+
+// TestStorageConformance_LatestEventPerRepository 测试每个仓库只返回 id 最大的事件，按 id 降序
+func TestStorageConformance_LatestEventPerRepository(t *testing.T) {
+	repos := []string{"org/a", "org/b", "org/a", "org/b", "org/a"}
+	want := []string{"latest-4", "latest-3"}
+
+	for name, s := range conformanceStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			for i, repo := range repos {
+				now := models.Now()
+				event := &models.GitHubEvent{
+					EventID:     fmt.Sprintf("latest-%d", i),
+					EventType:   models.EventTypePush,
+					EventStatus: models.EventStatusPending,
+					Repository:  repo,
+					Branch:      "main",
+					Payload:     []byte(`{}`),
+					CreatedAt:   now,
+					UpdatedAt:   now,
+				}
+				if err := s.CreateEvent(context.Background(), event); err != nil {
+					t.Fatalf("CreateEvent failed: %v", err)
+				}
+			}
+
+			latest, err := s.LatestEventPerRepository(context.Background())
+			if err != nil {
+				t.Fatalf("LatestEventPerRepository failed: %v", err)
+			}
+			assertOrder(t, "LatestEventPerRepository", eventIDs(latest), want)
+			if latest[0].Repository != "org/a" || latest[1].Repository != "org/b" {
+				t.Errorf("unexpected repositories %s, %s", latest[0].Repository, latest[1].Repository)
+			}
+		})
+	}
+}
+
+// TestSortEventsNewestFirst_TieBreak 测试 id 相同时按 event_id 升序排列
+func TestSortEventsNewestFirst_TieBreak(t *testing.T) {
+	events := []*models.GitHubEvent{
+		{ID: 1, EventID: "b"},
+		{ID: 2, EventID: "c"},
+		{ID: 2, EventID: "a"},
+		{ID: 1, EventID: "a"},
+	}
+	sortEventsNewestFirst(events)
+	assertOrder(t, "sortEventsNewestFirst", eventIDs(events), []string{"a", "c", "a", "b"})
+	if events[0].ID != 2 || events[2].ID != 1 {
+		t.Errorf("expected id DESC, got %d, %d", events[0].ID, events[2].ID)
+	}
+}
+
+func assertOrder(t *testing.T, label string, got, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: expected %v, got %v", label, want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("%s: expected %v, got %v", label, want, got)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Migration 一次数据库结构变更；Version 递增且发布后不可修改
+type Migration struct {
+	Version    int
+	Name       string
+	Statements []string
+}
+
+// Migrations 全部迁移，按版本升序执行
+// 前两个版本与 scripts/init-mysql.sql 一致，对已用该脚本初始化的库是空操作
+var Migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_github_events",
+		Statements: []string{`CREATE TABLE IF NOT EXISTS github_events (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    event_id VARCHAR(36) NOT NULL UNIQUE,
+    event_type VARCHAR(50) NOT NULL,
+    event_status VARCHAR(50) NOT NULL,
+    repository VARCHAR(255) NOT NULL,
+    branch VARCHAR(255) NOT NULL,
+    target_branch VARCHAR(255),
+    commit_sha VARCHAR(255),
+    pr_number INT,
+    action VARCHAR(50),
+    pusher VARCHAR(255),
+    author VARCHAR(255),
+    payload JSON,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    processed_at TIMESTAMP NULL,
+    INDEX idx_event_id (event_id),
+    INDEX idx_event_type (event_type),
+    INDEX idx_event_status (event_status),
+    INDEX idx_repository (repository)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`},
+	},
+	{
+		Version: 2,
+		Name:    "create_pr_quality_checks",
+		Statements: []string{`CREATE TABLE IF NOT EXISTS pr_quality_checks (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    github_event_id VARCHAR(36) NOT NULL,
+    check_type VARCHAR(50) NOT NULL,
+    check_status VARCHAR(50) NOT NULL,
+    stage VARCHAR(50) NOT NULL,
+    stage_order INT NOT NULL,
+    check_order INT NOT NULL,
+    started_at TIMESTAMP NULL,
+    completed_at TIMESTAMP NULL,
+    duration_seconds DOUBLE,
+    error_message TEXT,
+    output TEXT,
+    retry_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_github_event_id (github_event_id),
+    INDEX idx_check_type (check_type),
+    INDEX idx_check_status (check_status),
+    INDEX idx_stage (stage),
+    FOREIGN KEY (github_event_id) REFERENCES github_events(event_id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`},
+	},
+	{
+		Version:    3,
+		Name:       "add_github_events_run_count",
+		Statements: []string{`ALTER TABLE github_events ADD COLUMN run_count INT NOT NULL DEFAULT 0`},
+	},
+	{
+		Version:    4,
+		Name:       "add_github_events_changed_files",
+		Statements: []string{`ALTER TABLE github_events ADD COLUMN changed_files JSON NULL`},
+	},
+	{
+		Version:    5,
+		Name:       "add_github_events_skip_reason",
+		Statements: []string{`ALTER TABLE github_events ADD COLUMN skip_reason VARCHAR(255) NULL`},
+	},
+	{
+		Version:    6,
+		Name:       "add_github_events_error_message",
+		Statements: []string{`ALTER TABLE github_events ADD COLUMN error_message TEXT NULL`},
+	},
+	{
+		Version:    7,
+		Name:       "add_github_events_retry_count",
+		Statements: []string{`ALTER TABLE github_events ADD COLUMN retry_count INT NOT NULL DEFAULT 0`},
+	},
+}
+
+// MigrationTarget 迁移的目标库
+// 规划阶段只调用 AppliedMigrations，不会修改数据库
+type MigrationTarget interface {
+	// AppliedMigrations 返回已执行的迁移版本；schema_migrations 表不存在时返回空集合
+	AppliedMigrations() (map[int]bool, error)
+	// ApplyMigration 执行一个迁移并记录到 schema_migrations
+	ApplyMigration(m Migration) error
+}
+
+// PlanMigrations 返回尚未执行的迁移，按版本升序
+func PlanMigrations(target MigrationTarget, all []Migration) ([]Migration, error) {
+	applied, err := target.AppliedMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	var pending []Migration
+	for _, m := range all {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Version < pending[j].Version })
+	return pending, nil
+}
+
+// ApplyMigrations 依次执行计划中的迁移，遇到错误即停止
+func ApplyMigrations(target MigrationTarget, plan []Migration) error {
+	for _, m := range plan {
+		if err := target.ApplyMigration(m); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// Migrate 规划并执行迁移；dryRun 为 true 时只把待执行的迁移及其 DDL 写入 report，不修改数据库
+func Migrate(target MigrationTarget, dryRun bool, report io.Writer) ([]Migration, error) {
+	plan, err := PlanMigrations(target, Migrations)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		WriteMigrationPlan(report, plan)
+		return plan, nil
+	}
+	return plan, ApplyMigrations(target, plan)
+}
+
+// WriteMigrationPlan 输出迁移计划报告
+func WriteMigrationPlan(w io.Writer, plan []Migration) {
+	if len(plan) == 0 {
+		fmt.Fprintln(w, "No pending migrations.")
+		return
+	}
+	fmt.Fprintf(w, "%d pending migration(s):\n", len(plan))
+	for _, m := range plan {
+		fmt.Fprintf(w, "\n-- %d %s\n", m.Version, m.Name)
+		for _, stmt := range m.Statements {
+			fmt.Fprintf(w, "%s;\n", strings.TrimSpace(stmt))
+		}
+	}
+}
+
+// mysqlErrNoSuchTable 表不存在的 MySQL 错误码
+const mysqlErrNoSuchTable = 1146
+
+// AppliedMigrations 读取 schema_migrations 中已执行的版本
+func (s *MySQLStorage) AppliedMigrations() (map[int]bool, error) {
+	applied := make(map[int]bool)
+	rows, err := s.db.Query("SELECT version FROM schema_migrations")
+	if err != nil {
+		var me *mysql.MySQLError
+		if errors.As(err, &me) && me.Number == mysqlErrNoSuchTable {
+			return applied, nil
+		}
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to scan migration version: %w", err)
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// ApplyMigration 执行迁移语句并记录版本
+// MySQL 的 DDL 会隐式提交，无法放在事务中，失败时需人工检查后重试
+func (s *MySQLStorage) ApplyMigration(m Migration) error {
+	if _, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	for _, stmt := range m.Statements {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	if _, err := s.db.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.Version, m.Name); err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeMigrationTarget 模拟 schema_migrations 表
+type fakeMigrationTarget struct {
+	applied map[int]bool
+	calls   []int
+	failOn  int
+}
+
+func (f *fakeMigrationTarget) AppliedMigrations() (map[int]bool, error) {
+	out := make(map[int]bool, len(f.applied))
+	for v := range f.applied {
+		out[v] = true
+	}
+	return out, nil
+}
+
+func (f *fakeMigrationTarget) ApplyMigration(m Migration) error {
+	f.calls = append(f.calls, m.Version)
+	if m.Version == f.failOn {
+		return errors.New("boom")
+	}
+	f.applied[m.Version] = true
+	return nil
+}
+
+// TestMigrate_DryRun 测试 dry-run 只列出待执行迁移，不修改 schema_migrations
+func TestMigrate_DryRun(t *testing.T) {
+	target := &fakeMigrationTarget{applied: map[int]bool{1: true}}
+	var report bytes.Buffer
+
+	plan, err := Migrate(target, true, &report)
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if len(plan) != len(Migrations)-1 || plan[0].Version != 2 {
+		t.Fatalf("expected every migration after version 1 to be pending, got %+v", plan)
+	}
+	if len(target.calls) != 0 || len(target.applied) != 1 {
+		t.Errorf("dry-run must not apply migrations, applied %v", target.calls)
+	}
+	out := report.String()
+	if !strings.Contains(out, "-- 2 create_pr_quality_checks") || !strings.Contains(out, "CREATE TABLE IF NOT EXISTS pr_quality_checks") {
+		t.Errorf("report should list the pending migration and its DDL:\n%s", out)
+	}
+	if strings.Contains(out, "create_github_events") {
+		t.Errorf("report should not list applied migrations:\n%s", out)
+	}
+}
+
+// TestMigrate_Apply 测试按版本顺序执行，失败后停止
+func TestMigrate_Apply(t *testing.T) {
+	target := &fakeMigrationTarget{applied: map[int]bool{}}
+	if _, err := Migrate(target, false, nil); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if len(target.applied) != len(Migrations) {
+		t.Errorf("expected all migrations applied, got %v", target.applied)
+	}
+
+	plan, err := PlanMigrations(target, Migrations)
+	if err != nil || len(plan) != 0 {
+		t.Errorf("expected nothing pending after apply, got %v (%v)", plan, err)
+	}
+	var report bytes.Buffer
+	WriteMigrationPlan(&report, plan)
+	if !strings.Contains(report.String(), "No pending migrations") {
+		t.Errorf("unexpected empty report %q", report.String())
+	}
+
+	failing := &fakeMigrationTarget{applied: map[int]bool{}, failOn: 1}
+	if _, err := Migrate(failing, false, nil); err == nil {
+		t.Fatal("expected migration error")
+	}
+	if len(failing.calls) != 1 {
+		t.Errorf("expected to stop after the failed migration, got calls %v", failing.calls)
+	}
+}
@@ -1,13 +1,14 @@
 package storage
 
 import (
-	"errors"
+	"context"
+	"fmt"
 	"time"
 
 	"github-hub/internal/quality/models"
 )
 
-// MockStorage 模拟存储实现，用于测试
+// MockStorage 模拟存储实现，用于测试；操作在内存中立即完成，忽略 ctx
 type MockStorage struct {
 	events        map[int]*models.GitHubEvent
 	eventsByID    map[string]*models.GitHubEvent
@@ -16,6 +17,9 @@ type MockStorage struct {
 	nextCheckID   int
 	createError   error
 	getError      error
+	pingError     error
+	maxEvents     int // 最多保留的事件数，<= 0 表示不限制
+	oldestID      int // 淘汰游标：不小于它的最小事件 ID 即最旧的事件
 }
 
 // NewMockStorage 创建新的模拟存储
@@ -26,11 +30,12 @@ func NewMockStorage() *MockStorage {
 		qualityChecks: make(map[int]*models.PRQualityCheck),
 		nextEventID:   1,
 		nextCheckID:   1,
+		oldestID:      1,
 	}
 }
 
 // CreateEvent 创建事件
-func (m *MockStorage) CreateEvent(event *models.GitHubEvent) error {
+func (m *MockStorage) CreateEvent(ctx context.Context, event *models.GitHubEvent) error {
 	if m.createError != nil {
 		return m.createError
 	}
@@ -49,45 +54,80 @@ func (m *MockStorage) CreateEvent(event *models.GitHubEvent) error {
 		m.qualityChecks[check.ID] = check
 	}
 
+	m.evictOverflow()
+	return nil
+}
+
+// CreateEvents 批量创建事件
+func (m *MockStorage) CreateEvents(ctx context.Context, events []*models.GitHubEvent) error {
+	if m.createError != nil {
+		return m.createError
+	}
+	for _, event := range events {
+		if err := m.CreateEvent(ctx, event); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // GetEvent 获取事件
-func (m *MockStorage) GetEvent(id int) (*models.GitHubEvent, error) {
+func (m *MockStorage) GetEvent(ctx context.Context, id int) (*models.GitHubEvent, error) {
 	if m.getError != nil {
 		return nil, m.getError
 	}
 
 	event, ok := m.events[id]
 	if !ok {
-		return nil, errors.New("event not found")
+		return nil, ErrEventNotFound
 	}
 	return event, nil
 }
 
 // GetEventByEventID 通过 event_id 获取事件
-func (m *MockStorage) GetEventByEventID(eventID string) (*models.GitHubEvent, error) {
+func (m *MockStorage) GetEventByEventID(ctx context.Context, eventID string) (*models.GitHubEvent, error) {
 	for _, event := range m.events {
 		if event.EventID == eventID {
 			return event, nil
 		}
 	}
-	return nil, errors.New("event not found")
+	return nil, ErrEventNotFound
 }
 
 // ListEvents 列出所有事件
-func (m *MockStorage) ListEvents() ([]*models.GitHubEvent, error) {
+func (m *MockStorage) ListEvents(ctx context.Context) ([]*models.GitHubEvent, error) {
 	events := make([]*models.GitHubEvent, 0, len(m.events))
 	for _, event := range m.events {
 		events = append(events, event)
 	}
+	sortEventsNewestFirst(events)
 	return events, nil
 }
 
+// ListEventsFiltered 列出满足过滤条件的事件
+func (m *MockStorage) ListEventsFiltered(ctx context.Context, filter EventFilter) ([]*models.GitHubEvent, error) {
+	events := make([]*models.GitHubEvent, 0, len(m.events))
+	for _, event := range m.events {
+		if filter.Matches(event) {
+			events = append(events, event)
+		}
+	}
+	sortEventsNewestFirst(events)
+	return events, nil
+}
+
+// ListPendingEvents 列出超过 olderThan 仍为 pending 的事件
+func (m *MockStorage) ListPendingEvents(ctx context.Context, olderThan time.Duration) ([]*models.GitHubEvent, error) {
+	return m.ListEventsFiltered(ctx, EventFilter{
+		Status: string(models.EventStatusPending),
+		To:     time.Now().Add(-olderThan),
+	})
+}
+
 // UpdateEvent 更新事件
-func (m *MockStorage) UpdateEvent(event *models.GitHubEvent) error {
+func (m *MockStorage) UpdateEvent(ctx context.Context, event *models.GitHubEvent) error {
 	if _, ok := m.events[event.ID]; !ok {
-		return errors.New("event not found")
+		return ErrEventNotFound
 	}
 	m.events[event.ID] = event
 	m.eventsByID[event.EventID] = event
@@ -95,10 +135,10 @@ func (m *MockStorage) UpdateEvent(event *models.GitHubEvent) error {
 }
 
 // DeleteEvent 删除事件
-func (m *MockStorage) DeleteEvent(id int) error {
+func (m *MockStorage) DeleteEvent(ctx context.Context, id int) error {
 	event, ok := m.events[id]
 	if !ok {
-		return errors.New("event not found")
+		return ErrEventNotFound
 	}
 
 	delete(m.events, id)
@@ -107,7 +147,7 @@ func (m *MockStorage) DeleteEvent(id int) error {
 }
 
 // DeleteAllEvents 删除所有事件
-func (m *MockStorage) DeleteAllEvents() error {
+func (m *MockStorage) DeleteAllEvents(ctx context.Context) error {
 	m.events = make(map[int]*models.GitHubEvent)
 	m.eventsByID = make(map[string]*models.GitHubEvent)
 	m.qualityChecks = make(map[int]*models.PRQualityCheck)
@@ -115,7 +155,7 @@ func (m *MockStorage) DeleteAllEvents() error {
 }
 
 // CreateQualityCheck 创建质量检查
-func (m *MockStorage) CreateQualityCheck(check *models.PRQualityCheck) error {
+func (m *MockStorage) CreateQualityCheck(ctx context.Context, check *models.PRQualityCheck) error {
 	check.ID = m.nextCheckID
 	m.nextCheckID++
 	m.qualityChecks[check.ID] = check
@@ -123,36 +163,70 @@ func (m *MockStorage) CreateQualityCheck(check *models.PRQualityCheck) error {
 }
 
 // GetQualityCheck 获取质量检查
-func (m *MockStorage) GetQualityCheck(id int) (*models.PRQualityCheck, error) {
+func (m *MockStorage) GetQualityCheck(ctx context.Context, id int) (*models.PRQualityCheck, error) {
 	check, ok := m.qualityChecks[id]
 	if !ok {
-		return nil, errors.New("quality check not found")
+		return nil, ErrCheckNotFound
 	}
 	return check, nil
 }
 
 // ListQualityChecksByEventID 列出事件的所有质量检查
-func (m *MockStorage) ListQualityChecksByEventID(eventID string) ([]models.PRQualityCheck, error) {
+func (m *MockStorage) ListQualityChecksByEventID(ctx context.Context, eventID string) ([]models.PRQualityCheck, error) {
 	var checks []models.PRQualityCheck
 	for _, check := range m.qualityChecks {
 		if check.GitHubEventID == eventID {
 			checks = append(checks, *check)
 		}
 	}
+	sortChecksByStage(checks)
 	return checks, nil
 }
 
+// ListQualityChecksByEventIDPaginated 分页列出事件的质量检查
+func (m *MockStorage) ListQualityChecksByEventIDPaginated(ctx context.Context, eventID string, offset, limit int) ([]models.PRQualityCheck, int, error) {
+	checks, _ := m.ListQualityChecksByEventID(ctx, eventID)
+	total := len(checks)
+
+	start := offset
+	if start > total {
+		start = total
+	}
+	end := start + limit
+	if end > total {
+		end = total
+	}
+
+	if start >= end {
+		return []models.PRQualityCheck{}, total, nil
+	}
+
+	return checks[start:end], total, nil
+}
+
 // UpdateQualityCheck 更新质量检查
-func (m *MockStorage) UpdateQualityCheck(check *models.PRQualityCheck) error {
+func (m *MockStorage) UpdateQualityCheck(ctx context.Context, check *models.PRQualityCheck) error {
 	if _, ok := m.qualityChecks[check.ID]; !ok {
-		return errors.New("quality check not found")
+		return ErrCheckNotFound
 	}
 	m.qualityChecks[check.ID] = check
 	return nil
 }
 
+// AppendQualityCheckOutput 追加质量检查输出
+func (m *MockStorage) AppendQualityCheckOutput(ctx context.Context, id int, chunk string) (*models.PRQualityCheck, error) {
+	check, ok := m.qualityChecks[id]
+	if !ok {
+		return nil, ErrCheckNotFound
+	}
+	output := models.AppendCheckOutput(check.Output, chunk)
+	check.Output = &output
+	check.UpdatedAt = models.Now()
+	return check, nil
+}
+
 // CleanupExpired 清理过期数据
-func (m *MockStorage) CleanupExpired(ttl time.Duration) error {
+func (m *MockStorage) CleanupExpired(ctx context.Context, ttl time.Duration) error {
 	now := time.Now()
 	for id, event := range m.events {
 		if now.Sub(event.UpdatedAt.ToTime()) > ttl {
@@ -163,6 +237,33 @@ func (m *MockStorage) CleanupExpired(ttl time.Duration) error {
 	return nil
 }
 
+// SetMaxEvents 设置最多保留的事件数，超出时淘汰最旧的事件及其质量检查；n <= 0 表示不限制
+// 适用于长时间运行的开发服务器，避免内存无限增长
+func (m *MockStorage) SetMaxEvents(n int) {
+	m.maxEvents = n
+	m.evictOverflow()
+}
+
+// evictOverflow 按 ID 从小到大淘汰事件，直到数量不超过上限
+func (m *MockStorage) evictOverflow() {
+	if m.maxEvents <= 0 {
+		return
+	}
+	for len(m.events) > m.maxEvents {
+		for m.events[m.oldestID] == nil {
+			m.oldestID++
+		}
+		event := m.events[m.oldestID]
+		delete(m.events, event.ID)
+		delete(m.eventsByID, event.EventID)
+		for id, check := range m.qualityChecks {
+			if check.GitHubEventID == event.EventID {
+				delete(m.qualityChecks, id)
+			}
+		}
+	}
+}
+
 // SetCreateError 设置创建错误（用于测试错误处理）
 func (m *MockStorage) SetCreateError(err error) {
 	m.createError = err
@@ -173,22 +274,24 @@ func (m *MockStorage) SetGetError(err error) {
 	m.getError = err
 }
 
+// SetPingError 设置健康检查错误（用于模拟数据库不可达）
+func (m *MockStorage) SetPingError(err error) {
+	m.pingError = err
+}
+
+// Ping 健康检查
+func (m *MockStorage) Ping(ctx context.Context) error {
+	return m.pingError
+}
+
 // ListEventsPaginated 分页查询事件
-func (m *MockStorage) ListEventsPaginated(offset, limit int) ([]*models.GitHubEvent, int, error) {
+func (m *MockStorage) ListEventsPaginated(ctx context.Context, offset, limit int) ([]*models.GitHubEvent, int, error) {
 	events := make([]*models.GitHubEvent, 0, len(m.events))
 	for _, event := range m.events {
 		events = append(events, event)
 	}
 
-	// 按 ID 降序排序（使用冒泡排序）
-	n := len(events)
-	for i := 0; i < n-1; i++ {
-		for j := 0; j < n-i-1; j++ {
-			if events[j].ID < events[j+1].ID {
-				events[j], events[j+1] = events[j+1], events[j]
-			}
-		}
-	}
+	sortEventsNewestFirst(events)
 
 	total := len(events)
 
@@ -210,10 +313,10 @@ func (m *MockStorage) ListEventsPaginated(offset, limit int) ([]*models.GitHubEv
 }
 
 // UpdateEventStatus 更新事件状态
-func (m *MockStorage) UpdateEventStatus(id int, status models.EventStatus, processedAt *models.LocalTime) error {
+func (m *MockStorage) UpdateEventStatus(ctx context.Context, id int, status models.EventStatus, processedAt *models.LocalTime) error {
 	event, ok := m.events[id]
 	if !ok {
-		return errors.New("event not found")
+		return ErrEventNotFound
 	}
 
 	event.EventStatus = status
@@ -226,11 +329,121 @@ func (m *MockStorage) UpdateEventStatus(id int, status models.EventStatus, proce
 	return nil
 }
 
+// UpdateEventError 更新事件的错误信息和重试次数
+func (m *MockStorage) UpdateEventError(ctx context.Context, id int, errorMessage *string, retryCount *int) error {
+	event, ok := m.events[id]
+	if !ok {
+		return ErrEventNotFound
+	}
+
+	if errorMessage != nil {
+		if *errorMessage == "" {
+			event.ErrorMessage = nil
+		} else {
+			msg := *errorMessage
+			event.ErrorMessage = &msg
+		}
+	}
+	if retryCount != nil {
+		event.RetryCount = *retryCount
+	}
+	event.UpdatedAt = models.Now()
+
+	return nil
+}
+
+// ReplayEventInPlace 用新的检查项替换事件原有的检查并重置事件
+func (m *MockStorage) ReplayEventInPlace(ctx context.Context, id int, checks []models.PRQualityCheck) error {
+	event, ok := m.events[id]
+	if !ok {
+		return ErrEventNotFound
+	}
+
+	for checkID, check := range m.qualityChecks {
+		if check.GitHubEventID == event.EventID {
+			delete(m.qualityChecks, checkID)
+		}
+	}
+	event.QualityChecks = checks
+	for i := range event.QualityChecks {
+		check := &event.QualityChecks[i]
+		check.GitHubEventID = event.EventID
+		check.ID = m.nextCheckID
+		m.nextCheckID++
+		m.qualityChecks[check.ID] = check
+	}
+
+	event.EventStatus = models.EventStatusPending
+	event.ProcessedAt = nil
+	event.ErrorMessage = nil
+	event.RunCount++
+	event.UpdatedAt = models.Now()
+	return nil
+}
+
+// AttachQualityChecks 为还没有检查项的事件写入检查项
+func (m *MockStorage) AttachQualityChecks(ctx context.Context, id int, checks []models.PRQualityCheck) error {
+	event, ok := m.events[id]
+	if !ok {
+		return ErrEventNotFound
+	}
+	for _, check := range m.qualityChecks {
+		if check.GitHubEventID == event.EventID {
+			return fmt.Errorf("event %d already has quality checks: %w", id, ErrConflict)
+		}
+	}
+
+	event.QualityChecks = checks
+	for i := range event.QualityChecks {
+		check := &event.QualityChecks[i]
+		check.GitHubEventID = event.EventID
+		check.ID = m.nextCheckID
+		m.nextCheckID++
+		m.qualityChecks[check.ID] = check
+	}
+	event.UpdatedAt = models.Now()
+	return nil
+}
+
+// RerunEvent 重置事件及其全部质量检查
+func (m *MockStorage) RerunEvent(ctx context.Context, id int) error {
+	event, ok := m.events[id]
+	if !ok {
+		return ErrEventNotFound
+	}
+
+	now := models.Now()
+	reset := func(check *models.PRQualityCheck) {
+		check.CheckStatus = models.QualityCheckStatusPending
+		check.StartedAt = nil
+		check.CompletedAt = nil
+		check.DurationSeconds = nil
+		check.ErrorMessage = nil
+		check.Output = nil
+		check.UpdatedAt = now
+	}
+	for _, check := range m.qualityChecks {
+		if check.GitHubEventID == event.EventID {
+			reset(check)
+		}
+	}
+	for i := range event.QualityChecks {
+		reset(&event.QualityChecks[i])
+	}
+
+	event.EventStatus = models.EventStatusPending
+	event.ErrorMessage = nil
+	event.ProcessedAt = nil
+	event.RunCount++
+	event.UpdatedAt = now
+	return nil
+}
+
 // BatchUpdateQualityChecks 批量更新质量检查
-func (m *MockStorage) BatchUpdateQualityChecks(checks []models.PRQualityCheck) error {
+func (m *MockStorage) BatchUpdateQualityChecks(ctx context.Context, checks []models.PRQualityCheck) error {
 	for _, check := range checks {
 		if _, ok := m.qualityChecks[check.ID]; !ok {
-			return errors.New("quality check not found")
+			return ErrCheckNotFound
 		}
 		// 更新副本
 		updatedCheck := check
@@ -251,7 +464,7 @@ func (m *MockStorage) BatchUpdateQualityChecks(checks []models.PRQualityCheck) e
 }
 
 // GetEventStats 获取事件统计
-func (m *MockStorage) GetEventStats() (total int, pending int, err error) {
+func (m *MockStorage) GetEventStats(ctx context.Context) (total int, pending int, err error) {
 	total = len(m.events)
 	pending = 0
 
@@ -263,3 +476,43 @@ func (m *MockStorage) GetEventStats() (total int, pending int, err error) {
 
 	return total, pending, nil
 }
+
+// LatestEventPerRepository 返回每个仓库最新的事件
+func (m *MockStorage) LatestEventPerRepository(ctx context.Context) ([]*models.GitHubEvent, error) {
+	latest := make(map[string]*models.GitHubEvent)
+	for _, event := range m.events {
+		if cur, ok := latest[event.Repository]; !ok || event.ID > cur.ID {
+			latest[event.Repository] = event
+		}
+	}
+
+	events := make([]*models.GitHubEvent, 0, len(latest))
+	for _, event := range latest {
+		events = append(events, event)
+	}
+	sortEventsNewestFirst(events)
+	return events, nil
+}
+
+// ListRepositories 按仓库汇总事件
+func (m *MockStorage) ListRepositories(ctx context.Context) ([]RepoSummary, error) {
+	byRepo := make(map[string]*RepoSummary)
+	for _, event := range m.events {
+		summary, ok := byRepo[event.Repository]
+		if !ok {
+			summary = &RepoSummary{Repository: event.Repository}
+			byRepo[event.Repository] = summary
+		}
+		summary.EventCount++
+		if event.CreatedAt.After(summary.LastSeenAt.Time) {
+			summary.LastSeenAt = event.CreatedAt
+		}
+	}
+
+	repos := make([]RepoSummary, 0, len(byRepo))
+	for _, summary := range byRepo {
+		repos = append(repos, *summary)
+	}
+	sortRepoSummaries(repos)
+	return repos, nil
+}
@@ -1,13 +1,16 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github-hub/internal/quality/models"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 // MySQLStorage MySQL存储实现
@@ -29,24 +32,115 @@ func NewMySQLStorage(dsn string) (*MySQLStorage, error) {
 	return &MySQLStorage{db: db}, nil
 }
 
+// RedactDSN 将 MySQL DSN 中的密码替换为 ***，用于日志输出
+// 无法解析的 DSN 整体替换，避免泄露凭据
+func RedactDSN(dsn string) string {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "***"
+	}
+	if cfg.Passwd != "" {
+		cfg.Passwd = "***"
+	}
+	return cfg.FormatDSN()
+}
+
+// ParseDSNInfo 从 DSN 中解析数据库地址和库名（不包含凭据），用于状态展示
+func ParseDSNInfo(dsn string) (host, dbName string, err error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse dsn: %w", err)
+	}
+	return cfg.Addr, cfg.DBName, nil
+}
+
 // Close 关闭数据库连接
 func (s *MySQLStorage) Close() error {
 	return s.db.Close()
 }
 
+// Ping 检查数据库连接是否可用
+func (s *MySQLStorage) Ping(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+	return nil
+}
+
 // CreateEvent 创建事件
-func (s *MySQLStorage) CreateEvent(event *models.GitHubEvent) error {
-	tx, err := s.db.Begin()
+func (s *MySQLStorage) CreateEvent(ctx context.Context, event *models.GitHubEvent) error {
+	return s.CreateEvents(ctx, []*models.GitHubEvent{event})
+}
+
+// CreateEvents 在同一个事务中批量创建事件及其质量检查项，任一失败则整批回滚
+func (s *MySQLStorage) CreateEvents(ctx context.Context, events []*models.GitHubEvent) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	result, err := tx.Exec(`
-		INSERT INTO github_events (event_id, event_type, event_status, repository, branch, target_branch, commit_sha, pr_number, action, pusher, author, payload, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, event.EventID, event.EventType, event.EventStatus, event.Repository, event.Branch, event.TargetBranch, event.CommitSHA, event.PRNumber, event.Action, event.Pusher, event.Author, event.Payload, event.CreatedAt, event.UpdatedAt)
+	for _, event := range events {
+		if err := s.createEventInTx(ctx, tx, event); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// mysqlErrDuplicateEntry 唯一键冲突的 MySQL 错误码
+const mysqlErrDuplicateEntry = 1062
+
+// isDuplicateKey 判断是否为唯一键冲突
+func isDuplicateKey(err error) bool {
+	var me *mysql.MySQLError
+	return errors.As(err, &me) && me.Number == mysqlErrDuplicateEntry
+}
+
+// encodeChangedFiles 将变更文件列表序列化为 JSON，空列表存为 NULL
+func encodeChangedFiles(files []string) (interface{}, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(files)
 	if err != nil {
+		return nil, fmt.Errorf("failed to encode changed files: %w", err)
+	}
+	return data, nil
+}
+
+// decodeChangedFiles 解析 changed_files 列，NULL 返回 nil
+func decodeChangedFiles(raw []byte) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var files []string
+	if err := json.Unmarshal(raw, &files); err != nil {
+		return nil, fmt.Errorf("failed to decode changed files: %w", err)
+	}
+	return files, nil
+}
+
+// createEventInTx 在事务中创建事件
+func (s *MySQLStorage) createEventInTx(ctx context.Context, tx *sql.Tx, event *models.GitHubEvent) error {
+	changedFiles, err := encodeChangedFiles(event.ChangedFiles)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO github_events (event_id, event_type, event_status, repository, branch, target_branch, commit_sha, pr_number, action, pusher, author, payload, changed_files, skip_reason, error_message, retry_count, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, event.EventID, event.EventType, event.EventStatus, event.Repository, event.Branch, event.TargetBranch, event.CommitSHA, event.PRNumber, event.Action, event.Pusher, event.Author, event.Payload, changedFiles, event.SkipReason, event.ErrorMessage, event.RetryCount, event.CreatedAt, event.UpdatedAt)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return fmt.Errorf("event %s already exists: %w", event.EventID, ErrConflict)
+		}
 		return fmt.Errorf("failed to insert event: %w", err)
 	}
 
@@ -58,35 +152,32 @@ func (s *MySQLStorage) CreateEvent(event *models.GitHubEvent) error {
 
 	for i := range event.QualityChecks {
 		event.QualityChecks[i].GitHubEventID = event.EventID
-		if err := s.createQualityCheckInTx(tx, &event.QualityChecks[i]); err != nil {
-			return fmt.Errorf("failed to create quality check: %w", err)
-		}
 	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	if err := s.createQualityChecksInTx(ctx, tx, event.QualityChecks); err != nil {
+		return fmt.Errorf("failed to create quality check: %w", err)
 	}
 
 	return nil
 }
 
 // GetEvent 获取事件
-func (s *MySQLStorage) GetEvent(id int) (*models.GitHubEvent, error) {
+func (s *MySQLStorage) GetEvent(ctx context.Context, id int) (*models.GitHubEvent, error) {
 	var event models.GitHubEvent
-	var targetBranch, commitSHA, action, pusher, author sql.NullString
+	var targetBranch, commitSHA, action, pusher, author, skipReason, errorMessage sql.NullString
 	var prNumber sql.NullInt64
 	var processedAt sql.NullTime
+	var changedFiles []byte
 
-	err := s.db.QueryRow(`
-		SELECT id, event_id, event_type, event_status, repository, branch, target_branch, commit_sha, pr_number, action, pusher, author, payload, created_at, updated_at, processed_at
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, event_id, event_type, event_status, repository, branch, target_branch, commit_sha, pr_number, action, pusher, author, payload, created_at, updated_at, processed_at, run_count, changed_files, skip_reason, error_message, retry_count
 		FROM github_events
 		WHERE id = ?
 	`, id).Scan(
-		&event.ID, &event.EventID, &event.EventType, &event.EventStatus, &event.Repository, &event.Branch, &targetBranch, &commitSHA, &prNumber, &action, &pusher, &author, &event.Payload, &event.CreatedAt, &event.UpdatedAt, &processedAt,
+		&event.ID, &event.EventID, &event.EventType, &event.EventStatus, &event.Repository, &event.Branch, &targetBranch, &commitSHA, &prNumber, &action, &pusher, &author, &event.Payload, &event.CreatedAt, &event.UpdatedAt, &processedAt, &event.RunCount, &changedFiles, &skipReason, &errorMessage, &event.RetryCount,
 	)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("event not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrEventNotFound
 		}
 		return nil, fmt.Errorf("failed to query event: %w", err)
 	}
@@ -106,6 +197,12 @@ func (s *MySQLStorage) GetEvent(id int) (*models.GitHubEvent, error) {
 	if author.Valid {
 		event.Author = &author.String
 	}
+	if skipReason.Valid {
+		event.SkipReason = &skipReason.String
+	}
+	if errorMessage.Valid {
+		event.ErrorMessage = &errorMessage.String
+	}
 	if prNumber.Valid {
 		n := int(prNumber.Int64)
 		event.PRNumber = &n
@@ -114,8 +211,11 @@ func (s *MySQLStorage) GetEvent(id int) (*models.GitHubEvent, error) {
 		lt := models.FromTime(processedAt.Time)
 		event.ProcessedAt = &lt
 	}
+	if event.ChangedFiles, err = decodeChangedFiles(changedFiles); err != nil {
+		return nil, err
+	}
 
-	checks, err := s.ListQualityChecksByEventID(event.EventID)
+	checks, err := s.ListQualityChecksByEventID(ctx, event.EventID)
 	if err != nil {
 		event.QualityChecks = []models.PRQualityCheck{}
 	} else {
@@ -126,22 +226,23 @@ func (s *MySQLStorage) GetEvent(id int) (*models.GitHubEvent, error) {
 }
 
 // GetEventByEventID 根据EventID获取事件
-func (s *MySQLStorage) GetEventByEventID(eventID string) (*models.GitHubEvent, error) {
+func (s *MySQLStorage) GetEventByEventID(ctx context.Context, eventID string) (*models.GitHubEvent, error) {
 	var event models.GitHubEvent
-	var targetBranch, commitSHA, action, pusher, author sql.NullString
+	var targetBranch, commitSHA, action, pusher, author, skipReason, errorMessage sql.NullString
 	var prNumber sql.NullInt64
 	var processedAt sql.NullTime
+	var changedFiles []byte
 
-	err := s.db.QueryRow(`
-		SELECT id, event_id, event_type, event_status, repository, branch, target_branch, commit_sha, pr_number, action, pusher, author, payload, created_at, updated_at, processed_at
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, event_id, event_type, event_status, repository, branch, target_branch, commit_sha, pr_number, action, pusher, author, payload, created_at, updated_at, processed_at, run_count, changed_files, skip_reason, error_message, retry_count
 		FROM github_events
 		WHERE event_id = ?
 	`, eventID).Scan(
-		&event.ID, &event.EventID, &event.EventType, &event.EventStatus, &event.Repository, &event.Branch, &targetBranch, &commitSHA, &prNumber, &action, &pusher, &author, &event.Payload, &event.CreatedAt, &event.UpdatedAt, &processedAt,
+		&event.ID, &event.EventID, &event.EventType, &event.EventStatus, &event.Repository, &event.Branch, &targetBranch, &commitSHA, &prNumber, &action, &pusher, &author, &event.Payload, &event.CreatedAt, &event.UpdatedAt, &processedAt, &event.RunCount, &changedFiles, &skipReason, &errorMessage, &event.RetryCount,
 	)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("event not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrEventNotFound
 		}
 		return nil, fmt.Errorf("failed to query event: %w", err)
 	}
@@ -161,6 +262,12 @@ func (s *MySQLStorage) GetEventByEventID(eventID string) (*models.GitHubEvent, e
 	if author.Valid {
 		event.Author = &author.String
 	}
+	if skipReason.Valid {
+		event.SkipReason = &skipReason.String
+	}
+	if errorMessage.Valid {
+		event.ErrorMessage = &errorMessage.String
+	}
 	if prNumber.Valid {
 		n := int(prNumber.Int64)
 		event.PRNumber = &n
@@ -169,8 +276,11 @@ func (s *MySQLStorage) GetEventByEventID(eventID string) (*models.GitHubEvent, e
 		lt := models.FromTime(processedAt.Time)
 		event.ProcessedAt = &lt
 	}
+	if event.ChangedFiles, err = decodeChangedFiles(changedFiles); err != nil {
+		return nil, err
+	}
 
-	checks, err := s.ListQualityChecksByEventID(event.EventID)
+	checks, err := s.ListQualityChecksByEventID(ctx, event.EventID)
 	if err != nil {
 		event.QualityChecks = []models.PRQualityCheck{}
 	} else {
@@ -181,12 +291,62 @@ func (s *MySQLStorage) GetEventByEventID(eventID string) (*models.GitHubEvent, e
 }
 
 // ListEvents 列出所有事件
-func (s *MySQLStorage) ListEvents() ([]*models.GitHubEvent, error) {
-	rows, err := s.db.Query(`
-		SELECT id, event_id, event_type, event_status, repository, branch, target_branch, commit_sha, pr_number, action, pusher, author, payload, created_at, updated_at, processed_at
+func (s *MySQLStorage) ListEvents(ctx context.Context) ([]*models.GitHubEvent, error) {
+	return s.queryEvents(ctx, "", nil)
+}
+
+// ListEventsFiltered 列出满足过滤条件的事件，所有条件合并为一个参数化的 WHERE 子句
+func (s *MySQLStorage) ListEventsFiltered(ctx context.Context, filter EventFilter) ([]*models.GitHubEvent, error) {
+	var conditions []string
+	var args []interface{}
+	for _, c := range []struct {
+		column string
+		value  string
+	}{
+		{"event_type", filter.EventType},
+		{"event_status", filter.Status},
+		{"branch", filter.Branch},
+		{"repository", filter.Repository},
+		{"pusher", filter.Pusher},
+		{"author", filter.Author},
+	} {
+		if c.value != "" {
+			conditions = append(conditions, c.column+" = ?")
+			args = append(args, c.value)
+		}
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, models.FromTime(filter.From))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "created_at <= ?")
+		args = append(args, models.FromTime(filter.To))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	return s.queryEvents(ctx, where, args)
+}
+
+// ListPendingEvents 列出超过 olderThan 仍为 pending 的事件
+func (s *MySQLStorage) ListPendingEvents(ctx context.Context, olderThan time.Duration) ([]*models.GitHubEvent, error) {
+	return s.ListEventsFiltered(ctx, EventFilter{
+		Status: string(models.EventStatusPending),
+		To:     time.Now().Add(-olderThan),
+	})
+}
+
+// queryEvents 按给定 WHERE 子句查询事件并加载其质量检查
+func (s *MySQLStorage) queryEvents(ctx context.Context, where string, args []interface{}) ([]*models.GitHubEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, event_id, event_type, event_status, repository, branch, target_branch, commit_sha, pr_number, action, pusher, author, payload, created_at, updated_at, processed_at, run_count, changed_files, skip_reason, error_message, retry_count
 		FROM github_events
-		ORDER BY id DESC
-	`)
+		`+where+`
+		ORDER BY id DESC, event_id ASC
+	`, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query events: %w", err)
 	}
@@ -195,12 +355,13 @@ func (s *MySQLStorage) ListEvents() ([]*models.GitHubEvent, error) {
 	var events []*models.GitHubEvent
 	for rows.Next() {
 		var event models.GitHubEvent
-		var targetBranch, commitSHA, action, pusher, author sql.NullString
+		var targetBranch, commitSHA, action, pusher, author, skipReason, errorMessage sql.NullString
 		var prNumber sql.NullInt64
 		var processedAt sql.NullTime
+		var changedFiles []byte
 
 		if err := rows.Scan(
-			&event.ID, &event.EventID, &event.EventType, &event.EventStatus, &event.Repository, &event.Branch, &targetBranch, &commitSHA, &prNumber, &action, &pusher, &author, &event.Payload, &event.CreatedAt, &event.UpdatedAt, &processedAt,
+			&event.ID, &event.EventID, &event.EventType, &event.EventStatus, &event.Repository, &event.Branch, &targetBranch, &commitSHA, &prNumber, &action, &pusher, &author, &event.Payload, &event.CreatedAt, &event.UpdatedAt, &processedAt, &event.RunCount, &changedFiles, &skipReason, &errorMessage, &event.RetryCount,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan event: %w", err)
 		}
@@ -220,6 +381,12 @@ func (s *MySQLStorage) ListEvents() ([]*models.GitHubEvent, error) {
 		if author.Valid {
 			event.Author = &author.String
 		}
+		if skipReason.Valid {
+			event.SkipReason = &skipReason.String
+		}
+		if errorMessage.Valid {
+			event.ErrorMessage = &errorMessage.String
+		}
 		if prNumber.Valid {
 			n := int(prNumber.Int64)
 			event.PRNumber = &n
@@ -228,8 +395,11 @@ func (s *MySQLStorage) ListEvents() ([]*models.GitHubEvent, error) {
 			lt := models.FromTime(processedAt.Time)
 			event.ProcessedAt = &lt
 		}
+		if event.ChangedFiles, err = decodeChangedFiles(changedFiles); err != nil {
+			return nil, err
+		}
 
-		checks, err := s.ListQualityChecksByEventID(event.EventID)
+		checks, err := s.ListQualityChecksByEventID(ctx, event.EventID)
 		if err != nil {
 			event.QualityChecks = []models.PRQualityCheck{}
 		} else {
@@ -243,20 +413,20 @@ func (s *MySQLStorage) ListEvents() ([]*models.GitHubEvent, error) {
 }
 
 // ListEventsPaginated 分页查询事件（优化版本）
-func (s *MySQLStorage) ListEventsPaginated(offset, limit int) ([]*models.GitHubEvent, int, error) {
+func (s *MySQLStorage) ListEventsPaginated(ctx context.Context, offset, limit int) ([]*models.GitHubEvent, int, error) {
 	// 第一步：分页查询事件（不关联 quality_checks，确保 LIMIT 作用于事件数）
 	query := `
 		SELECT
 			id, event_id, event_type, event_status,
 			repository, branch, target_branch, commit_sha,
 			pr_number, action, pusher, author,
-			payload, created_at, updated_at, processed_at
+			payload, created_at, updated_at, processed_at, run_count, changed_files, skip_reason, error_message, retry_count
 		FROM github_events
-		ORDER BY id DESC
+		ORDER BY id DESC, event_id ASC
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := s.db.Query(query, limit, offset)
+	rows, err := s.db.QueryContext(ctx, query, limit, offset)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to query paginated events: %w", err)
 	}
@@ -268,15 +438,16 @@ func (s *MySQLStorage) ListEventsPaginated(offset, limit int) ([]*models.GitHubE
 
 	for rows.Next() {
 		var event models.GitHubEvent
-		var targetBranch, commitSHA, action, pusher, author sql.NullString
+		var targetBranch, commitSHA, action, pusher, author, skipReason, errorMessage sql.NullString
 		var prNumber sql.NullInt64
 		var processedAt sql.NullTime
+		var changedFiles []byte
 
 		if err := rows.Scan(
 			&event.ID, &event.EventID, &event.EventType, &event.EventStatus,
 			&event.Repository, &event.Branch, &targetBranch, &commitSHA,
 			&prNumber, &action, &pusher, &author,
-			&event.Payload, &event.CreatedAt, &event.UpdatedAt, &processedAt,
+			&event.Payload, &event.CreatedAt, &event.UpdatedAt, &processedAt, &event.RunCount, &changedFiles, &skipReason, &errorMessage, &event.RetryCount,
 		); err != nil {
 			return nil, 0, fmt.Errorf("failed to scan paginated event: %w", err)
 		}
@@ -297,6 +468,12 @@ func (s *MySQLStorage) ListEventsPaginated(offset, limit int) ([]*models.GitHubE
 		if author.Valid {
 			event.Author = &author.String
 		}
+		if skipReason.Valid {
+			event.SkipReason = &skipReason.String
+		}
+		if errorMessage.Valid {
+			event.ErrorMessage = &errorMessage.String
+		}
 		if prNumber.Valid {
 			n := int(prNumber.Int64)
 			event.PRNumber = &n
@@ -305,6 +482,9 @@ func (s *MySQLStorage) ListEventsPaginated(offset, limit int) ([]*models.GitHubE
 			lt := models.FromTime(processedAt.Time)
 			event.ProcessedAt = &lt
 		}
+		if event.ChangedFiles, err = decodeChangedFiles(changedFiles); err != nil {
+			return nil, 0, err
+		}
 
 		// 存储事件
 		eventMap[event.EventID] = &event
@@ -333,7 +513,7 @@ func (s *MySQLStorage) ListEventsPaginated(offset, limit int) ([]*models.GitHubE
 			ORDER BY stage_order, check_order
 		`
 
-		checkRows, err := s.db.Query(checkQuery, args...)
+		checkRows, err := s.db.QueryContext(ctx, checkQuery, args...)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to query quality checks: %w", err)
 		}
@@ -381,7 +561,7 @@ func (s *MySQLStorage) ListEventsPaginated(offset, limit int) ([]*models.GitHubE
 
 	// 查询总数
 	var total int
-	err = s.db.QueryRow("SELECT COUNT(*) FROM github_events").Scan(&total)
+	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM github_events").Scan(&total)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count events: %w", err)
 	}
@@ -396,8 +576,8 @@ func (s *MySQLStorage) ListEventsPaginated(offset, limit int) ([]*models.GitHubE
 }
 
 // UpdateEvent 更新事件
-func (s *MySQLStorage) UpdateEvent(event *models.GitHubEvent) error {
-	_, err := s.db.Exec(`
+func (s *MySQLStorage) UpdateEvent(ctx context.Context, event *models.GitHubEvent) error {
+	_, err := s.db.ExecContext(ctx, `
 		UPDATE github_events
 		SET event_status = ?, processed_at = ?, updated_at = ?
 		WHERE id = ?
@@ -409,7 +589,7 @@ func (s *MySQLStorage) UpdateEvent(event *models.GitHubEvent) error {
 }
 
 // UpdateEventStatus 更新事件状态（更灵活的版本，只更新状态字段）
-func (s *MySQLStorage) UpdateEventStatus(id int, status models.EventStatus, processedAt *models.LocalTime) error {
+func (s *MySQLStorage) UpdateEventStatus(ctx context.Context, id int, status models.EventStatus, processedAt *models.LocalTime) error {
 	query := `UPDATE github_events SET event_status = ?, updated_at = ?`
 	args := []interface{}{status, models.Now()}
 
@@ -421,30 +601,182 @@ func (s *MySQLStorage) UpdateEventStatus(id int, status models.EventStatus, proc
 	query += ` WHERE id = ?`
 	args = append(args, id)
 
-	_, err := s.db.Exec(query, args...)
+	_, err := s.db.ExecContext(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to update event status: %w", err)
 	}
 	return nil
 }
 
+// UpdateEventError 记录事件的错误信息和重试次数，nil 参数对应的列保持不变，空错误信息清空该列
+func (s *MySQLStorage) UpdateEventError(ctx context.Context, id int, errorMessage *string, retryCount *int) error {
+	query := `UPDATE github_events SET updated_at = ?`
+	args := []interface{}{models.Now()}
+
+	if errorMessage != nil {
+		query += `, error_message = ?`
+		if *errorMessage == "" {
+			args = append(args, nil)
+		} else {
+			args = append(args, *errorMessage)
+		}
+	}
+	if retryCount != nil {
+		query += `, retry_count = ?`
+		args = append(args, *retryCount)
+	}
+
+	query += ` WHERE id = ?`
+	args = append(args, id)
+
+	if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to update event error: %w", err)
+	}
+	return nil
+}
+
+// RerunEvent 在一个事务中把事件的全部质量检查重置为 pending 并清空耗时与输出，
+// 同时把事件状态重置为 pending、run_count 加 1；任一步失败整体回滚
+func (s *MySQLStorage) RerunEvent(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var eventID string
+	err = tx.QueryRowContext(ctx, "SELECT event_id FROM github_events WHERE id = ? FOR UPDATE", id).Scan(&eventID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEventNotFound
+		}
+		return fmt.Errorf("failed to lock event: %w", err)
+	}
+
+	now := models.Now()
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE pr_quality_checks
+		SET check_status = ?, started_at = NULL, completed_at = NULL, duration_seconds = NULL, error_message = NULL, output = NULL, updated_at = ?
+		WHERE github_event_id = ?
+	`, models.QualityCheckStatusPending, now, eventID); err != nil {
+		return fmt.Errorf("failed to reset quality checks: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE github_events
+		SET event_status = ?, processed_at = NULL, error_message = NULL, run_count = run_count + 1, updated_at = ?
+		WHERE id = ?
+	`, models.EventStatusPending, now, id); err != nil {
+		return fmt.Errorf("failed to reset event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ReplayEventInPlace 在一个事务中删除事件原有的质量检查、写入按当前流水线重新生成的检查，
+// 同时把事件重置为 pending、run_count 加 1；任一步失败整体回滚
+func (s *MySQLStorage) ReplayEventInPlace(ctx context.Context, id int, checks []models.PRQualityCheck) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var eventID string
+	err = tx.QueryRowContext(ctx, "SELECT event_id FROM github_events WHERE id = ? FOR UPDATE", id).Scan(&eventID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEventNotFound
+		}
+		return fmt.Errorf("failed to lock event: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM pr_quality_checks WHERE github_event_id = ?", eventID); err != nil {
+		return fmt.Errorf("failed to delete quality checks: %w", err)
+	}
+	for i := range checks {
+		checks[i].GitHubEventID = eventID
+	}
+	if err := s.createQualityChecksInTx(ctx, tx, checks); err != nil {
+		return fmt.Errorf("failed to create quality check: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE github_events
+		SET event_status = ?, processed_at = NULL, error_message = NULL, run_count = run_count + 1, updated_at = ?
+		WHERE id = ?
+	`, models.EventStatusPending, models.Now(), id); err != nil {
+		return fmt.Errorf("failed to reset event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// AttachQualityChecks 在一个事务中锁定事件、确认它还没有质量检查后写入 checks；
+// 并发处理同一事件时只有一方能写入，另一方得到 ErrConflict
+func (s *MySQLStorage) AttachQualityChecks(ctx context.Context, id int, checks []models.PRQualityCheck) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var eventID string
+	err = tx.QueryRowContext(ctx, "SELECT event_id FROM github_events WHERE id = ? FOR UPDATE", id).Scan(&eventID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEventNotFound
+		}
+		return fmt.Errorf("failed to lock event: %w", err)
+	}
+
+	var existing int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM pr_quality_checks WHERE github_event_id = ?", eventID).Scan(&existing); err != nil {
+		return fmt.Errorf("failed to count quality checks: %w", err)
+	}
+	if existing > 0 {
+		return fmt.Errorf("event %d already has quality checks: %w", id, ErrConflict)
+	}
+
+	for i := range checks {
+		checks[i].GitHubEventID = eventID
+	}
+	if err := s.createQualityChecksInTx(ctx, tx, checks); err != nil {
+		return fmt.Errorf("failed to create quality check: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
 // DeleteEvent 删除事件
-func (s *MySQLStorage) DeleteEvent(id int) error {
-	tx, err := s.db.Begin()
+func (s *MySQLStorage) DeleteEvent(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	_, err = tx.Exec("DELETE FROM pr_quality_checks WHERE github_event_id = (SELECT event_id FROM github_events WHERE id = ?)", id)
+	_, err = tx.ExecContext(ctx, "DELETE FROM pr_quality_checks WHERE github_event_id = (SELECT event_id FROM github_events WHERE id = ?)", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete quality checks: %w", err)
 	}
 
-	_, err = tx.Exec("DELETE FROM github_events WHERE id = ?", id)
+	result, err := tx.ExecContext(ctx, "DELETE FROM github_events WHERE id = ?", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete event: %w", err)
 	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrEventNotFound
+	}
 
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
@@ -454,19 +786,19 @@ func (s *MySQLStorage) DeleteEvent(id int) error {
 }
 
 // DeleteAllEvents 删除所有事件
-func (s *MySQLStorage) DeleteAllEvents() error {
-	tx, err := s.db.Begin()
+func (s *MySQLStorage) DeleteAllEvents(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	_, err = tx.Exec("DELETE FROM pr_quality_checks")
+	_, err = tx.ExecContext(ctx, "DELETE FROM pr_quality_checks")
 	if err != nil {
 		return fmt.Errorf("failed to delete quality checks: %w", err)
 	}
 
-	_, err = tx.Exec("DELETE FROM github_events")
+	_, err = tx.ExecContext(ctx, "DELETE FROM github_events")
 	if err != nil {
 		return fmt.Errorf("failed to delete events: %w", err)
 	}
@@ -479,14 +811,14 @@ func (s *MySQLStorage) DeleteAllEvents() error {
 }
 
 // CreateQualityCheck 创建质量检查
-func (s *MySQLStorage) CreateQualityCheck(check *models.PRQualityCheck) error {
-	tx, err := s.db.Begin()
+func (s *MySQLStorage) CreateQualityCheck(ctx context.Context, check *models.PRQualityCheck) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	if err := s.createQualityCheckInTx(tx, check); err != nil {
+	if err := s.createQualityCheckInTx(ctx, tx, check); err != nil {
 		return err
 	}
 
@@ -497,11 +829,10 @@ func (s *MySQLStorage) CreateQualityCheck(check *models.PRQualityCheck) error {
 	return nil
 }
 
-func (s *MySQLStorage) createQualityCheckInTx(tx *sql.Tx, check *models.PRQualityCheck) error {
-	result, err := tx.Exec(`
-		INSERT INTO pr_quality_checks (github_event_id, check_type, check_status, stage, stage_order, check_order, started_at, completed_at, duration_seconds, error_message, output, retry_count, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, check.GitHubEventID, check.CheckType, check.CheckStatus, check.Stage, check.StageOrder, check.CheckOrder, check.StartedAt, check.CompletedAt, check.DurationSeconds, check.ErrorMessage, check.Output, check.RetryCount, check.CreatedAt, check.UpdatedAt)
+func (s *MySQLStorage) createQualityCheckInTx(ctx context.Context, tx *sql.Tx, check *models.PRQualityCheck) error {
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO pr_quality_checks (`+qualityCheckInsertColumns+`)
+		VALUES `+qualityCheckValuesRow, qualityCheckInsertArgs(check)...)
 	if err != nil {
 		return fmt.Errorf("failed to insert quality check: %w", err)
 	}
@@ -515,14 +846,83 @@ func (s *MySQLStorage) createQualityCheckInTx(tx *sql.Tx, check *models.PRQualit
 	return nil
 }
 
+// 批量插入质量检查项的参数
+const (
+	// qualityCheckBatchThreshold 检查项超过该数量时改用多行 INSERT，少量检查项仍逐条插入
+	qualityCheckBatchThreshold = 4
+	// qualityCheckBatchSize 单条多行 INSERT 最多包含的行数，避免占位符过多
+	qualityCheckBatchSize = 500
+
+	qualityCheckInsertColumns = "github_event_id, check_type, check_status, stage, stage_order, check_order, started_at, completed_at, duration_seconds, error_message, output, retry_count, created_at, updated_at"
+	qualityCheckValuesRow     = "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+)
+
+// qualityCheckInsertArgs 按 qualityCheckInsertColumns 的顺序返回检查项的插入参数
+func qualityCheckInsertArgs(check *models.PRQualityCheck) []interface{} {
+	return []interface{}{check.GitHubEventID, check.CheckType, check.CheckStatus, check.Stage, check.StageOrder, check.CheckOrder, check.StartedAt, check.CompletedAt, check.DurationSeconds, check.ErrorMessage, check.Output, check.RetryCount, check.CreatedAt, check.UpdatedAt}
+}
+
+// createQualityChecksInTx 在事务中插入一组质量检查项并回填 ID。
+// 数量不超过 qualityCheckBatchThreshold 时逐条插入，否则按 qualityCheckBatchSize 分批使用多行 INSERT；
+// 多行 INSERT 的 LastInsertId 是第一行的 ID，InnoDB 为同一条语句分配连续的自增值
+// （要求 auto_increment_increment 为默认值 1），其余行依次递增
+func (s *MySQLStorage) createQualityChecksInTx(ctx context.Context, tx *sql.Tx, checks []models.PRQualityCheck) error {
+	if len(checks) <= qualityCheckBatchThreshold {
+		for i := range checks {
+			if err := s.createQualityCheckInTx(ctx, tx, &checks[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for start := 0; start < len(checks); start += qualityCheckBatchSize {
+		end := start + qualityCheckBatchSize
+		if end > len(checks) {
+			end = len(checks)
+		}
+		batch := checks[start:end]
+
+		rows := make([]string, len(batch))
+		args := make([]interface{}, 0, len(batch)*strings.Count(qualityCheckValuesRow, "?"))
+		for i := range batch {
+			rows[i] = qualityCheckValuesRow
+			args = append(args, qualityCheckInsertArgs(&batch[i])...)
+		}
+		result, err := tx.ExecContext(ctx, `
+		INSERT INTO pr_quality_checks (`+qualityCheckInsertColumns+`)
+		VALUES `+strings.Join(rows, ", "), args...)
+		if err != nil {
+			return fmt.Errorf("failed to insert quality checks: %w", err)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if affected != int64(len(batch)) {
+			return fmt.Errorf("inserted %d quality checks, expected %d", affected, len(batch))
+		}
+		firstID, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get last insert id: %w", err)
+		}
+		for i := range batch {
+			batch[i].ID = int(firstID) + i
+		}
+	}
+
+	return nil
+}
+
 // GetQualityCheck 获取质量检查
-func (s *MySQLStorage) GetQualityCheck(id int) (*models.PRQualityCheck, error) {
+func (s *MySQLStorage) GetQualityCheck(ctx context.Context, id int) (*models.PRQualityCheck, error) {
 	var check models.PRQualityCheck
 	var errorMessage, output sql.NullString
 	var durationSeconds sql.NullFloat64
 	var startedAtTime, completedAtTime sql.NullTime
 
-	err := s.db.QueryRow(`
+	err := s.db.QueryRowContext(ctx, `
 		SELECT id, github_event_id, check_type, check_status, stage, stage_order, check_order, started_at, completed_at, duration_seconds, error_message, output, retry_count, created_at, updated_at
 		FROM pr_quality_checks
 		WHERE id = ?
@@ -530,8 +930,8 @@ func (s *MySQLStorage) GetQualityCheck(id int) (*models.PRQualityCheck, error) {
 		&check.ID, &check.GitHubEventID, &check.CheckType, &check.CheckStatus, &check.Stage, &check.StageOrder, &check.CheckOrder, &startedAtTime, &completedAtTime, &durationSeconds, &errorMessage, &output, &check.RetryCount, &check.CreatedAt, &check.UpdatedAt,
 	)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("quality check not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCheckNotFound
 		}
 		return nil, fmt.Errorf("failed to query quality check: %w", err)
 	}
@@ -558,18 +958,49 @@ func (s *MySQLStorage) GetQualityCheck(id int) (*models.PRQualityCheck, error) {
 }
 
 // ListQualityChecksByEventID 列出事件的质量检查项
-func (s *MySQLStorage) ListQualityChecksByEventID(eventID string) ([]models.PRQualityCheck, error) {
-	rows, err := s.db.Query(`
+func (s *MySQLStorage) ListQualityChecksByEventID(ctx context.Context, eventID string) ([]models.PRQualityCheck, error) {
+	rows, err := s.db.QueryContext(ctx, `
 		SELECT id, github_event_id, check_type, check_status, stage, stage_order, check_order, started_at, completed_at, duration_seconds, error_message, output, retry_count, created_at, updated_at
 		FROM pr_quality_checks
 		WHERE github_event_id = ?
-		ORDER BY stage_order, check_order
+		ORDER BY stage_order, check_order, id
 	`, eventID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query quality checks: %w", err)
 	}
 	defer rows.Close()
 
+	return scanQualityChecks(rows)
+}
+
+// ListQualityChecksByEventIDPaginated 分页列出事件的质量检查项
+func (s *MySQLStorage) ListQualityChecksByEventIDPaginated(ctx context.Context, eventID string, offset, limit int) ([]models.PRQualityCheck, int, error) {
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pr_quality_checks WHERE github_event_id = ?`, eventID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count quality checks: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, github_event_id, check_type, check_status, stage, stage_order, check_order, started_at, completed_at, duration_seconds, error_message, output, retry_count, created_at, updated_at
+		FROM pr_quality_checks
+		WHERE github_event_id = ?
+		ORDER BY stage_order, check_order, id
+		LIMIT ? OFFSET ?
+	`, eventID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query paginated quality checks: %w", err)
+	}
+	defer rows.Close()
+
+	checks, err := scanQualityChecks(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return checks, total, nil
+}
+
+// scanQualityChecks 读取检查项查询的全部结果行
+func scanQualityChecks(rows *sql.Rows) ([]models.PRQualityCheck, error) {
 	var checks []models.PRQualityCheck
 	for rows.Next() {
 		var check models.PRQualityCheck
@@ -603,13 +1034,16 @@ func (s *MySQLStorage) ListQualityChecksByEventID(eventID string) ([]models.PRQu
 
 		checks = append(checks, check)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate quality checks: %w", err)
+	}
 
 	return checks, nil
 }
 
 // UpdateQualityCheck 更新质量检查
-func (s *MySQLStorage) UpdateQualityCheck(check *models.PRQualityCheck) error {
-	_, err := s.db.Exec(`
+func (s *MySQLStorage) UpdateQualityCheck(ctx context.Context, check *models.PRQualityCheck) error {
+	_, err := s.db.ExecContext(ctx, `
 		UPDATE pr_quality_checks
 		SET check_status = ?, started_at = ?, completed_at = ?, duration_seconds = ?, error_message = ?, output = ?, updated_at = ?
 		WHERE id = ?
@@ -620,19 +1054,52 @@ func (s *MySQLStorage) UpdateQualityCheck(check *models.PRQualityCheck) error {
 	return nil
 }
 
+// AppendQualityCheckOutput 追加质量检查输出
+// 在事务中用 SELECT ... FOR UPDATE 锁住该行再读改写，并发追加不会互相覆盖
+func (s *MySQLStorage) AppendQualityCheckOutput(ctx context.Context, id int, chunk string) (*models.PRQualityCheck, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var output sql.NullString
+	err = tx.QueryRowContext(ctx, "SELECT output FROM pr_quality_checks WHERE id = ? FOR UPDATE", id).Scan(&output)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCheckNotFound
+		}
+		return nil, fmt.Errorf("failed to query quality check output: %w", err)
+	}
+
+	var existing *string
+	if output.Valid {
+		existing = &output.String
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE pr_quality_checks SET output = ?, updated_at = ? WHERE id = ?",
+		models.AppendCheckOutput(existing, chunk), models.Now(), id); err != nil {
+		return nil, fmt.Errorf("failed to append quality check output: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s.GetQualityCheck(ctx, id)
+}
+
 // BatchUpdateQualityChecks 批量更新质量检查
-func (s *MySQLStorage) BatchUpdateQualityChecks(checks []models.PRQualityCheck) error {
+func (s *MySQLStorage) BatchUpdateQualityChecks(ctx context.Context, checks []models.PRQualityCheck) error {
 	if len(checks) == 0 {
 		return nil
 	}
 
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.Prepare(`
+	stmt, err := tx.PrepareContext(ctx, `
 		UPDATE pr_quality_checks
 		SET check_status = ?, started_at = COALESCE(?, started_at),
 		    completed_at = COALESCE(?, completed_at), duration_seconds = COALESCE(?, duration_seconds),
@@ -646,7 +1113,7 @@ func (s *MySQLStorage) BatchUpdateQualityChecks(checks []models.PRQualityCheck)
 	defer stmt.Close()
 
 	for _, check := range checks {
-		_, err := stmt.Exec(
+		_, err := stmt.ExecContext(ctx,
 			check.CheckStatus,
 			check.StartedAt, check.CompletedAt, check.DurationSeconds,
 			check.ErrorMessage, check.Output, check.UpdatedAt,
@@ -665,20 +1132,20 @@ func (s *MySQLStorage) BatchUpdateQualityChecks(checks []models.PRQualityCheck)
 }
 
 // CleanupExpired 清理过期数据
-func (s *MySQLStorage) CleanupExpired(ttl time.Duration) error {
+func (s *MySQLStorage) CleanupExpired(ctx context.Context, ttl time.Duration) error {
 	cutoff := time.Now().Add(-ttl)
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	_, err = tx.Exec("DELETE FROM pr_quality_checks WHERE github_event_id IN (SELECT event_id FROM github_events WHERE created_at < ?)", cutoff)
+	_, err = tx.ExecContext(ctx, "DELETE FROM pr_quality_checks WHERE github_event_id IN (SELECT event_id FROM github_events WHERE created_at < ?)", cutoff)
 	if err != nil {
 		return fmt.Errorf("failed to delete expired quality checks: %w", err)
 	}
 
-	_, err = tx.Exec("DELETE FROM github_events WHERE created_at < ?", cutoff)
+	_, err = tx.ExecContext(ctx, "DELETE FROM github_events WHERE created_at < ?", cutoff)
 	if err != nil {
 		return fmt.Errorf("failed to delete expired events: %w", err)
 	}
@@ -691,18 +1158,53 @@ func (s *MySQLStorage) CleanupExpired(ttl time.Duration) error {
 }
 
 // GetEventStats 获取事件统计信息（使用数据库 COUNT 查询，避免加载所有数据）
-func (s *MySQLStorage) GetEventStats() (total int, pending int, err error) {
+func (s *MySQLStorage) GetEventStats(ctx context.Context) (total int, pending int, err error) {
 	// 查询总数
-	err = s.db.QueryRow("SELECT COUNT(*) FROM github_events").Scan(&total)
+	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM github_events").Scan(&total)
 	if err != nil {
 		return 0, 0, fmt.Errorf("failed to count total events: %w", err)
 	}
 
 	// 查询 pending 状态的数量
-	err = s.db.QueryRow("SELECT COUNT(*) FROM github_events WHERE event_status = 'pending'").Scan(&pending)
+	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM github_events WHERE event_status = 'pending'").Scan(&pending)
 	if err != nil {
 		return 0, 0, fmt.Errorf("failed to count pending events: %w", err)
 	}
 
 	return total, pending, nil
-}
\ No newline at end of file
+}
+
+// ListRepositories 按仓库汇总事件
+func (s *MySQLStorage) ListRepositories(ctx context.Context) ([]RepoSummary, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT repository, COUNT(*), MAX(created_at)
+		FROM github_events
+		GROUP BY repository
+		ORDER BY MAX(created_at) DESC, repository ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query repositories: %w", err)
+	}
+	defer rows.Close()
+
+	repos := []RepoSummary{}
+	for rows.Next() {
+		var repo RepoSummary
+		if err := rows.Scan(&repo.Repository, &repo.EventCount, &repo.LastSeenAt); err != nil {
+			return nil, fmt.Errorf("failed to scan repository: %w", err)
+		}
+		repos = append(repos, repo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate repositories: %w", err)
+	}
+	return repos, nil
+}
+
+// LatestEventPerRepository 用相关子查询取每个仓库 id 最大的事件
+// InnoDB 的二级索引 idx_repository 隐含主键 id，子查询的 MAX(id) 直接在 (repository, id) 上完成
+func (s *MySQLStorage) LatestEventPerRepository(ctx context.Context) ([]*models.GitHubEvent, error) {
+	return s.queryEvents(ctx, `WHERE id = (
+			SELECT MAX(latest.id) FROM github_events latest WHERE latest.repository = github_events.repository
+		)`, nil)
+}
@@ -0,0 +1,317 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github-hub/internal/quality/models"
+)
+
+// fakeSQL 最小化的 database/sql 驱动，记录执行的语句和事务结果，
+// 在语句包含 failOn 时返回错误，用于在没有 MySQL 的环境下验证事务回滚
+type fakeSQL struct {
+	eventID    string // SELECT 返回的 event_id，空表示没有记录
+	failOn     string
+	execs      []string
+	committed  bool
+	rolledBack bool
+	lastID     int64 // 已分配的最大自增 ID，INSERT 按行数连续分配
+	hang       bool  // 查询一直阻塞，直到 context 被取消
+}
+
+func (f *fakeSQL) Connect(context.Context) (driver.Conn, error) { return &fakeConn{f}, nil }
+func (f *fakeSQL) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ f *fakeSQL }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{c.f, query}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return &fakeTx{c.f}, nil }
+
+type fakeTx struct{ f *fakeSQL }
+
+func (t *fakeTx) Commit() error   { t.f.committed = true; return nil }
+func (t *fakeTx) Rollback() error { t.f.rolledBack = true; return nil }
+
+type fakeStmt struct {
+	f     *fakeSQL
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.f.execs = append(s.f.execs, s.query)
+	if s.f.failOn != "" && strings.Contains(s.query, s.f.failOn) {
+		return nil, errors.New("injected failure")
+	}
+	if !strings.Contains(s.query, "INSERT") {
+		return driver.RowsAffected(1), nil
+	}
+	rows := int64(strings.Count(s.query, "(?"))
+	res := fakeResult{firstID: s.f.lastID + 1, rows: rows}
+	s.f.lastID += rows
+	return res, nil
+}
+
+// fakeResult 模拟 MySQL 的 INSERT 结果：LastInsertId 为本条语句插入的第一行 ID
+type fakeResult struct {
+	firstID int64
+	rows    int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) { return r.firstID, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return r.rows, nil }
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	var values []string
+	if s.f.eventID != "" {
+		values = []string{s.f.eventID}
+	}
+	return &fakeRows{values: values}, nil
+}
+
+// QueryContext 在 hang 时模拟卡住的查询，只有 context 结束才返回
+func (s *fakeStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if s.f.hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return s.Query(nil)
+}
+
+type fakeRows struct{ values []string }
+
+func (r *fakeRows) Columns() []string { return []string{"event_id"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0] = r.values[0]
+	r.values = r.values[1:]
+	return nil
+}
+
+// TestMySQLStorage_RerunEvent 测试重新运行在事务中执行，任一语句失败都会回滚
+func TestMySQLStorage_RerunEvent(t *testing.T) {
+	tests := []struct {
+		name          string
+		eventID       string
+		failOn        string
+		wantErr       error
+		wantExecs     int
+		wantCommitted bool
+	}{
+		{name: "success", eventID: "evt-1", wantExecs: 2, wantCommitted: true},
+		{name: "event update fails after checks reset", eventID: "evt-1", failOn: "UPDATE github_events", wantExecs: 2},
+		{name: "checks reset fails", eventID: "evt-1", failOn: "UPDATE pr_quality_checks", wantExecs: 1},
+		{name: "event not found", wantErr: ErrEventNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeSQL{eventID: tt.eventID, failOn: tt.failOn}
+			db := sql.OpenDB(f)
+			defer db.Close()
+			s := &MySQLStorage{db: db}
+
+			err := s.RerunEvent(context.Background(), 7)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+			case tt.failOn != "":
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+			case err != nil:
+				t.Fatalf("RerunEvent failed: %v", err)
+			}
+
+			if len(f.execs) != tt.wantExecs {
+				t.Errorf("expected %d statements, got %d: %v", tt.wantExecs, len(f.execs), f.execs)
+			}
+			if f.committed != tt.wantCommitted {
+				t.Errorf("committed=%v, want %v", f.committed, tt.wantCommitted)
+			}
+			if !tt.wantCommitted && !f.rolledBack {
+				t.Error("expected the transaction to be rolled back")
+			}
+		})
+	}
+}
+
+// TestMySQLStorage_ContextCancellation 测试 context 取消或超时会中止数据库操作
+func TestMySQLStorage_ContextCancellation(t *testing.T) {
+	t.Run("hung query aborted by deadline", func(t *testing.T) {
+		f := &fakeSQL{hang: true}
+		db := sql.OpenDB(f)
+		defer db.Close()
+		s := &MySQLStorage{db: db}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		done := make(chan error, 1)
+		go func() {
+			_, err := s.GetEvent(ctx, 1)
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("query did not stop after the context deadline")
+		}
+	})
+
+	t.Run("cancelled before a transaction starts", func(t *testing.T) {
+		f := &fakeSQL{eventID: "evt-1"}
+		db := sql.OpenDB(f)
+		defer db.Close()
+		s := &MySQLStorage{db: db}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := s.RerunEvent(ctx, 7); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+		if len(f.execs) != 0 || f.committed {
+			t.Errorf("no statement should run: execs=%v committed=%v", f.execs, f.committed)
+		}
+	})
+}
+
+// TestMySQLStorage_CreateEventBatchesChecks 测试检查项较多时使用多行 INSERT，并按插入顺序回填连续 ID
+func TestMySQLStorage_CreateEventBatchesChecks(t *testing.T) {
+	tests := []struct {
+		name        string
+		checks      int
+		wantInserts int // 检查项的 INSERT 语句数
+	}{
+		{name: "few checks inserted one by one", checks: qualityCheckBatchThreshold, wantInserts: qualityCheckBatchThreshold},
+		{name: "many checks in one statement", checks: 9, wantInserts: 1},
+		{name: "split across statements", checks: qualityCheckBatchSize + 3, wantInserts: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeSQL{}
+			db := sql.OpenDB(f)
+			defer db.Close()
+			s := &MySQLStorage{db: db}
+
+			event := &models.GitHubEvent{EventID: "batch-1", EventType: models.EventTypePush, EventStatus: models.EventStatusPending}
+			for i := 0; i < tt.checks; i++ {
+				event.QualityChecks = append(event.QualityChecks, models.PRQualityCheck{
+					CheckType:   models.QualityCheckTypeUnitTest,
+					CheckStatus: models.QualityCheckStatusPending,
+					Stage:       models.StageTypeBasicCI,
+					CheckOrder:  i,
+				})
+			}
+			if err := s.CreateEvent(context.Background(), event); err != nil {
+				t.Fatalf("CreateEvent failed: %v", err)
+			}
+
+			var inserts int
+			for _, q := range f.execs {
+				if strings.Contains(q, "INSERT INTO pr_quality_checks") {
+					inserts++
+				}
+			}
+			if inserts != tt.wantInserts {
+				t.Errorf("expected %d check inserts, got %d", tt.wantInserts, inserts)
+			}
+			if event.ID != 1 {
+				t.Errorf("event id = %d, want 1", event.ID)
+			}
+			for i, c := range event.QualityChecks {
+				if c.ID != i+2 || c.GitHubEventID != "batch-1" {
+					t.Fatalf("check %d: id=%d event=%q, want id %d", i, c.ID, c.GitHubEventID, i+2)
+				}
+			}
+			if !f.committed {
+				t.Error("expected the transaction to be committed")
+			}
+		})
+	}
+}
+
+// TestMockStorage_RerunEvent 测试模拟存储重置事件和检查
+func TestMockStorage_RerunEvent(t *testing.T) {
+	s := NewMockStorage()
+	output := "build log"
+	processed := models.Now()
+	event := &models.GitHubEvent{
+		EventID:       "rerun-1",
+		EventType:     models.EventTypePush,
+		EventStatus:   models.EventStatusFailed,
+		ProcessedAt:   &processed,
+		QualityChecks: models.CreateChecksForEvent("rerun-1"),
+	}
+	event.QualityChecks[0].CheckStatus = models.QualityCheckStatusFailed
+	event.QualityChecks[0].Output = &output
+	event.QualityChecks[0].StartedAt = &processed
+	if err := s.CreateEvent(context.Background(), event); err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+
+	for run := 1; run <= 2; run++ {
+		if err := s.RerunEvent(context.Background(), event.ID); err != nil {
+			t.Fatalf("RerunEvent failed: %v", err)
+		}
+		got, _ := s.GetEvent(context.Background(), event.ID)
+		if got.RunCount != run || got.EventStatus != models.EventStatusPending || got.ProcessedAt != nil {
+			t.Fatalf("run %d: run_count=%d status=%s processed_at=%v", run, got.RunCount, got.EventStatus, got.ProcessedAt)
+		}
+	}
+
+	checks, _ := s.ListQualityChecksByEventID(context.Background(), "rerun-1")
+	for _, c := range checks {
+		if c.CheckStatus != models.QualityCheckStatusPending || c.Output != nil || c.StartedAt != nil {
+			t.Errorf("check %d not reset: %+v", c.ID, c)
+		}
+	}
+
+	if err := s.RerunEvent(context.Background(), 999); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("expected ErrEventNotFound, got %v", err)
+	}
+}
+
+// TestChangedFilesEncoding 测试变更文件列表与 changed_files 列的相互转换
+func TestChangedFilesEncoding(t *testing.T) {
+	if v, err := encodeChangedFiles(nil); err != nil || v != nil {
+		t.Errorf("expected NULL for an empty list, got %v, %v", v, err)
+	}
+
+	files := []string{"src/main.go", "docs/README.md"}
+	v, err := encodeChangedFiles(files)
+	if err != nil {
+		t.Fatalf("encodeChangedFiles failed: %v", err)
+	}
+	got, err := decodeChangedFiles(v.([]byte))
+	if err != nil {
+		t.Fatalf("decodeChangedFiles failed: %v", err)
+	}
+	if strings.Join(got, ",") != strings.Join(files, ",") {
+		t.Errorf("round trip = %v, want %v", got, files)
+	}
+
+	if got, err := decodeChangedFiles(nil); err != nil || got != nil {
+		t.Errorf("expected nil for NULL, got %v, %v", got, err)
+	}
+	if _, err := decodeChangedFiles([]byte("not json")); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
@@ -1,34 +1,161 @@
 package storage
 
 import (
+	"context"
+	"errors"
+	"sort"
 	"time"
 
 	"github-hub/internal/quality/models"
 )
 
-// Storage 存储接口定义
+// 存储层哨兵错误，调用方用 errors.Is 判断，handler 据此映射 HTTP 状态码
+var (
+	// ErrEventNotFound 事件不存在
+	ErrEventNotFound = errors.New("event not found")
+	// ErrCheckNotFound 质量检查不存在
+	ErrCheckNotFound = errors.New("quality check not found")
+	// ErrConflict 写入与已有数据冲突（如 event_id 重复）
+	ErrConflict = errors.New("conflict")
+)
+
+// Storage 存储接口定义；所有方法的第一个参数 ctx 控制取消和超时，
+// HTTP 处理器传入 r.Context()，请求结束后仍未完成的数据库操作会被中止
 type Storage interface {
 	// Event 操作
-	CreateEvent(event *models.GitHubEvent) error
-	GetEvent(id int) (*models.GitHubEvent, error)
-	GetEventByEventID(eventID string) (*models.GitHubEvent, error)
-	ListEvents() ([]*models.GitHubEvent, error)
-	ListEventsPaginated(offset, limit int) ([]*models.GitHubEvent, int, error)
-	UpdateEvent(event *models.GitHubEvent) error
-	UpdateEventStatus(id int, status models.EventStatus, processedAt *models.LocalTime) error
-	DeleteEvent(id int) error
-	DeleteAllEvents() error
+	CreateEvent(ctx context.Context, event *models.GitHubEvent) error
+	CreateEvents(ctx context.Context, events []*models.GitHubEvent) error
+	GetEvent(ctx context.Context, id int) (*models.GitHubEvent, error)
+	GetEventByEventID(ctx context.Context, eventID string) (*models.GitHubEvent, error)
+	ListEvents(ctx context.Context) ([]*models.GitHubEvent, error)
+	ListEventsPaginated(ctx context.Context, offset, limit int) ([]*models.GitHubEvent, int, error)
+	ListEventsFiltered(ctx context.Context, filter EventFilter) ([]*models.GitHubEvent, error)
+	// ListPendingEvents 列出创建时间早于 olderThan 之前、仍处于 pending 状态的事件（含检查项）
+	ListPendingEvents(ctx context.Context, olderThan time.Duration) ([]*models.GitHubEvent, error)
+	UpdateEvent(ctx context.Context, event *models.GitHubEvent) error
+	UpdateEventStatus(ctx context.Context, id int, status models.EventStatus, processedAt *models.LocalTime) error
+	// UpdateEventError 记录事件的错误信息和重试次数；nil 表示不修改对应字段，空错误信息表示清空
+	UpdateEventError(ctx context.Context, id int, errorMessage *string, retryCount *int) error
+	// RerunEvent 把事件及其全部检查重置为 pending，run_count 加 1，事件和检查的修改是原子的
+	RerunEvent(ctx context.Context, id int) error
+	// ReplayEventInPlace 用 checks 替换事件原有的全部检查，并把事件重置为 pending、run_count 加 1，整体是原子的
+	ReplayEventInPlace(ctx context.Context, id int, checks []models.PRQualityCheck) error
+	// AttachQualityChecks 为还没有检查项的事件写入 checks；事件已有检查项时返回 ErrConflict，保证同一事件只处理一次
+	AttachQualityChecks(ctx context.Context, id int, checks []models.PRQualityCheck) error
+	DeleteEvent(ctx context.Context, id int) error
+	DeleteAllEvents(ctx context.Context) error
 
 	// QualityCheck 操作
-	CreateQualityCheck(check *models.PRQualityCheck) error
-	GetQualityCheck(id int) (*models.PRQualityCheck, error)
-	ListQualityChecksByEventID(eventID string) ([]models.PRQualityCheck, error)
-	UpdateQualityCheck(check *models.PRQualityCheck) error
-	BatchUpdateQualityChecks(checks []models.PRQualityCheck) error
+	CreateQualityCheck(ctx context.Context, check *models.PRQualityCheck) error
+	GetQualityCheck(ctx context.Context, id int) (*models.PRQualityCheck, error)
+	ListQualityChecksByEventID(ctx context.Context, eventID string) ([]models.PRQualityCheck, error)
+	// ListQualityChecksByEventIDPaginated 按 stage_order、check_order 分页列出事件的检查项，同时返回总数
+	ListQualityChecksByEventIDPaginated(ctx context.Context, eventID string, offset, limit int) ([]models.PRQualityCheck, int, error)
+	UpdateQualityCheck(ctx context.Context, check *models.PRQualityCheck) error
+	BatchUpdateQualityChecks(ctx context.Context, checks []models.PRQualityCheck) error
+	// AppendQualityCheckOutput 把 chunk 追加到检查输出末尾（超过上限时截断），返回更新后的检查
+	AppendQualityCheckOutput(ctx context.Context, id int, chunk string) (*models.PRQualityCheck, error)
 
 	// 清理操作
-	CleanupExpired(ttl time.Duration) error
+	CleanupExpired(ctx context.Context, ttl time.Duration) error
 
 	// 统计操作
-	GetEventStats() (total int, pending int, err error)
+	GetEventStats(ctx context.Context) (total int, pending int, err error)
+	// ListRepositories 按仓库汇总事件数和最近一次事件时间，最近活跃的仓库在前
+	ListRepositories(ctx context.Context) ([]RepoSummary, error)
+	// LatestEventPerRepository 返回每个仓库 id 最大的事件（含检查项），按 id 降序
+	LatestEventPerRepository(ctx context.Context) ([]*models.GitHubEvent, error)
+
+	// 健康检查
+	Ping(ctx context.Context) error
+}
+
+// RepoSummary 一个仓库的事件汇总
+type RepoSummary struct {
+	Repository string           `json:"repository"`
+	EventCount int              `json:"event_count"`
+	LastSeenAt models.LocalTime `json:"last_seen_at"`
+}
+
+// sortRepoSummaries 按最近事件时间降序排列，时间相同时按仓库名升序，与 MySQL 的 ORDER BY 一致
+func sortRepoSummaries(repos []RepoSummary) {
+	sort.Slice(repos, func(i, j int) bool {
+		if !repos[i].LastSeenAt.Equal(repos[j].LastSeenAt.Time) {
+			return repos[i].LastSeenAt.After(repos[j].LastSeenAt.Time)
+		}
+		return repos[i].Repository < repos[j].Repository
+	})
+}
+
+// EventFilter 事件列表过滤条件，字符串为空、时间为零值表示不限制
+// 时间范围按 created_at 过滤，两端均为闭区间
+type EventFilter struct {
+	EventType  string
+	Status     string
+	Branch     string
+	Repository string
+	Pusher     string // 精确匹配，pusher 为空的事件在设置该条件时被排除
+	Author     string // 精确匹配，author 为空的事件在设置该条件时被排除
+	From       time.Time
+	To         time.Time
+}
+
+// IsEmpty 判断是否没有任何过滤条件
+func (f EventFilter) IsEmpty() bool {
+	return f == EventFilter{}
+}
+
+// Matches 判断事件是否满足过滤条件，供内存实现使用，语义与 MySQL 的 WHERE 子句一致
+func (f EventFilter) Matches(event *models.GitHubEvent) bool {
+	if f.EventType != "" && string(event.EventType) != f.EventType {
+		return false
+	}
+	if f.Status != "" && string(event.EventStatus) != f.Status {
+		return false
+	}
+	if f.Branch != "" && event.Branch != f.Branch {
+		return false
+	}
+	if f.Repository != "" && event.Repository != f.Repository {
+		return false
+	}
+	if f.Pusher != "" && (event.Pusher == nil || *event.Pusher != f.Pusher) {
+		return false
+	}
+	if f.Author != "" && (event.Author == nil || *event.Author != f.Author) {
+		return false
+	}
+	createdAt := event.CreatedAt.ToTime()
+	if !f.From.IsZero() && createdAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && createdAt.After(f.To) {
+		return false
+	}
+	return true
+}
+
+// sortChecksByStage 按 stage_order、check_order 升序排列检查项，相同时按 id 升序
+// 与 MySQL 查询的 ORDER BY stage_order, check_order, id 保持一致
+func sortChecksByStage(checks []models.PRQualityCheck) {
+	sort.Slice(checks, func(i, j int) bool {
+		if checks[i].StageOrder != checks[j].StageOrder {
+			return checks[i].StageOrder < checks[j].StageOrder
+		}
+		if checks[i].CheckOrder != checks[j].CheckOrder {
+			return checks[i].CheckOrder < checks[j].CheckOrder
+		}
+		return checks[i].ID < checks[j].ID
+	})
+}
+
+// sortEventsNewestFirst 按 id 降序排列事件，id 相同时按 event_id 升序
+// 与 MySQL 查询的 ORDER BY id DESC, event_id ASC 保持一致
+func sortEventsNewestFirst(events []*models.GitHubEvent) {
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].ID != events[j].ID {
+			return events[i].ID > events[j].ID
+		}
+		return events[i].EventID < events[j].EventID
+	})
 }
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
@@ -24,7 +25,7 @@ func TestMockStorage_CreateEvent(t *testing.T) {
 		UpdatedAt:   models.Now(),
 	}
 
-	err := storage.CreateEvent(event)
+	err := storage.CreateEvent(context.Background(), event)
 	if err != nil {
 		t.Fatalf("CreateEvent failed: %v", err)
 	}
@@ -34,7 +35,7 @@ func TestMockStorage_CreateEvent(t *testing.T) {
 	}
 
 	// 验证事件可以被检索
-	retrieved, err := storage.GetEvent(event.ID)
+	retrieved, err := storage.GetEvent(context.Background(), event.ID)
 	if err != nil {
 		t.Fatalf("GetEvent failed: %v", err)
 	}
@@ -63,13 +64,13 @@ func TestMockStorage_CreateEventWithChecks(t *testing.T) {
 		UpdatedAt:     models.Now(),
 	}
 
-	err := storage.CreateEvent(event)
+	err := storage.CreateEvent(context.Background(), event)
 	if err != nil {
 		t.Fatalf("CreateEvent failed: %v", err)
 	}
 
 	// 验证质量检查被保存
-	retrievedChecks, err := storage.ListQualityChecksByEventID(event.EventID)
+	retrievedChecks, err := storage.ListQualityChecksByEventID(context.Background(), event.EventID)
 	if err != nil {
 		t.Fatalf("ListQualityChecksByEventID failed: %v", err)
 	}
@@ -85,7 +86,7 @@ func TestMockStorage_GetEvent(t *testing.T) {
 	storage := NewMockStorage()
 
 	// 测试获取不存在的事件
-	_, err := storage.GetEvent(999)
+	_, err := storage.GetEvent(context.Background(), 999)
 	if err == nil {
 		t.Error("expected error when getting non-existent event")
 	}
@@ -102,9 +103,9 @@ func TestMockStorage_GetEvent(t *testing.T) {
 		UpdatedAt:   models.Now(),
 	}
 
-	storage.CreateEvent(event)
+	storage.CreateEvent(context.Background(), event)
 
-	retrieved, err := storage.GetEvent(event.ID)
+	retrieved, err := storage.GetEvent(context.Background(), event.ID)
 	if err != nil {
 		t.Fatalf("GetEvent failed: %v", err)
 	}
@@ -129,9 +130,9 @@ func TestMockStorage_GetEventByEventID(t *testing.T) {
 		UpdatedAt:   models.Now(),
 	}
 
-	storage.CreateEvent(event)
+	storage.CreateEvent(context.Background(), event)
 
-	retrieved, err := storage.GetEventByEventID(event.EventID)
+	retrieved, err := storage.GetEventByEventID(context.Background(), event.EventID)
 	if err != nil {
 		t.Fatalf("GetEventByEventID failed: %v", err)
 	}
@@ -157,10 +158,10 @@ func TestMockStorage_ListEvents(t *testing.T) {
 			CreatedAt:   models.Now(),
 			UpdatedAt:   models.Now(),
 		}
-		storage.CreateEvent(event)
+		storage.CreateEvent(context.Background(), event)
 	}
 
-	events, err := storage.ListEvents()
+	events, err := storage.ListEvents(context.Background())
 	if err != nil {
 		t.Fatalf("ListEvents failed: %v", err)
 	}
@@ -185,17 +186,17 @@ func TestMockStorage_UpdateEvent(t *testing.T) {
 		UpdatedAt:   models.Now(),
 	}
 
-	storage.CreateEvent(event)
+	storage.CreateEvent(context.Background(), event)
 
 	// 更新事件状态
 	event.EventStatus = models.EventStatusCompleted
-	err := storage.UpdateEvent(event)
+	err := storage.UpdateEvent(context.Background(), event)
 	if err != nil {
 		t.Fatalf("UpdateEvent failed: %v", err)
 	}
 
 	// 验证更新
-	retrieved, _ := storage.GetEvent(event.ID)
+	retrieved, _ := storage.GetEvent(context.Background(), event.ID)
 	if retrieved.EventStatus != models.EventStatusCompleted {
 		t.Errorf("expected status '%s', got '%s'", models.EventStatusCompleted, retrieved.EventStatus)
 	}
@@ -217,7 +218,7 @@ func TestMockStorage_UpdateNonExistentEvent(t *testing.T) {
 		UpdatedAt:   models.Now(),
 	}
 
-	err := storage.UpdateEvent(event)
+	err := storage.UpdateEvent(context.Background(), event)
 	if err == nil {
 		t.Error("expected error when updating non-existent event")
 	}
@@ -238,15 +239,15 @@ func TestMockStorage_DeleteEvent(t *testing.T) {
 		UpdatedAt:   models.Now(),
 	}
 
-	storage.CreateEvent(event)
+	storage.CreateEvent(context.Background(), event)
 
-	err := storage.DeleteEvent(event.ID)
+	err := storage.DeleteEvent(context.Background(), event.ID)
 	if err != nil {
 		t.Fatalf("DeleteEvent failed: %v", err)
 	}
 
 	// 验证事件已删除
-	_, err = storage.GetEvent(event.ID)
+	_, err = storage.GetEvent(context.Background(), event.ID)
 	if err == nil {
 		t.Error("expected error when getting deleted event")
 	}
@@ -256,7 +257,7 @@ func TestMockStorage_DeleteEvent(t *testing.T) {
 func TestMockStorage_DeleteNonExistentEvent(t *testing.T) {
 	storage := NewMockStorage()
 
-	err := storage.DeleteEvent(999)
+	err := storage.DeleteEvent(context.Background(), 999)
 	if err == nil {
 		t.Error("expected error when deleting non-existent event")
 	}
@@ -278,16 +279,16 @@ func TestMockStorage_DeleteAllEvents(t *testing.T) {
 			CreatedAt:   models.Now(),
 			UpdatedAt:   models.Now(),
 		}
-		storage.CreateEvent(event)
+		storage.CreateEvent(context.Background(), event)
 	}
 
-	err := storage.DeleteAllEvents()
+	err := storage.DeleteAllEvents(context.Background())
 	if err != nil {
 		t.Fatalf("DeleteAllEvents failed: %v", err)
 	}
 
 	// 验证所有事件已删除
-	events, _ := storage.ListEvents()
+	events, _ := storage.ListEvents(context.Background())
 	if len(events) != 0 {
 		t.Errorf("expected 0 events, got %d", len(events))
 	}
@@ -310,13 +311,13 @@ func TestMockStorage_QualityCheckOperations(t *testing.T) {
 		UpdatedAt:      models.Now(),
 	}
 
-	err := storage.CreateQualityCheck(check)
+	err := storage.CreateQualityCheck(context.Background(), check)
 	if err != nil {
 		t.Fatalf("CreateQualityCheck failed: %v", err)
 	}
 
 	// 获取质量检查
-	retrieved, err := storage.GetQualityCheck(check.ID)
+	retrieved, err := storage.GetQualityCheck(context.Background(), check.ID)
 	if err != nil {
 		t.Fatalf("GetQualityCheck failed: %v", err)
 	}
@@ -327,13 +328,13 @@ func TestMockStorage_QualityCheckOperations(t *testing.T) {
 
 	// 更新质量检查
 	check.CheckStatus = models.QualityCheckStatusPassed
-	err = storage.UpdateQualityCheck(check)
+	err = storage.UpdateQualityCheck(context.Background(), check)
 	if err != nil {
 		t.Fatalf("UpdateQualityCheck failed: %v", err)
 	}
 
 	// 验证更新
-	retrieved, _ = storage.GetQualityCheck(check.ID)
+	retrieved, _ = storage.GetQualityCheck(context.Background(), check.ID)
 	if retrieved.CheckStatus != models.QualityCheckStatusPassed {
 		t.Errorf("expected status '%s', got '%s'", models.QualityCheckStatusPassed, retrieved.CheckStatus)
 	}
@@ -354,7 +355,7 @@ func TestMockStorage_CleanupExpired(t *testing.T) {
 		CreatedAt:   models.FromTime(time.Now().Add(-2 * time.Hour)),
 		UpdatedAt:   models.FromTime(time.Now().Add(-2 * time.Hour)),
 	}
-	storage.CreateEvent(oldEvent)
+	storage.CreateEvent(context.Background(), oldEvent)
 
 	// 创建新事件
 	newEvent := &models.GitHubEvent{
@@ -367,16 +368,16 @@ func TestMockStorage_CleanupExpired(t *testing.T) {
 		CreatedAt:   models.Now(),
 		UpdatedAt:   models.Now(),
 	}
-	storage.CreateEvent(newEvent)
+	storage.CreateEvent(context.Background(), newEvent)
 
 	// 清理1小时前的数据
-	err := storage.CleanupExpired(1 * time.Hour)
+	err := storage.CleanupExpired(context.Background(), 1 * time.Hour)
 	if err != nil {
 		t.Fatalf("CleanupExpired failed: %v", err)
 	}
 
 	// 验证旧事件被删除，新事件保留
-	events, _ := storage.ListEvents()
+	events, _ := storage.ListEvents(context.Background())
 	if len(events) != 1 {
 		t.Errorf("expected 1 event after cleanup, got %d", len(events))
 	}
@@ -404,7 +405,7 @@ func TestMockStorage_ErrorHandling(t *testing.T) {
 		UpdatedAt:   models.Now(),
 	}
 
-	err := storage.CreateEvent(event)
+	err := storage.CreateEvent(context.Background(), event)
 	if err != testError {
 		t.Errorf("expected test error, got %v", err)
 	}
@@ -413,8 +414,8 @@ func TestMockStorage_ErrorHandling(t *testing.T) {
 	storage.SetCreateError(nil)
 	storage.SetGetError(testError)
 
-	storage.CreateEvent(event)
-	_, err = storage.GetEvent(event.ID)
+	storage.CreateEvent(context.Background(), event)
+	_, err = storage.GetEvent(context.Background(), event.ID)
 	if err != testError {
 		t.Errorf("expected test error from GetEvent, got %v", err)
 	}
@@ -441,11 +442,11 @@ func TestMockStorage_ListEventsPaginated(t *testing.T) {
 			CreatedAt:   models.Now(),
 			UpdatedAt:   models.Now(),
 		}
-		storage.CreateEvent(event)
+		storage.CreateEvent(context.Background(), event)
 	}
 
 	// 测试第一页（20条）
-	events, total, err := storage.ListEventsPaginated(0, 20)
+	events, total, err := storage.ListEventsPaginated(context.Background(), 0, 20)
 	if err != nil {
 		t.Fatalf("ListEventsPaginated failed: %v", err)
 	}
@@ -459,7 +460,7 @@ func TestMockStorage_ListEventsPaginated(t *testing.T) {
 	}
 
 	// 测试第二页（5条）
-	events, total, err = storage.ListEventsPaginated(20, 20)
+	events, total, err = storage.ListEventsPaginated(context.Background(), 20, 20)
 	if err != nil {
 		t.Fatalf("ListEventsPaginated (page 2) failed: %v", err)
 	}
@@ -491,17 +492,17 @@ func TestMockStorage_UpdateEventStatus(t *testing.T) {
 		UpdatedAt:   models.Now(),
 	}
 
-	storage.CreateEvent(event)
+	storage.CreateEvent(context.Background(), event)
 
 	// 测试只更新状态（不设置processed_at）
 	processedAt := models.FromTime(time.Now())
-	err := storage.UpdateEventStatus(event.ID, models.EventStatusCompleted, &processedAt)
+	err := storage.UpdateEventStatus(context.Background(), event.ID, models.EventStatusCompleted, &processedAt)
 	if err != nil {
 		t.Fatalf("UpdateEventStatus failed: %v", err)
 	}
 
 	// 验证更新
-	retrieved, _ := storage.GetEvent(event.ID)
+	retrieved, _ := storage.GetEvent(context.Background(), event.ID)
 	if retrieved.EventStatus != models.EventStatusCompleted {
 		t.Errorf("expected status '%s', got '%s'", models.EventStatusCompleted, retrieved.EventStatus)
 	}
@@ -511,12 +512,12 @@ func TestMockStorage_UpdateEventStatus(t *testing.T) {
 	}
 
 	// 测试不设置processed_at
-	err = storage.UpdateEventStatus(event.ID, models.EventStatusFailed, nil)
+	err = storage.UpdateEventStatus(context.Background(), event.ID, models.EventStatusFailed, nil)
 	if err != nil {
 		t.Fatalf("UpdateEventStatus (without processed_at) failed: %v", err)
 	}
 
-	retrieved, _ = storage.GetEvent(event.ID)
+	retrieved, _ = storage.GetEvent(context.Background(), event.ID)
 	if retrieved.EventStatus != models.EventStatusFailed {
 		t.Errorf("expected status '%s', got '%s'", models.EventStatusFailed, retrieved.EventStatus)
 	}
@@ -539,7 +540,7 @@ func TestMockStorage_BatchUpdateQualityChecks(t *testing.T) {
 		UpdatedAt:     models.Now(),
 	}
 
-	storage.CreateEvent(event)
+	storage.CreateEvent(context.Background(), event)
 
 	// 准备更新的质量检查
 	checksToUpdate := []models.PRQualityCheck{}
@@ -557,14 +558,14 @@ func TestMockStorage_BatchUpdateQualityChecks(t *testing.T) {
 	}
 
 	// 批量更新
-	err := storage.BatchUpdateQualityChecks(checksToUpdate)
+	err := storage.BatchUpdateQualityChecks(context.Background(), checksToUpdate)
 	if err != nil {
 		t.Fatalf("BatchUpdateQualityChecks failed: %v", err)
 	}
 
 	// 验证所有检查都已更新
 	for i, checkID := range event.QualityChecks {
-		retrieved, err := storage.GetQualityCheck(checkID.ID)
+		retrieved, err := storage.GetQualityCheck(context.Background(), checkID.ID)
 		if err != nil {
 			t.Fatalf("GetQualityCheck failed for ID %d: %v", checkID.ID, err)
 		}
@@ -594,7 +595,7 @@ func TestMockStorage_BatchUpdateQualityChecksEmpty(t *testing.T) {
 	storage := NewMockStorage()
 
 	// 测试空数组
-	err := storage.BatchUpdateQualityChecks([]models.PRQualityCheck{})
+	err := storage.BatchUpdateQualityChecks(context.Background(), []models.PRQualityCheck{})
 	if err != nil {
 		t.Errorf("BatchUpdateQualityChecks with empty array should not return error, got %v", err)
 	}
@@ -621,11 +622,11 @@ func TestMockStorage_GetEventStats(t *testing.T) {
 			CreatedAt:   models.Now(),
 			UpdatedAt:   models.Now(),
 		}
-		storage.CreateEvent(event)
+		storage.CreateEvent(context.Background(), event)
 	}
 
 	// 获取统计
-	total, pending, err := storage.GetEventStats()
+	total, pending, err := storage.GetEventStats(context.Background())
 	if err != nil {
 		t.Fatalf("GetEventStats failed: %v", err)
 	}
@@ -639,3 +640,285 @@ func TestMockStorage_GetEventStats(t *testing.T) {
 		t.Errorf("expected 7 pending events, got %d", pending)
 	}
 }
+
+// TestRedactDSN 测试 DSN 密码脱敏
+func TestRedactDSN(t *testing.T) {
+	tests := []struct {
+		name string
+		dsn  string
+		want string
+	}{
+		{"with password", "root:s3cret@tcp(db:3306)/quality?parseTime=true", "root:***@tcp(db:3306)/quality?parseTime=true"},
+		{"without password", "root@tcp(db:3306)/quality", "root@tcp(db:3306)/quality"},
+		{"unparseable", "root:s3cret@bogus", "***"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactDSN(tt.dsn); got != tt.want {
+				t.Errorf("RedactDSN(%q) = %q, want %q", tt.dsn, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMockStorage_MaxEvents 测试超过上限时淘汰最旧的事件及其检查项
+func TestMockStorage_MaxEvents(t *testing.T) {
+	storage := NewMockStorage()
+	storage.SetMaxEvents(3)
+
+	for i := 1; i <= 5; i++ {
+		eventID := fmt.Sprintf("test-event-cap-%d", i)
+		event := &models.GitHubEvent{
+			EventID:       eventID,
+			EventType:     models.EventTypePush,
+			EventStatus:   models.EventStatusPending,
+			Repository:    "test/repo",
+			Branch:        "main",
+			Payload:       []byte(`{}`),
+			QualityChecks: models.CreateChecksForEvent(eventID),
+			CreatedAt:     models.Now(),
+			UpdatedAt:     models.Now(),
+		}
+		if err := storage.CreateEvent(context.Background(), event); err != nil {
+			t.Fatalf("CreateEvent failed: %v", err)
+		}
+	}
+
+	events, _ := storage.ListEvents(context.Background())
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+	for i, want := range []string{"test-event-cap-5", "test-event-cap-4", "test-event-cap-3"} {
+		if events[i].EventID != want {
+			t.Errorf("event %d: expected %s, got %s", i, want, events[i].EventID)
+		}
+	}
+	for _, evicted := range []string{"test-event-cap-1", "test-event-cap-2"} {
+		if _, err := storage.GetEventByEventID(context.Background(), evicted); err == nil {
+			t.Errorf("expected %s to be evicted", evicted)
+		}
+		if checks, _ := storage.ListQualityChecksByEventID(context.Background(), evicted); len(checks) != 0 {
+			t.Errorf("expected checks of %s to be evicted, got %d", evicted, len(checks))
+		}
+	}
+	if checks, _ := storage.ListQualityChecksByEventID(context.Background(), "test-event-cap-5"); len(checks) == 0 {
+		t.Error("expected checks of retained events to be kept")
+	}
+
+	// 降低上限时立即淘汰；删除过的 ID 会被跳过
+	if err := storage.DeleteEvent(context.Background(), events[2].ID); err != nil {
+		t.Fatalf("DeleteEvent failed: %v", err)
+	}
+	storage.SetMaxEvents(1)
+	if events, _ := storage.ListEvents(context.Background()); len(events) != 1 || events[0].EventID != "test-event-cap-5" {
+		t.Errorf("expected only the newest event after lowering the cap, got %d events", len(events))
+	}
+}
+
+// TestMockStorage_ListPendingEvents 测试只返回超过时长仍为 pending 的事件
+func TestMockStorage_ListPendingEvents(t *testing.T) {
+	storage := NewMockStorage()
+	old := models.LocalTime{Time: time.Now().Add(-time.Hour)}
+	for _, e := range []struct {
+		id      string
+		status  models.EventStatus
+		created models.LocalTime
+	}{
+		{"pending-old", models.EventStatusPending, old},
+		{"pending-new", models.EventStatusPending, models.Now()},
+		{"completed-old", models.EventStatusCompleted, old},
+	} {
+		event := &models.GitHubEvent{
+			EventID:     e.id,
+			EventType:   models.EventTypePush,
+			EventStatus: e.status,
+			Payload:     []byte(`{}`),
+			CreatedAt:   e.created,
+			UpdatedAt:   e.created,
+		}
+		if err := storage.CreateEvent(context.Background(), event); err != nil {
+			t.Fatalf("CreateEvent failed: %v", err)
+		}
+	}
+
+	events, err := storage.ListPendingEvents(context.Background(), 10 * time.Minute)
+	if err != nil {
+		t.Fatalf("ListPendingEvents failed: %v", err)
+	}
+	if len(events) != 1 || events[0].EventID != "pending-old" {
+		t.Fatalf("expected only pending-old, got %d events", len(events))
+	}
+}
+
+// TestMockStorage_AttachQualityChecks 测试只能为还没有检查项的事件写入检查项
+func TestMockStorage_AttachQualityChecks(t *testing.T) {
+	storage := NewMockStorage()
+	ctx := context.Background()
+	event := &models.GitHubEvent{
+		EventID:     "attach-1",
+		EventType:   models.EventTypePush,
+		EventStatus: models.EventStatusPending,
+		Repository:  "test/repo",
+		Branch:      "main",
+		Payload:     []byte(`{}`),
+		CreatedAt:   models.Now(),
+		UpdatedAt:   models.Now(),
+	}
+	if err := storage.CreateEvent(ctx, event); err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+
+	checks := []models.PRQualityCheck{
+		{CheckType: models.QualityCheckTypeCompilation, CheckStatus: models.QualityCheckStatusPending},
+	}
+	if err := storage.AttachQualityChecks(ctx, event.ID, checks); err != nil {
+		t.Fatalf("AttachQualityChecks failed: %v", err)
+	}
+	stored, err := storage.ListQualityChecksByEventID(ctx, event.EventID)
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("expected 1 stored check, got %d (err=%v)", len(stored), err)
+	}
+
+	if err := storage.AttachQualityChecks(ctx, event.ID, checks); !errors.Is(err, ErrConflict) {
+		t.Errorf("second attach: expected ErrConflict, got %v", err)
+	}
+	if err := storage.AttachQualityChecks(ctx, 999, checks); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("missing event: expected ErrEventNotFound, got %v", err)
+	}
+}
+
+// TestMockStorage_ListRepositories 测试按仓库汇总事件数和最近事件时间
+func TestMockStorage_ListRepositories(t *testing.T) {
+	storage := NewMockStorage()
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	for i, e := range []struct {
+		repo   string
+		offset time.Duration
+	}{
+		{"org/a", 0},
+		{"org/b", time.Hour},
+		{"org/a", 2 * time.Hour},
+		{"org/c", 2 * time.Hour},
+	} {
+		created := models.FromTime(base.Add(e.offset))
+		event := &models.GitHubEvent{
+			EventID:     fmt.Sprintf("repo-%d", i),
+			EventType:   models.EventTypePush,
+			EventStatus: models.EventStatusPending,
+			Repository:  e.repo,
+			Payload:     []byte(`{}`),
+			CreatedAt:   created,
+			UpdatedAt:   created,
+		}
+		if err := storage.CreateEvent(context.Background(), event); err != nil {
+			t.Fatalf("CreateEvent failed: %v", err)
+		}
+	}
+
+	repos, err := storage.ListRepositories(context.Background())
+	if err != nil {
+		t.Fatalf("ListRepositories failed: %v", err)
+	}
+	want := []struct {
+		repo  string
+		count int
+		last  time.Time
+	}{
+		{"org/a", 2, base.Add(2 * time.Hour)},
+		{"org/c", 1, base.Add(2 * time.Hour)},
+		{"org/b", 1, base.Add(time.Hour)},
+	}
+	if len(repos) != len(want) {
+		t.Fatalf("expected %d repositories, got %d", len(want), len(repos))
+	}
+	for i, w := range want {
+		got := repos[i]
+		if got.Repository != w.repo || got.EventCount != w.count || !got.LastSeenAt.Equal(w.last) {
+			t.Errorf("repo %d: got %s count=%d last=%s, want %s count=%d last=%s",
+				i, got.Repository, got.EventCount, got.LastSeenAt, w.repo, w.count, w.last)
+		}
+	}
+}
+
+// TestEventFilter_PusherAuthor 测试 pusher/author 精确匹配，字段为空的事件在设置条件时被排除
+func TestEventFilter_PusherAuthor(t *testing.T) {
+	alice, bob := "alice", "bob"
+	tests := []struct {
+		name   string
+		filter EventFilter
+		event  models.GitHubEvent
+		want   bool
+	}{
+		{"no filter matches nil pusher", EventFilter{}, models.GitHubEvent{}, true},
+		{"pusher matches", EventFilter{Pusher: "alice"}, models.GitHubEvent{Pusher: &alice}, true},
+		{"pusher differs", EventFilter{Pusher: "alice"}, models.GitHubEvent{Pusher: &bob}, false},
+		{"nil pusher excluded", EventFilter{Pusher: "alice"}, models.GitHubEvent{Author: &alice}, false},
+		{"author matches", EventFilter{Author: "bob"}, models.GitHubEvent{Author: &bob}, true},
+		{"nil author excluded", EventFilter{Author: "bob"}, models.GitHubEvent{Pusher: &bob}, false},
+		{"pusher is not a prefix match", EventFilter{Pusher: "ali"}, models.GitHubEvent{Pusher: &alice}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(&tt.event); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMockStorage_ListQualityChecksByEventIDPaginated 测试检查项按阶段顺序分页
+func TestMockStorage_ListQualityChecksByEventIDPaginated(t *testing.T) {
+	storage := NewMockStorage()
+	event := &models.GitHubEvent{
+		EventID:       "paged-checks",
+		EventType:     models.EventTypePush,
+		EventStatus:   models.EventStatusPending,
+		Payload:       []byte(`{}`),
+		QualityChecks: models.CreateChecksForEvent("paged-checks"),
+	}
+	if err := storage.CreateEvent(context.Background(), event); err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+	all, err := storage.ListQualityChecksByEventID(context.Background(), "paged-checks")
+	if err != nil {
+		t.Fatalf("ListQualityChecksByEventID failed: %v", err)
+	}
+	for i := 1; i < len(all); i++ {
+		prev, cur := all[i-1], all[i]
+		if prev.StageOrder > cur.StageOrder || (prev.StageOrder == cur.StageOrder && prev.CheckOrder > cur.CheckOrder) {
+			t.Fatalf("checks not ordered by stage_order, check_order at %d: %+v before %+v", i, prev, cur)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		offset, limit int
+		wantFrom      int
+		wantLen       int
+	}{
+		{name: "first page", offset: 0, limit: 2, wantFrom: 0, wantLen: 2},
+		{name: "second page", offset: 2, limit: 2, wantFrom: 2, wantLen: 2},
+		{name: "last partial page", offset: len(all) - 1, limit: 5, wantFrom: len(all) - 1, wantLen: 1},
+		{name: "past the end", offset: len(all) + 3, limit: 2, wantLen: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks, total, err := storage.ListQualityChecksByEventIDPaginated(context.Background(), "paged-checks", tt.offset, tt.limit)
+			if err != nil {
+				t.Fatalf("ListQualityChecksByEventIDPaginated failed: %v", err)
+			}
+			if total != len(all) {
+				t.Errorf("total=%d, want %d", total, len(all))
+			}
+			if len(checks) != tt.wantLen {
+				t.Fatalf("got %d checks, want %d", len(checks), tt.wantLen)
+			}
+			for i, c := range checks {
+				if c.ID != all[tt.wantFrom+i].ID {
+					t.Errorf("check %d: id=%d, want %d", i, c.ID, all[tt.wantFrom+i].ID)
+				}
+			}
+		})
+	}
+}
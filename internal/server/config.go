@@ -6,15 +6,34 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	cfgpkg "github-hub/internal/config"
 )
 
 // Config holds server defaults for root path, auth token, and default user grouping.
 type Config struct {
-	Addr            string `json:"addr"`
-	Root            string `json:"root"`
-	Token           string `json:"token"`
-	DefaultUser     string `json:"default_user"`
-	DownloadTimeout string `json:"download_timeout"` // e.g. "10m", "5m"
+	Addr            string `json:"addr" yaml:"addr"`
+	Root            string `json:"root" yaml:"root"`
+	Token           string `json:"token" yaml:"token"`
+	DefaultUser     string `json:"default_user" yaml:"default_user"`
+	DownloadTimeout string `json:"download_timeout" yaml:"download_timeout"` // e.g. "10m", "5m"
+	CleanupInterval string `json:"cleanup_interval" yaml:"cleanup_interval"` // janitor interval, e.g. "1m", "1h"
+	TTL             string `json:"ttl" yaml:"ttl"`                           // cache retention, e.g. "24h", "168h"
+
+	// GitHub Enterprise endpoints; empty values use the public github.com hosts.
+	GitHubAPIURL      string `json:"github_api_url" yaml:"github_api_url"`           // e.g. "https://ghe.example.com/api/v3"
+	GitHubCodeloadURL string `json:"github_codeload_url" yaml:"github_codeload_url"` // e.g. "https://codeload.ghe.example.com"
+	GitHubURL         string `json:"github_url" yaml:"github_url"`                   // git clone host, e.g. "https://ghe.example.com"
+
+	// Branch used when the default branch cannot be resolved (e.g. rate limited);
+	// empty reuses the most recently cached branch for the repo.
+	FallbackBranch string `json:"fallback_branch" yaml:"fallback_branch"`
+
+	// Optional webhook notified when a repo archive is fetched fresh (cache miss).
+	MissWebhookURL      string `json:"miss_webhook_url" yaml:"miss_webhook_url"`
+	MissWebhookMinBytes int64  `json:"miss_webhook_min_bytes" yaml:"miss_webhook_min_bytes"` // only notify for archives at least this large
 }
 
 func DefaultConfig() Config {
@@ -23,10 +42,13 @@ func DefaultConfig() Config {
 		Root:            "data",
 		DefaultUser:     "default",
 		DownloadTimeout: "30m",
+		CleanupInterval: "1m",
+		TTL:             "24h",
 	}
 }
 
 // LoadConfig loads YAML or JSON config. If path is empty or file missing, returns default config.
+// String values may reference environment variables (${VAR}, $VAR; $$ for a literal $).
 func LoadConfig(path string) (Config, error) {
 	cfg := DefaultConfig()
 	if path == "" {
@@ -45,12 +67,14 @@ func LoadConfig(path string) (Config, error) {
 		if err != nil {
 			return Config{}, fmt.Errorf("yaml parse error: %w", err)
 		}
+		cfgpkg.ExpandEnvFields(&c)
 		return c, nil
 	}
 
 	if err := json.Unmarshal(b, &cfg); err != nil {
 		return Config{}, err
 	}
+	cfgpkg.ExpandEnvFields(&cfg)
 	return cfg, nil
 }
 
@@ -64,42 +88,27 @@ func looksLikeYAML(s string) bool {
 	return strings.HasPrefix(trim, "addr:") || strings.HasPrefix(trim, "root:") || strings.HasPrefix(trim, "token:") || strings.Contains(trim, "default_user")
 }
 
-// Minimal YAML parser for the limited schema of Config.
+// parseYAMLConfig decodes a YAML config on top of the defaults. Keys that are
+// missing or empty keep their default values.
 func parseYAMLConfig(s string) (Config, error) {
 	cfg := DefaultConfig()
-	for _, raw := range strings.Split(s, "\n") {
-		line := strings.TrimRight(raw, "\r")
-		t := strings.TrimSpace(line)
-		if t == "" || strings.HasPrefix(t, "#") {
-			continue
-		}
-		kv := strings.SplitN(t, ":", 2)
-		if len(kv) != 2 {
-			continue
-		}
-		k := strings.TrimSpace(kv[0])
-		v := strings.Trim(strings.TrimSpace(kv[1]), "\"'")
-		switch k {
-		case "addr":
-			if v != "" {
-				cfg.Addr = v
-			}
-		case "root":
-			if v != "" {
-				cfg.Root = v
-			}
-		case "token":
-			if v != "" {
-				cfg.Token = v
-			}
-		case "default_user":
-			if v != "" {
-				cfg.DefaultUser = v
-			}
-		case "download_timeout":
-			if v != "" {
-				cfg.DownloadTimeout = v
-			}
+	if err := yaml.Unmarshal([]byte(s), &cfg); err != nil {
+		return Config{}, err
+	}
+	def := DefaultConfig()
+	for _, f := range []struct {
+		val *string
+		def string
+	}{
+		{&cfg.Addr, def.Addr},
+		{&cfg.Root, def.Root},
+		{&cfg.DefaultUser, def.DefaultUser},
+		{&cfg.DownloadTimeout, def.DownloadTimeout},
+		{&cfg.CleanupInterval, def.CleanupInterval},
+		{&cfg.TTL, def.TTL},
+	} {
+		if strings.TrimSpace(*f.val) == "" {
+			*f.val = f.def
 		}
 	}
 	return cfg, nil
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"embed"
 	"encoding/json"
@@ -8,32 +9,65 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github-hub/internal/storage"
+	"github-hub/internal/version"
 )
 
-const defaultDownloadTimeout = 30 * time.Minute
+const (
+	defaultDownloadTimeout = 30 * time.Minute
+	defaultCleanupInterval = time.Minute
+	defaultTTL             = 24 * time.Hour
+
+	// maxListDepth and maxListEntries bound recursive directory listings.
+	maxListDepth   = 32
+	maxListEntries = 10000
+)
 
 //go:embed static/*
 var uiFS embed.FS
 
 // Store is the abstraction for workspace/cache storage used by the server.
 type Store interface {
-	EnsureRepo(ctx context.Context, user, ownerRepo, branch, token string, force, legacy bool) (string, error)
+	EnsureRepo(ctx context.Context, user, ownerRepo, branch, token, format string, force, legacy bool) (string, error)
 	EnsurePackage(ctx context.Context, user, pkgURL string) (string, error)
 	EnsureBareRepo(ctx context.Context, ownerRepo, token string) (string, error)
+	ListRemoteBranches(ctx context.Context, ownerRepo, token string) ([]string, error)
+	StatRepo(user, ownerRepo, branch, format string, legacy bool) (storage.RepoStat, error)
+	CacheStats(user string) (storage.CacheStats, error)
+	ClearUser(user string) (int64, error)
 	ExportSparseZip(ctx context.Context, ownerRepo, branch string, paths []string, destZip string) (string, error)
 	ExportSparseDir(ctx context.Context, ownerRepo, branch string, paths []string, destDir string) (string, error)
 	List(rel string) ([]storage.Entry, error)
+	ListRecursive(rel string, maxDepth, maxEntries int) ([]storage.Entry, bool, error)
 	Delete(rel string, recursive bool) error
+	Move(from, to string) error
+	ExtractZip(user, rel, zipPath string) error
 	Touch(rel string) error
-	CleanupExpired(ttl time.Duration) error
+	CleanupExpired(ttl time.Duration) ([]string, error)
+}
+
+// storeCloser is implemented by stores with background work to finish on shutdown.
+type storeCloser interface {
+	Close() error
+}
+
+// sizeCleaner is implemented by stores that can evict cached archives to stay under a byte cap.
+type sizeCleaner interface {
+	CleanupToSize(maxBytes int64) ([]string, error)
+}
+
+// flightStatsProvider is implemented by stores that coalesce concurrent EnsureRepo calls.
+type flightStatsProvider interface {
+	FlightStats() storage.FlightStats
 }
 
 type Server struct {
@@ -44,60 +78,179 @@ type Server struct {
 
 	cleanupInterval time.Duration
 	ttl             time.Duration
+	maxCacheBytes   int64
+
+	// maxUploadBytes caps the request body accepted by /api/v1/upload.
+	maxUploadBytes int64
 
 	janitorCtx    context.Context
 	janitorCancel context.CancelFunc
+
+	// ready is set once the workspace root exists and the janitor is running,
+	// and cleared by Shutdown so /readyz fails while the process drains.
+	ready atomic.Bool
+}
+
+// Options configures a Server. Zero durations fall back to the defaults.
+type Options struct {
+	Root            string
+	DefaultUser     string
+	GitHubToken     string
+	DownloadTimeout time.Duration
+	CleanupInterval time.Duration // how often the janitor runs
+	TTL             time.Duration // cached entries idle longer than this are removed
+	MaxCacheBytes   int64         // janitor evicts least recently used archives above this size (0 = no cap)
+
+	// GitHub endpoints for Enterprise installs; empty values use the public github.com hosts.
+	GitHubAPIURL      string
+	GitHubCodeloadURL string
+	GitHubURL         string
+
+	// FallbackBranch is used when the default branch cannot be resolved from
+	// the GitHub API; empty means reuse the most recently cached branch.
+	FallbackBranch string
+
+	// Debug logs the remaining GitHub API quota after each API call.
+	Debug bool
+
+	// Store overrides the filesystem storage rooted at Root (used by tests).
+	Store Store
 }
 
 func NewServer(root, defaultUser, githubToken string, downloadTimeout time.Duration) (*Server, error) {
-	if err := os.MkdirAll(root, 0o755); err != nil {
-		return nil, err
+	return NewServerWithOptions(Options{
+		Root:            root,
+		DefaultUser:     defaultUser,
+		GitHubToken:     githubToken,
+		DownloadTimeout: downloadTimeout,
+	})
+}
+
+// NewServerWithOptions creates a Server and starts its janitor with the configured interval and TTL.
+func NewServerWithOptions(opts Options) (*Server, error) {
+	if opts.DownloadTimeout <= 0 {
+		opts.DownloadTimeout = defaultDownloadTimeout
+	}
+	if opts.CleanupInterval <= 0 {
+		opts.CleanupInterval = defaultCleanupInterval
 	}
-	if downloadTimeout <= 0 {
-		downloadTimeout = defaultDownloadTimeout
+	if opts.TTL <= 0 {
+		opts.TTL = defaultTTL
+	}
+	store := opts.Store
+	if store == nil {
+		if err := os.MkdirAll(opts.Root, 0o755); err != nil {
+			return nil, err
+		}
+		// Pass download timeout to storage HTTP client
+		st := storage.NewWithTimeout(opts.Root, opts.DownloadTimeout)
+		if opts.GitHubAPIURL != "" {
+			st.APIBaseURL = opts.GitHubAPIURL
+		}
+		if opts.GitHubCodeloadURL != "" {
+			st.CodeloadBaseURL = opts.GitHubCodeloadURL
+		}
+		if opts.GitHubURL != "" {
+			st.GitBaseURL = opts.GitHubURL
+		}
+		st.FallbackBranch = opts.FallbackBranch
+		st.Debug = opts.Debug
+		store = st
 	}
-	// Pass download timeout to storage HTTP client
-	st := storage.NewWithTimeout(root, downloadTimeout)
 	ctx, cancel := context.WithCancel(context.Background())
 	s := &Server{
-		store:           st,
-		token:           githubToken,
-		defaultUser:     defaultUser,
-		downloadTO:      downloadTimeout,
-		cleanupInterval: time.Minute,
-		ttl:             24 * time.Hour,
+		store:           store,
+		token:           opts.GitHubToken,
+		defaultUser:     opts.DefaultUser,
+		downloadTO:      opts.DownloadTimeout,
+		cleanupInterval: opts.CleanupInterval,
+		ttl:             opts.TTL,
+		maxCacheBytes:   opts.MaxCacheBytes,
+		maxUploadBytes:  storage.DefaultMaxExtractBytes,
 		janitorCtx:      ctx,
 		janitorCancel:   cancel,
 	}
 	go s.startJanitor()
+	s.ready.Store(true)
 	return s, nil
 }
 
 // NewServerWithStore allows tests to inject a fake store.
 func NewServerWithStore(store Store, githubToken, defaultUser string) *Server {
-	ctx, cancel := context.WithCancel(context.Background())
-	s := &Server{
-		store:           store,
-		token:           githubToken,
-		defaultUser:     defaultUser,
-		downloadTO:      defaultDownloadTimeout,
-		cleanupInterval: time.Minute,
-		ttl:             24 * time.Hour,
-		janitorCtx:      ctx,
-		janitorCancel:   cancel,
-	}
-	go s.startJanitor()
+	s, _ := NewServerWithOptions(Options{
+		Store:       store,
+		GitHubToken: githubToken,
+		DefaultUser: defaultUser,
+	})
 	return s
 }
 
+// SetMissWebhook posts a JSON notification to url whenever EnsureRepo fetches a fresh
+// archive of at least minBytes. Delivery is async and best-effort. An empty url disables it.
+func (s *Server) SetMissWebhook(url string, minBytes int64) {
+	st, ok := s.store.(*storage.Storage)
+	if !ok {
+		return
+	}
+	url = strings.TrimSpace(url)
+	if url == "" {
+		st.OnFreshDownload = nil
+		return
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	st.OnFreshDownload = func(ev storage.FreshDownload) {
+		if ev.Bytes < minBytes {
+			return
+		}
+		go postMissWebhook(client, url, ev)
+	}
+}
+
+func postMissWebhook(client *http.Client, url string, ev storage.FreshDownload) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"user":     ev.User,
+		"repo":     ev.Repo,
+		"branch":   ev.Branch,
+		"bytes":    ev.Bytes,
+		"duration": ev.Duration.String(),
+	})
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Printf("miss webhook error repo=%s branch=%s err=%v\n", ev.Repo, ev.Branch, err)
+		return
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		fmt.Printf("miss webhook error repo=%s branch=%s status=%d\n", ev.Repo, ev.Branch, resp.StatusCode)
+	}
+}
+
+// SetUserQuota limits the bytes of cached repo zips each user may hold (0 = unlimited).
+func (s *Server) SetUserQuota(bytes int64) {
+	if st, ok := s.store.(*storage.Storage); ok {
+		st.UserQuotaBytes = bytes
+	}
+}
+
 func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/download", s.handleDownload)
 	mux.HandleFunc("/api/v1/download/commit", s.handleDownloadCommit)
 	mux.HandleFunc("/api/v1/download/package", s.handleDownloadPackage)
 	mux.HandleFunc("/api/v1/download/sparse", s.handleDownloadSparse)
 	mux.HandleFunc("/api/v1/branch/switch", s.handleBranchSwitch)
+	mux.HandleFunc("/api/v1/branches", s.handleBranches)
+	mux.HandleFunc("/api/v1/stat", s.handleStat)
 	mux.HandleFunc("/api/v1/dir/list", s.handleDirList)
 	mux.HandleFunc("/api/v1/dir", s.handleDir)
+	mux.HandleFunc("/api/v1/dir/move", s.handleDirMove)
+	mux.HandleFunc("/api/v1/upload", s.handleUpload)
+	mux.HandleFunc("/api/v1/cache/stats", s.handleCacheStats)
+	mux.HandleFunc("/api/v1/cache", s.handleCacheClear)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/readyz", s.handleReadyz)
+	mux.HandleFunc("/api/v1/version", s.handleVersion)
 	// Static UI for browsing cached workspace
 	sub, _ := fs.Sub(uiFS, "static")
 	mux.Handle("/", http.FileServer(http.FS(sub)))
@@ -114,12 +267,18 @@ func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
 	branch := strings.TrimSpace(r.URL.Query().Get("branch"))
 	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
 	legacy, _ := strconv.ParseBool(r.URL.Query().Get("legacy"))
+	format := strings.TrimSpace(r.URL.Query().Get("format"))
 	debugDelayStr := strings.TrimSpace(r.URL.Query().Get("debug_delay"))
 	debugStreamDelayStr := strings.TrimSpace(r.URL.Query().Get("debug_stream_delay"))
 	if repo == "" {
 		http.Error(w, "missing repo", http.StatusBadRequest)
 		return
 	}
+	ext, err := storage.ArchiveExt(format)
+	if err != nil {
+		httpError(w, "download", err)
+		return
+	}
 	ctx, cancel := context.WithTimeout(r.Context(), s.downloadTO)
 	defer cancel()
 
@@ -144,26 +303,39 @@ func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Ensure cached copy exists (download if missing), and then stream a zip.
+	// Ensure cached copy exists (download if missing), and then stream the archive.
 	// If branch is empty, EnsureRepo will use "main" (git mode) or fetch default from GitHub (legacy mode).
 	// If force is true, bypass cache validation and always download fresh.
 	// If legacy is true, use old GitHub zipball API instead of git archive.
-	zipPath, err := s.store.EnsureRepo(ctx, user, repo, branch, token, force, legacy)
+	// format=tar.gz serves a gzipped tarball instead of a zip.
+	zipPath, err := s.store.EnsureRepo(ctx, user, repo, branch, token, ext, force, legacy)
 	if err != nil {
 		fmt.Printf("download error user=%s repo=%s branch=%s err=%v\n", user, repo, branch, err)
 		httpError(w, "ensure repo", err)
 		return
 	}
-	// Extract actual branch name from zipPath (e.g., "main.zip" -> "main")
-	actualBranch := strings.TrimSuffix(filepath.Base(zipPath), ".zip")
-	commitPath := strings.TrimSuffix(zipPath, ".zip") + ".commit.txt"
+	// Extract the branch file segment from zipPath (e.g., "main.zip" -> "main",
+	// "feature%2Fx.zip" -> "feature%2Fx"); slashes were escaped by storage.BranchFileName.
+	branchFile := strings.TrimSuffix(filepath.Base(zipPath), "."+ext)
+	actualBranch := storage.BranchFromFileName(branchFile)
+	commitPath := storage.CommitFilePath(zipPath)
 	if commit := readCommitFile(commitPath); commit != "" {
 		w.Header().Set("X-GHH-Commit", commit)
 	}
-	w.Header().Set("Content-Type", "application/zip")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.zip\"", safeName(repo, actualBranch)))
-	// Update access time for the zip file itself
-	zipRelPath := s.userPath(user, filepath.Join("repos", repo, actualBranch+".zip"))
+	// Conditional download: the client already has this commit, skip the body.
+	if known := strings.TrimSpace(r.Header.Get("X-GHH-Known-Commit")); known != "" && commitMatches(known, readCommitFile(zipPath+".meta")) {
+		w.WriteHeader(http.StatusNotModified)
+		fmt.Printf("download not modified user=%s repo=%s branch=%s commit=%s\n", user, repo, actualBranch, known)
+		return
+	}
+	if ext == storage.FormatTarGz {
+		w.Header().Set("Content-Type", "application/gzip")
+	} else {
+		w.Header().Set("Content-Type", "application/zip")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s\"", safeName(repo, actualBranch), ext))
+	// Update access time for the archive itself
+	zipRelPath := s.userPath(user, filepath.Join("repos", repo, branchFile+"."+ext))
 	_ = s.store.Touch(zipRelPath)
 	f, err := os.Open(zipPath)
 	if err != nil {
@@ -174,6 +346,13 @@ func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
 	defer func() { _ = f.Close() }()
 	var reader io.Reader = f
 	if fi, err := f.Stat(); err == nil {
+		sum, err := storage.ArchiveChecksum(zipPath)
+		if err != nil {
+			fmt.Printf("zip checksum error user=%s repo=%s branch=%s err=%v\n", user, repo, actualBranch, err)
+			httpError(w, "checksum zip", err)
+			return
+		}
+		w.Header().Set("X-GHH-SHA256", sum)
 		w.Header().Set("Content-Length", strconv.FormatInt(fi.Size(), 10))
 		if streamDelay > 0 {
 			reader = newSlowReader(f, r.Context(), streamDelay, fi.Size())
@@ -206,14 +385,13 @@ func (s *Server) handleDownloadCommit(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), s.downloadTO)
 	defer cancel()
 
-	zipPath, err := s.store.EnsureRepo(ctx, user, repo, branch, token, force, legacy)
+	zipPath, err := s.store.EnsureRepo(ctx, user, repo, branch, token, "", force, legacy)
 	if err != nil {
 		fmt.Printf("download commit error user=%s repo=%s branch=%s err=%v\n", user, repo, branch, err)
 		httpError(w, "ensure repo", err)
 		return
 	}
-	commitPath := strings.TrimSuffix(zipPath, ".zip") + ".commit.txt"
-	commit := readCommitFile(commitPath)
+	commit := readCommitFile(storage.CommitFilePath(zipPath))
 	if commit == "" {
 		http.NotFound(w, r)
 		return
@@ -386,7 +564,7 @@ func (s *Server) handleBranchSwitch(w http.ResponseWriter, r *http.Request) {
 	}
 	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
 	defer cancel()
-	if _, err := s.store.EnsureRepo(ctx, user, req.Repo, req.Branch, token, req.Force, req.Legacy); err != nil {
+	if _, err := s.store.EnsureRepo(ctx, user, req.Repo, req.Branch, token, "", req.Force, req.Legacy); err != nil {
 		fmt.Printf("branch switch error user=%s repo=%s branch=%s err=%v\n", user, req.Repo, req.Branch, err)
 		httpError(w, "ensure branch", err)
 		return
@@ -399,6 +577,55 @@ func (s *Server) handleBranchSwitch(w http.ResponseWriter, r *http.Request) {
 	fmt.Printf("branch switch ok user=%s repo=%s branch=%s\n", user, req.Repo, req.Branch)
 }
 
+func (s *Server) handleBranches(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token := tokenFromRequest(r, s.token)
+	repo := strings.TrimSpace(r.URL.Query().Get("repo"))
+	if repo == "" {
+		http.Error(w, "missing repo", http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+	defer cancel()
+	branches, err := s.store.ListRemoteBranches(ctx, repo, token)
+	if err != nil {
+		fmt.Printf("list branches error repo=%s err=%v\n", repo, err)
+		httpError(w, "list branches", err)
+		return
+	}
+	if branches == nil {
+		branches = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(branches)
+}
+
+func (s *Server) handleStat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user := s.resolveUser(r)
+	repo := strings.TrimSpace(r.URL.Query().Get("repo"))
+	branch := strings.TrimSpace(r.URL.Query().Get("branch"))
+	legacy, _ := strconv.ParseBool(r.URL.Query().Get("legacy"))
+	format := strings.TrimSpace(r.URL.Query().Get("format"))
+	if repo == "" {
+		http.Error(w, "missing repo", http.StatusBadRequest)
+		return
+	}
+	st, err := s.store.StatRepo(user, repo, branch, format, legacy)
+	if err != nil {
+		httpError(w, "stat", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(st)
+}
+
 func (s *Server) handleDirList(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -410,6 +637,11 @@ func (s *Server) handleDirList(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "bad path", http.StatusBadRequest)
 		return
 	}
+	glob := r.URL.Query().Get("glob")
+	if _, err := storage.FilterEntries(nil, glob); err != nil {
+		httpError(w, "list", err)
+		return
+	}
 
 	// Support listing git-cache directory (shared bare repo cache)
 	cleanRel := strings.TrimLeft(filepath.ToSlash(rel), "./")
@@ -422,6 +654,11 @@ func (s *Server) handleDirList(w http.ResponseWriter, r *http.Request) {
 		_ = s.store.Touch(listPath)
 	}
 
+	if recursive, _ := strconv.ParseBool(r.URL.Query().Get("recursive")); recursive {
+		s.writeDirTree(w, user, rel, cleanRel, listPath, glob)
+		return
+	}
+
 	list, err := s.store.List(listPath)
 	if err != nil {
 		// Return empty list for not found paths (e.g., new user with no cached repos)
@@ -445,6 +682,8 @@ func (s *Server) handleDirList(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	list, _ = storage.FilterEntries(list, glob)
+
 	// Rewrite paths to be relative to user root (no users/<user> prefix), so UI can delete correctly.
 	for i := range list {
 		name := list[i].Name
@@ -462,6 +701,41 @@ func (s *Server) handleDirList(w http.ResponseWriter, r *http.Request) {
 	fmt.Printf("dir list ok user=%s path=%s entries=%d\n", user, rel, len(list))
 }
 
+// writeDirTree answers a recursive listing as {"path", "entries", "truncated"}.
+// Entry paths are relative to the user root, like the flat listing, and only
+// entries whose name matches glob are returned.
+func (s *Server) writeDirTree(w http.ResponseWriter, user, rel, cleanRel, listPath, glob string) {
+	list, truncated, err := s.store.ListRecursive(listPath, maxListDepth, maxListEntries)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			list = []storage.Entry{}
+		} else {
+			fmt.Printf("dir list error user=%s path=%s err=%v\n", user, rel, err)
+			httpError(w, "list", err)
+			return
+		}
+	}
+	list, _ = storage.FilterEntries(list, glob)
+	for i := range list {
+		sub := strings.TrimPrefix(strings.TrimPrefix(list[i].Path, listPath), "/")
+		if cleanRel == "" || cleanRel == "." {
+			list[i].Path = sub
+		} else {
+			list[i].Path = filepath.ToSlash(filepath.Join(cleanRel, sub))
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"path":      cleanRel,
+		"entries":   list,
+		"truncated": truncated,
+	}); err != nil {
+		fmt.Printf("dir list write error user=%s path=%s err=%v\n", user, rel, err)
+		return
+	}
+	fmt.Printf("dir list ok user=%s path=%s entries=%d truncated=%v\n", user, rel, len(list), truncated)
+}
+
 func (s *Server) handleDir(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodDelete:
@@ -498,18 +772,215 @@ func (s *Server) handleDir(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleDirMove renames a path in the user's workspace. Body: {"from", "to"},
+// both relative to the user root.
+func (s *Server) handleDirMove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user := s.resolveUser(r)
+	var req struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	from, to := strings.TrimSpace(req.From), strings.TrimSpace(req.To)
+	if from == "" || to == "" || badRel(from) || badRel(to) {
+		http.Error(w, "bad path", http.StatusBadRequest)
+		return
+	}
+	if err := s.store.Move(s.userPath(user, from), s.userPath(user, to)); err != nil {
+		fmt.Printf("move error user=%s from=%s to=%s err=%v\n", user, from, to, err)
+		httpError(w, "move", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.WriteString(w, "moved"); err != nil {
+		return
+	}
+	fmt.Printf("move ok user=%s from=%s to=%s\n", user, from, to)
+}
+
+func (s *Server) flightStats() storage.FlightStats {
+	if p, ok := s.store.(flightStatsProvider); ok {
+		return p.FlightStats()
+	}
+	return storage.FlightStats{}
+}
+
+func (s *Server) handleCacheStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user := s.resolveUser(r)
+	stats, err := s.store.CacheStats(user)
+	if err != nil {
+		httpError(w, "cache stats", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"ensure_repo": s.flightStats(),
+		"user":        user,
+		"entries":     stats.Entries,
+		"total_size":  stats.TotalSize,
+		"total_count": stats.TotalCount,
+	})
+}
+
+// handleCacheClear removes the requesting user's whole cache (users/<user>) and
+// reports how many bytes were freed.
+func (s *Server) handleCacheClear(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user := s.resolveUser(r)
+	freed, err := s.store.ClearUser(user)
+	if err != nil {
+		fmt.Printf("cache clear error user=%s err=%v\n", user, err)
+		httpError(w, "clear cache", err)
+		return
+	}
+	fmt.Printf("cache cleared user=%s freed=%d\n", user, freed)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"user":        user,
+		"freed_bytes": freed,
+	})
+}
+
+// handleHealthz reports liveness: it answers 200 whenever the process can serve requests.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = io.WriteString(w, "ok\n")
+}
+
+// handleReadyz answers 200 once NewServer finished initializing and 503 after
+// MarkNotReady or Shutdown.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if !s.ready.Load() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = io.WriteString(w, "ok\n")
+}
+
+// handleVersion reports the build metadata of the running binary.
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(version.Current())
+}
+
+// handleMetrics exposes counters in the Prometheus text exposition format.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	fs := s.flightStats()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintln(w, "# HELP ghh_ensure_repo_requests_total EnsureRepo calls by single-flight role.")
+	fmt.Fprintln(w, "# TYPE ghh_ensure_repo_requests_total counter")
+	fmt.Fprintf(w, "ghh_ensure_repo_requests_total{role=\"leader\"} %d\n", fs.Leaders)
+	fmt.Fprintf(w, "ghh_ensure_repo_requests_total{role=\"coalesced\"} %d\n", fs.Coalesced)
+}
+
+// handleUpload accepts a zip body and extracts it under the user's workspace at ?path=.
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user := s.resolveUser(r)
+	rel := strings.TrimSpace(r.URL.Query().Get("path"))
+	if rel == "" || badRel(rel) {
+		http.Error(w, "bad path", http.StatusBadRequest)
+		return
+	}
+	rel = s.userPath(user, rel)
+
+	tmpFile, err := os.CreateTemp("", "ghh-upload-*.zip")
+	if err != nil {
+		httpError(w, "upload", err)
+		return
+	}
+	tmpPath := tmpFile.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+	// The compressed upload can never legitimately exceed the extraction cap.
+	n, err := io.Copy(tmpFile, http.MaxBytesReader(w, r.Body, s.maxUploadBytes))
+	_ = tmpFile.Close()
+	if err != nil {
+		fmt.Printf("upload read error user=%s path=%s err=%v\n", user, rel, err)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("upload: body exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "upload: read body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.store.ExtractZip(user, rel, tmpPath); err != nil {
+		fmt.Printf("upload extract error user=%s path=%s err=%v\n", user, rel, err)
+		httpError(w, "upload", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.WriteString(w, "uploaded"); err != nil {
+		return
+	}
+	fmt.Printf("upload ok user=%s path=%s bytes=%d\n", user, rel, n)
+}
+
 func httpError(w http.ResponseWriter, op string, err error) {
 	code := http.StatusInternalServerError
-	if errors.Is(err, storage.ErrBadPath) || errors.Is(err, storage.ErrNotFound) {
+	var rl *storage.ErrRateLimited
+	if errors.Is(err, storage.ErrBadPath) || errors.Is(err, storage.ErrBadArchive) {
 		code = http.StatusBadRequest
+	} else if errors.Is(err, storage.ErrNotFound) {
+		code = http.StatusNotFound
+	} else if errors.Is(err, storage.ErrExists) {
+		code = http.StatusConflict
+	} else if errors.Is(err, storage.ErrQuotaExceeded) {
+		code = http.StatusInsufficientStorage
+	} else if errors.Is(err, storage.ErrTooLarge) {
+		code = http.StatusRequestEntityTooLarge
+	} else if errors.As(err, &rl) {
+		code = http.StatusTooManyRequests
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(rl.Reset)))
 	}
 	http.Error(w, op+": "+err.Error(), code)
 }
 
+// retryAfterSeconds converts a rate-limit reset time to a Retry-After value,
+// defaulting to a minute when GitHub did not report one.
+func retryAfterSeconds(reset time.Time) int {
+	if reset.IsZero() {
+		return 60
+	}
+	secs := int(math.Ceil(time.Until(reset).Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	return secs
+}
+
+// safeName builds a download file name from repo and branch. The branch is
+// escaped the same way as the cache layout (feature/x -> feature%2Fx).
 func safeName(repo, branch string) string {
 	name := strings.ReplaceAll(repo, "/", "-")
 	if strings.TrimSpace(branch) != "" {
-		name += "-" + branch
+		name += "-" + storage.BranchFileName(branch)
 	}
 	return name
 }
@@ -580,6 +1051,14 @@ func readCommitFile(path string) string {
 	return commit
 }
 
+// commitMatches reports whether known (full or abbreviated, at least 7 chars) names cachedSHA.
+func commitMatches(known, cachedSHA string) bool {
+	if cachedSHA == "" || len(known) < 7 {
+		return false
+	}
+	return strings.HasPrefix(cachedSHA, known)
+}
+
 func tokenFromRequest(r *http.Request, fallback string) string {
 	h := strings.TrimSpace(r.Header.Get("Authorization"))
 	if strings.HasPrefix(strings.ToLower(h), "bearer ") {
@@ -670,14 +1149,45 @@ func (s *Server) startJanitor() {
 		case <-s.janitorCtx.Done():
 			return
 		case <-ticker.C:
-			_ = s.store.CleanupExpired(s.ttl)
+			s.runCleanup()
 		}
 	}
 }
 
-// Shutdown stops the janitor goroutine and releases associated resources.
+// runCleanup applies the TTL and optional size cap once, logging every
+// removed path so disappearing cache entries can be traced.
+func (s *Server) runCleanup() {
+	removed, err := s.store.CleanupExpired(s.ttl)
+	logCleanup("expired", removed, err)
+	if sc, ok := s.store.(sizeCleaner); ok && s.maxCacheBytes > 0 {
+		removed, err := sc.CleanupToSize(s.maxCacheBytes)
+		logCleanup("over max-cache-bytes", removed, err)
+	}
+}
+
+func logCleanup(reason string, removed []string, err error) {
+	for _, p := range removed {
+		fmt.Printf("INFO: janitor removed %s path=%s\n", reason, p)
+	}
+	if err != nil {
+		fmt.Printf("janitor cleanup error reason=%s err=%v\n", reason, err)
+	}
+}
+
+// MarkNotReady makes /readyz answer 503 while requests are still served, so
+// load balancers can stop routing to the server before it drains.
+func (s *Server) MarkNotReady() {
+	s.ready.Store(false)
+}
+
+// Shutdown marks the server not ready, stops the janitor goroutine and releases
+// associated resources, including pending access-time updates queued by the store.
 func (s *Server) Shutdown() {
+	s.ready.Store(false)
 	if s.janitorCancel != nil {
 		s.janitorCancel()
 	}
+	if c, ok := s.store.(storeCloser); ok {
+		_ = c.Close()
+	}
 }
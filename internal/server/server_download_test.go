@@ -4,13 +4,18 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -25,10 +30,16 @@ type fakeStore struct {
 	lastRepo   string
 	lastBranch string
 	lastForce  bool
+	lastFormat string
+	branches   []string
+
+	cleanupCalls int32
+	cleanupTTL   atomic.Value // time.Duration
 }
 
-func (f *fakeStore) EnsureRepo(ctx context.Context, user, ownerRepo, branch, token string, force, legacy bool) (string, error) {
+func (f *fakeStore) EnsureRepo(ctx context.Context, user, ownerRepo, branch, token, format string, force, legacy bool) (string, error) {
 	f.lastUser = user
+	f.lastFormat = format
 	f.lastRepo = ownerRepo
 	f.lastBranch = branch
 	f.lastForce = force
@@ -42,16 +53,39 @@ func (f *fakeStore) EnsurePackage(ctx context.Context, user, pkgURL string) (str
 func (f *fakeStore) EnsureBareRepo(ctx context.Context, ownerRepo, token string) (string, error) {
 	return "", nil
 }
+func (f *fakeStore) ListRemoteBranches(ctx context.Context, ownerRepo, token string) ([]string, error) {
+	f.lastRepo = ownerRepo
+	return f.branches, f.ensureErr
+}
+func (f *fakeStore) StatRepo(user, ownerRepo, branch, format string, legacy bool) (storage.RepoStat, error) {
+	return storage.RepoStat{}, nil
+}
+func (f *fakeStore) CacheStats(user string) (storage.CacheStats, error) {
+	return storage.CacheStats{}, nil
+}
+func (f *fakeStore) ClearUser(user string) (int64, error) {
+	f.lastUser = user
+	return 0, f.ensureErr
+}
 func (f *fakeStore) ExportSparseZip(ctx context.Context, ownerRepo, branch string, paths []string, destZip string) (string, error) {
 	return "", nil
 }
 func (f *fakeStore) ExportSparseDir(ctx context.Context, ownerRepo, branch string, paths []string, destDir string) (string, error) {
 	return "", nil
 }
-func (f *fakeStore) List(rel string) ([]storage.Entry, error) { return nil, nil }
-func (f *fakeStore) Delete(rel string, recursive bool) error  { return nil }
-func (f *fakeStore) Touch(rel string) error                   { return nil }
-func (f *fakeStore) CleanupExpired(ttl time.Duration) error   { return nil }
+func (f *fakeStore) List(rel string) ([]storage.Entry, error)   { return nil, nil }
+func (f *fakeStore) Delete(rel string, recursive bool) error    { return nil }
+func (f *fakeStore) Touch(rel string) error                     { return nil }
+func (f *fakeStore) ExtractZip(user, rel, zipPath string) error { return nil }
+func (f *fakeStore) Move(from, to string) error                 { return nil }
+func (f *fakeStore) ListRecursive(rel string, maxDepth, maxEntries int) ([]storage.Entry, bool, error) {
+	return nil, false, nil
+}
+func (f *fakeStore) CleanupExpired(ttl time.Duration) ([]string, error) {
+	atomic.AddInt32(&f.cleanupCalls, 1)
+	f.cleanupTTL.Store(ttl)
+	return nil, nil
+}
 
 func TestDownloadHandler_UsesStore(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -103,6 +137,54 @@ func TestDownloadHandler_UsesStore(t *testing.T) {
 	}
 }
 
+func TestDownloadHandler_TarGzFormat(t *testing.T) {
+	tarPath := filepath.Join(t.TempDir(), "main.tar.gz")
+	if err := os.WriteFile(tarPath, []byte("tarball"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tarPath+".commit.txt", []byte("abc123\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := &fakeStore{ensurePath: tarPath}
+	s := NewServerWithStore(fs, "", "default")
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/download?repo=own/repo&branch=main&format=tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "tarball" {
+		t.Fatalf("status=%d body=%q", resp.StatusCode, body)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/gzip" {
+		t.Fatalf("ct=%s", ct)
+	}
+	if cd := resp.Header.Get("Content-Disposition"); !strings.Contains(cd, `filename="own-repo-main.tar.gz"`) {
+		t.Fatalf("content-disposition=%s", cd)
+	}
+	if resp.Header.Get("X-GHH-Commit") != "abc123" {
+		t.Fatalf("commit header mismatch: %q", resp.Header.Get("X-GHH-Commit"))
+	}
+	if fs.lastFormat != "tar.gz" {
+		t.Fatalf("store called with format=%q", fs.lastFormat)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/v1/download?repo=own/repo&format=rar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unknown format status=%d, want 400", resp.StatusCode)
+	}
+}
+
 func TestDownloadHandler_ForceRefresh(t *testing.T) {
 	tmpDir := t.TempDir()
 	zipPath := filepath.Join(tmpDir, "repo.zip")
@@ -238,6 +320,319 @@ func TestBranchSwitchHandler_UsesStore(t *testing.T) {
 	}
 }
 
+func TestDownloadHandler_QuotaExceeded(t *testing.T) {
+	fs := &fakeStore{ensureErr: fmt.Errorf("user alice over limit: %w", storage.ErrQuotaExceeded)}
+	s := NewServerWithStore(fs, "", "fallback")
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/download?repo=own/repo&branch=main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusInsufficientStorage {
+		t.Fatalf("download status=%d, want %d", resp.StatusCode, http.StatusInsufficientStorage)
+	}
+
+	body, _ := json.Marshal(map[string]string{"repo": "own/repo", "branch": "dev"})
+	resp, err = http.Post(ts.URL+"/api/v1/branch/switch", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusInsufficientStorage {
+		t.Fatalf("switch status=%d, want %d", resp.StatusCode, http.StatusInsufficientStorage)
+	}
+}
+
+func TestDownloadHandler_RateLimited(t *testing.T) {
+	reset := time.Now().Add(90 * time.Second)
+	fs := &fakeStore{ensureErr: fmt.Errorf("fetch default branch: %w", &storage.ErrRateLimited{Reset: reset})}
+	s := NewServerWithStore(fs, "", "fallback")
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/download?repo=own/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("download status=%d, want %d", resp.StatusCode, http.StatusTooManyRequests)
+	}
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs < 85 || secs > 91 {
+		t.Fatalf("unexpected Retry-After %q", resp.Header.Get("Retry-After"))
+	}
+}
+
+func TestBranchesHandler_UsesStore(t *testing.T) {
+	fs := &fakeStore{branches: []string{"main", "dev"}}
+	s := NewServerWithStore(fs, "", "fallback")
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/branches?repo=own/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d", resp.StatusCode)
+	}
+	var branches []string
+	if err := json.NewDecoder(resp.Body).Decode(&branches); err != nil {
+		t.Fatal(err)
+	}
+	if len(branches) != 2 || branches[0] != "main" || branches[1] != "dev" {
+		t.Fatalf("unexpected branches: %v", branches)
+	}
+	if fs.lastRepo != "own/repo" {
+		t.Fatalf("store called with repo=%s", fs.lastRepo)
+	}
+
+	missing, err := http.Get(ts.URL + "/api/v1/branches")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = missing.Body.Close()
+	if missing.StatusCode != http.StatusBadRequest {
+		t.Fatalf("missing repo status=%d", missing.StatusCode)
+	}
+}
+
+type flightFakeStore struct {
+	fakeStore
+	stats storage.FlightStats
+}
+
+func (f *flightFakeStore) FlightStats() storage.FlightStats { return f.stats }
+
+func TestCacheStatsAndMetrics(t *testing.T) {
+	fs := &flightFakeStore{stats: storage.FlightStats{Leaders: 3, Coalesced: 7}}
+	s := NewServerWithStore(fs, "", "fallback")
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/cache/stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body struct {
+		EnsureRepo storage.FlightStats `json:"ensure_repo"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	_ = resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body.EnsureRepo != fs.stats {
+		t.Fatalf("unexpected stats: %+v", body.EnsureRepo)
+	}
+
+	resp, err = http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	for _, want := range []string{
+		`ghh_ensure_repo_requests_total{role="leader"} 3`,
+		`ghh_ensure_repo_requests_total{role="coalesced"} 7`,
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Fatalf("metrics missing %q:\n%s", want, b)
+		}
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestMissWebhook_CalledOnMissNotOnHit(t *testing.T) {
+	received := make(chan map[string]interface{}, 4)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		received <- body
+	}))
+	defer receiver.Close()
+
+	st := storage.New(t.TempDir())
+	st.HTTPClient = &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body := "zipdata"
+		if strings.Contains(req.URL.Path, "/branches/") {
+			body = `{"commit":{"sha":"abc123"}}`
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})}
+	s, err := NewServerWithOptions(Options{Store: st, DefaultUser: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Shutdown()
+	s.SetMissWebhook(receiver.URL, 0)
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	download := func() {
+		t.Helper()
+		resp, err := http.Get(ts.URL + "/api/v1/download?repo=own/repo&branch=main&legacy=true&user=alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status=%d", resp.StatusCode)
+		}
+	}
+
+	// cold download fires the webhook
+	download()
+	select {
+	case body := <-received:
+		if body["user"] != "alice" || body["repo"] != "own/repo" || body["branch"] != "main" || body["bytes"] != float64(len("zipdata")) {
+			t.Fatalf("unexpected webhook body: %v", body)
+		}
+		if _, ok := body["duration"].(string); !ok {
+			t.Fatalf("missing duration: %v", body)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook not called on cache miss")
+	}
+
+	// cache hit does not
+	download()
+	select {
+	case body := <-received:
+		t.Fatalf("webhook called on cache hit: %v", body)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// archives below the threshold are ignored
+	s.SetMissWebhook(receiver.URL, 1<<20)
+	resp, err := http.Get(ts.URL + "/api/v1/download?repo=own/repo&branch=main&legacy=true&user=alice&force=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	select {
+	case body := <-received:
+		t.Fatalf("webhook called below threshold: %v", body)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestDownloadHandler_KnownCommitNotModified(t *testing.T) {
+	tmpDir := t.TempDir()
+	zipPath := filepath.Join(tmpDir, "main.zip")
+	createZip(t, zipPath)
+	if err := os.WriteFile(zipPath+".meta", []byte("abc1234def5678"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.commit.txt"), []byte("abc1234"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := &fakeStore{ensurePath: zipPath}
+	s := NewServerWithStore(fs, "", "fallback")
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	tests := []struct {
+		name       string
+		known      string
+		wantStatus int
+	}{
+		{name: "no header", wantStatus: http.StatusOK},
+		{name: "short sha matches", known: "abc1234", wantStatus: http.StatusNotModified},
+		{name: "full sha matches", known: "abc1234def5678", wantStatus: http.StatusNotModified},
+		{name: "stale commit", known: "fff0000", wantStatus: http.StatusOK},
+		{name: "too short to trust", known: "abc", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/download?repo=own/repo&branch=main", nil)
+			if tt.known != "" {
+				req.Header.Set("X-GHH-Known-Commit", tt.known)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status=%d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if resp.Header.Get("X-GHH-Commit") != "abc1234" {
+				t.Fatalf("X-GHH-Commit=%q", resp.Header.Get("X-GHH-Commit"))
+			}
+			if tt.wantStatus == http.StatusNotModified && len(body) != 0 {
+				t.Fatalf("304 response carried a body of %d bytes", len(body))
+			}
+		})
+	}
+}
+
+func TestDownloadHandler_SetsSHA256Header(t *testing.T) {
+	tmpDir := t.TempDir()
+	zipPath := filepath.Join(tmpDir, "main.zip")
+	createZip(t, zipPath)
+	data, err := os.ReadFile(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	want := hex.EncodeToString(sum[:])
+
+	fs := &fakeStore{ensurePath: zipPath}
+	s := NewServerWithStore(fs, "", "fallback")
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	// Second request is served from the .sha256 sidecar and must still match the body.
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(ts.URL + "/api/v1/download?repo=own/repo&branch=main")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if got := resp.Header.Get("X-GHH-SHA256"); got != want {
+			t.Fatalf("request %d: X-GHH-SHA256=%q, want %q", i, got, want)
+		}
+		if !bytes.Equal(body, data) {
+			t.Fatalf("request %d: body differs from zip on disk", i)
+		}
+		if got, err := os.ReadFile(zipPath + ".sha256"); err != nil || string(got) != want {
+			t.Fatalf("request %d: sidecar=%q err=%v, want %q", i, got, err, want)
+		}
+	}
+}
+
 func createZip(t *testing.T, path string) {
 	t.Helper()
 	f, err := os.Create(path)
@@ -1,13 +1,21 @@
 package server
 
 import (
+	"archive/zip"
+	"bytes"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github-hub/internal/storage"
+	"github-hub/internal/version"
 )
 
 func TestDirListAndDeleteHandlers(t *testing.T) {
@@ -73,6 +81,182 @@ func TestDirListAndDeleteHandlers(t *testing.T) {
 	}
 }
 
+func TestDirListRecursive(t *testing.T) {
+	root := t.TempDir()
+	user := "tester"
+	userRoot := filepath.Join(root, "users", user)
+	if err := os.MkdirAll(filepath.Join(userRoot, "repos", "o", "r"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(userRoot, "repos", "o", "r", "main.zip"), []byte("zip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewServer(root, user, "", defaultDownloadTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/dir/list?path=repos&recursive=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("list status=%d", resp.StatusCode)
+	}
+	var out struct {
+		Path      string          `json:"path"`
+		Entries   []storage.Entry `json:"entries"`
+		Truncated bool            `json:"truncated"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	var paths []string
+	for _, e := range out.Entries {
+		paths = append(paths, e.Path)
+	}
+	if got := strings.Join(paths, ","); got != "repos/o,repos/o/r,repos/o/r/main.zip" {
+		t.Fatalf("paths=%s", got)
+	}
+	if out.Truncated || out.Path != "repos" {
+		t.Fatalf("unexpected path=%s truncated=%v", out.Path, out.Truncated)
+	}
+
+	bad, err := http.Get(ts.URL + "/api/v1/dir/list?path=../other&recursive=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = bad.Body.Close()
+	if bad.StatusCode != http.StatusBadRequest {
+		t.Fatalf("escaping path status=%d, want 400", bad.StatusCode)
+	}
+}
+
+func TestDirListGlob(t *testing.T) {
+	root := t.TempDir()
+	user := "tester"
+	dir := filepath.Join(root, "users", user, "repos", "o", "r")
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"main.zip", "dev.zip", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := NewServer(root, user, "", defaultDownloadTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantNames  string
+	}{
+		{name: "zip files", query: "path=repos/o/r&glob=*.zip", wantStatus: http.StatusOK, wantNames: "dev.zip,main.zip"},
+		{name: "no glob lists everything", query: "path=repos/o/r", wantStatus: http.StatusOK, wantNames: "dev.zip,main.zip,notes.txt,sub"},
+		{name: "recursive", query: "path=repos&recursive=true&glob=*.zip", wantStatus: http.StatusOK, wantNames: "dev.zip,main.zip"},
+		{name: "invalid pattern", query: "path=repos/o/r&glob=[", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + "/api/v1/dir/list?" + strings.ReplaceAll(tt.query, "[", "%5B"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status=%d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var entries []storage.Entry
+			if strings.Contains(tt.query, "recursive") {
+				var tree struct {
+					Entries []storage.Entry `json:"entries"`
+				}
+				err = json.NewDecoder(resp.Body).Decode(&tree)
+				entries = tree.Entries
+			} else {
+				err = json.NewDecoder(resp.Body).Decode(&entries)
+			}
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name)
+			}
+			sort.Strings(names)
+			if got := strings.Join(names, ","); got != tt.wantNames {
+				t.Fatalf("names=%s, want %s", got, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestDirMoveHandler(t *testing.T) {
+	root := t.TempDir()
+	user := "tester"
+	userRoot := filepath.Join(root, "users", user)
+	for _, d := range []string{"uploads/tmp", "uploads/taken"} {
+		if err := os.MkdirAll(filepath.Join(userRoot, filepath.FromSlash(d)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := NewServer(root, user, "", defaultDownloadTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"move", `{"from":"uploads/tmp","to":"uploads/release"}`, http.StatusOK},
+		{"missing source", `{"from":"uploads/tmp","to":"uploads/again"}`, http.StatusNotFound},
+		{"existing destination", `{"from":"uploads/release","to":"uploads/taken"}`, http.StatusConflict},
+		{"escaping path", `{"from":"uploads/release","to":"../../escape"}`, http.StatusBadRequest},
+		{"missing field", `{"from":"uploads/release"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(ts.URL+"/api/v1/dir/move", "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status=%d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+
+	if _, err := os.Stat(filepath.Join(userRoot, "uploads", "release")); err != nil {
+		t.Fatalf("moved directory missing: %v", err)
+	}
+}
+
 func TestStaticIndexServed(t *testing.T) {
 	root := t.TempDir()
 	s, err := NewServer(root, "default", "", defaultDownloadTimeout)
@@ -171,6 +355,401 @@ func TestShutdownStopsJanitor(t *testing.T) {
 	s.Shutdown()
 }
 
+func TestHealthAndReadiness(t *testing.T) {
+	s, err := NewServer(t.TempDir(), "default", "", defaultDownloadTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	get := func(path string) int {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("get %s: %v", path, err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := get("/healthz"); code != http.StatusOK {
+		t.Fatalf("healthz expected 200, got %d", code)
+	}
+	if code := get("/readyz"); code != http.StatusOK {
+		t.Fatalf("readyz expected 200, got %d", code)
+	}
+
+	s.MarkNotReady()
+	if code := get("/readyz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("readyz after MarkNotReady expected 503, got %d", code)
+	}
+
+	s.Shutdown()
+	if code := get("/readyz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("readyz after shutdown expected 503, got %d", code)
+	}
+	if code := get("/healthz"); code != http.StatusOK {
+		t.Fatalf("healthz after shutdown expected 200, got %d", code)
+	}
+}
+
+func TestVersionEndpoint(t *testing.T) {
+	s, err := NewServer(t.TempDir(), "default", "", defaultDownloadTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Shutdown()
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/version", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status=%d", rec.Code)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	for _, key := range []string{"version", "commit", "build_date", "string"} {
+		if _, ok := got[key]; !ok {
+			t.Errorf("missing field %q in %s", key, rec.Body.String())
+		}
+	}
+	if got["string"] != version.String() {
+		t.Errorf("string=%v, want %q", got["string"], version.String())
+	}
+}
+
+func TestJanitorUsesConfiguredIntervalAndTTL(t *testing.T) {
+	fs := &fakeStore{}
+	s, err := NewServerWithOptions(Options{
+		Store:           fs,
+		CleanupInterval: 10 * time.Millisecond,
+		TTL:             time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Shutdown()
+
+	deadline := time.After(time.Second)
+	for atomic.LoadInt32(&fs.cleanupCalls) < 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("janitor ran %d times, want at least 2", atomic.LoadInt32(&fs.cleanupCalls))
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	if ttl, _ := fs.cleanupTTL.Load().(time.Duration); ttl != time.Hour {
+		t.Fatalf("janitor ttl=%s, want 1h", ttl)
+	}
+}
+
+func TestLoadConfig_CleanupSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	content := "addr: \":9090\"\ncleanup_interval: \"1h\"\nttl: \"168h\"\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.CleanupInterval != "1h" || cfg.TTL != "168h" {
+		t.Fatalf("cleanup_interval=%q ttl=%q", cfg.CleanupInterval, cfg.TTL)
+	}
+
+	def := DefaultConfig()
+	if def.CleanupInterval != "1m" || def.TTL != "24h" {
+		t.Fatalf("unexpected defaults cleanup_interval=%q ttl=%q", def.CleanupInterval, def.TTL)
+	}
+}
+
+func TestLoadConfig_YAMLValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	content := `token: "a:b:c"  # inline comment
+github_url: &ghe "https://ghe.example.com:8443"
+github_codeload_url: *ghe
+addr: ""
+miss_webhook_min_bytes: 1048576
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Token != "a:b:c" {
+		t.Errorf("token=%q, want a:b:c", cfg.Token)
+	}
+	if cfg.GitHubURL != "https://ghe.example.com:8443" || cfg.GitHubCodeloadURL != cfg.GitHubURL {
+		t.Errorf("github_url=%q github_codeload_url=%q", cfg.GitHubURL, cfg.GitHubCodeloadURL)
+	}
+	if cfg.Addr != DefaultConfig().Addr || cfg.Root != DefaultConfig().Root {
+		t.Errorf("empty and missing keys should keep defaults, got addr=%q root=%q", cfg.Addr, cfg.Root)
+	}
+	if cfg.MissWebhookMinBytes != 1<<20 {
+		t.Errorf("miss_webhook_min_bytes=%d", cfg.MissWebhookMinBytes)
+	}
+
+	if err := os.WriteFile(path, []byte("miss_webhook_min_bytes: lots\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected an error for a non-numeric miss_webhook_min_bytes")
+	}
+
+	if cfg, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err != nil || cfg != DefaultConfig() {
+		t.Errorf("missing file should return defaults, got %+v (%v)", cfg, err)
+	}
+}
+
+func TestLoadConfig_ExpandsEnv(t *testing.T) {
+	t.Setenv("GHH_TEST_TOKEN", "secret")
+	t.Setenv("GHH_TEST_ROOT", "/srv/ghh")
+	files := map[string]string{
+		"server.yaml": "token: \"${GHH_TEST_TOKEN}\"\nroot: \"$GHH_TEST_ROOT/data\"\ndefault_user: \"${GHH_TEST_UNSET_VAR}\"\ngithub_url: \"https://ghe.example.com/$$api\"\n",
+		"server.json": `{"token":"${GHH_TEST_TOKEN}","root":"$GHH_TEST_ROOT/data","default_user":"${GHH_TEST_UNSET_VAR}","github_url":"https://ghe.example.com/$$api"}`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.Token != "secret" || cfg.Root != "/srv/ghh/data" {
+				t.Errorf("token=%q root=%q", cfg.Token, cfg.Root)
+			}
+			if cfg.GitHubURL != "https://ghe.example.com/$api" {
+				t.Errorf("github_url=%q", cfg.GitHubURL)
+			}
+			if cfg.DefaultUser != "" {
+				t.Errorf("unset variable should expand to empty, got %q", cfg.DefaultUser)
+			}
+		})
+	}
+}
+
+func TestStatHandler_HitAndMiss(t *testing.T) {
+	root := t.TempDir()
+	repoDir := filepath.Join(root, "users", "alice", "repos", "own", "repo")
+	if err := os.MkdirAll(repoDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "main.zip"), []byte("zipdata"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "main.zip.meta"), []byte("abc123"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "dev.tar.gz"), []byte("tarball-data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "dev.tar.gz.meta"), []byte("def456"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewServer(root, "default", "", defaultDownloadTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Shutdown()
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	tests := []struct {
+		name       string
+		query      string
+		wantCached bool
+		wantSize   int64
+		wantSHA    string
+	}{
+		{name: "cached hit", query: "repo=own/repo&branch=main&user=alice", wantCached: true, wantSize: 7, wantSHA: "abc123"},
+		{name: "default branch is main", query: "repo=own/repo&user=alice", wantCached: true, wantSize: 7, wantSHA: "abc123"},
+		{name: "uncached branch", query: "repo=own/repo&branch=dev&user=alice"},
+		{name: "tar.gz cache", query: "repo=own/repo&branch=dev&format=tar.gz&user=alice", wantCached: true, wantSize: 12, wantSHA: "def456"},
+		{name: "tar.gz not cached", query: "repo=own/repo&branch=main&format=tar.gz&user=alice"},
+		{name: "other user", query: "repo=own/repo&branch=main&user=bob"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + "/api/v1/stat?" + tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status=%d", resp.StatusCode)
+			}
+			var st struct {
+				Cached     bool       `json:"cached"`
+				Size       int64      `json:"size"`
+				SHA        string     `json:"sha"`
+				LastAccess *time.Time `json:"last_access"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+				t.Fatal(err)
+			}
+			if st.Cached != tt.wantCached || st.Size != tt.wantSize || st.SHA != tt.wantSHA {
+				t.Fatalf("unexpected stat: %+v", st)
+			}
+			if tt.wantCached != (st.LastAccess != nil) {
+				t.Fatalf("last_access presence mismatch: %+v", st)
+			}
+		})
+	}
+
+	resp, err := http.Get(ts.URL + "/api/v1/stat?repo=bad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bad repo status=%d", resp.StatusCode)
+	}
+}
+
+func TestUploadHandler(t *testing.T) {
+	root := t.TempDir()
+	s, err := NewServer(root, "default", "", defaultDownloadTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Shutdown()
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	buildZip := func(files map[string]string) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for name, content := range files {
+			w, err := zw.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = w.Write([]byte(content))
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		body       []byte
+		wantStatus int
+	}{
+		{name: "extracts under user root", path: "artifacts/build-1", body: buildZip(map[string]string{"bin/app": "binary", "README": "hi"}), wantStatus: http.StatusOK},
+		{name: "traversal in path", path: "../escape", body: buildZip(map[string]string{"a": "a"}), wantStatus: http.StatusBadRequest},
+		{name: "hidden segment", path: "artifacts/.git", body: buildZip(map[string]string{"a": "a"}), wantStatus: http.StatusBadRequest},
+		{name: "zip slip entry", path: "artifacts/evil", body: buildZip(map[string]string{"../../../../outside.txt": "x"}), wantStatus: http.StatusBadRequest},
+		{name: "not a zip", path: "artifacts/junk", body: []byte("plain text"), wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/v1/upload?path="+tt.path, bytes.NewReader(tt.body))
+			req.Header.Set("X-GHH-User", "alice")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status=%d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "users", "alice", "artifacts", "build-1", "bin", "app"))
+	if err != nil || string(data) != "binary" {
+		t.Fatalf("uploaded file: %q err=%v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(root), "outside.txt")); err == nil {
+		t.Fatalf("zip slip entry escaped the workspace")
+	}
+
+	s.maxUploadBytes = 16
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/v1/upload?path=artifacts/big", bytes.NewReader(buildZip(map[string]string{"a": "a"})))
+	req.Header.Set("X-GHH-User", "alice")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized upload status=%d, want %d", resp.StatusCode, http.StatusRequestEntityTooLarge)
+	}
+	if _, err := os.Stat(filepath.Join(root, "users", "alice", "artifacts", "big")); !os.IsNotExist(err) {
+		t.Fatalf("oversized upload should not be extracted: %v", err)
+	}
+}
+
+func TestCacheStatsHandler_ScopedToUser(t *testing.T) {
+	root := t.TempDir()
+	for rel, size := range map[string]int{
+		"users/alice/repos/o/r/main.zip": 10,
+		"users/alice/repos/o/r/dev.zip":  25,
+		"users/bob/repos/o/r/main.zip":   99,
+	} {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := NewServer(root, "default", "", defaultDownloadTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Shutdown()
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/cache/stats", nil)
+	req.Header.Set("X-GHH-User", "alice")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		User       string               `json:"user"`
+		Entries    []storage.CacheEntry `json:"entries"`
+		TotalSize  int64                `json:"total_size"`
+		TotalCount int                  `json:"total_count"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.User != "alice" || body.TotalCount != 2 || body.TotalSize != 35 {
+		t.Fatalf("unexpected stats: %+v", body)
+	}
+	if body.Entries[0].Branch != "dev" || body.Entries[1].Branch != "main" {
+		t.Fatalf("entries not sorted by size: %+v", body.Entries)
+	}
+}
+
 func TestDownloadSparseHandler_Validation(t *testing.T) {
 	root := t.TempDir()
 	s, err := NewServer(root, "default", "", defaultDownloadTimeout)
@@ -204,3 +783,87 @@ func TestDownloadSparseHandler_Validation(t *testing.T) {
 		t.Fatalf("expected 400 for invalid path, got %d", resp.StatusCode)
 	}
 }
+
+func TestSafeName(t *testing.T) {
+	tests := []struct {
+		repo, branch, want string
+	}{
+		{"owner/repo", "main", "owner-repo-main"},
+		{"owner/repo", "feature/sub", "owner-repo-feature%2Fsub"},
+		{"owner/repo", "feature__sub", "owner-repo-feature__sub"},
+		{"owner/repo", "", "owner-repo"},
+	}
+	for _, tt := range tests {
+		if got := safeName(tt.repo, tt.branch); got != tt.want {
+			t.Errorf("safeName(%q, %q) = %q, want %q", tt.repo, tt.branch, got, tt.want)
+		}
+	}
+}
+
+func TestCacheClearHandler(t *testing.T) {
+	root := t.TempDir()
+	for rel, size := range map[string]int{
+		"users/alice/repos/o/r/main.zip": 10,
+		"users/alice/repos/o/r/dev.zip":  25,
+		"users/bob/repos/o/r/main.zip":   99,
+	} {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := NewServer(root, "default", "", defaultDownloadTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Shutdown()
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/cache?user=alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("GET status=%d, want 405", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/api/v1/cache?user=alice", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		User       string `json:"user"`
+		FreedBytes int64  `json:"freed_bytes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || body.User != "alice" || body.FreedBytes != 35 {
+		t.Fatalf("status=%d body=%+v", resp.StatusCode, body)
+	}
+	if _, err := os.Stat(filepath.Join(root, "users", "alice")); !os.IsNotExist(err) {
+		t.Fatalf("users/alice still exists: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "users", "bob", "repos", "o", "r", "main.zip")); err != nil {
+		t.Fatalf("bob's cache was removed: %v", err)
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, ts.URL+"/api/v1/cache?user=..", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("user=.. status=%d, want 400", resp.StatusCode)
+	}
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// FlightStats reports how often concurrent identical EnsureRepo calls were coalesced.
+type FlightStats struct {
+	Leaders   int64 `json:"leaders"`   // calls that performed the work
+	Coalesced int64 `json:"coalesced"` // calls that waited for and reused a leader's result
+}
+
+type flightCall struct {
+	wg   sync.WaitGroup
+	path string
+	err  error
+}
+
+// flightGroup deduplicates concurrent calls with the same key so only one runs at a time.
+type flightGroup struct {
+	mu        sync.Mutex
+	calls     map[string]*flightCall
+	leaders   int64
+	coalesced int64
+}
+
+// do runs fn once per in-flight key; concurrent callers with the same key wait
+// and receive the leader's result. shared reports whether the result was reused.
+func (g *flightGroup) do(key string, fn func() (string, error)) (path string, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if c, ok := g.calls[key]; ok {
+		atomic.AddInt64(&g.coalesced, 1)
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.path, c.err, true
+	}
+	c := &flightCall{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	atomic.AddInt64(&g.leaders, 1)
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+
+	c.path, c.err = fn()
+	return c.path, c.err, false
+}
+
+func (g *flightGroup) stats() FlightStats {
+	return FlightStats{
+		Leaders:   atomic.LoadInt64(&g.leaders),
+		Coalesced: atomic.LoadInt64(&g.coalesced),
+	}
+}
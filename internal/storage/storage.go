@@ -2,6 +2,7 @@ package storage
 
 import (
 	"archive/tar"
+	"archive/zip"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
@@ -9,12 +10,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -22,20 +26,191 @@ import (
 )
 
 var (
-	ErrBadPath  = errors.New("bad path")
-	ErrNotFound = errors.New("not found")
+	ErrBadPath       = errors.New("bad path")
+	ErrNotFound      = errors.New("not found")
+	ErrQuotaExceeded = errors.New("user quota exceeded")
+	ErrBadArchive    = errors.New("bad archive")
+	ErrExists        = errors.New("already exists")
+	ErrTooLarge      = errors.New("too large")
+)
+
+// Default caps for extracting uploaded archives; the client uses the same values
+// when extracting downloads.
+const (
+	DefaultMaxExtractBytes     int64 = 2 << 30
+	DefaultMaxExtractFileBytes int64 = 512 << 20
+)
+
+// ErrRateLimited is returned when GitHub rejects a request because the API
+// rate limit is exhausted. Reset is when the quota refills (zero if unknown).
+type ErrRateLimited struct {
+	Reset time.Time
+}
+
+func (e *ErrRateLimited) Error() string {
+	if e.Reset.IsZero() {
+		return "github rate limit exceeded"
+	}
+	return fmt.Sprintf("github rate limit exceeded, resets at %s", e.Reset.UTC().Format(time.RFC3339))
+}
+
+// Archive formats accepted by EnsureRepo.
+const (
+	FormatZip   = "zip"
+	FormatTarGz = "tar.gz"
+)
+
+// ArchiveExt validates an archive format and returns its file extension
+// (without the leading dot). An empty format means zip.
+func ArchiveExt(format string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", FormatZip:
+		return FormatZip, nil
+	case FormatTarGz, "tgz":
+		return FormatTarGz, nil
+	default:
+		return "", fmt.Errorf("unsupported archive format %q: %w", format, ErrBadPath)
+	}
+}
+
+// CommitFilePath returns the short-commit file written next to a cached archive:
+// main.zip -> main.commit.txt, main.tar.gz -> main.tar.gz.commit.txt.
+func CommitFilePath(archivePath string) string {
+	if strings.HasSuffix(archivePath, ".zip") {
+		return strings.TrimSuffix(archivePath, ".zip") + ".commit.txt"
+	}
+	return archivePath + ".commit.txt"
+}
+
+// ChecksumFilePath returns the SHA-256 sidecar written next to a cached archive.
+func ChecksumFilePath(archivePath string) string {
+	return archivePath + ".sha256"
+}
+
+// ArchiveChecksum returns the hex SHA-256 of the archive at path from its sidecar.
+// Archives cached before sidecars existed are hashed once and the sidecar written.
+func ArchiveChecksum(path string) (string, error) {
+	if sum, err := readSHA(ChecksumFilePath(path)); err == nil && sum != "" {
+		return sum, nil
+	}
+	return writeChecksum(path)
+}
+
+// writeChecksum hashes the archive at path and records the result in its sidecar.
+func writeChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	_ = writeSHA(ChecksumFilePath(path), sum)
+	return sum, nil
+}
+
+// isSidecarName reports whether name is hidden metadata kept next to an archive.
+func isSidecarName(name string) bool {
+	return strings.HasSuffix(name, ".meta") || strings.HasSuffix(name, ".sha256")
+}
+
+// isArchiveName reports whether name is a cached repo archive (not an in-flight temp file).
+func isArchiveName(name string) bool {
+	if strings.HasPrefix(name, ".tmp-") {
+		return false
+	}
+	return strings.HasSuffix(name, ".zip") || strings.HasSuffix(name, "."+FormatTarGz)
+}
+
+// trimArchiveExt strips the archive extension from a cached file name.
+func trimArchiveExt(name string) string {
+	if strings.HasSuffix(name, "."+FormatTarGz) {
+		return strings.TrimSuffix(name, "."+FormatTarGz)
+	}
+	return strings.TrimSuffix(name, ".zip")
+}
+
+// BranchFileName maps a branch to the single path segment used for its cache files,
+// path-escaping it so slashes never nest directories, e.g. feature/x -> feature%2Fx.zip.
+// The mapping is reversible, so feature/x and feature__x get distinct files.
+func BranchFileName(branch string) string {
+	return url.PathEscape(branch)
+}
+
+// BranchFromFileName reverses BranchFileName. Segments that are not valid escapes
+// (such as caches written before escaping) are returned unchanged.
+func BranchFromFileName(name string) string {
+	if branch, err := url.PathUnescape(name); err == nil {
+		return branch
+	}
+	return name
+}
+
+// Public GitHub endpoints used when the corresponding Storage fields are empty.
+const (
+	DefaultGitHubAPIURL      = "https://api.github.com"
+	DefaultGitHubCodeloadURL = "https://codeload.github.com"
+	DefaultGitHubURL         = "https://github.com"
 )
 
 type Storage struct {
 	Root            string
 	HTTPClient      *http.Client
+	APIBaseURL      string // REST API base, e.g. https://ghe.example.com/api/v3
+	CodeloadBaseURL string // zip archive host, e.g. https://codeload.ghe.example.com
+	GitBaseURL      string // git clone host, e.g. https://ghe.example.com
 	DebugSlowReader time.Duration // DEBUG: delay per read chunk to simulate slow network
+	Debug           bool          // log GitHub rate-limit quota after each API call
 	RetryMax        int
 	RetryBackoff    time.Duration
+	// FallbackBranch is used in legacy mode when the default branch cannot be
+	// resolved from the API; empty means reuse the most recently cached branch.
+	FallbackBranch string
+	UserQuotaBytes  int64 // max bytes of cached repo zips per user; 0 = unlimited
+	// MaxExtractBytes caps the total bytes ExtractZip writes and MaxExtractFileBytes
+	// caps any single entry; <= 0 uses DefaultMaxExtractBytes / DefaultMaxExtractFileBytes.
+	MaxExtractBytes     int64
+	MaxExtractFileBytes int64
+
+	// OnFreshDownload, if set, is called after EnsureRepo writes a newly fetched archive
+	// (cache miss or forced refresh). It runs synchronously; callers should not block.
+	OnFreshDownload func(FreshDownload)
 
 	mu     sync.Mutex
 	lock   map[string]*sync.Mutex
 	rwLock map[string]*sync.RWMutex // for git cache read/write locks
+
+	flight  flightGroup // coalesces concurrent identical EnsureRepo calls
+	toucher toucher     // applies Touch in the background
+}
+
+// FreshDownload describes an archive fetched because the cache could not be reused.
+type FreshDownload struct {
+	User     string
+	Repo     string
+	Branch   string
+	Bytes    int64
+	Duration time.Duration
+}
+
+func (s *Storage) notifyFresh(user, ownerRepo, branch, zipPath string, start time.Time) {
+	if s.OnFreshDownload == nil {
+		return
+	}
+	var size int64
+	if info, err := os.Stat(zipPath); err == nil {
+		size = info.Size()
+	}
+	s.OnFreshDownload(FreshDownload{
+		User:     user,
+		Repo:     ownerRepo,
+		Branch:   branch,
+		Bytes:    size,
+		Duration: time.Since(start),
+	})
 }
 
 func sanitizeName(v string) string {
@@ -81,7 +256,7 @@ func (s *Storage) EnsurePackage(ctx context.Context, user, pkgURL string) (strin
 
 	// If exists, reuse
 	if info, err := os.Stat(pkgPath); err == nil && !info.IsDir() {
-		_ = s.touch(pkgPath)
+		s.toucher.enqueue(pkgPath)
 		return pkgPath, nil
 	}
 
@@ -104,7 +279,7 @@ func (s *Storage) EnsurePackage(ctx context.Context, user, pkgURL string) (strin
 		_ = os.Remove(tmpPath)
 		return "", err
 	}
-	_ = s.touch(pkgPath)
+	s.toucher.enqueue(pkgPath)
 	return pkgPath, nil
 }
 
@@ -132,13 +307,36 @@ func NewWithTimeout(root string, timeout time.Duration) *Storage {
 		client.Timeout = timeout
 	}
 	return &Storage{
-		Root:         root,
-		HTTPClient:   client,
-		RetryMax:     5,
-		RetryBackoff: 2 * time.Second,
+		Root:            root,
+		HTTPClient:      client,
+		APIBaseURL:      DefaultGitHubAPIURL,
+		CodeloadBaseURL: DefaultGitHubCodeloadURL,
+		GitBaseURL:      DefaultGitHubURL,
+		RetryMax:        5,
+		RetryBackoff:    2 * time.Second,
 	}
 }
 
+func (s *Storage) apiBase() string {
+	return baseOrDefault(s.APIBaseURL, DefaultGitHubAPIURL)
+}
+
+func (s *Storage) codeloadBase() string {
+	return baseOrDefault(s.CodeloadBaseURL, DefaultGitHubCodeloadURL)
+}
+
+func (s *Storage) gitBase() string {
+	return baseOrDefault(s.GitBaseURL, DefaultGitHubURL)
+}
+
+func baseOrDefault(v, def string) string {
+	v = strings.TrimRight(strings.TrimSpace(v), "/")
+	if v == "" {
+		return def
+	}
+	return v
+}
+
 func (s *Storage) httpClient() *http.Client {
 	if s.HTTPClient != nil {
 		return s.HTTPClient
@@ -153,17 +351,35 @@ func (s *Storage) httpClient() *http.Client {
 // Returns the path to the zip file and the commit SHA.
 //
 // If branch is empty, fetches the default branch from GitHub API.
+// format selects the archive type ("zip" or "tar.gz"; empty means zip). Each format
+// is cached in its own file, so zip and tarball caches of a branch coexist.
 // If force is true, bypasses cache validation and always downloads fresh.
-func (s *Storage) EnsureRepo(ctx context.Context, user, ownerRepo, branch, token string, force, legacy bool) (string, error) {
-	if legacy {
-		return s.ensureRepoLegacy(ctx, user, ownerRepo, branch, token, force)
+//
+// Concurrent calls with identical arguments are coalesced: one leader does the work
+// and the others reuse its result (see FlightStats).
+func (s *Storage) EnsureRepo(ctx context.Context, user, ownerRepo, branch, token, format string, force, legacy bool) (string, error) {
+	ext, err := ArchiveExt(format)
+	if err != nil {
+		return "", err
 	}
-	return s.ensureRepoViaGit(ctx, user, ownerRepo, branch, token, force)
+	key := fmt.Sprintf("%s|%s|%s|%s|%t|%t", strings.Trim(user, "/ "), strings.Trim(ownerRepo, "/"), branch, ext, force, legacy)
+	path, err, _ := s.flight.do(key, func() (string, error) {
+		if legacy {
+			return s.ensureRepoLegacy(ctx, user, ownerRepo, branch, token, ext, force)
+		}
+		return s.ensureRepoViaGit(ctx, user, ownerRepo, branch, token, ext, force)
+	})
+	return path, err
+}
+
+// FlightStats returns single-flight counters for EnsureRepo.
+func (s *Storage) FlightStats() FlightStats {
+	return s.flight.stats()
 }
 
 // ensureRepoViaGit uses bare repo cache + git archive for downloading.
 // This is faster and shares cache across users.
-func (s *Storage) ensureRepoViaGit(ctx context.Context, user, ownerRepo, branch, token string, force bool) (string, error) {
+func (s *Storage) ensureRepoViaGit(ctx context.Context, user, ownerRepo, branch, token, ext string, force bool) (string, error) {
 	user = strings.Trim(user, "/ ")
 	if user == "" {
 		user = "default"
@@ -187,7 +403,7 @@ func (s *Storage) ensureRepoViaGit(ctx context.Context, user, ownerRepo, branch,
 		branch = "main"
 	}
 
-	zipPath := filepath.Join(s.Root, "users", user, "repos", ownerRepo, branch+".zip")
+	zipPath := filepath.Join(s.Root, "users", user, "repos", ownerRepo, BranchFileName(branch)+"."+ext)
 	metaPath := zipPath + ".meta"
 	unlock := s.acquire(user, ownerRepo, branch)
 	defer unlock()
@@ -209,15 +425,20 @@ func (s *Storage) ensureRepoViaGit(ctx context.Context, user, ownerRepo, branch,
 	if !force {
 		if info, err := os.Stat(zipPath); err == nil && !info.IsDir() {
 			if cachedSHA, err := readSHA(metaPath); err == nil && cachedSHA == remoteSHA {
-				_ = s.touch(zipPath)
+				s.toucher.enqueue(zipPath)
 				return zipPath, nil
 			}
 		}
 	}
 
+	if err := s.checkQuota(user, zipPath, 0); err != nil {
+		return "", err
+	}
+
 	// Export via git archive
 	fmt.Printf("exporting %s@%s via git archive...\n", ownerRepo, branch)
-	tmpFile, err := os.CreateTemp(parent, ".tmp-download-*.zip")
+	start := time.Now()
+	tmpFile, err := os.CreateTemp(parent, ".tmp-download-*."+ext)
 	if err != nil {
 		return "", err
 	}
@@ -240,8 +461,8 @@ func (s *Storage) ensureRepoViaGit(ctx context.Context, user, ownerRepo, branch,
 	safeBranch = strings.ReplaceAll(safeBranch, "\\", "-")
 	prefix := repoName + "-" + safeBranch + "/"
 
-	// Use git archive to create zip with --prefix for top-level directory
-	args := []string{"-C", barePath, "archive", "--format=zip", "--prefix=" + prefix, "--output=" + absTmpPath, remoteSHA}
+	// Use git archive to create the archive with --prefix for top-level directory
+	args := []string{"-C", barePath, "archive", "--format=" + ext, "--prefix=" + prefix, "--output=" + absTmpPath, remoteSHA}
 	cmd := exec.CommandContext(ctx, "git", args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -249,27 +470,34 @@ func (s *Storage) ensureRepoViaGit(ctx context.Context, user, ownerRepo, branch,
 		_ = os.Remove(tmpPath)
 		return "", fmt.Errorf("git archive failed: %w", err)
 	}
+	if err := s.checkQuotaForFile(user, zipPath, tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return "", err
+	}
 
 	_ = os.Remove(zipPath)
+	_ = os.Remove(ChecksumFilePath(zipPath))
 	if err := os.Rename(tmpPath, zipPath); err != nil {
 		_ = os.Remove(tmpPath)
 		return "", err
 	}
+	_, _ = writeChecksum(zipPath)
 
 	// Write metadata
-	commitPath := strings.TrimSuffix(zipPath, ".zip") + ".commit.txt"
+	commitPath := CommitFilePath(zipPath)
 	_ = writeSHA(metaPath, remoteSHA)
 	short := remoteSHA
 	if len(short) > 7 {
 		short = short[:7]
 	}
 	_ = writeSHA(commitPath, short)
-	_ = s.touch(zipPath)
+	s.toucher.enqueue(zipPath)
+	s.notifyFresh(user, ownerRepo, branch, zipPath, start)
 	return zipPath, nil
 }
 
 // ensureRepoLegacy uses the old GitHub zipball API method.
-func (s *Storage) ensureRepoLegacy(ctx context.Context, user, ownerRepo, branch, token string, force bool) (string, error) {
+func (s *Storage) ensureRepoLegacy(ctx context.Context, user, ownerRepo, branch, token, ext string, force bool) (string, error) {
 	user = strings.Trim(user, "/ ")
 	if user == "" {
 		user = "default"
@@ -283,19 +511,24 @@ func (s *Storage) ensureRepoLegacy(ctx context.Context, user, ownerRepo, branch,
 		return "", fmt.Errorf("owner/repo expected: %w", ErrBadPath)
 	}
 	// If branch not specified, fetch the default branch from GitHub
+	usedFallback := false
 	if branch == "" {
 		defaultBranch, err := s.fetchDefaultBranch(ctx, ownerRepo, token)
 		if err != nil {
-			return "", fmt.Errorf("fetch default branch: %w", err)
+			fallback := s.fallbackBranch(user, ownerRepo)
+			if fallback == "" {
+				return "", fmt.Errorf("fetch default branch: %w", err)
+			}
+			fmt.Printf("warning: fetch default branch for %s failed (%v), falling back to %s\n", ownerRepo, err, fallback)
+			defaultBranch = fallback
+			usedFallback = true
+		} else {
+			fmt.Printf("resolved default branch for %s: %s\n", ownerRepo, defaultBranch)
 		}
-		fmt.Printf("resolved default branch for %s: %s\n", ownerRepo, defaultBranch)
 		branch = defaultBranch
 	}
-	// Sanitize branch name for use in file paths (replace / and \ with -)
-	safeBranch := strings.ReplaceAll(branch, "/", "-")
-	safeBranch = strings.ReplaceAll(safeBranch, "\\", "-")
-	// Use .legacy.zip suffix to separate from git mode cache
-	zipPath := filepath.Join(s.Root, "users", user, "repos", ownerRepo, safeBranch+".legacy.zip")
+	// Use .legacy.<ext> suffix to separate from git mode cache
+	zipPath := filepath.Join(s.Root, "users", user, "repos", ownerRepo, BranchFileName(branch)+".legacy."+ext)
 	metaPath := zipPath + ".meta"
 	unlock := s.acquire(user, ownerRepo, branch+"-legacy")
 	defer unlock()
@@ -312,33 +545,50 @@ func (s *Storage) ensureRepoLegacy(ctx context.Context, user, ownerRepo, branch,
 		if info, err := os.Stat(zipPath); err == nil && !info.IsDir() {
 			if fetchErr == nil && remoteSHA != "" {
 				if cachedSHA, err := readSHA(metaPath); err == nil && cachedSHA == remoteSHA {
-					_ = s.touch(zipPath)
+					s.toucher.enqueue(zipPath)
 					return zipPath, nil
 				}
 			}
+			// The API is unavailable and the branch was a fallback guess: serve
+			// what we have rather than failing the request.
+			if fetchErr != nil && usedFallback {
+				s.toucher.enqueue(zipPath)
+				return zipPath, nil
+			}
 			// If fetchErr != nil, we cannot verify, so we fall through to force refresh
 		}
 	}
 
+	if err := s.checkQuota(user, zipPath, 0); err != nil {
+		return "", err
+	}
+
 	// Download fresh zip (to temp then replace).
-	tmpFile, err := os.CreateTemp(parent, ".tmp-download-*.zip")
+	start := time.Now()
+	tmpFile, err := os.CreateTemp(parent, ".tmp-download-*."+ext)
 	if err != nil {
 		return "", err
 	}
 	tmpPath := tmpFile.Name()
 	_ = tmpFile.Close()
 
-	if err := s.downloadZip(ctx, ownerRepo, branch, token, tmpPath); err != nil {
+	if err := s.downloadArchive(ctx, ownerRepo, branch, token, ext, tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return "", err
+	}
+	if err := s.checkQuotaForFile(user, zipPath, tmpPath); err != nil {
 		_ = os.Remove(tmpPath)
 		return "", err
 	}
 	_ = os.Remove(zipPath)
+	_ = os.Remove(ChecksumFilePath(zipPath))
 	if err := os.Rename(tmpPath, zipPath); err != nil {
 		_ = os.Remove(tmpPath)
 		return "", err
 	}
+	_, _ = writeChecksum(zipPath)
 
-	commitPath := strings.TrimSuffix(zipPath, ".zip") + ".commit.txt"
+	commitPath := CommitFilePath(zipPath)
 	if remoteSHA != "" {
 		_ = writeSHA(metaPath, remoteSHA)
 		short := remoteSHA
@@ -350,10 +600,310 @@ func (s *Storage) ensureRepoLegacy(ctx context.Context, user, ownerRepo, branch,
 		_ = os.Remove(metaPath)
 		// 若无法获取远端 SHA，则保持已有 commit 文件（如果存在），不强删
 	}
-	_ = s.touch(zipPath)
+	s.toucher.enqueue(zipPath)
+	s.notifyFresh(user, ownerRepo, branch, zipPath, start)
 	return zipPath, nil
 }
 
+// fallbackBranch picks the branch to use when the default branch cannot be
+// resolved: the configured FallbackBranch, or else the most recently modified
+// legacy archive cached for user. Returns "" when neither is available.
+func (s *Storage) fallbackBranch(user, ownerRepo string) string {
+	if s.FallbackBranch != "" {
+		return s.FallbackBranch
+	}
+	dir := filepath.Join(s.Root, "users", user, "repos", ownerRepo)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	var best string
+	var bestMod time.Time
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !isArchiveName(name) || !strings.HasSuffix(trimArchiveExt(name), ".legacy") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		if best == "" || info.ModTime().After(bestMod) {
+			best = BranchFromFileName(strings.TrimSuffix(trimArchiveExt(name), ".legacy"))
+			bestMod = info.ModTime()
+		}
+	}
+	return best
+}
+
+// RepoStat describes the cached archive for a repo/branch.
+type RepoStat struct {
+	Cached     bool       `json:"cached"`
+	Size       int64      `json:"size"`
+	SHA        string     `json:"sha,omitempty"`
+	LastAccess *time.Time `json:"last_access,omitempty"`
+}
+
+// StatRepo reports whether user has a cached archive for ownerRepo@branch in the
+// given format without downloading or touching it. An empty branch means "main",
+// as in git mode; an empty format means zip.
+func (s *Storage) StatRepo(user, ownerRepo, branch, format string, legacy bool) (RepoStat, error) {
+	ext, err := ArchiveExt(format)
+	if err != nil {
+		return RepoStat{}, err
+	}
+	user = strings.Trim(user, "/ ")
+	if user == "" {
+		user = "default"
+	}
+	if strings.ContainsRune(user, '/') || strings.ContainsRune(user, '\\') {
+		return RepoStat{}, fmt.Errorf("invalid user: %w", ErrBadPath)
+	}
+	user = sanitizeName(user)
+	ownerRepo = strings.Trim(ownerRepo, "/")
+	if ownerRepo == "" || strings.Count(ownerRepo, "/") != 1 {
+		return RepoStat{}, fmt.Errorf("owner/repo expected: %w", ErrBadPath)
+	}
+	if branch == "" {
+		branch = "main"
+	}
+	name := BranchFileName(branch) + "." + ext
+	if legacy {
+		name = BranchFileName(branch) + ".legacy." + ext
+	}
+	rel := filepath.Join("users", user, "repos", ownerRepo, name)
+	archivePath, err := s.safeJoin(rel)
+	if err != nil {
+		return RepoStat{}, err
+	}
+	info, err := os.Stat(archivePath)
+	if err != nil || info.IsDir() {
+		return RepoStat{Cached: false}, nil
+	}
+	lastAccess := info.ModTime()
+	st := RepoStat{Cached: true, Size: info.Size(), LastAccess: &lastAccess}
+	if sha, err := readSHA(archivePath + ".meta"); err == nil {
+		st.SHA = sha
+	}
+	return st, nil
+}
+
+// UserRepoUsage returns the total size of cached repo zips under users/<user>/repos.
+// In-flight temp downloads are not counted.
+func (s *Storage) UserRepoUsage(user string) (int64, error) {
+	reposDir := filepath.Join(s.Root, "users", user, "repos")
+	var total int64
+	err := filepath.WalkDir(reposDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !isArchiveName(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// CacheEntry describes one cached repo archive.
+type CacheEntry struct {
+	User    string    `json:"user"`
+	Repo    string    `json:"repo"`
+	Branch  string    `json:"branch"`
+	Legacy  bool      `json:"legacy,omitempty"`
+	Format  string    `json:"format"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// CacheStats summarizes cached repo archives.
+type CacheStats struct {
+	Entries    []CacheEntry `json:"entries"`
+	TotalSize  int64        `json:"total_size"`
+	TotalCount int          `json:"total_count"`
+}
+
+// CacheStats walks users/<user>/repos and reports every cached archive with
+// totals. An empty user reports all users. Entries are sorted by size, largest first.
+func (s *Storage) CacheStats(user string) (CacheStats, error) {
+	users := []string{}
+	if user != "" {
+		if strings.ContainsRune(user, '/') || strings.ContainsRune(user, '\\') {
+			return CacheStats{}, fmt.Errorf("invalid user: %w", ErrBadPath)
+		}
+		users = append(users, sanitizeName(user))
+	} else {
+		dirs, err := os.ReadDir(filepath.Join(s.Root, "users"))
+		if err != nil && !os.IsNotExist(err) {
+			return CacheStats{}, err
+		}
+		for _, d := range dirs {
+			if d.IsDir() {
+				users = append(users, d.Name())
+			}
+		}
+	}
+
+	stats := CacheStats{Entries: []CacheEntry{}}
+	for _, u := range users {
+		reposDir := filepath.Join(s.Root, "users", u, "repos")
+		err := filepath.WalkDir(reposDir, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() || !isArchiveName(d.Name()) {
+				return nil
+			}
+			rel, err := filepath.Rel(reposDir, path)
+			if err != nil {
+				return nil
+			}
+			// Layout: <owner>/<repo>/<branch>.<ext>. Slashes in branches are stored as
+			// BranchFileName segments; older caches may still nest them in directories.
+			parts := strings.SplitN(filepath.ToSlash(rel), "/", 3)
+			if len(parts) != 3 {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			entry := CacheEntry{
+				User:    u,
+				Repo:    parts[0] + "/" + parts[1],
+				Branch:  BranchFromFileName(trimArchiveExt(parts[2])),
+				Format:  FormatZip,
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+			}
+			if strings.HasSuffix(parts[2], "."+FormatTarGz) {
+				entry.Format = FormatTarGz
+			}
+			if strings.HasSuffix(entry.Branch, ".legacy") {
+				entry.Branch = strings.TrimSuffix(entry.Branch, ".legacy")
+				entry.Legacy = true
+			}
+			stats.Entries = append(stats.Entries, entry)
+			stats.TotalSize += entry.Size
+			return nil
+		})
+		if err != nil {
+			return CacheStats{}, err
+		}
+	}
+	stats.TotalCount = len(stats.Entries)
+	sort.Slice(stats.Entries, func(i, j int) bool {
+		if stats.Entries[i].Size != stats.Entries[j].Size {
+			return stats.Entries[i].Size > stats.Entries[j].Size
+		}
+		return stats.Entries[i].Repo < stats.Entries[j].Repo
+	})
+	return stats, nil
+}
+
+// ClearUser removes users/<user> with everything cached under it and returns
+// the number of bytes freed. A user without a cache frees 0 bytes.
+func (s *Storage) ClearUser(user string) (int64, error) {
+	user = strings.Trim(user, "/ ")
+	if user == "" || user == "." || user == ".." || strings.ContainsRune(user, '/') || strings.ContainsRune(user, '\\') {
+		return 0, fmt.Errorf("invalid user: %w", ErrBadPath)
+	}
+	dir, err := s.safeJoin(filepath.Join("users", sanitizeName(user)))
+	if err != nil {
+		return 0, err
+	}
+	if filepath.Dir(dir) != filepath.Join(filepath.Clean(s.Root), "users") {
+		return 0, fmt.Errorf("invalid user: %w", ErrBadPath)
+	}
+	var freed int64
+	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				freed += info.Size()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return 0, err
+	}
+	return freed, nil
+}
+
+// checkQuota reports ErrQuotaExceeded if replacing zipPath with an archive of
+// incoming bytes would push the user's cache over UserQuotaBytes.
+func (s *Storage) checkQuota(user, zipPath string, incoming int64) error {
+	if s.UserQuotaBytes <= 0 {
+		return nil
+	}
+	used, err := s.UserRepoUsage(user)
+	if err != nil {
+		return fmt.Errorf("compute user usage: %w", err)
+	}
+	// The existing archive for this branch is replaced, so it does not count.
+	if info, err := os.Stat(zipPath); err == nil && !info.IsDir() {
+		used -= info.Size()
+	}
+	if used+incoming > s.UserQuotaBytes {
+		return fmt.Errorf("user %s uses %d bytes, adding %d exceeds limit %d: %w", user, used, incoming, s.UserQuotaBytes, ErrQuotaExceeded)
+	}
+	return nil
+}
+
+// checkQuotaForFile is checkQuota using the size of a freshly downloaded file.
+func (s *Storage) checkQuotaForFile(user, zipPath, newPath string) error {
+	if s.UserQuotaBytes <= 0 {
+		return nil
+	}
+	info, err := os.Stat(newPath)
+	if err != nil {
+		return err
+	}
+	return s.checkQuota(user, zipPath, info.Size())
+}
+
+// FilterEntries keeps the entries whose name matches the shell-style pattern
+// (filepath.Match syntax). An empty pattern keeps everything; a malformed
+// pattern is reported as ErrBadPath.
+func FilterEntries(entries []Entry, pattern string) ([]Entry, error) {
+	if pattern == "" {
+		return entries, nil
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", pattern, ErrBadPath)
+	}
+	kept := entries[:0]
+	for _, e := range entries {
+		if ok, _ := filepath.Match(pattern, e.Name); ok {
+			kept = append(kept, e)
+		}
+	}
+	return kept, nil
+}
+
 // List lists entries under the given relative path.
 func (s *Storage) List(rel string) ([]Entry, error) {
 	abs, err := s.safeJoin(rel)
@@ -369,7 +919,7 @@ func (s *Storage) List(rel string) ([]Entry, error) {
 	}
 	result := make([]Entry, 0, len(entries))
 	for _, e := range entries {
-		if strings.HasSuffix(e.Name(), ".meta") {
+		if isSidecarName(e.Name()) {
 			continue
 		}
 		info, _ := e.Info()
@@ -388,6 +938,67 @@ func (s *Storage) List(rel string) ([]Entry, error) {
 	return result, nil
 }
 
+// ListRecursive walks rel and returns every entry below it in lexical order,
+// with Path relative to the storage root like List. It does not descend more
+// than maxDepth levels (1 lists only rel itself) and stops after maxEntries
+// entries; truncated reports whether either limit cut the listing short.
+// Symlinks are returned as entries but never followed.
+func (s *Storage) ListRecursive(rel string, maxDepth, maxEntries int) ([]Entry, bool, error) {
+	abs, err := s.safeJoin(rel)
+	if err != nil {
+		return nil, false, err
+	}
+	if _, err := os.Stat(abs); err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, ErrNotFound
+		}
+		return nil, false, err
+	}
+	result := []Entry{}
+	truncated := false
+	errStop := errors.New("stop")
+	err = filepath.WalkDir(abs, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == abs {
+			return nil
+		}
+		sub, err := filepath.Rel(abs, p)
+		if err != nil {
+			return err
+		}
+		if isSidecarName(d.Name()) {
+			return nil
+		}
+		if len(result) >= maxEntries {
+			truncated = true
+			return errStop
+		}
+		size := int64(0)
+		if info, err := d.Info(); err == nil {
+			size = info.Size()
+		}
+		result = append(result, Entry{
+			Name:  d.Name(),
+			Path:  filepath.ToSlash(filepath.Join(rel, sub)),
+			IsDir: d.IsDir(),
+			Size:  size,
+		})
+		if d.IsDir() && strings.Count(filepath.ToSlash(sub), "/")+1 >= maxDepth {
+			if children, _ := os.ReadDir(p); len(children) > 0 {
+				truncated = true
+			}
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStop) {
+		return nil, false, err
+	}
+	return result, truncated, nil
+}
+
 // Delete removes the relative path. If recursive is false and path is a directory, it must be empty.
 func (s *Storage) Delete(rel string, recursive bool) error {
 	abs, err := s.safeJoin(rel)
@@ -400,6 +1011,151 @@ func (s *Storage) Delete(rel string, recursive bool) error {
 	return os.Remove(abs)
 }
 
+// Move renames from to to, creating the parent directories of to. It refuses
+// to overwrite an existing destination (ErrExists), to move the root or a
+// directory into itself (ErrBadPath), and reports a missing source as
+// ErrNotFound. Hidden ".meta" and ".sha256" sidecars move along with their archive.
+func (s *Storage) Move(from, to string) error {
+	src, err := s.safeJoin(from)
+	if err != nil {
+		return err
+	}
+	dst, err := s.safeJoin(to)
+	if err != nil {
+		return err
+	}
+	root := filepath.Clean(s.Root)
+	if src == root || dst == root || src == dst || strings.HasPrefix(dst, src+string(os.PathSeparator)) {
+		return ErrBadPath
+	}
+	if _, err := os.Lstat(src); err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return ErrExists
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err != nil {
+		return err
+	}
+	for _, sidecar := range []string{".meta", ".sha256"} {
+		if _, err := os.Stat(src + sidecar); err == nil {
+			_ = os.Rename(src+sidecar, dst+sidecar)
+		}
+	}
+	return nil
+}
+
+// ExtractZip extracts the zip archive at zipPath into the relative path rel under Root.
+// Entries that would escape the destination (ZipSlip) are rejected with ErrBadPath.
+// The archive must stay within MaxExtractBytes / MaxExtractFileBytes (ErrTooLarge)
+// and its declared size must fit in user's quota (ErrQuotaExceeded).
+func (s *Storage) ExtractZip(user, rel, zipPath string) error {
+	dest, err := s.safeJoin(rel)
+	if err != nil {
+		return err
+	}
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return fmt.Errorf("open zip: %v: %w", err, ErrBadArchive)
+	}
+	defer func() { _ = zr.Close() }()
+
+	maxTotal, maxFile := s.extractLimits()
+	var declared uint64
+	for _, f := range zr.File {
+		if f.UncompressedSize64 > uint64(maxFile) {
+			return fmt.Errorf("%s declares %d bytes, over the %d byte per-file cap: %w", f.Name, f.UncompressedSize64, maxFile, ErrTooLarge)
+		}
+		declared += f.UncompressedSize64
+		if declared > uint64(maxTotal) {
+			return fmt.Errorf("archive declares more than %d bytes: %w", maxTotal, ErrTooLarge)
+		}
+	}
+	if err := s.checkQuota(sanitizeName(user), "", int64(declared)); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return err
+	}
+	var written int64
+	for _, f := range zr.File {
+		fp := filepath.Join(dest, f.Name)
+		if !strings.HasPrefix(fp, dest+string(os.PathSeparator)) && fp != dest {
+			return fmt.Errorf("illegal file path %s: %w", f.Name, ErrBadPath)
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(fp, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		if !f.Mode().IsRegular() {
+			return fmt.Errorf("unsupported entry %s: %w", f.Name, ErrBadPath)
+		}
+		if err := os.MkdirAll(filepath.Dir(fp), 0o755); err != nil {
+			return err
+		}
+		// Declared sizes can lie, so cap the bytes actually written too.
+		allowed := maxFile
+		if remaining := maxTotal - written; remaining < allowed {
+			allowed = remaining
+		}
+		n, err := extractZipFile(f, fp, allowed)
+		written += n
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractLimits returns the effective ExtractZip caps.
+func (s *Storage) extractLimits() (total, perFile int64) {
+	total, perFile = s.MaxExtractBytes, s.MaxExtractFileBytes
+	if total <= 0 {
+		total = DefaultMaxExtractBytes
+	}
+	if perFile <= 0 {
+		perFile = DefaultMaxExtractFileBytes
+	}
+	return total, perFile
+}
+
+// extractZipFile writes f to dest, failing with ErrTooLarge once more than
+// allowed bytes come out of the entry. It returns the bytes written.
+func extractZipFile(f *zip.File, dest string, allowed int64) (int64, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = rc.Close() }()
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, f.Mode().Perm()|0o200)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(out, io.LimitReader(rc, allowed+1))
+	if err != nil {
+		_ = out.Close()
+		return n, err
+	}
+	if err := out.Close(); err != nil {
+		return n, err
+	}
+	if n > allowed {
+		return n, fmt.Errorf("%s exceeds the extraction limit of %d bytes: %w", f.Name, allowed, ErrTooLarge)
+	}
+	return n, nil
+}
+
 // Helpers
 func (s *Storage) safeJoin(rel string) (string, error) {
 	if rel == "" {
@@ -432,9 +1188,10 @@ func (s *Storage) acquire(user, repo, branch string) func() {
 	return m.Unlock
 }
 
-// downloadZip downloads archive into the given path.
-func (s *Storage) downloadZip(ctx context.Context, ownerRepo, branch, token, dest string) error {
-	downloadURL := fmt.Sprintf("https://codeload.github.com/%s/zip/%s", ownerRepo, url.PathEscape(branch))
+// downloadArchive fetches the codeload archive of ownerRepo@branch in the given
+// format ("zip" or "tar.gz") into dest.
+func (s *Storage) downloadArchive(ctx context.Context, ownerRepo, branch, token, ext, dest string) error {
+	downloadURL := fmt.Sprintf("%s/%s/%s/%s", s.codeloadBase(), ownerRepo, ext, url.PathEscape(branch))
 	reqBuilder := func(ctx context.Context) (*http.Request, error) {
 		req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
 		if err != nil {
@@ -443,7 +1200,11 @@ func (s *Storage) downloadZip(ctx context.Context, ownerRepo, branch, token, des
 		if strings.TrimSpace(token) != "" {
 			req.Header.Set("Authorization", "Bearer "+token)
 		}
-		req.Header.Set("Accept", "application/zip")
+		if ext == FormatTarGz {
+			req.Header.Set("Accept", "application/x-gzip")
+		} else {
+			req.Header.Set("Accept", "application/zip")
+		}
 		return req, nil
 	}
 	readerFn := func(resp *http.Response) io.Reader {
@@ -475,11 +1236,13 @@ func (s *Storage) downloadFile(ctx context.Context, fileURL, dest string) error
 func (s *Storage) downloadWithRetry(ctx context.Context, dest string, label string, reqBuilder func(context.Context) (*http.Request, error), readerFn func(*http.Response) io.Reader) error {
 	attempts := s.retryAttempts()
 	var lastErr error
+	var retryAfter time.Duration
 	for attempt := 0; attempt < attempts; attempt++ {
 		if attempt > 0 {
-			if err := sleepWithBackoff(ctx, s.retryBackoff(), attempt); err != nil {
+			if err := s.waitRetry(ctx, attempt, retryAfter); err != nil {
 				return err
 			}
+			retryAfter = 0
 		}
 		req, err := reqBuilder(ctx)
 		if err != nil {
@@ -493,14 +1256,19 @@ func (s *Storage) downloadWithRetry(ctx context.Context, dest string, label stri
 			}
 			continue
 		}
+		s.logRateLimit(req, resp)
 		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 			body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
 			_ = resp.Body.Close()
 			err := fmt.Errorf("download failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
 			lastErr = err
-			if attempt == attempts-1 || !isRetryableStatus(resp.StatusCode) {
+			if rl := rateLimitError(resp); rl != nil && (attempt == attempts-1 || time.Until(rl.Reset) > maxRetryWait) {
+				return rl
+			}
+			if attempt == attempts-1 || !isRetryableResponse(resp) {
 				return err
 			}
+			retryAfter = retryAfterDelay(resp)
 			continue
 		}
 
@@ -577,12 +1345,23 @@ func (s *Storage) retryBackoff() time.Duration {
 	return s.RetryBackoff
 }
 
-func sleepWithBackoff(ctx context.Context, base time.Duration, attempt int) error {
-	backoff := base * time.Duration(attempt)
-	if backoff <= 0 {
-		return nil
+// maxRetryWait caps both exponential backoff and server-provided Retry-After waits.
+const maxRetryWait = 30 * time.Second
+
+// waitRetry sleeps before retry number attempt (1-based): RetryBackoff doubled per
+// attempt, or the server's Retry-After hint when it is longer.
+func (s *Storage) waitRetry(ctx context.Context, attempt int, retryAfter time.Duration) error {
+	wait := s.retryBackoff()
+	for i := 1; i < attempt && wait < maxRetryWait; i++ {
+		wait *= 2
+	}
+	if retryAfter > wait {
+		wait = retryAfter
 	}
-	timer := time.NewTimer(backoff)
+	if wait > maxRetryWait {
+		wait = maxRetryWait
+	}
+	timer := time.NewTimer(wait)
 	defer timer.Stop()
 	select {
 	case <-ctx.Done():
@@ -592,12 +1371,116 @@ func sleepWithBackoff(ctx context.Context, base time.Duration, attempt int) erro
 	}
 }
 
+// doAPIRequest sends a GitHub API request, retrying transport errors, 5xx and
+// rate-limit responses. The final response is returned as-is for the caller to check.
+func (s *Storage) doAPIRequest(ctx context.Context, reqBuilder func(context.Context) (*http.Request, error)) (*http.Response, error) {
+	attempts := s.retryAttempts()
+	var retryAfter time.Duration
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if err := s.waitRetry(ctx, attempt, retryAfter); err != nil {
+				return nil, err
+			}
+			retryAfter = 0
+		}
+		req, err := reqBuilder(ctx)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.httpClient().Do(req)
+		if err != nil {
+			if attempt == attempts-1 || !isRetryableError(err) {
+				return nil, err
+			}
+			continue
+		}
+		s.logRateLimit(req, resp)
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		}
+		rl := rateLimitError(resp)
+		// Waiting out a primary rate limit that resets far in the future is
+		// pointless; report it straight away.
+		if rl != nil && (attempt == attempts-1 || time.Until(rl.Reset) > maxRetryWait) {
+			_ = resp.Body.Close()
+			return nil, rl
+		}
+		if attempt == attempts-1 || !isRetryableResponse(resp) {
+			return resp, nil
+		}
+		retryAfter = retryAfterDelay(resp)
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+		_ = resp.Body.Close()
+	}
+}
+
+// rateLimitError returns an *ErrRateLimited for 403/429 responses whose
+// X-RateLimit-Remaining is 0, with Reset taken from X-RateLimit-Reset.
+func rateLimitError(resp *http.Response) *ErrRateLimited {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+	if strings.TrimSpace(resp.Header.Get("X-RateLimit-Remaining")) != "0" {
+		return nil
+	}
+	rl := &ErrRateLimited{}
+	if secs, err := strconv.ParseInt(strings.TrimSpace(resp.Header.Get("X-RateLimit-Reset")), 10, 64); err == nil && secs > 0 {
+		rl.Reset = time.Unix(secs, 0)
+	}
+	return rl
+}
+
+// logRateLimit prints the remaining GitHub API quota when Debug is enabled.
+func (s *Storage) logRateLimit(req *http.Request, resp *http.Response) {
+	if !s.Debug {
+		return
+	}
+	remaining := resp.Header.Get("X-RateLimit-Remaining")
+	if remaining == "" {
+		return
+	}
+	reset := resp.Header.Get("X-RateLimit-Reset")
+	if secs, err := strconv.ParseInt(reset, 10, 64); err == nil {
+		reset = time.Unix(secs, 0).UTC().Format(time.RFC3339)
+	}
+	fmt.Printf("DEBUG: github rate limit %s %s: remaining=%s limit=%s reset=%s\n",
+		req.Method, req.URL.Path, remaining, resp.Header.Get("X-RateLimit-Limit"), reset)
+}
+
 func isRetryableStatus(status int) bool {
 	return status == http.StatusRequestTimeout ||
 		status == http.StatusTooManyRequests ||
 		status >= 500
 }
 
+// isRetryableResponse also treats GitHub's secondary rate limit (403 with
+// Retry-After or an exhausted X-RateLimit-Remaining) as transient.
+func isRetryableResponse(resp *http.Response) bool {
+	if isRetryableStatus(resp.StatusCode) {
+		return true
+	}
+	return resp.StatusCode == http.StatusForbidden &&
+		(resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0")
+}
+
+// retryAfterDelay parses Retry-After (seconds or HTTP date) on 403/429 responses.
+func retryAfterDelay(resp *http.Response) time.Duration {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0
+	}
+	v := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
 func isRetryableError(err error) bool {
 	if err == nil {
 		return false
@@ -678,8 +1561,20 @@ func formatBytes(n int64) string {
 	return fmt.Sprintf("%.1f %s", value, suffixes[exp-1])
 }
 
-// Touch records access time for a relative path (file or directory). It ignores missing paths.
+// Touch records access time for a relative path (file or directory) without
+// waiting: the update is queued for a background worker. It ignores missing paths.
 func (s *Storage) Touch(rel string) error {
+	abs, err := s.safeJoin(rel)
+	if err != nil {
+		return err
+	}
+	s.toucher.enqueue(abs)
+	return nil
+}
+
+// TouchSync updates the access time of rel immediately, for callers that
+// need the timestamp in place before they continue.
+func (s *Storage) TouchSync(rel string) error {
 	abs, err := s.safeJoin(rel)
 	if err != nil {
 		return err
@@ -691,24 +1586,33 @@ func (s *Storage) Touch(rel string) error {
 	return nil
 }
 
+// Close applies pending access-time updates and stops the background
+// toucher; later Touch calls are ignored.
+func (s *Storage) Close() error {
+	s.toucher.close()
+	return nil
+}
+
 func (s *Storage) touch(abs string) error {
 	now := time.Now()
 	return os.Chtimes(abs, now, now)
 }
 
-// CleanupExpired removes cached items unused beyond ttl.
+// CleanupExpired removes cached items unused beyond ttl and returns the
+// removed archive and package paths, relative to Root with forward slashes.
 // - Repos: users/<user>/repos/<owner>/<repo>/<branch>.zip (+.meta, commit)
 // - Packages: users/<user>/packages/** (any file)
-func (s *Storage) CleanupExpired(ttl time.Duration) error {
+func (s *Storage) CleanupExpired(ttl time.Duration) ([]string, error) {
 	cutoff := time.Now().Add(-ttl)
 	root := filepath.Join(s.Root, "users")
 	if _, err := os.Stat(root); err != nil {
 		if os.IsNotExist(err) {
-			return nil
+			return nil, nil
 		}
-		return err
+		return nil, err
 	}
-	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+	var removed []string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return nil // ignore inaccessible
 		}
@@ -723,20 +1627,25 @@ func (s *Storage) CleanupExpired(ttl time.Duration) error {
 
 		switch parts[2] {
 		case "repos":
-			// expect users/<user>/repos/<owner>/<repo>/<branch>.zip
-			if filepath.Ext(path) != ".zip" || len(parts) < 6 {
+			// expect users/<user>/repos/<owner>/<repo>/<branch>.<ext>
+			if !isArchiveName(d.Name()) || len(parts) < 6 {
 				return nil
 			}
 			if expired(path, cutoff) {
-				_ = os.Remove(path)
+				if os.Remove(path) == nil {
+					removed = append(removed, filepath.ToSlash(rel))
+				}
 				_ = os.Remove(path + ".meta")
-				_ = os.Remove(strings.TrimSuffix(path, ".zip") + ".commit.txt")
+				_ = os.Remove(ChecksumFilePath(path))
+				_ = os.Remove(CommitFilePath(path))
 				trimEmpty(filepath.Dir(path), filepath.Join(s.Root, "users"))
 			}
 		case "packages":
 			// any package file under users/<user>/packages/**
 			if expired(path, cutoff) {
-				_ = os.Remove(path)
+				if os.Remove(path) == nil {
+					removed = append(removed, filepath.ToSlash(rel))
+				}
 				trimEmpty(filepath.Dir(path), filepath.Join(s.Root, "users"))
 			}
 		default:
@@ -744,6 +1653,81 @@ func (s *Storage) CleanupExpired(ttl time.Duration) error {
 		}
 		return nil
 	})
+	return removed, err
+}
+
+// CleanupToSize deletes the least recently used repo archives (with their
+// .meta and commit files) until the archives under users/*/repos total at
+// most maxBytes, and returns the removed archive paths like CleanupExpired.
+// Access time is tracked through the archive's mtime, as in CleanupExpired.
+// Packages are not counted. maxBytes <= 0 disables the cap.
+func (s *Storage) CleanupToSize(maxBytes int64) ([]string, error) {
+	if maxBytes <= 0 {
+		return nil, nil
+	}
+	root := filepath.Join(s.Root, "users")
+	if _, err := os.Stat(root); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	type archive struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var archives []archive
+	var total int64
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil // ignore inaccessible
+		}
+		if d.IsDir() || !isArchiveName(d.Name()) {
+			return nil
+		}
+		rel, _ := filepath.Rel(s.Root, path)
+		parts := splitPath(rel)
+		// expect users/<user>/repos/<owner>/<repo>/<branch>.<ext>
+		if len(parts) < 6 || parts[2] != "repos" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		archives = append(archives, archive{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(archives, func(i, j int) bool {
+		if !archives[i].modTime.Equal(archives[j].modTime) {
+			return archives[i].modTime.Before(archives[j].modTime)
+		}
+		return archives[i].path < archives[j].path
+	})
+	var removed []string
+	for _, a := range archives {
+		if total <= maxBytes {
+			break
+		}
+		if err := os.Remove(a.path); err != nil && !os.IsNotExist(err) {
+			continue
+		}
+		_ = os.Remove(a.path + ".meta")
+		_ = os.Remove(ChecksumFilePath(a.path))
+		_ = os.Remove(CommitFilePath(a.path))
+		trimEmpty(filepath.Dir(a.path), root)
+		total -= a.size
+		rel, _ := filepath.Rel(s.Root, a.path)
+		removed = append(removed, filepath.ToSlash(rel))
+	}
+	return removed, nil
 }
 
 func expired(path string, cutoff time.Time) bool {
@@ -779,18 +1763,25 @@ func splitPath(p string) []string {
 	return out
 }
 
+// apiRequest builds a GitHub REST GET request with the JSON accept header and optional token.
+func apiRequest(u, token string) func(context.Context) (*http.Request, error) {
+	return func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if strings.TrimSpace(token) != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return req, nil
+	}
+}
+
 // fetchDefaultBranch retrieves the default branch name from GitHub API.
 func (s *Storage) fetchDefaultBranch(ctx context.Context, ownerRepo, token string) (string, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s", ownerRepo)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-	if strings.TrimSpace(token) != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
-	}
-	resp, err := s.httpClient().Do(req)
+	url := fmt.Sprintf("%s/repos/%s", s.apiBase(), ownerRepo)
+	resp, err := s.doAPIRequest(ctx, apiRequest(url, token))
 	if err != nil {
 		return "", err
 	}
@@ -819,16 +1810,8 @@ func (s *Storage) fetchBranchSHA(ctx context.Context, ownerRepo, branch, token s
 	if len(parts) != 2 {
 		return "", fmt.Errorf("invalid owner/repo")
 	}
-	url := fmt.Sprintf("https://api.github.com/repos/%s/branches/%s", ownerRepo, url.PathEscape(branch))
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-	if strings.TrimSpace(token) != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
-	}
-	resp, err := s.httpClient().Do(req)
+	url := fmt.Sprintf("%s/repos/%s/branches/%s", s.apiBase(), ownerRepo, url.PathEscape(branch))
+	resp, err := s.doAPIRequest(ctx, apiRequest(url, token))
 	if err != nil {
 		return "", err
 	}
@@ -851,6 +1834,43 @@ func (s *Storage) fetchBranchSHA(ctx context.Context, ownerRepo, branch, token s
 	return data.Commit.Sha, nil
 }
 
+// ListRemoteBranches returns the branch names of ownerRepo from the GitHub API,
+// following pagination until the last page.
+func (s *Storage) ListRemoteBranches(ctx context.Context, ownerRepo, token string) ([]string, error) {
+	ownerRepo = strings.Trim(ownerRepo, "/")
+	if ownerRepo == "" || strings.Count(ownerRepo, "/") != 1 {
+		return nil, fmt.Errorf("owner/repo expected: %w", ErrBadPath)
+	}
+	const perPage = 100
+	var names []string
+	for page := 1; ; page++ {
+		u := fmt.Sprintf("%s/repos/%s/branches?per_page=%d&page=%d", s.apiBase(), ownerRepo, perPage, page)
+		resp, err := s.doAPIRequest(ctx, apiRequest(u, token))
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("list branches failed: status=%d body=%s", resp.StatusCode, string(b))
+		}
+		var data []struct {
+			Name string `json:"name"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&data)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range data {
+			names = append(names, b.Name)
+		}
+		if len(data) < perPage {
+			return names, nil
+		}
+	}
+}
+
 func readSHA(path string) (string, error) {
 	b, err := os.ReadFile(path)
 	if err != nil {
@@ -1004,9 +2024,12 @@ func (s *Storage) EnsureBareRepo(ctx context.Context, ownerRepo, token string) (
 	barePath := s.gitCachePath(ownerRepo)
 
 	// Build the remote URL with optional token
-	remoteURL := fmt.Sprintf("https://github.com/%s.git", ownerRepo)
+	remoteURL := fmt.Sprintf("%s/%s.git", s.gitBase(), ownerRepo)
 	if strings.TrimSpace(token) != "" {
-		remoteURL = fmt.Sprintf("https://%s@github.com/%s.git", token, ownerRepo)
+		if u, err := url.Parse(remoteURL); err == nil {
+			u.User = url.User(token)
+			remoteURL = u.String()
+		}
 	}
 
 	// Check if bare repo exists
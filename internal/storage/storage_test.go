@@ -1,14 +1,23 @@
 package storage
 
 import (
+	"archive/zip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestListAndDelete(t *testing.T) {
@@ -56,6 +65,190 @@ func TestList_NotFound(t *testing.T) {
 	}
 }
 
+func TestFilterEntries(t *testing.T) {
+	entries := []Entry{{Name: "main.zip"}, {Name: "dev.zip"}, {Name: "notes.txt"}, {Name: "sub", IsDir: true}}
+	tests := []struct {
+		pattern string
+		want    string
+		wantErr bool
+	}{
+		{pattern: "", want: "main.zip,dev.zip,notes.txt,sub"},
+		{pattern: "*.zip", want: "main.zip,dev.zip"},
+		{pattern: "m*", want: "main.zip"},
+		{pattern: "*.tar", want: ""},
+		{pattern: "[", wantErr: true},
+	}
+	for _, tt := range tests {
+		in := append([]Entry(nil), entries...)
+		got, err := FilterEntries(in, tt.pattern)
+		if tt.wantErr {
+			if !errors.Is(err, ErrBadPath) {
+				t.Errorf("pattern %q: expected ErrBadPath, got %v", tt.pattern, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("pattern %q: %v", tt.pattern, err)
+		}
+		var names []string
+		for _, e := range got {
+			names = append(names, e.Name)
+		}
+		if s := strings.Join(names, ","); s != tt.want {
+			t.Errorf("pattern %q: got %s, want %s", tt.pattern, s, tt.want)
+		}
+	}
+}
+
+func TestListRecursive(t *testing.T) {
+	root := t.TempDir()
+	s := New(root)
+	for _, f := range []string{"u/a/b/c/deep.txt", "u/a/x.txt", "u/a/x.txt.meta", "u/z.txt"} {
+		p := filepath.Join(root, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		maxDepth      int
+		maxEntries    int
+		wantPaths     string
+		wantTruncated bool
+	}{
+		{"full tree", 10, 100, "u/a,u/a/b,u/a/b/c,u/a/b/c/deep.txt,u/a/x.txt,u/z.txt", false},
+		{"depth cap", 2, 100, "u/a,u/a/b,u/a/x.txt,u/z.txt", true},
+		{"entry cap", 10, 3, "u/a,u/a/b,u/a/b/c", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, truncated, err := s.ListRecursive("u", tt.maxDepth, tt.maxEntries)
+			if err != nil {
+				t.Fatalf("ListRecursive: %v", err)
+			}
+			var paths []string
+			for _, e := range entries {
+				paths = append(paths, e.Path)
+			}
+			if got := strings.Join(paths, ","); got != tt.wantPaths {
+				t.Errorf("paths=%s, want %s", got, tt.wantPaths)
+			}
+			if truncated != tt.wantTruncated {
+				t.Errorf("truncated=%v, want %v", truncated, tt.wantTruncated)
+			}
+		})
+	}
+
+	if _, _, err := s.ListRecursive("..", 10, 100); !errors.Is(err, ErrBadPath) {
+		t.Errorf("expected ErrBadPath for escaping path, got %v", err)
+	}
+	if _, _, err := s.ListRecursive("nope", 10, 100); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMove(t *testing.T) {
+	root := t.TempDir()
+	s := New(root)
+	for _, f := range []string{"u/a/main.zip", "u/a/main.zip.meta", "u/b/keep.txt"} {
+		p := filepath.Join(root, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(f), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		wantErr error
+	}{
+		{"rename file with sidecar", "u/a/main.zip", "u/c/renamed.zip", nil},
+		{"destination exists", "u/a", "u/b", ErrExists},
+		{"missing source", "u/nope", "u/x", ErrNotFound},
+		{"escaping destination", "u/a", "../outside", ErrBadPath},
+		{"into itself", "u/a", "u/a/inner", ErrBadPath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.Move(tt.from, tt.to)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Move: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err=%v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "u", "c", "renamed.zip.meta")); err != nil {
+		t.Errorf("sidecar not moved: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "u", "b", "keep.txt")); err != nil {
+		t.Errorf("existing destination was modified: %v", err)
+	}
+}
+
+func TestTouch_AsyncAndSync(t *testing.T) {
+	root := t.TempDir()
+	s := New(root)
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"async.zip", "sync.zip"} {
+		p := filepath.Join(root, name)
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	modTime := func(name string) time.Time {
+		t.Helper()
+		info, err := os.Stat(filepath.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.ModTime()
+	}
+
+	if err := s.TouchSync("sync.zip"); err != nil {
+		t.Fatalf("TouchSync: %v", err)
+	}
+	if !modTime("sync.zip").After(old.Add(time.Hour)) {
+		t.Fatal("TouchSync did not update mtime before returning")
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := s.Touch("async.zip"); err != nil {
+			t.Fatalf("Touch: %v", err)
+		}
+	}
+	if err := s.Touch("missing.zip"); err != nil {
+		t.Fatalf("Touch missing: %v", err)
+	}
+	if err := s.Touch("../escape"); !errors.Is(err, ErrBadPath) {
+		t.Fatalf("expected ErrBadPath, got %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if !modTime("async.zip").After(old.Add(time.Hour)) {
+		t.Fatal("queued touch was not applied by Close")
+	}
+
+	// Touch after Close is a no-op rather than a panic on the closed queue.
+	if err := s.Touch("async.zip"); err != nil {
+		t.Fatalf("Touch after Close: %v", err)
+	}
+}
+
 func TestSafeJoinPreventsEscape(t *testing.T) {
 	root := t.TempDir()
 	s := New(root)
@@ -86,8 +279,8 @@ func TestDownloadZip_EscapesSlashBranch(t *testing.T) {
 		}, nil
 	})}
 
-	if err := s.downloadZip(ctx, "owner/repo", branch, "", dest); err != nil {
-		t.Fatalf("downloadZip: %v", err)
+	if err := s.downloadArchive(ctx, "owner/repo", branch, "", FormatZip, dest); err != nil {
+		t.Fatalf("downloadArchive: %v", err)
 	}
 	data, err := os.ReadFile(dest)
 	if err != nil {
@@ -128,6 +321,62 @@ func TestFetchBranchSHA_EscapesSlashBranch(t *testing.T) {
 	}
 }
 
+func TestGitHubAPI_RetryOnTransientFailure(t *testing.T) {
+	tests := []struct {
+		name         string
+		firstStatus  int
+		firstHeader  http.Header
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "bad gateway", firstStatus: http.StatusBadGateway, wantAttempts: 2},
+		{name: "secondary rate limit", firstStatus: http.StatusForbidden, firstHeader: http.Header{"X-Ratelimit-Remaining": []string{"0"}}, wantAttempts: 2},
+		{name: "too many requests", firstStatus: http.StatusTooManyRequests, firstHeader: http.Header{"Retry-After": []string{"0"}}, wantAttempts: 2},
+		{name: "plain forbidden", firstStatus: http.StatusForbidden, wantAttempts: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(t.TempDir())
+			s.RetryMax = 2
+			s.RetryBackoff = time.Millisecond
+			var attempts int
+			s.HTTPClient = &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				if attempts == 1 {
+					h := tt.firstHeader
+					if h == nil {
+						h = make(http.Header)
+					}
+					return &http.Response{StatusCode: tt.firstStatus, Body: io.NopCloser(strings.NewReader("nope")), Header: h}, nil
+				}
+				body := `{"default_branch":"trunk","commit":{"sha":"abc123"}}`
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+			})}
+
+			branch, err := s.fetchDefaultBranch(context.Background(), "owner/repo", "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fetchDefaultBranch err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if !tt.wantErr && branch != "trunk" {
+				t.Fatalf("unexpected default branch %q", branch)
+			}
+			if attempts != tt.wantAttempts {
+				t.Fatalf("expected %d attempts, got %d", tt.wantAttempts, attempts)
+			}
+
+			attempts = 0
+			sha, err := s.fetchBranchSHA(context.Background(), "owner/repo", "main", "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fetchBranchSHA err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if !tt.wantErr && sha != "abc123" {
+				t.Fatalf("unexpected sha %q", sha)
+			}
+		})
+	}
+}
+
 func TestDownloadZip_RetryOnServerError(t *testing.T) {
 	root := t.TempDir()
 	s := New(root)
@@ -153,8 +402,8 @@ func TestDownloadZip_RetryOnServerError(t *testing.T) {
 		}, nil
 	})}
 
-	if err := s.downloadZip(ctx, "owner/repo", "main", "", dest); err != nil {
-		t.Fatalf("downloadZip: %v", err)
+	if err := s.downloadArchive(ctx, "owner/repo", "main", "", FormatZip, dest); err != nil {
+		t.Fatalf("downloadArchive: %v", err)
 	}
 	if attempts != 2 {
 		t.Fatalf("expected 2 attempts, got %d", attempts)
@@ -208,12 +457,777 @@ func TestDownloadFile_RetryOnServerError(t *testing.T) {
 	}
 }
 
+func TestFetchBranchSHA_RateLimited(t *testing.T) {
+	s := New(t.TempDir())
+	s.RetryMax = 3
+	s.RetryBackoff = time.Millisecond
+	reset := time.Now().Add(time.Hour).Unix()
+	var attempts int
+	s.HTTPClient = &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		h := make(http.Header)
+		h.Set("X-RateLimit-Remaining", "0")
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+		return &http.Response{StatusCode: http.StatusForbidden, Body: io.NopCloser(strings.NewReader("API rate limit exceeded")), Header: h}, nil
+	})}
+
+	_, err := s.fetchBranchSHA(context.Background(), "owner/repo", "main", "")
+	var rl *ErrRateLimited
+	if !errors.As(err, &rl) {
+		t.Fatalf("expected ErrRateLimited, got %v", err)
+	}
+	if rl.Reset.Unix() != reset {
+		t.Fatalf("reset=%v, want %v", rl.Reset.Unix(), reset)
+	}
+	// A reset an hour away is not worth waiting for.
+	if attempts != 1 {
+		t.Fatalf("expected 1 attempt, got %d", attempts)
+	}
+}
+
+func TestEnsureRepoLegacy_DefaultBranchFallback(t *testing.T) {
+	root := t.TempDir()
+	s := New(root)
+	s.RetryMax = 1
+	s.RetryBackoff = time.Millisecond
+
+	cached := filepath.Join(root, "users", "alice", "repos", "owner", "repo", "main.legacy.zip")
+	if err := os.MkdirAll(filepath.Dir(cached), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cached, []byte("cached"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int
+	s.HTTPClient = &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		h := make(http.Header)
+		h.Set("X-RateLimit-Remaining", "0")
+		return &http.Response{StatusCode: http.StatusForbidden, Body: io.NopCloser(strings.NewReader("rate limited")), Header: h}, nil
+	})}
+
+	zipPath, err := s.EnsureRepo(context.Background(), "alice", "owner/repo", "", "", "", false, true)
+	if err != nil {
+		t.Fatalf("EnsureRepo: %v", err)
+	}
+	if zipPath != cached {
+		t.Fatalf("expected cached zip %s, got %s", cached, zipPath)
+	}
+	data, err := os.ReadFile(zipPath)
+	if err != nil || string(data) != "cached" {
+		t.Fatalf("cached zip was replaced: %q, %v", data, err)
+	}
+	if calls == 0 {
+		t.Fatal("expected the default branch lookup to hit the API")
+	}
+
+	// Without a cached branch or a configured fallback the error is surfaced.
+	if _, err := s.EnsureRepo(context.Background(), "bob", "owner/repo", "", "", "", false, true); err == nil {
+		t.Fatal("expected error when no fallback branch is available")
+	}
+}
+
+func TestEnsureRepoLegacy_TarballCachedSeparately(t *testing.T) {
+	root := t.TempDir()
+	s := New(root)
+	ctx := context.Background()
+
+	var paths []string
+	s.HTTPClient = &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body := "archive:" + req.URL.Path
+		if strings.Contains(req.URL.Path, "/branches/") {
+			body = `{"commit":{"sha":"abc123"}}`
+		} else {
+			paths = append(paths, req.URL.Path)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+	})}
+
+	zipPath, err := s.EnsureRepo(ctx, "alice", "owner/repo", "main", "", FormatZip, false, true)
+	if err != nil {
+		t.Fatalf("EnsureRepo zip: %v", err)
+	}
+	tarPath, err := s.EnsureRepo(ctx, "alice", "owner/repo", "main", "", FormatTarGz, false, true)
+	if err != nil {
+		t.Fatalf("EnsureRepo tar.gz: %v", err)
+	}
+	if filepath.Base(zipPath) != "main.legacy.zip" || filepath.Base(tarPath) != "main.legacy.tar.gz" {
+		t.Fatalf("unexpected cache files: %s, %s", zipPath, tarPath)
+	}
+	want := []string{"/owner/repo/zip/main", "/owner/repo/tar.gz/main"}
+	if len(paths) != 2 || paths[0] != want[0] || paths[1] != want[1] {
+		t.Fatalf("codeload paths = %v, want %v", paths, want)
+	}
+	for _, p := range []string{zipPath, tarPath, zipPath + ".meta", tarPath + ".meta", CommitFilePath(zipPath), CommitFilePath(tarPath)} {
+		if _, err := os.Stat(p); err != nil {
+			t.Fatalf("missing %s: %v", p, err)
+		}
+	}
+
+	if _, err := s.EnsureRepo(ctx, "alice", "owner/repo", "main", "", "rar", false, true); !errors.Is(err, ErrBadPath) {
+		t.Fatalf("expected ErrBadPath for unknown format, got %v", err)
+	}
+}
+
+func TestStatRepo_Formats(t *testing.T) {
+	root := t.TempDir()
+	s := New(root)
+	repoDir := filepath.Join(root, "users", "alice", "repos", "owner", "repo")
+	if err := os.MkdirAll(repoDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"main.tar.gz":            "tarball",
+		"main.tar.gz.meta":       "def456",
+		"dev.legacy.tar.gz":      "legacy-tar",
+		"dev.legacy.tar.gz.meta": "fed789",
+		"feature%2Fx.zip":        "zipdata",
+		"feature%2Fx.zip.meta":   "abc123",
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		branch     string
+		format     string
+		legacy     bool
+		wantCached bool
+		wantSize   int64
+		wantSHA    string
+	}{
+		{name: "tar.gz cache", branch: "main", format: FormatTarGz, wantCached: true, wantSize: 7, wantSHA: "def456"},
+		{name: "tgz alias", branch: "main", format: "tgz", wantCached: true, wantSize: 7, wantSHA: "def456"},
+		{name: "zip not cached when only tar.gz is", branch: "main", format: FormatZip},
+		{name: "legacy tar.gz cache", branch: "dev", format: FormatTarGz, legacy: true, wantCached: true, wantSize: 10, wantSHA: "fed789"},
+		{name: "empty format means zip", branch: "feature/x", wantCached: true, wantSize: 7, wantSHA: "abc123"},
+		{name: "tar.gz not cached when only zip is", branch: "feature/x", format: FormatTarGz},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, err := s.StatRepo("alice", "owner/repo", tt.branch, tt.format, tt.legacy)
+			if err != nil {
+				t.Fatalf("StatRepo: %v", err)
+			}
+			if st.Cached != tt.wantCached || st.Size != tt.wantSize || st.SHA != tt.wantSHA {
+				t.Errorf("StatRepo = %+v, want cached=%v size=%d sha=%q", st, tt.wantCached, tt.wantSize, tt.wantSHA)
+			}
+		})
+	}
+
+	if _, err := s.StatRepo("alice", "owner/repo", "main", "rar", false); !errors.Is(err, ErrBadPath) {
+		t.Errorf("expected ErrBadPath for unknown format, got %v", err)
+	}
+}
+
+func TestEnsureRepoLegacy_SlashBranchFlatCache(t *testing.T) {
+	root := t.TempDir()
+	s := New(root)
+	ctx := context.Background()
+	s.HTTPClient = &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body := "zipdata"
+		if strings.Contains(req.URL.Path, "/branches/") {
+			body = `{"commit":{"sha":"abc123"}}`
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+	})}
+
+	zipPath, err := s.EnsureRepo(ctx, "alice", "owner/repo", "feature/sub", "", "", false, true)
+	if err != nil {
+		t.Fatalf("EnsureRepo: %v", err)
+	}
+	if filepath.Base(zipPath) != "feature%2Fsub.legacy.zip" {
+		t.Fatalf("unexpected cache file %s", zipPath)
+	}
+
+	entries, err := s.List("users/alice/repos/owner/repo")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var archives int
+	for _, e := range entries {
+		if e.IsDir {
+			t.Errorf("unexpected nested directory %s", e.Path)
+		}
+		if isArchiveName(e.Name) {
+			archives++
+		}
+	}
+	if archives != 1 {
+		t.Errorf("expected 1 archive in listing, got %+v", entries)
+	}
+
+	stats, err := s.CacheStats("alice")
+	if err != nil {
+		t.Fatalf("CacheStats: %v", err)
+	}
+	if len(stats.Entries) != 1 || stats.Entries[0].Branch != "feature/sub" || !stats.Entries[0].Legacy {
+		t.Errorf("unexpected cache entries: %+v", stats.Entries)
+	}
+	if st, err := s.StatRepo("alice", "owner/repo", "feature/sub", "", true); err != nil || !st.Cached || st.SHA != "abc123" {
+		t.Errorf("StatRepo = %+v, %v", st, err)
+	}
+
+	// A real branch containing "__" must not share the slash branch's cache file.
+	otherPath, err := s.EnsureRepo(ctx, "alice", "owner/repo", "feature__sub", "", "", false, true)
+	if err != nil {
+		t.Fatalf("EnsureRepo feature__sub: %v", err)
+	}
+	if otherPath == zipPath {
+		t.Fatalf("feature__sub and feature/sub share cache file %s", zipPath)
+	}
+	stats, err = s.CacheStats("alice")
+	if err != nil {
+		t.Fatalf("CacheStats: %v", err)
+	}
+	branches := map[string]bool{}
+	for _, e := range stats.Entries {
+		branches[e.Branch] = true
+	}
+	if len(stats.Entries) != 2 || !branches["feature/sub"] || !branches["feature__sub"] {
+		t.Errorf("unexpected cache entries: %+v", stats.Entries)
+	}
+}
+
+func TestEnsureRepoLegacy_ChecksumSidecar(t *testing.T) {
+	root := t.TempDir()
+	s := New(root)
+	ctx := context.Background()
+	body := "first"
+	s.HTTPClient = &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		payload := body
+		if strings.Contains(req.URL.Path, "/branches/") {
+			payload = `{"commit":{"sha":"abc123"}}`
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(payload)), Header: make(http.Header)}, nil
+	})}
+	sumOf := func(data string) string {
+		sum := sha256.Sum256([]byte(data))
+		return hex.EncodeToString(sum[:])
+	}
+
+	zipPath, err := s.EnsureRepo(ctx, "alice", "owner/repo", "main", "", "", false, true)
+	if err != nil {
+		t.Fatalf("EnsureRepo: %v", err)
+	}
+	if got, err := ArchiveChecksum(zipPath); err != nil || got != sumOf("first") {
+		t.Fatalf("ArchiveChecksum = %q, %v", got, err)
+	}
+	if err := s.TouchSync("users/alice/repos/owner/repo/main.legacy.zip"); err != nil {
+		t.Fatalf("TouchSync: %v", err)
+	}
+	if got, _ := ArchiveChecksum(zipPath); got != sumOf("first") {
+		t.Fatalf("touch changed checksum to %q", got)
+	}
+
+	// A forced re-download replaces the sidecar along with the archive.
+	body = "second"
+	if _, err := s.EnsureRepo(ctx, "alice", "owner/repo", "main", "", "", true, true); err != nil {
+		t.Fatalf("EnsureRepo force: %v", err)
+	}
+	if got, _ := ArchiveChecksum(zipPath); got != sumOf("second") {
+		t.Fatalf("checksum after re-download = %q, want %q", got, sumOf("second"))
+	}
+
+	entries, err := s.List("users/alice/repos/owner/repo")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name, ".sha256") {
+			t.Errorf("sidecar %s should be hidden from listings", e.Name)
+		}
+	}
+}
+
+func TestEnsureRepoLegacy_CacheHitTouchesInBackground(t *testing.T) {
+	root := t.TempDir()
+	s := New(root)
+	ctx := context.Background()
+	s.HTTPClient = &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body := "zipdata"
+		if strings.Contains(req.URL.Path, "/branches/") {
+			body = `{"commit":{"sha":"abc123"}}`
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+	})}
+
+	zipPath, err := s.EnsureRepo(ctx, "alice", "owner/repo", "main", "", "", false, true)
+	if err != nil {
+		t.Fatalf("EnsureRepo: %v", err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(zipPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.EnsureRepo(ctx, "alice", "owner/repo", "main", "", "", false, true); err != nil {
+		t.Fatalf("EnsureRepo cache hit: %v", err)
+	}
+	// Close flushes the background toucher, so the cache hit's touch is applied by now.
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	info, err := os.Stat(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().After(old.Add(time.Hour)) {
+		t.Fatalf("cache hit did not refresh mtime: %s", info.ModTime())
+	}
+}
+
+func TestEnsureRepoLegacy_UserQuota(t *testing.T) {
+	tests := []struct {
+		name    string
+		quota   int64
+		wantErr bool
+	}{
+		{name: "unlimited", quota: 0},
+		{name: "within quota", quota: 100},
+		{name: "exceeds quota", quota: 12, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			s := New(root)
+			s.UserQuotaBytes = tt.quota
+			ctx := context.Background()
+
+			// existing 10-byte cache for another repo
+			existing := filepath.Join(root, "users", "alice", "repos", "owner", "other", "main.legacy.zip")
+			if err := os.MkdirAll(filepath.Dir(existing), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(existing, []byte("0123456789"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			s.HTTPClient = &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				body := "zipdata"
+				if strings.Contains(req.URL.Path, "/branches/") {
+					body = `{"commit":{"sha":"abc123"}}`
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader(body)),
+					Header:     make(http.Header),
+				}, nil
+			})}
+
+			zipPath, err := s.EnsureRepo(ctx, "alice", "owner/repo", "main", "", "", false, true)
+			if tt.wantErr {
+				if !errors.Is(err, ErrQuotaExceeded) {
+					t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+				}
+				used, err := s.UserRepoUsage("alice")
+				if err != nil {
+					t.Fatalf("UserRepoUsage: %v", err)
+				}
+				if used != 10 {
+					t.Fatalf("rejected download should leave usage at 10, got %d", used)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EnsureRepo: %v", err)
+			}
+			if _, err := os.Stat(zipPath); err != nil {
+				t.Fatalf("zip not cached: %v", err)
+			}
+		})
+	}
+}
+
+func TestListRemoteBranches_Paginates(t *testing.T) {
+	root := t.TempDir()
+	s := New(root)
+	ctx := context.Background()
+
+	var pages []string
+	s.HTTPClient = &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		page := req.URL.Query().Get("page")
+		pages = append(pages, page)
+		count := 100
+		if page == "2" {
+			count = 2
+		}
+		items := make([]string, count)
+		for i := range items {
+			items[i] = fmt.Sprintf(`{"name":"b%s-%d"}`, page, i)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("[" + strings.Join(items, ",") + "]")),
+			Header:     make(http.Header),
+		}, nil
+	})}
+
+	names, err := s.ListRemoteBranches(ctx, "owner/repo", "")
+	if err != nil {
+		t.Fatalf("ListRemoteBranches: %v", err)
+	}
+	if len(names) != 102 {
+		t.Fatalf("expected 102 branches, got %d", len(names))
+	}
+	if strings.Join(pages, ",") != "1,2" {
+		t.Fatalf("unexpected pages requested: %v", pages)
+	}
+	if names[101] != "b2-1" {
+		t.Fatalf("unexpected last branch: %s", names[101])
+	}
+
+	if _, err := s.ListRemoteBranches(ctx, "no-slash", ""); !errors.Is(err, ErrBadPath) {
+		t.Fatalf("expected ErrBadPath, got %v", err)
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	root := t.TempDir()
+	s := New(root)
+	files := map[string]int{
+		"users/alice/repos/o/r/main.zip":            10,
+		"users/alice/repos/o/r/feature/x.zip":       30,
+		"users/alice/repos/o/r/dev.legacy.zip":      20,
+		"users/alice/repos/o/r/main.tar.gz":         15,
+		"users/alice/repos/o/r/main.zip.meta":       5,
+		"users/alice/repos/o/r/.tmp-download-1.zip": 99,
+		"users/bob/repos/other/repo/main.zip":       40,
+	}
+	for rel, size := range files {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := s.CacheStats("alice")
+	if err != nil {
+		t.Fatalf("CacheStats: %v", err)
+	}
+	if stats.TotalCount != 4 || stats.TotalSize != 75 {
+		t.Fatalf("unexpected totals: count=%d size=%d", stats.TotalCount, stats.TotalSize)
+	}
+	want := []CacheEntry{
+		{User: "alice", Repo: "o/r", Branch: "feature/x", Format: FormatZip, Size: 30},
+		{User: "alice", Repo: "o/r", Branch: "dev", Legacy: true, Format: FormatZip, Size: 20},
+		{User: "alice", Repo: "o/r", Branch: "main", Format: FormatTarGz, Size: 15},
+		{User: "alice", Repo: "o/r", Branch: "main", Format: FormatZip, Size: 10},
+	}
+	for i, w := range want {
+		got := stats.Entries[i]
+		got.ModTime = time.Time{}
+		if got != w {
+			t.Errorf("entry %d = %+v, want %+v", i, got, w)
+		}
+	}
+
+	all, err := s.CacheStats("")
+	if err != nil {
+		t.Fatalf("CacheStats all: %v", err)
+	}
+	if all.TotalCount != 5 || all.Entries[0].User != "bob" {
+		t.Fatalf("unexpected all-user stats: %+v", all)
+	}
+
+	if _, err := s.CacheStats("../alice"); !errors.Is(err, ErrBadPath) {
+		t.Fatalf("expected ErrBadPath for bad user, got %v", err)
+	}
+}
+
+func TestCleanupExpired_ReportsRemoved(t *testing.T) {
+	root := t.TempDir()
+	s := New(root)
+	old := time.Now().Add(-2 * time.Hour)
+	files := map[string]bool{ // rel -> expired
+		"users/alice/repos/o/r/main.zip":      true,
+		"users/alice/repos/o/r/main.zip.meta": true,
+		"users/alice/repos/o/r/dev.zip":       false,
+		"users/bob/packages/abc/tool.tar":     true,
+		"users/bob/packages/def/fresh.tar":    false,
+	}
+	for rel, isOld := range files {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if isOld {
+			if err := os.Chtimes(p, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	removed, err := s.CleanupExpired(time.Hour)
+	if err != nil {
+		t.Fatalf("CleanupExpired: %v", err)
+	}
+	want := []string{"users/alice/repos/o/r/main.zip", "users/bob/packages/abc/tool.tar"}
+	if strings.Join(removed, ",") != strings.Join(want, ",") {
+		t.Fatalf("removed=%v, want %v", removed, want)
+	}
+	if _, err := os.Stat(filepath.Join(root, "users", "alice", "repos", "o", "r", "main.zip.meta")); !os.IsNotExist(err) {
+		t.Errorf("expected meta file to be removed with its archive, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "users", "alice", "repos", "o", "r", "dev.zip")); err != nil {
+		t.Errorf("fresh archive should be kept: %v", err)
+	}
+}
+
+func TestCleanupToSize(t *testing.T) {
+	root := t.TempDir()
+	s := New(root)
+	base := time.Now().Add(-time.Hour)
+	// oldest first; each archive is 10 bytes
+	archives := []string{
+		"users/alice/repos/o/r/old.zip",
+		"users/bob/repos/o/r/older-than-main.zip",
+		"users/alice/repos/o/r/main.zip",
+		"users/alice/repos/o/other/main.tar.gz",
+		"users/bob/repos/x/y/main.zip",
+	}
+	for i, rel := range archives {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range []string{p, p + ".meta", CommitFilePath(p)} {
+			if err := os.WriteFile(f, make([]byte, 10), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		mt := base.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(p, mt, mt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pkg := filepath.Join(root, "users", "alice", "packages", "pkg.bin")
+	if err := os.MkdirAll(filepath.Dir(pkg), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pkg, make([]byte, 100), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := s.CleanupToSize(25)
+	if err != nil {
+		t.Fatalf("CleanupToSize: %v", err)
+	}
+	if strings.Join(removed, ",") != strings.Join(archives[:3], ",") {
+		t.Errorf("removed=%v, want %v", removed, archives[:3])
+	}
+	for i, rel := range archives {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		_, err := os.Stat(p)
+		if removed := i < 3; removed != os.IsNotExist(err) {
+			t.Errorf("%s: removed=%v, stat err=%v", rel, removed, err)
+		}
+		if i < 3 {
+			for _, f := range []string{p + ".meta", CommitFilePath(p)} {
+				if _, err := os.Stat(f); !os.IsNotExist(err) {
+					t.Errorf("expected %s to be removed, got %v", f, err)
+				}
+			}
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "users", "bob", "repos", "o")); !os.IsNotExist(err) {
+		t.Errorf("expected empty repo dirs to be trimmed, got %v", err)
+	}
+	if _, err := os.Stat(pkg); err != nil {
+		t.Errorf("packages should not be evicted: %v", err)
+	}
+
+	// already under the cap: nothing else is removed
+	if removed, err := s.CleanupToSize(25); err != nil || len(removed) != 0 {
+		t.Fatalf("CleanupToSize under cap: removed=%v err=%v", removed, err)
+	}
+	stats, err := s.CacheStats("")
+	if err != nil {
+		t.Fatalf("CacheStats: %v", err)
+	}
+	if stats.TotalCount != 2 || stats.TotalSize != 20 {
+		t.Errorf("unexpected remaining cache: count=%d size=%d", stats.TotalCount, stats.TotalSize)
+	}
+}
+
+func TestEnsureRepo_CoalescesConcurrentCalls(t *testing.T) {
+	root := t.TempDir()
+	s := New(root)
+	ctx := context.Background()
+
+	const n = 5
+	release := make(chan struct{})
+	var downloads int32
+	s.HTTPClient = &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body := "zipdata"
+		if strings.Contains(req.URL.Path, "/branches/") {
+			body = `{"commit":{"sha":"abc123"}}`
+		} else {
+			atomic.AddInt32(&downloads, 1)
+			<-release
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.EnsureRepo(ctx, "alice", "owner/repo", "main", "", "", false, true)
+			errs <- err
+		}()
+	}
+
+	// Hold the leader's download until every other caller has joined the flight.
+	deadline := time.After(2 * time.Second)
+	for s.FlightStats().Coalesced < n-1 {
+		select {
+		case <-deadline:
+			close(release)
+			t.Fatalf("only %d callers coalesced", s.FlightStats().Coalesced)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("EnsureRepo: %v", err)
+		}
+	}
+	stats := s.FlightStats()
+	if stats.Leaders != 1 || stats.Coalesced != n-1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if downloads != 1 {
+		t.Fatalf("expected 1 download, got %d", downloads)
+	}
+}
+
+func TestEnterpriseBaseURLs(t *testing.T) {
+	root := t.TempDir()
+	s := New(root)
+	s.APIBaseURL = "https://ghe.example.com/api/v3/"
+	s.CodeloadBaseURL = "https://codeload.ghe.example.com"
+	ctx := context.Background()
+
+	var seen []string
+	s.HTTPClient = &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		seen = append(seen, req.URL.Host+req.URL.Path)
+		body := "zipdata"
+		switch {
+		case strings.Contains(req.URL.Path, "/branches/"):
+			body = `{"commit":{"sha":"abc123"}}`
+		case strings.HasSuffix(req.URL.Path, "/repos/owner/repo"):
+			body = `{"default_branch":"trunk"}`
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})}
+
+	if _, err := s.fetchDefaultBranch(ctx, "owner/repo", ""); err != nil {
+		t.Fatalf("fetchDefaultBranch: %v", err)
+	}
+	if _, err := s.fetchBranchSHA(ctx, "owner/repo", "trunk", ""); err != nil {
+		t.Fatalf("fetchBranchSHA: %v", err)
+	}
+	if err := s.downloadArchive(ctx, "owner/repo", "trunk", "", FormatZip, filepath.Join(root, "out.zip")); err != nil {
+		t.Fatalf("downloadArchive: %v", err)
+	}
+
+	want := []string{
+		"ghe.example.com/api/v3/repos/owner/repo",
+		"ghe.example.com/api/v3/repos/owner/repo/branches/trunk",
+		"codeload.ghe.example.com/owner/repo/zip/trunk",
+	}
+	if strings.Join(seen, " ") != strings.Join(want, " ") {
+		t.Fatalf("unexpected URLs:\n got %v\nwant %v", seen, want)
+	}
+}
+
 type roundTripperFunc func(*http.Request) (*http.Response, error)
 
 func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
 	return f(r)
 }
 
+func TestExtractZip_Limits(t *testing.T) {
+	root := t.TempDir()
+	zipPath := filepath.Join(root, "upload.zip")
+	f, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(strings.Repeat("x", 100)))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	tests := []struct {
+		name    string
+		total   int64
+		perFile int64
+		quota   int64
+		wantErr error
+	}{
+		{name: "within limits", total: 1000, perFile: 500, quota: 1000},
+		{name: "file over per-file cap", total: 1000, perFile: 50, wantErr: ErrTooLarge},
+		{name: "archive over total cap", total: 150, perFile: 500, wantErr: ErrTooLarge},
+		{name: "over user quota", total: 1000, perFile: 500, quota: 150, wantErr: ErrQuotaExceeded},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(root)
+			s.MaxExtractBytes = tt.total
+			s.MaxExtractFileBytes = tt.perFile
+			s.UserQuotaBytes = tt.quota
+			rel := fmt.Sprintf("users/alice/uploads/%d", i)
+			err := s.ExtractZip("alice", rel, zipPath)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("ExtractZip: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if _, err := os.Stat(filepath.Join(root, rel)); !os.IsNotExist(err) {
+				t.Fatalf("rejected upload should not create %s: %v", rel, err)
+			}
+		})
+	}
+}
+
 func TestExportSparseZip_PathValidation(t *testing.T) {
 	root := t.TempDir()
 	s := New(root)
@@ -238,6 +1252,67 @@ func TestExportSparseZip_PathValidation(t *testing.T) {
 	}
 }
 
+func TestExportSparseZip_OnlyRequestedPaths(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	root := t.TempDir()
+	s := New(root)
+	ctx := context.Background()
+
+	// Build a small work tree and clone it bare into the git cache layout.
+	work := t.TempDir()
+	for _, f := range []string{"src/main.go", "src/pkg/util.go", "docs/README.md", "testdata/big.bin", "LICENSE"} {
+		if err := os.MkdirAll(filepath.Join(work, filepath.Dir(f)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(work, f), []byte(f), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git := func(dir string, args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git(work, "init", "-q", "-b", "main")
+	git(work, "add", ".")
+	git(work, "commit", "-q", "-m", "init")
+	bare := s.gitCachePath("owner/repo")
+	if err := os.MkdirAll(filepath.Dir(bare), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	git(root, "clone", "-q", "--bare", work, bare)
+
+	dest := filepath.Join(root, "out.zip")
+	if _, err := s.ExportSparseZip(ctx, "owner/repo", "main", []string{"src", "docs"}, dest); err != nil {
+		t.Fatalf("ExportSparseZip: %v", err)
+	}
+
+	zr, err := zip.OpenReader(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	got := map[string]bool{}
+	for _, f := range zr.File {
+		if !f.FileInfo().IsDir() {
+			got[f.Name] = true
+		}
+	}
+	for _, want := range []string{"repo-main/src/main.go", "repo-main/src/pkg/util.go", "repo-main/docs/README.md"} {
+		if !got[want] {
+			t.Errorf("missing %s in sparse zip: %v", want, got)
+		}
+	}
+	for name := range got {
+		if strings.Contains(name, "testdata/") || strings.HasSuffix(name, "LICENSE") {
+			t.Errorf("unrequested entry %s in sparse zip", name)
+		}
+	}
+}
+
 func TestExportSparseDir_PathValidation(t *testing.T) {
 	root := t.TempDir()
 	s := New(root)
@@ -309,3 +1384,42 @@ func TestGitCachePath(t *testing.T) {
 		})
 	}
 }
+
+func TestClearUser(t *testing.T) {
+	root := t.TempDir()
+	s := New(root)
+	for rel, size := range map[string]int{
+		"users/alice/repos/o/r/main.zip":      10,
+		"users/alice/repos/o/r/main.zip.meta": 5,
+		"users/alice/artifacts/a.txt":         7,
+		"users/bob/repos/o/r/main.zip":        40,
+	} {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	freed, err := s.ClearUser("alice")
+	if err != nil || freed != 22 {
+		t.Fatalf("ClearUser = %d, %v; want 22", freed, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "users", "alice")); !os.IsNotExist(err) {
+		t.Fatalf("users/alice still exists: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "users", "bob", "repos", "o", "r", "main.zip")); err != nil {
+		t.Fatalf("other user's cache was touched: %v", err)
+	}
+
+	if freed, err := s.ClearUser("alice"); err != nil || freed != 0 {
+		t.Fatalf("clearing an empty cache = %d, %v", freed, err)
+	}
+	for _, user := range []string{"", ".", "..", "a/b", `a\b`} {
+		if _, err := s.ClearUser(user); !errors.Is(err, ErrBadPath) {
+			t.Errorf("ClearUser(%q) = %v, want ErrBadPath", user, err)
+		}
+	}
+}
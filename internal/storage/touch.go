@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// touchQueueSize bounds the number of distinct paths waiting for an
+// access-time update.
+const touchQueueSize = 256
+
+// toucher updates access times on a background worker so request handlers do
+// not wait on os.Chtimes. Pending paths are deduplicated; when the queue is
+// full the update is dropped, since the next access touches the path again.
+type toucher struct {
+	mu      sync.Mutex
+	queue   chan string
+	pending map[string]bool
+	closed  bool
+	done    chan struct{}
+}
+
+// enqueue schedules abs for a touch, starting the worker on first use.
+// It reports whether the path was queued (or already pending).
+func (t *toucher) enqueue(abs string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return false
+	}
+	if t.queue == nil {
+		t.queue = make(chan string, touchQueueSize)
+		t.pending = make(map[string]bool)
+		t.done = make(chan struct{})
+		go t.run()
+	}
+	if t.pending[abs] {
+		return true
+	}
+	select {
+	case t.queue <- abs:
+		t.pending[abs] = true
+		return true
+	default:
+		return false
+	}
+}
+
+func (t *toucher) run() {
+	defer close(t.done)
+	for abs := range t.queue {
+		t.mu.Lock()
+		delete(t.pending, abs)
+		t.mu.Unlock()
+		if _, err := os.Stat(abs); err == nil {
+			now := time.Now()
+			_ = os.Chtimes(abs, now, now)
+		}
+	}
+}
+
+// close stops accepting new paths and waits until queued ones are applied.
+func (t *toucher) close() {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return
+	}
+	t.closed = true
+	queue, done := t.queue, t.done
+	t.mu.Unlock()
+	if queue != nil {
+		close(queue)
+		<-done
+	}
+}
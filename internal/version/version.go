@@ -30,3 +30,21 @@ func String() string {
 	}
 	return v + " (" + strings.Join(meta, ", ") + ")"
 }
+
+// Info is the build metadata reported by the servers' version endpoints.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	String    string `json:"string"` // same as String()
+}
+
+// Current returns the build metadata of the running binary.
+func Current() Info {
+	return Info{
+		Version:   strings.TrimSpace(Version),
+		Commit:    strings.TrimSpace(Commit),
+		BuildDate: strings.TrimSpace(BuildDate),
+		String:    String(),
+	}
+}
@@ -60,6 +60,14 @@ GitHub-Hub Quality Server 负载测试脚本
     -type <类型>    事件类型 (push 或 pr)
     -qps <数量>     速率限制 (每秒请求数)
     -timeout <秒>   请求超时时间
+    -success-codes <列表>  视为成功的 HTTP 状态码，如 202 (默认: 任意 2xx)
+    -payload <文件>  以该 JSON 文件作为请求体，替代内置 payload
+    -event <类型>    X-GitHub-Event 请求头 (默认: push，-type pr 时为 pull_request)
+    -secret <密钥>   使用 webhook 密钥为请求添加 X-Hub-Signature-256 签名
+    -output <格式>   结果格式: text、json 或 csv (默认: text)
+    -csv-header     配合 -output csv，先输出列名
+    -rampup <时长>   在该时间窗口内逐步启动并发连接，如 10s (默认: 0，立即全部启动)
+    -rampup-exclude 预热期间发出的请求不计入延迟统计
 EOF
 }
 
@@ -3,27 +3,23 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
-// Statistics for load testing
-type Stats struct {
-	TotalRequests   int64
-	SuccessRequests int64
-	FailedRequests  int64
-	TotalBytes      int64
-	MinLatency      time.Duration
-	MaxLatency      time.Duration
-	latencies       []time.Duration
-	mu              sync.Mutex
-}
-
 // Load test configuration
 type Config struct {
 	ServerURL      string
@@ -32,6 +28,14 @@ type Config struct {
 	TotalRequests  int
 	Timeout        time.Duration
 	QPS            int // Queries per second (0 = unlimited)
+	SuccessCodes   map[int]bool // HTTP status codes counted as success (nil = any 2xx)
+	Payload        []byte       // Request body from -payload (nil = built-in payload for EventType)
+	GitHubEvent    string       // X-GitHub-Event header from -event (empty = derived from EventType)
+	Secret         string       // Webhook secret used to sign each request (empty = unsigned)
+	Output         string       // Result format: text, json or csv (empty = text)
+	CSVHeader      bool         // Print the CSV header row before the result row
+	Rampup         time.Duration // Start workers linearly over this window (0 = all at once)
+	RampupExclude  bool          // Leave requests sent during ramp-up out of the latency stats
 }
 
 // Webhook payloads
@@ -166,25 +170,85 @@ func getPayload(eventType string) []byte {
 	}
 }
 
-func sendRequest(client *http.Client, url string, eventType string, stats *Stats) {
-	payload := getPayload(eventType)
-	req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+// webhookRequest is the body and headers shared by every request in a run.
+type webhookRequest struct {
+	body      []byte
+	event     string
+	signature string
+}
+
+// buildWebhookRequest resolves the payload and headers for a run. The body is
+// identical for every request, so the signature is computed once up front to
+// keep HMAC work off the measured path.
+func buildWebhookRequest(config Config) webhookRequest {
+	req := webhookRequest{body: config.Payload, event: config.GitHubEvent}
+	if req.body == nil {
+		req.body = getPayload(config.EventType)
+	}
+	if req.event == "" {
+		req.event = githubEventName(config.EventType)
+	}
+	if config.Secret != "" {
+		req.signature = signPayload(config.Secret, req.body)
+	}
+	return req
+}
+
+// githubEventName maps a -type value to its X-GitHub-Event header.
+func githubEventName(eventType string) string {
+	if eventType == "pr" {
+		return "pull_request"
+	}
+	return eventType
+}
+
+// signPayload returns the X-Hub-Signature-256 value for body.
+func signPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// loadPayloadFile reads a request body for -payload and rejects invalid JSON.
+func loadPayloadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("%s is not valid JSON", path)
+	}
+	return data, nil
+}
+
+// sendRequest sends one webhook and records the outcome. A request aborted
+// because ctx was cancelled is not counted at all.
+func sendRequest(ctx context.Context, client *http.Client, url string, webhook webhookRequest, successCodes map[int]bool, stats *Stats, rec *Recorder) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(webhook.body))
 	if err != nil {
 		atomic.AddInt64(&stats.FailedRequests, 1)
+		atomic.AddInt64(&stats.TransportErrors, 1)
 		atomic.AddInt64(&stats.TotalRequests, 1)
 		return
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-GitHub-Event", eventType)
+	req.Header.Set("X-GitHub-Event", webhook.event)
+	if webhook.signature != "" {
+		req.Header.Set("X-Hub-Signature-256", webhook.signature)
+	}
 	req.Header.Set("X-GitHub-Delivery", fmt.Sprintf("%d-%d", time.Now().UnixNano(), os.Getpid()))
 
 	start := time.Now()
 	resp, err := client.Do(req)
 	latency := time.Since(start)
 
+	if err != nil && ctx.Err() != nil {
+		return
+	}
 	if err != nil {
 		atomic.AddInt64(&stats.FailedRequests, 1)
+		atomic.AddInt64(&stats.TransportErrors, 1)
 		atomic.AddInt64(&stats.TotalRequests, 1)
 		return
 	}
@@ -193,38 +257,90 @@ func sendRequest(client *http.Client, url string, eventType string, stats *Stats
 	body, _ := io.ReadAll(resp.Body)
 	atomic.AddInt64(&stats.TotalBytes, int64(len(body)))
 
-	stats.mu.Lock()
-	stats.latencies = append(stats.latencies, latency)
-	if stats.MinLatency == 0 || latency < stats.MinLatency {
-		stats.MinLatency = latency
-	}
-	if latency > stats.MaxLatency {
-		stats.MaxLatency = latency
+	if !start.Before(stats.warmupUntil) {
+		rec.Observe(latency)
 	}
-	stats.mu.Unlock()
+	rec.ObserveStatus(bodyStatus(body))
 
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+	if isSuccess(resp.StatusCode, successCodes) {
 		atomic.AddInt64(&stats.SuccessRequests, 1)
 	} else {
 		atomic.AddInt64(&stats.FailedRequests, 1)
+		rec.ObserveFailedCode(resp.StatusCode)
 	}
 	atomic.AddInt64(&stats.TotalRequests, 1)
 }
 
-func worker(client *http.Client, url string, eventType string, stats *Stats, requests int, rateLimiter <-chan time.Time) {
+// isSuccess reports whether code counts as a successful response.
+func isSuccess(code int, successCodes map[int]bool) bool {
+	if len(successCodes) == 0 {
+		return code >= 200 && code < 300
+	}
+	return successCodes[code]
+}
+
+// bodyStatus extracts the top-level "status" field of a JSON response body,
+// e.g. received or skipped for the webhook endpoint.
+func bodyStatus(body []byte) string {
+	var data struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil || data.Status == "" {
+		return "unknown"
+	}
+	return data.Status
+}
+
+// parseSuccessCodes parses a comma-separated list of HTTP status codes.
+func parseSuccessCodes(s string) (map[int]bool, error) {
+	codes := map[int]bool{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		code, err := strconv.Atoi(part)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("invalid status code %q", part)
+		}
+		codes[code] = true
+	}
+	return codes, nil
+}
+
+// worker sends its share of requests, stopping early once ctx is cancelled.
+func worker(ctx context.Context, client *http.Client, url string, webhook webhookRequest, successCodes map[int]bool, stats *Stats, requests int, rateLimiter <-chan time.Time) {
+	rec := stats.NewRecorder(requests)
+	defer rec.Flush()
 	for i := 0; i < requests; i++ {
 		if rateLimiter != nil {
-			<-rateLimiter
+			select {
+			case <-rateLimiter:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if ctx.Err() != nil {
+			return
 		}
-		sendRequest(client, url, eventType, stats)
+		sendRequest(ctx, client, url, webhook, successCodes, stats, rec)
 	}
 }
 
-func runLoadTest(config Config) *Stats {
-	stats := &Stats{
-		latencies:  make([]time.Duration, 0, config.TotalRequests),
-		MinLatency: time.Hour,
+// rampupDelay returns when worker i of n starts so that the number of active
+// workers grows linearly from 1 to n over the ramp-up window.
+func rampupDelay(i, n int, rampup time.Duration) time.Duration {
+	if rampup <= 0 || n <= 1 {
+		return 0
 	}
+	return rampup * time.Duration(i) / time.Duration(n)
+}
+
+// runLoadTest runs the test and prints the summary. Cancelling ctx stops the
+// workers between requests; the summary then covers the requests completed
+// so far and is marked as interrupted.
+func runLoadTest(ctx context.Context, config Config) *Stats {
+	stats := &Stats{}
 
 	client := &http.Client{
 		Timeout: config.Timeout,
@@ -236,14 +352,21 @@ func runLoadTest(config Config) *Stats {
 
 	var rateLimiter <-chan time.Time
 	if config.QPS > 0 {
-		rateLimiter = time.Tick(time.Second / time.Duration(config.QPS))
+		ticker := time.NewTicker(time.Second / time.Duration(config.QPS))
+		defer ticker.Stop()
+		rateLimiter = ticker.C
 	}
 
+	webhook := buildWebhookRequest(config)
+
 	requestsPerWorker := config.TotalRequests / config.Concurrent
 	remaining := config.TotalRequests % config.Concurrent
 
 	var wg sync.WaitGroup
 	startTime := time.Now()
+	if config.RampupExclude {
+		stats.warmupUntil = startTime.Add(config.Rampup)
+	}
 
 	for i := 0; i < config.Concurrent; i++ {
 		wg.Add(1)
@@ -251,70 +374,30 @@ func runLoadTest(config Config) *Stats {
 		if i < remaining {
 			workerRequests++
 		}
+		delay := rampupDelay(i, config.Concurrent, config.Rampup)
 
 		go func() {
 			defer wg.Done()
-			worker(client, config.ServerURL+"/webhook", config.EventType, stats, workerRequests, rateLimiter)
+			if delay > 0 {
+				timer := time.NewTimer(delay)
+				defer timer.Stop()
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					return
+				}
+			}
+			worker(ctx, client, config.ServerURL+"/webhook", webhook, config.SuccessCodes, stats, workerRequests, rateLimiter)
 		}()
 	}
 
 	wg.Wait()
 	duration := time.Since(startTime)
 
-	fmt.Printf("\n\n")
-	fmt.Println("========================================")
-	fmt.Println("  Load Test Results")
-	fmt.Println("========================================")
-	fmt.Printf("Server URL:       %s\n", config.ServerURL)
-	fmt.Printf("Event Type:       %s\n", config.EventType)
-	fmt.Printf("Total Requests:   %d\n", config.TotalRequests)
-	fmt.Printf("Concurrent:       %d\n", config.Concurrent)
-	if config.QPS > 0 {
-		fmt.Printf("Rate Limit:       %d QPS\n", config.QPS)
-	}
-	fmt.Printf("Total Duration:   %v\n", duration)
-	fmt.Printf("\n")
-
-	success := atomic.LoadInt64(&stats.SuccessRequests)
-	failed := atomic.LoadInt64(&stats.FailedRequests)
-	totalBytes := atomic.LoadInt64(&stats.TotalBytes)
-
-	fmt.Printf("Results:\n")
-	fmt.Printf("  Success:         %d\n", success)
-	fmt.Printf("  Failed:          %d\n", failed)
-	fmt.Printf("  Success Rate:    %.2f%%\n", float64(success)*100/float64(config.TotalRequests))
-	fmt.Printf("  Throughput:      %.2f req/s\n", float64(config.TotalRequests)/duration.Seconds())
-	fmt.Printf("  Data Transferred: %.2f MB\n", float64(totalBytes)/(1024*1024))
-	fmt.Printf("\n")
-
-	if len(stats.latencies) > 0 {
-		// Calculate percentiles
-		sorted := make([]time.Duration, len(stats.latencies))
-		copy(sorted, stats.latencies)
-
-		// Simple bubble sort (good enough for small datasets)
-		for i := 0; i < len(sorted); i++ {
-			for j := i + 1; j < len(sorted); j++ {
-				if sorted[i] > sorted[j] {
-					sorted[i], sorted[j] = sorted[j], sorted[i]
-				}
-			}
-		}
-
-		p50 := sorted[len(sorted)*50/100]
-		p90 := sorted[len(sorted)*90/100]
-		p95 := sorted[len(sorted)*95/100]
-		p99 := sorted[len(sorted)*99/100]
-
-		fmt.Printf("Latency:\n")
-		fmt.Printf("  Min:             %v\n", stats.MinLatency)
-		fmt.Printf("  Max:             %v\n", stats.MaxLatency)
-		fmt.Printf("  Average:         %v\n", duration/time.Duration(config.TotalRequests))
-		fmt.Printf("  P50 (Median):    %v\n", p50)
-		fmt.Printf("  P90:             %v\n", p90)
-		fmt.Printf("  P95:             %v\n", p95)
-		fmt.Printf("  P99:             %v\n", p99)
-		fmt.Printf("\n")
+	result := Summarize(stats, config, duration)
+	result.Interrupted = ctx.Err() != nil
+	if err := writeResult(os.Stdout, config.Output, result, config.CSVHeader); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write results: %v\n", err)
 	}
 
 	return stats
@@ -358,6 +441,60 @@ func main() {
 					fmt.Sscanf(os.Args[i+1], "%d", &config.QPS)
 					i++
 				}
+			case "-success-codes":
+				if i+1 < len(os.Args) {
+					codes, err := parseSuccessCodes(os.Args[i+1])
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "-success-codes: %v\n", err)
+						os.Exit(2)
+					}
+					config.SuccessCodes = codes
+					i++
+				}
+			case "-payload":
+				if i+1 < len(os.Args) {
+					payload, err := loadPayloadFile(os.Args[i+1])
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "-payload: %v\n", err)
+						os.Exit(2)
+					}
+					config.Payload = payload
+					i++
+				}
+			case "-event":
+				if i+1 < len(os.Args) {
+					config.GitHubEvent = os.Args[i+1]
+					i++
+				}
+			case "-secret":
+				if i+1 < len(os.Args) {
+					config.Secret = os.Args[i+1]
+					i++
+				}
+			case "-output":
+				if i+1 < len(os.Args) {
+					format, err := parseOutputFormat(os.Args[i+1])
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "-output: %v\n", err)
+						os.Exit(2)
+					}
+					config.Output = format
+					i++
+				}
+			case "-csv-header":
+				config.CSVHeader = true
+			case "-rampup":
+				if i+1 < len(os.Args) {
+					d, err := time.ParseDuration(os.Args[i+1])
+					if err != nil || d < 0 {
+						fmt.Fprintf(os.Stderr, "-rampup: invalid duration %q\n", os.Args[i+1])
+						os.Exit(2)
+					}
+					config.Rampup = d
+					i++
+				}
+			case "-rampup-exclude":
+				config.RampupExclude = true
 			case "-timeout":
 				if i+1 < len(os.Args) {
 					timeoutSec, _ := fmt.Sscanf(os.Args[i+1], "%d", &config.Timeout)
@@ -377,6 +514,14 @@ func main() {
 				fmt.Println("  -n, -requests <n>    Total requests (default: 100)")
 				fmt.Println("  -qps <n>             Rate limit in queries per second (default: unlimited)")
 				fmt.Println("  -timeout <seconds>   Request timeout (default: 30)")
+				fmt.Println("  -success-codes <list> HTTP codes counted as success, e.g. 202 (default: any 2xx)")
+				fmt.Println("  -payload <file>      Send this JSON file as the body instead of the built-in payload")
+				fmt.Println("  -event <type>        X-GitHub-Event header (default: push, or pull_request for -type pr)")
+				fmt.Println("  -secret <key>        Sign requests with X-Hub-Signature-256 using this webhook secret")
+				fmt.Println("  -output <format>     Result format: text, json or csv (default: text)")
+				fmt.Println("  -csv-header          Print the CSV header row before the result (with -output csv)")
+				fmt.Println("  -rampup <duration>   Start workers gradually over this window, e.g. 10s (default: 0, all at once)")
+				fmt.Println("  -rampup-exclude      Leave requests sent during ramp-up out of the latency stats")
 				fmt.Println("  -h, --help           Show this help")
 				fmt.Println("\nExamples:")
 				fmt.Println("  # Basic load test")
@@ -385,6 +530,10 @@ func main() {
 				fmt.Println("  ./loadtest -url http://localhost:5001 -n 500 -c 20 -qps 100")
 				fmt.Println("\n  # Test PR events")
 				fmt.Println("  ./loadtest -url http://localhost:5001 -type pr -n 200 -c 10")
+				fmt.Println("\n  # Replay a captured payload against a server with signature checks")
+				fmt.Println("  ./loadtest -payload pr.json -event pull_request -secret $QUALITY_WEBHOOK_SECRET -n 500")
+				fmt.Println("\n  # Append one CSV row per run for dashboards")
+				fmt.Println("  ./loadtest -n 1000 -c 50 -output csv >> results.csv")
 				fmt.Println("\n  # Stress test")
 				fmt.Println("  ./loadtest -url http://localhost:5001 -n 10000 -c 100")
 				os.Exit(0)
@@ -403,19 +552,47 @@ func main() {
 		config.EventType = "push"
 	}
 
-	fmt.Println("========================================")
-	fmt.Println("  Quality Server Load Test")
-	fmt.Println("========================================")
-	fmt.Printf("Target:     %s\n", config.ServerURL)
-	fmt.Printf("Event:      %s\n", config.EventType)
-	fmt.Printf("Requests:   %d\n", config.TotalRequests)
-	fmt.Printf("Concurrent: %d\n", config.Concurrent)
-	if config.QPS > 0 {
-		fmt.Printf("Rate Limit: %d QPS\n", config.QPS)
+	// Machine-readable formats keep stdout to the result only.
+	if config.Output == "" || config.Output == outputText {
+		fmt.Println("========================================")
+		fmt.Println("  Quality Server Load Test")
+		fmt.Println("========================================")
+		fmt.Printf("Target:     %s\n", config.ServerURL)
+		fmt.Printf("Event:      %s\n", buildWebhookRequest(config).event)
+		if config.Payload != nil {
+			fmt.Printf("Payload:    %d bytes from -payload\n", len(config.Payload))
+		}
+		if config.Secret != "" {
+			fmt.Printf("Signed:     yes (X-Hub-Signature-256)\n")
+		}
+		fmt.Printf("Requests:   %d\n", config.TotalRequests)
+		fmt.Printf("Concurrent: %d\n", config.Concurrent)
+		if config.QPS > 0 {
+			fmt.Printf("Rate Limit: %d QPS\n", config.QPS)
+		}
+		if config.Rampup > 0 {
+			fmt.Printf("Ramp-up:    %v\n", config.Rampup)
+		}
+		fmt.Println("========================================")
+		fmt.Println("Starting load test...")
+		fmt.Println("========================================")
 	}
-	fmt.Println("========================================")
-	fmt.Println("Starting load test...")
-	fmt.Println("========================================")
 
-	runLoadTest(config)
+	runLoadTest(interruptContext(), config)
+}
+
+// interruptContext returns a context cancelled by the first Ctrl-C so the
+// run can stop and report partial results; a second Ctrl-C exits at once.
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt)
+	go func() {
+		<-sigs
+		fmt.Fprintln(os.Stderr, "\nInterrupted, waiting for in-flight requests (press Ctrl-C again to quit)...")
+		cancel()
+		<-sigs
+		os.Exit(130)
+	}()
+	return ctx
 }
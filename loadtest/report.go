@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Output formats for -output.
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputCSV  = "csv"
+)
+
+// Result is the summary of one load test run, shared by every output format.
+// Latencies are in milliseconds so dashboards can plot them directly.
+type Result struct {
+	Timestamp        time.Time        `json:"timestamp"`
+	ServerURL        string           `json:"server_url"`
+	Event            string           `json:"event"`
+	Concurrent       int              `json:"concurrent"`
+	QPS              int              `json:"qps"`
+	RampupSeconds    float64          `json:"rampup_seconds,omitempty"`
+	RampupExcluded   bool             `json:"rampup_excluded,omitempty"`
+	TotalRequests    int64            `json:"total_requests"`
+	Success          int64            `json:"success"`
+	Failed           int64            `json:"failed"`
+	FailedByCode     map[int]int64    `json:"failed_by_code,omitempty"`
+	TransportErrors  int64            `json:"transport_errors"`
+	SuccessRate      float64          `json:"success_rate"`
+	DurationSeconds  float64          `json:"duration_seconds"`
+	Throughput       float64          `json:"throughput_rps"`
+	BytesTransferred int64            `json:"bytes_transferred"`
+	StatusBreakdown  map[string]int64 `json:"status_breakdown,omitempty"`
+	Latency          LatencySummary   `json:"latency_ms"`
+	Interrupted      bool             `json:"interrupted,omitempty"` // stopped early by Ctrl-C
+
+	duration time.Duration
+}
+
+// LatencySummary holds latency statistics in milliseconds.
+type LatencySummary struct {
+	Samples int     `json:"samples"`
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+	Mean    float64 `json:"mean"`
+	P50     float64 `json:"p50"`
+	P90     float64 `json:"p90"`
+	P95     float64 `json:"p95"`
+	P99     float64 `json:"p99"`
+	P999    float64 `json:"p99_9"`
+}
+
+// Summarize computes the run summary from the collected stats.
+func Summarize(stats *Stats, config Config, duration time.Duration) Result {
+	total := atomic.LoadInt64(&stats.TotalRequests)
+	success := atomic.LoadInt64(&stats.SuccessRequests)
+	r := Result{
+		Timestamp:        time.Now().UTC(),
+		ServerURL:        config.ServerURL,
+		Event:            buildWebhookRequest(config).event,
+		Concurrent:       config.Concurrent,
+		QPS:              config.QPS,
+		RampupSeconds:    config.Rampup.Seconds(),
+		RampupExcluded:   config.RampupExclude && config.Rampup > 0,
+		TotalRequests:    total,
+		Success:          success,
+		Failed:           atomic.LoadInt64(&stats.FailedRequests),
+		FailedByCode:     stats.FailedCodes(),
+		TransportErrors:  atomic.LoadInt64(&stats.TransportErrors),
+		DurationSeconds:  duration.Seconds(),
+		BytesTransferred: atomic.LoadInt64(&stats.TotalBytes),
+		StatusBreakdown:  stats.StatusBreakdown(),
+		duration:         duration,
+	}
+	if total > 0 {
+		r.SuccessRate = float64(success) * 100 / float64(total)
+	}
+	if duration > 0 {
+		r.Throughput = float64(total) / duration.Seconds()
+	}
+	if n := stats.Samples(); n > 0 {
+		r.Latency = LatencySummary{
+			Samples: n,
+			Min:     millis(stats.MinLatency()),
+			Max:     millis(stats.MaxLatency()),
+			Mean:    millis(stats.Mean()),
+			P50:     millis(stats.Percentile(50)),
+			P90:     millis(stats.Percentile(90)),
+			P95:     millis(stats.Percentile(95)),
+			P99:     millis(stats.Percentile(99)),
+			P999:    millis(stats.Percentile(99.9)),
+		}
+	}
+	return r
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func fromSeconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func fromMillis(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// parseOutputFormat validates a -output value.
+func parseOutputFormat(s string) (string, error) {
+	switch s {
+	case outputText, outputJSON, outputCSV:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown output format %q (want text, json or csv)", s)
+}
+
+// writeResult writes r in the given format.
+func writeResult(w io.Writer, format string, r Result, csvHeader bool) error {
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case outputCSV:
+		return writeCSV(w, r, csvHeader)
+	default:
+		writeText(w, r)
+		return nil
+	}
+}
+
+// csvColumns are the CSV columns, in order. The status and failure-code
+// breakdowns are left out because their keys vary between runs.
+var csvColumns = []string{
+	"timestamp", "server_url", "event", "concurrent", "qps",
+	"total_requests", "success", "failed", "success_rate",
+	"duration_seconds", "throughput_rps", "bytes_transferred",
+	"latency_samples", "min_ms", "max_ms", "mean_ms",
+	"p50_ms", "p90_ms", "p95_ms", "p99_ms", "p99_9_ms",
+	"transport_errors", "interrupted",
+}
+
+// writeCSV writes one row per run so results can be appended to one file;
+// the header is written only when requested.
+func writeCSV(w io.Writer, r Result, header bool) error {
+	cw := csv.NewWriter(w)
+	if header {
+		if err := cw.Write(csvColumns); err != nil {
+			return err
+		}
+	}
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 3, 64) }
+	l := r.Latency
+	row := []string{
+		r.Timestamp.Format(time.RFC3339), r.ServerURL, r.Event,
+		strconv.Itoa(r.Concurrent), strconv.Itoa(r.QPS),
+		strconv.FormatInt(r.TotalRequests, 10), strconv.FormatInt(r.Success, 10), strconv.FormatInt(r.Failed, 10),
+		f(r.SuccessRate), f(r.DurationSeconds), f(r.Throughput), strconv.FormatInt(r.BytesTransferred, 10),
+		strconv.Itoa(l.Samples), f(l.Min), f(l.Max), f(l.Mean),
+		f(l.P50), f(l.P90), f(l.P95), f(l.P99), f(l.P999),
+		strconv.FormatInt(r.TransportErrors, 10), strconv.FormatBool(r.Interrupted),
+	}
+	if err := cw.Write(row); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeText prints the human-readable report.
+func writeText(w io.Writer, r Result) {
+	fmt.Fprintf(w, "\n\n")
+	fmt.Fprintln(w, "========================================")
+	fmt.Fprintln(w, "  Load Test Results")
+	fmt.Fprintln(w, "========================================")
+	if r.Interrupted {
+		fmt.Fprintln(w, "Interrupted:      partial results for the requests completed so far")
+	}
+	fmt.Fprintf(w, "Server URL:       %s\n", r.ServerURL)
+	fmt.Fprintf(w, "Event Type:       %s\n", r.Event)
+	fmt.Fprintf(w, "Total Requests:   %d\n", r.TotalRequests)
+	fmt.Fprintf(w, "Concurrent:       %d\n", r.Concurrent)
+	if r.QPS > 0 {
+		fmt.Fprintf(w, "Rate Limit:       %d QPS\n", r.QPS)
+	}
+	if r.RampupSeconds > 0 {
+		note := ""
+		if r.RampupExcluded {
+			note = " (excluded from latency)"
+		}
+		fmt.Fprintf(w, "Ramp-up:          %v%s\n", fromSeconds(r.RampupSeconds), note)
+	}
+	fmt.Fprintf(w, "Total Duration:   %v\n", r.duration)
+	fmt.Fprintf(w, "\n")
+
+	fmt.Fprintf(w, "Results:\n")
+	fmt.Fprintf(w, "  Success:         %d\n", r.Success)
+	fmt.Fprintf(w, "  Failed:          %d\n", r.Failed)
+	if r.Failed > 0 {
+		fmt.Fprintf(w, "  Failures:        %s\n", failureBreakdown(r.FailedByCode, r.TransportErrors))
+	}
+	fmt.Fprintf(w, "  Success Rate:    %.2f%%\n", r.SuccessRate)
+	fmt.Fprintf(w, "  Throughput:      %.2f req/s\n", r.Throughput)
+	fmt.Fprintf(w, "  Data Transferred: %.2f MB\n", float64(r.BytesTransferred)/(1024*1024))
+	fmt.Fprintf(w, "\n")
+
+	if len(r.StatusBreakdown) > 0 {
+		statuses := make([]string, 0, len(r.StatusBreakdown))
+		for status := range r.StatusBreakdown {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+		fmt.Fprintf(w, "Response Status:\n")
+		for _, status := range statuses {
+			fmt.Fprintf(w, "  %-16s %d\n", status+":", r.StatusBreakdown[status])
+		}
+		fmt.Fprintf(w, "\n")
+	}
+
+	if l := r.Latency; l.Samples > 0 {
+		fmt.Fprintf(w, "Latency:\n")
+		fmt.Fprintf(w, "  Min:             %v\n", fromMillis(l.Min))
+		fmt.Fprintf(w, "  Max:             %v\n", fromMillis(l.Max))
+		fmt.Fprintf(w, "  Average:         %v\n", fromMillis(l.Mean))
+		fmt.Fprintf(w, "  P50 (Median):    %v\n", fromMillis(l.P50))
+		fmt.Fprintf(w, "  P90:             %v\n", fromMillis(l.P90))
+		fmt.Fprintf(w, "  P95:             %v\n", fromMillis(l.P95))
+		fmt.Fprintf(w, "  P99:             %v\n", fromMillis(l.P99))
+		fmt.Fprintf(w, "  P99.9:           %v\n", fromMillis(l.P999))
+		fmt.Fprintf(w, "\n")
+	}
+}
+
+// failureBreakdown formats failures as "503: 1240, 500: 12, transport errors: 3",
+// most frequent status code first.
+func failureBreakdown(codes map[int]int64, transportErrors int64) string {
+	keys := make([]int, 0, len(codes))
+	for code := range codes {
+		keys = append(keys, code)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if codes[keys[i]] != codes[keys[j]] {
+			return codes[keys[i]] > codes[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	parts := make([]string, 0, len(keys)+1)
+	for _, code := range keys {
+		parts = append(parts, fmt.Sprintf("%d: %d", code, codes[code]))
+	}
+	if transportErrors > 0 || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("transport errors: %d", transportErrors))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Statistics for load testing.
+//
+// Counters and min/max are updated atomically. Latencies are collected by each
+// worker in its own Recorder and merged once when the worker finishes, so the
+// hot path never takes a lock.
+type Stats struct {
+	TotalRequests   int64
+	SuccessRequests int64
+	FailedRequests  int64
+	TransportErrors int64 // failures without an HTTP response (connection refused, timeout, ...)
+	TotalBytes      int64
+
+	minLatency int64 // nanoseconds, 0 = no sample yet
+	maxLatency int64 // nanoseconds
+
+	// warmupUntil is set before workers start; requests sent earlier are
+	// counted but left out of the latency stats (-rampup-exclude).
+	warmupUntil time.Time
+
+	mu          sync.Mutex // guards latencies, bodyStatus and failedCodes
+	latencies   []time.Duration
+	sorted      bool
+	bodyStatus  map[string]int64
+	failedCodes map[int]int64
+}
+
+// Recorder collects latencies for a single worker without synchronization.
+type Recorder struct {
+	stats       *Stats
+	latencies   []time.Duration
+	bodyStatus  map[string]int64
+	failedCodes map[int]int64
+}
+
+// NewRecorder returns a Recorder for one worker; sizeHint preallocates its buffer.
+func (s *Stats) NewRecorder(sizeHint int) *Recorder {
+	return &Recorder{
+		stats:       s,
+		latencies:   make([]time.Duration, 0, sizeHint),
+		bodyStatus:  map[string]int64{},
+		failedCodes: map[int]int64{},
+	}
+}
+
+// Observe records one latency sample.
+func (r *Recorder) Observe(latency time.Duration) {
+	r.latencies = append(r.latencies, latency)
+	r.stats.observeMinMax(latency)
+}
+
+// ObserveStatus counts a response by the "status" field of its JSON body
+// (received, skipped, ...).
+func (r *Recorder) ObserveStatus(status string) {
+	r.bodyStatus[status]++
+}
+
+// ObserveFailedCode counts a response whose HTTP status was not a success code.
+func (r *Recorder) ObserveFailedCode(code int) {
+	r.failedCodes[code]++
+}
+
+// Flush merges the recorded samples into the shared Stats.
+func (r *Recorder) Flush() {
+	r.stats.merge(r.latencies, r.bodyStatus, r.failedCodes)
+	r.latencies = nil
+	r.bodyStatus = map[string]int64{}
+	r.failedCodes = map[int]int64{}
+}
+
+// observeMinMax updates min/max latency with compare-and-swap.
+func (s *Stats) observeMinMax(latency time.Duration) {
+	v := int64(latency)
+	for {
+		cur := atomic.LoadInt64(&s.minLatency)
+		if cur != 0 && cur <= v {
+			break
+		}
+		if atomic.CompareAndSwapInt64(&s.minLatency, cur, v) {
+			break
+		}
+	}
+	for {
+		cur := atomic.LoadInt64(&s.maxLatency)
+		if cur >= v {
+			break
+		}
+		if atomic.CompareAndSwapInt64(&s.maxLatency, cur, v) {
+			break
+		}
+	}
+}
+
+func (s *Stats) merge(latencies []time.Duration, bodyStatus map[string]int64, failedCodes map[int]int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(latencies) > 0 {
+		s.latencies = append(s.latencies, latencies...)
+		s.sorted = false
+	}
+	for status, n := range bodyStatus {
+		if s.bodyStatus == nil {
+			s.bodyStatus = map[string]int64{}
+		}
+		s.bodyStatus[status] += n
+	}
+	for code, n := range failedCodes {
+		if s.failedCodes == nil {
+			s.failedCodes = map[int]int64{}
+		}
+		s.failedCodes[code] += n
+	}
+}
+
+// StatusBreakdown returns response counts keyed by the body "status" field.
+func (s *Stats) StatusBreakdown() map[string]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]int64, len(s.bodyStatus))
+	for k, v := range s.bodyStatus {
+		out[k] = v
+	}
+	return out
+}
+
+// FailedCodes returns failed response counts keyed by HTTP status code.
+// Transport errors are counted separately in TransportErrors.
+func (s *Stats) FailedCodes() map[int]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[int]int64, len(s.failedCodes))
+	for k, v := range s.failedCodes {
+		out[k] = v
+	}
+	return out
+}
+
+// MinLatency returns the smallest observed latency.
+func (s *Stats) MinLatency() time.Duration {
+	return time.Duration(atomic.LoadInt64(&s.minLatency))
+}
+
+// MaxLatency returns the largest observed latency.
+func (s *Stats) MaxLatency() time.Duration {
+	return time.Duration(atomic.LoadInt64(&s.maxLatency))
+}
+
+// Samples returns the number of merged latency samples.
+func (s *Stats) Samples() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.latencies)
+}
+
+// Mean returns the average of the merged latencies (0 without samples).
+func (s *Stats) Mean() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.latencies) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, l := range s.latencies {
+		sum += l
+	}
+	return sum / time.Duration(len(s.latencies))
+}
+
+// Percentile returns the p-th percentile (0-100, fractional values such as
+// 99.9 allowed) of the merged latencies using the nearest-rank method: the
+// smallest sample with at least p% of all samples at or below it.
+func (s *Stats) Percentile(p float64) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.latencies) == 0 {
+		return 0
+	}
+	if !s.sorted {
+		sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+		s.sorted = true
+	}
+	return s.latencies[nearestRank(p, len(s.latencies))-1]
+}
+
+// nearestRank returns the 1-based rank ceil(p/100*n), clamped to [1, n].
+// The small epsilon keeps exact ranks such as 99.9% of 1000 from rounding up
+// because of floating-point error.
+func nearestRank(p float64, n int) int {
+	rank := int(math.Ceil(p/100*float64(n) - 1e-9))
+	if rank < 1 {
+		return 1
+	}
+	if rank > n {
+		return n
+	}
+	return rank
+}
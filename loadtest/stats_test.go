@@ -0,0 +1,435 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStats_MergedPercentiles(t *testing.T) {
+	const workers, perWorker = 8, 250
+	stats := &Stats{}
+	var all []time.Duration
+	for w := 0; w < workers; w++ {
+		for i := 0; i < perWorker; i++ {
+			all = append(all, time.Duration(i*workers+w+1)*time.Microsecond)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rec := stats.NewRecorder(perWorker)
+			defer rec.Flush()
+			for i := 0; i < perWorker; i++ {
+				rec.Observe(all[w*perWorker+i])
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	sorted := append([]time.Duration(nil), all...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	if got := stats.Samples(); got != len(all) {
+		t.Fatalf("samples=%d, want %d", got, len(all))
+	}
+	// 2000 samples: nearest rank is ceil(p/100*2000)
+	for _, tc := range []struct {
+		p    float64
+		rank int
+	}{{50, 1000}, {90, 1800}, {95, 1900}, {99, 1980}, {99.9, 1998}} {
+		if got, want := stats.Percentile(tc.p), sorted[tc.rank-1]; got != want {
+			t.Errorf("p%v=%v, want %v", tc.p, got, want)
+		}
+	}
+	if stats.MinLatency() != sorted[0] || stats.MaxLatency() != sorted[len(sorted)-1] {
+		t.Errorf("min/max=%v/%v, want %v/%v", stats.MinLatency(), stats.MaxLatency(), sorted[0], sorted[len(sorted)-1])
+	}
+}
+
+func TestNearestRank(t *testing.T) {
+	tests := []struct {
+		p    float64
+		n    int
+		want int
+	}{
+		{50, 1, 1},
+		{99, 1, 1},
+		{0, 10, 1},
+		{50, 10, 5},
+		{90, 10, 9},
+		{99, 10, 10},
+		{100, 10, 10},
+		{99, 100, 99},
+		{99.9, 1000, 999},
+		{99.9, 100000, 99900},
+		{99.9, 10, 10},
+	}
+	for _, tt := range tests {
+		if got := nearestRank(tt.p, tt.n); got != tt.want {
+			t.Errorf("nearestRank(%v, %d)=%d, want %d", tt.p, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestStats_SmallSamplePercentiles(t *testing.T) {
+	stats := &Stats{}
+	rec := stats.NewRecorder(0)
+	for _, ms := range []int{5, 1, 4, 2, 3} {
+		rec.Observe(time.Duration(ms) * time.Millisecond)
+	}
+	rec.Flush()
+	// With 5 samples, P99 and P99.9 are the maximum, not an out-of-range index.
+	if got := stats.Percentile(99); got != 5*time.Millisecond {
+		t.Errorf("p99=%v, want 5ms", got)
+	}
+	if got := stats.Percentile(99.9); got != 5*time.Millisecond {
+		t.Errorf("p99.9=%v, want 5ms", got)
+	}
+	if got := stats.Percentile(50); got != 3*time.Millisecond {
+		t.Errorf("p50=%v, want 3ms", got)
+	}
+}
+
+func TestStats_EmptyPercentile(t *testing.T) {
+	stats := &Stats{}
+	if stats.Percentile(99) != 0 || stats.MinLatency() != 0 || stats.MaxLatency() != 0 {
+		t.Fatal("expected zero values without samples")
+	}
+}
+
+// BenchmarkRecorder_Observe measures the per-request cost on the hot path,
+// which no longer contends on a shared mutex.
+func BenchmarkRecorder_Observe(b *testing.B) {
+	stats := &Stats{}
+	b.RunParallel(func(pb *testing.PB) {
+		rec := stats.NewRecorder(0)
+		var i time.Duration
+		for pb.Next() {
+			i++
+			rec.Observe(i % time.Millisecond)
+		}
+		rec.Flush()
+	})
+}
+
+// BenchmarkMutexAppend is the previous approach, kept for comparison.
+func BenchmarkMutexAppend(b *testing.B) {
+	var mu sync.Mutex
+	var latencies []time.Duration
+	var minL, maxL time.Duration
+	b.RunParallel(func(pb *testing.PB) {
+		var i time.Duration
+		for pb.Next() {
+			i++
+			l := i % time.Millisecond
+			mu.Lock()
+			latencies = append(latencies, l)
+			if minL == 0 || l < minL {
+				minL = l
+			}
+			if l > maxL {
+				maxL = l
+			}
+			mu.Unlock()
+		}
+	})
+}
+
+func TestRunLoadTest_StatusBreakdown(t *testing.T) {
+	var n int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&n, 1)%2 == 0 {
+			_, _ = w.Write([]byte(`{"status":"skipped","message":"event type ignored"}`))
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"status":"received","message":"queued"}`))
+	}))
+	defer server.Close()
+
+	stats := runLoadTest(context.Background(), Config{
+		ServerURL:     server.URL,
+		EventType:     "push",
+		Concurrent:    2,
+		TotalRequests: 10,
+		Timeout:       5 * time.Second,
+		SuccessCodes:  map[int]bool{http.StatusAccepted: true},
+	})
+
+	breakdown := stats.StatusBreakdown()
+	if breakdown["received"] != 5 || breakdown["skipped"] != 5 {
+		t.Fatalf("unexpected breakdown: %v", breakdown)
+	}
+	if stats.SuccessRequests != 5 || stats.FailedRequests != 5 {
+		t.Fatalf("success=%d failed=%d, want 5/5 with -success-codes 202", stats.SuccessRequests, stats.FailedRequests)
+	}
+	if codes := stats.FailedCodes(); codes[http.StatusOK] != 5 || len(codes) != 1 {
+		t.Fatalf("expected the 5 failures under 200, got %v", codes)
+	}
+}
+
+func TestRunLoadTest_FailuresByCode(t *testing.T) {
+	var n int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&n, 1) % 4 {
+		case 0:
+			w.WriteHeader(http.StatusInternalServerError)
+		case 1, 2:
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	stats := runLoadTest(context.Background(), Config{
+		ServerURL:     server.URL,
+		EventType:     "push",
+		Concurrent:    1,
+		TotalRequests: 8,
+		Timeout:       5 * time.Second,
+	})
+	codes := stats.FailedCodes()
+	if codes[http.StatusTooManyRequests] != 4 || codes[http.StatusInternalServerError] != 2 || stats.FailedRequests != 6 {
+		t.Fatalf("unexpected failures %v (failed=%d)", codes, stats.FailedRequests)
+	}
+
+	// Nothing listens on a closed server's address.
+	server.Close()
+	stats = runLoadTest(context.Background(), Config{ServerURL: server.URL, EventType: "push", Concurrent: 1, TotalRequests: 3, Timeout: time.Second})
+	if stats.TransportErrors != 3 || stats.FailedRequests != 3 || len(stats.FailedCodes()) != 0 {
+		t.Fatalf("expected 3 transport errors, got transport=%d failed=%d codes=%v",
+			stats.TransportErrors, stats.FailedRequests, stats.FailedCodes())
+	}
+}
+
+func TestFailureBreakdown(t *testing.T) {
+	got := failureBreakdown(map[int]int64{500: 12, 503: 1240, 429: 12}, 3)
+	if want := "503: 1240, 429: 12, 500: 12, transport errors: 3"; got != want {
+		t.Errorf("failureBreakdown = %q, want %q", got, want)
+	}
+	if got := failureBreakdown(map[int]int64{502: 1}, 0); got != "502: 1" {
+		t.Errorf("failureBreakdown without transport errors = %q", got)
+	}
+}
+
+func TestParseSuccessCodes(t *testing.T) {
+	codes, err := parseSuccessCodes("200, 202")
+	if err != nil || !codes[200] || !codes[202] || len(codes) != 2 {
+		t.Fatalf("codes=%v err=%v", codes, err)
+	}
+	if _, err := parseSuccessCodes("2xx"); err == nil {
+		t.Fatal("expected error for non-numeric code")
+	}
+}
+
+func TestRunLoadTest_PayloadFileAndSignature(t *testing.T) {
+	const secret = "s3cret"
+	body := []byte(`{"event_type":"push","repository":"o/r","branch":"main"}`)
+	path := filepath.Join(t.TempDir(), "payload.json")
+	if err := os.WriteFile(path, body, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	payload, err := loadPayloadFile(path)
+	if err != nil {
+		t.Fatalf("loadPayloadFile: %v", err)
+	}
+
+	var bad int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(got)
+		want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if string(got) != string(body) || r.Header.Get("X-GitHub-Event") != "custom" || r.Header.Get("X-Hub-Signature-256") != want {
+			atomic.AddInt32(&bad, 1)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"received"}`))
+	}))
+	defer server.Close()
+
+	stats := runLoadTest(context.Background(), Config{
+		ServerURL:     server.URL,
+		EventType:     "push",
+		Concurrent:    2,
+		TotalRequests: 6,
+		Timeout:       5 * time.Second,
+		Payload:       payload,
+		GitHubEvent:   "custom",
+		Secret:        secret,
+	})
+	if bad != 0 || stats.SuccessRequests != 6 {
+		t.Fatalf("expected 6 signed custom requests, got %d successes and %d bad", stats.SuccessRequests, bad)
+	}
+}
+
+func TestLoadPayloadFile_InvalidJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.json")
+	if err := os.WriteFile(path, []byte(`{"ref":`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadPayloadFile(path); err == nil {
+		t.Fatal("expected invalid JSON to be rejected")
+	}
+	if _, err := loadPayloadFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("expected a missing file to be rejected")
+	}
+}
+
+func TestBuildWebhookRequest_Defaults(t *testing.T) {
+	req := buildWebhookRequest(Config{EventType: "pr"})
+	if req.event != "pull_request" || string(req.body) != string(prPayload) || req.signature != "" {
+		t.Fatalf("unexpected defaults for -type pr: event=%q signed=%v", req.event, req.signature != "")
+	}
+	if req := buildWebhookRequest(Config{EventType: "push", Secret: "k"}); req.signature != signPayload("k", pushPayload) {
+		t.Fatalf("expected the built-in payload to be signed, got %q", req.signature)
+	}
+}
+
+func TestSummarizeAndFormats(t *testing.T) {
+	stats := &Stats{TotalRequests: 4, SuccessRequests: 3, FailedRequests: 1, TotalBytes: 2048}
+	rec := stats.NewRecorder(0)
+	for _, ms := range []int{10, 20, 30, 40} {
+		rec.Observe(time.Duration(ms) * time.Millisecond)
+	}
+	rec.ObserveStatus("received")
+	rec.Flush()
+
+	r := Summarize(stats, Config{ServerURL: "http://x", EventType: "pr", Concurrent: 2}, 2*time.Second)
+	if r.Event != "pull_request" || r.SuccessRate != 75 || r.Throughput != 2 {
+		t.Fatalf("unexpected summary %+v", r)
+	}
+	if l := r.Latency; l.Samples != 4 || l.Min != 10 || l.Max != 40 || l.Mean != 25 || l.P50 != 20 || l.P999 != 40 {
+		t.Fatalf("unexpected latency summary %+v", l)
+	}
+
+	var js bytes.Buffer
+	if err := writeResult(&js, outputJSON, r, false); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if decoded["success"] != float64(3) || decoded["latency_ms"].(map[string]interface{})["p99_9"] != float64(40) {
+		t.Errorf("unexpected JSON output: %s", js.String())
+	}
+
+	var row, withHeader bytes.Buffer
+	if err := writeResult(&row, outputCSV, r, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeResult(&withHeader, outputCSV, r, true); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&withHeader).ReadAll()
+	if err != nil || len(records) != 2 || len(records[0]) != len(records[1]) {
+		t.Fatalf("expected header and one row of equal width, got %v (%v)", records, err)
+	}
+	if strings.Count(row.String(), "\n") != 1 || records[1][5] != "4" {
+		t.Errorf("unexpected CSV row %q", row.String())
+	}
+
+	var text bytes.Buffer
+	if err := writeResult(&text, outputText, r, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Success Rate:    75.00%", "Average:         25ms", "P99.9:           40ms", "received:"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text output missing %q:\n%s", want, text.String())
+		}
+	}
+
+	if _, err := parseOutputFormat("xml"); err == nil {
+		t.Error("expected unknown format to be rejected")
+	}
+}
+
+func TestRunLoadTest_CancelStopsEarly(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var n int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&n, 1) == 5 {
+			cancel()
+		}
+	}))
+	defer server.Close()
+
+	stats := runLoadTest(ctx, Config{
+		ServerURL:     server.URL,
+		EventType:     "push",
+		Concurrent:    1,
+		TotalRequests: 1000,
+		Timeout:       5 * time.Second,
+		Output:        outputJSON,
+	})
+	if stats.TotalRequests < 4 || stats.TotalRequests > 5 {
+		t.Fatalf("expected the run to stop after the 5th request, got %d", stats.TotalRequests)
+	}
+	if stats.FailedRequests != 0 {
+		t.Errorf("aborted requests should not count as failures, got %d", stats.FailedRequests)
+	}
+}
+
+func TestRampupDelay(t *testing.T) {
+	for _, tt := range []struct {
+		i, n   int
+		rampup time.Duration
+		want   time.Duration
+	}{
+		{0, 4, 8 * time.Second, 0},
+		{1, 4, 8 * time.Second, 2 * time.Second},
+		{3, 4, 8 * time.Second, 6 * time.Second},
+		{3, 4, 0, 0},
+		{0, 1, time.Second, 0},
+	} {
+		if got := rampupDelay(tt.i, tt.n, tt.rampup); got != tt.want {
+			t.Errorf("rampupDelay(%d, %d, %v) = %v, want %v", tt.i, tt.n, tt.rampup, got, tt.want)
+		}
+	}
+}
+
+func TestRunLoadTest_RampupExclude(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(2 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	stats := runLoadTest(context.Background(), Config{
+		ServerURL:     server.URL,
+		EventType:     "push",
+		Concurrent:    2,
+		TotalRequests: 40,
+		Timeout:       5 * time.Second,
+		Rampup:        20 * time.Millisecond,
+		RampupExclude: true,
+		Output:        outputJSON,
+	})
+	if stats.TotalRequests != 40 || stats.SuccessRequests != 40 {
+		t.Fatalf("ramp-up must not change the request count, got %d/%d", stats.SuccessRequests, stats.TotalRequests)
+	}
+	if n := stats.Samples(); n == 0 || n >= 40 {
+		t.Errorf("expected ramp-up requests to be left out of the latency samples, got %d of 40", n)
+	}
+}